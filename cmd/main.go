@@ -1,8 +1,12 @@
 package main
 
 import (
+	"os"
+
+	"github.com/dansksupermarked/mariadb-galera-operator/pkg/agent"
 	"github.com/dansksupermarked/mariadb-galera-operator/pkg/initializer"
 	"github.com/dansksupermarked/mariadb-galera-operator/pkg/operator"
+	"github.com/dansksupermarked/mariadb-galera-operator/pkg/webhook"
 	"github.com/spf13/cobra"
 )
 
@@ -17,14 +21,40 @@ func main() {
 		},
 	}
 
+	op := operator.NewOperator()
+
 	var clusterCmd = &cobra.Command{
 		Use:   "cluster",
 		Short: "Run the cluster operator",
 		Run: func(cmd *cobra.Command, args []string) {
-			op := operator.NewOperator()
 			op.Start()
 		},
 	}
+	clusterCmd.Flags().StringVar(&op.Webhook.Addr, "webhook-addr", webhook.DefaultAddr, "Address the admission webhook listens on")
+	clusterCmd.Flags().StringVar(&op.Webhook.CertFile, "webhook-cert-file", "", "TLS certificate of the admission webhook, the webhook only runs when set")
+	clusterCmd.Flags().StringVar(&op.Webhook.KeyFile, "webhook-key-file", "", "TLS key of the admission webhook")
+	clusterCmd.Flags().BoolVar(&op.UpgradeCRDs, "upgrade-crds", false, "Update an existing MariaDBCluster CRD to the one built into the operator")
+	clusterCmd.Flags().StringVar(&op.ConversionService, "conversion-service", "", "Service of the webhook as namespace/name, v1beta1 is only served through its conversion webhook when set")
+	clusterCmd.Flags().StringVar(&op.ConversionCAFile, "conversion-ca-file", "", "CA of the webhook certificate, for the API server to call the conversion webhook")
+	clusterCmd.Flags().BoolVar(&op.MigrateStorage, "migrate-storage", false, "Rewrite every MariaDBCluster in the storage version and drop older versions from the stored versions of the CRD")
+	clusterCmd.Flags().IntVar(&op.Workers, "workers", operator.DefaultWorkers, "Number of clusters reconciled concurrently")
+	clusterCmd.Flags().StringVar(&op.LeaderElectionLock, "leader-election-lock", operator.LeasesResourceLock, "Kind of object leader election is held in: leases, endpoints or configmaps")
+	clusterCmd.Flags().StringVar(&op.LeaderElectionNamespace, "leader-election-namespace", "", "Namespace of the leader election lock, the one of the operator pod by default")
+	clusterCmd.Flags().StringVar(&op.Namespace, "watch-namespace", os.Getenv("WATCH_NAMESPACE"), "Namespace clusters are watched in, all of them when empty, defaults to $WATCH_NAMESPACE")
+	clusterCmd.Flags().StringVar(&op.InstanceSelector, "instance-selector", "", "Label selector of the clusters managed by this operator, all of them when empty")
+	clusterCmd.Flags().DurationVar(&op.ResyncPeriod, "resync-period", operator.DefaultResyncPeriod, "Period every cluster is reconciled again on, correcting drift of changes missed, 0 disables it")
+	clusterCmd.Flags().DurationVar(&op.RetryBaseDelay, "retry-base-delay", operator.DefaultRetryBaseDelay, "Backoff of the first retry of a cluster failing to reconcile, doubled on each retry")
+	clusterCmd.Flags().DurationVar(&op.RetryMaxDelay, "retry-max-delay", operator.DefaultRetryMaxDelay, "Longest backoff between retries of a cluster failing to reconcile")
+	clusterCmd.Flags().Float64Var(&op.RetryQPS, "retry-qps", operator.DefaultRetryQPS, "Retries per second of all clusters failing to reconcile")
+	clusterCmd.Flags().IntVar(&op.RetryBurst, "retry-burst", operator.DefaultRetryBurst, "Retries of all clusters allowed at once above --retry-qps")
+	clusterCmd.Flags().DurationVar(&op.Debounce, "debounce", operator.DefaultDebounce, "Delay of reconciling a cluster after an event, coalescing further events within it, 0 reconciles right away")
+	clusterCmd.Flags().DurationVar(&op.EventDedupWindow, "event-dedup-window", operator.DefaultEventDedupWindow, "Window an Event repeating the last one of its object and reason is left out within, 0 records all")
+	clusterCmd.Flags().DurationVar(&op.LogSampleWindow, "log-sample-window", operator.DefaultLogSampleWindow, "Window an info or debug line repeating one is left out within, 0 logs all")
+	clusterCmd.Flags().Float32Var(&op.KubeAPIQPS, "kube-api-qps", 0, "Requests per second to the API server, the client-go default of 5 when 0")
+	clusterCmd.Flags().IntVar(&op.KubeAPIBurst, "kube-api-burst", 0, "Requests to the API server allowed at once above --kube-api-qps, the client-go default of 10 when 0")
+	clusterCmd.Flags().IntVar(&op.MaxInflightRequests, "max-inflight-requests", 0, "Requests to the API server in flight at once, watches aside, unbounded when 0")
+	clusterCmd.Flags().BoolVar(&op.DryRun, "dry-run", false, "Only log and record Events of the changes the operator would make, sent to the API server as dry runs")
+	clusterCmd.Flags().StringVar(&op.LeaderElectionID, "leader-election-id", op.LeaderElectionID, "Name of the leader election lock, distinct for each operator of a different instance selector")
 
 	i := &initializer.Initializer{}
 
@@ -36,7 +66,29 @@ func main() {
 		},
 	}
 
+	t := &initializer.KernelTuner{}
+
+	var tuneCmd = &cobra.Command{
+		Use:   "tune [name=value...]",
+		Short: "Run inside an InitContainer of cluster pods, setting or checking sysctls of the node",
+		Run: func(cmd *cobra.Command, args []string) {
+			t.Run(args)
+		},
+	}
+
+	a := &agent.Agent{}
+
+	var agentCmd = &cobra.Command{
+		Use:   "agent",
+		Short: "Run as agent container of cluster pods, reporting galera state to MariaDBCluster object",
+		Run: func(cmd *cobra.Command, args []string) {
+			a.Run()
+		},
+	}
+
 	rootCmd.AddCommand(clusterCmd)
 	rootCmd.AddCommand(initCmd)
+	rootCmd.AddCommand(tuneCmd)
+	rootCmd.AddCommand(agentCmd)
 	rootCmd.Execute()
 }
@@ -0,0 +1,65 @@
+#!/usr/bin/env bash
+
+# Creates CLUSTERS single server clusters in NAMESPACE against the operator of
+# the current kubectl context, reports how long they took to become
+# Operational and the memory of the operator pods matched by OPERATOR_SELECTOR,
+# then deletes them. Storage classes must be able to provision the volumes.
+
+set -o errexit
+set -o nounset
+set -o pipefail
+
+CLUSTERS=${CLUSTERS:-200}
+NAMESPACE=${NAMESPACE:-soak}
+OPERATOR_NAMESPACE=${OPERATOR_NAMESPACE:-default}
+OPERATOR_SELECTOR=${OPERATOR_SELECTOR:-app=mariadb-operator}
+TIMEOUT=${TIMEOUT:-3600}
+KUBECTL=${KUBECTL:-kubectl}
+
+cleanup() {
+	${KUBECTL} delete namespace "${NAMESPACE}" --wait=false >/dev/null 2>&1 || true
+}
+trap cleanup EXIT
+
+memory() {
+	${KUBECTL} -n "${OPERATOR_NAMESPACE}" top pod -l "${OPERATOR_SELECTOR}" --no-headers 2>/dev/null || echo "memory unknown, no metrics"
+}
+
+${KUBECTL} create namespace "${NAMESPACE}"
+echo "operator before: $(memory)"
+
+start=$(date +%s)
+for i in $(seq 1 "${CLUSTERS}"); do
+	cat <<EOF
+---
+apiVersion: components.dsg.dk/v1alpha1
+kind: MariaDBCluster
+metadata:
+  name: soak-${i}
+spec:
+  replicas: 1
+  serviceName: soak-${i}
+  storages:
+    data:
+      initSize: 1Gi
+    snapshot:
+      initSize: 1Gi
+  proxy: false
+EOF
+done | ${KUBECTL} -n "${NAMESPACE}" apply -f - >/dev/null
+echo "created ${CLUSTERS} clusters in $(($(date +%s) - start))s"
+
+while true; do
+	operational=$(${KUBECTL} -n "${NAMESPACE}" get mariadbclusters -o jsonpath='{range .items[*]}{.status.phase}{"\n"}{end}' | grep -c '^Operational$' || true)
+	elapsed=$(($(date +%s) - start))
+	echo "${operational}/${CLUSTERS} operational after ${elapsed}s, operator: $(memory)"
+	if [ "${operational}" -ge "${CLUSTERS}" ]; then
+		break
+	fi
+	if [ "${elapsed}" -ge "${TIMEOUT}" ]; then
+		echo "timed out after ${TIMEOUT}s" >&2
+		exit 1
+	fi
+	sleep 30
+done
+echo "all ${CLUSTERS} clusters operational in ${elapsed}s"
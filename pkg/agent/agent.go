@@ -0,0 +1,389 @@
+package agent
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	components "github.com/dansksupermarked/mariadb-galera-operator/pkg/apis/components/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	defaultKubeAPIRequestTimeout = 30 * time.Second
+	pollInterval                 = 10 * time.Second
+	// unchanged state is still reported this often, so the operator can tell
+	// a live pod from a stale entry
+	reportInterval = 30 * time.Second
+	dataDir        = "/var/lib/mysql"
+	// wsrep local state of a pod receiving a state transfer
+	joiningState = "Joining"
+	syncedState  = "Synced"
+)
+
+// markers sst scripts keep in the data directory while a transfer runs, rsync
+// and mariabackup respectively
+var sstMarkers = []string{"sst_in_progress", ".sst"}
+
+// Agent runs next to mariadb in server pods and publishes the live galera
+// state of its pod into MariaDBCluster status
+type Agent struct {
+	*Reporter
+	color            string
+	sstPassword      string
+	operatorPassword string
+	clientPassword   string
+	// SST, operator and client users created by this agent already
+	usersReady bool
+	// wsrep counters at the last report, rates are reported from the difference
+	last counters
+	// flow control mitigations applied to the running server
+	fcLimit     int
+	desynced    bool
+	desyncKnown bool
+	// pc.weight set on the running server, -1 when not known
+	pcWeight int
+	// pc.bootstrap asked for by the operator and applied already
+	pcBootstrapped metav1.Time
+	// wsrep_cluster_status and when it was first seen
+	clusterStatus      string
+	clusterStatusSince metav1.Time
+	// wsrep_local_state_comment and when it was first seen
+	localState      string
+	localStateSince metav1.Time
+	// spec.config options set on the running server, nil until read from
+	// the config it started with
+	serverConfig map[string]string
+	// static options of spec.config waiting for a restart
+	pendingRestart []string
+	// hash of the configuration the pod started with
+	configHash string
+	// plugins of spec.server not active, nil until checked on the running
+	// server
+	inactivePlugins []string
+}
+
+// cumulative wsrep status counters
+type counters struct {
+	time time.Time
+	// replicated and received bytes
+	bytes int64
+	// time spent paused by flow control
+	pausedNs int64
+	// pause requests sent
+	fcSent int64
+	// write sets waiting to be applied, not cumulative but read along
+	recvQueue int64
+}
+
+func (a *Agent) Run() {
+
+	// Take care of termination by signal
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, syscall.SIGTERM, syscall.SIGSTOP, syscall.SIGHUP, syscall.SIGQUIT, syscall.SIGINT)
+	go func() {
+		logrus.Infof("received signal: %v, exiting", <-c)
+		os.Exit(0)
+	}()
+
+	var err error
+	if a.Reporter, err = NewReporter(); err != nil {
+		panic(err.Error())
+	}
+	a.color = os.Getenv("MARIADBCLUSTER_COLOR")
+	a.pcWeight = -1
+	a.sstPassword = os.Getenv("MARIADBCLUSTER_SST_PASSWORD")
+	a.operatorPassword = os.Getenv("MARIADBCLUSTER_OPERATOR_PASSWORD")
+	a.clientPassword = os.Getenv("MARIADBCLUSTER_CLIENT_PASSWORD")
+	a.configHash = readConfigHash()
+
+	for {
+		// mariadb does not take connections before it received its state
+		sst := readSSTProgress()
+		status, count, err := queryWSREPStatus()
+		if err != nil {
+			// a restarted server starts over with the rendered config
+			a.fcLimit = 0
+			a.desyncKnown = false
+			a.pcWeight = -1
+			a.serverConfig = nil
+			a.pendingRestart = nil
+			a.inactivePlugins = nil
+		}
+		if err != nil && sst == nil {
+			a.logger.Debugf("mariadb not answering : %s", err.Error())
+		} else {
+			if err != nil {
+				status.LocalState = joiningState
+			}
+			status.Color = a.color
+			if status.ClusterStatus != a.clusterStatus {
+				a.clusterStatus = status.ClusterStatus
+				a.clusterStatusSince = metav1.Now()
+			}
+			status.ClusterStatusSince = a.clusterStatusSince
+			if status.LocalState != a.localState {
+				a.localState = status.LocalState
+				a.localStateSince = metav1.Now()
+			}
+			status.LocalStateSince = a.localStateSince
+			status.SST = sst
+			status.Desynced = a.desynced
+			status.PendingRestart = a.pendingRestart
+			if len(a.inactivePlugins) > 0 {
+				status.InactivePlugins = a.inactivePlugins
+			}
+			status.ConfigHash = a.configHash
+			current := a.report(status, count)
+			if err == nil && current != nil {
+				a.applyFlowControl(current)
+				a.applyPCBootstrap(current)
+				a.applyPCWeight(current)
+				a.applyServerConfig(current)
+				a.checkPlugins(current)
+			}
+			if status.LocalState == syncedState {
+				a.ensureUsers()
+			}
+		}
+		time.Sleep(pollInterval)
+	}
+}
+
+// report patches the entry of this pod when it changed or is due for a refresh,
+// returning the object it fetched. Rates, queue length and free space always
+// change a bit, they do not trigger a report by themselves but are refreshed along.
+func (a *Agent) report(status components.WSREPStatus, count counters) *components.MariaDBCluster {
+	current, err := a.Get()
+	if err != nil {
+		a.logger.Errorf("Error fetching object : %s", err.Error())
+		return nil
+	}
+	if last, ok := current.Status.WSREP[a.Hostname]; ok {
+		status.Reported = last.Reported
+		status.WriteRate = last.WriteRate
+		status.DataAvailableBytes = last.DataAvailableBytes
+		status.FlowControlPausedPercent = last.FlowControlPausedPercent
+		status.FlowControlSent = last.FlowControlSent
+		status.RecvQueue = last.RecvQueue
+		status.Signature = last.Signature
+		if reflect.DeepEqual(status, last) && time.Since(last.Reported.Time) < reportInterval {
+			return current
+		}
+	}
+	status.RecvQueue = count.recvQueue
+	now := time.Now()
+	status.WriteRate, status.FlowControlPausedPercent, status.FlowControlSent = 0, 0, 0
+	// counters start over with the server
+	if elapsed := now.Sub(a.last.time); !a.last.time.IsZero() && elapsed >= time.Second && count.bytes >= a.last.bytes {
+		status.WriteRate = (count.bytes - a.last.bytes) / int64(elapsed/time.Second)
+		if count.pausedNs >= a.last.pausedNs {
+			status.FlowControlPausedPercent = int((count.pausedNs - a.last.pausedNs) * 100 / int64(elapsed))
+		}
+		if count.fcSent >= a.last.fcSent {
+			status.FlowControlSent = count.fcSent - a.last.fcSent
+		}
+	}
+	count.time = now
+	a.last = count
+	status.DataAvailableBytes = dataAvailableBytes()
+	status.Reported = metav1.NewTime(now)
+	a.reportWSREP(current, status)
+	return current
+}
+
+// applyFlowControl sets the gcs.fc_limit and wsrep_desync the operator asked
+// for in status on the running server, the limit is rendered into config as
+// well so it survives restarts
+func (a *Agent) applyFlowControl(mdbc *components.MariaDBCluster) {
+	var limit int
+	var desync bool
+	if fc := mdbc.Status.FlowControl; fc != nil {
+		limit = fc.Limits[a.Hostname]
+		desync = fc.Desynced == a.Hostname
+	}
+	if limit > 0 && limit != a.fcLimit {
+		if err := execSQL(fmt.Sprintf("SET GLOBAL wsrep_provider_options='gcs.fc_limit=%d';\n", limit)); err != nil {
+			a.logger.Errorf("failed to set gcs.fc_limit : %s", err.Error())
+		} else {
+			a.logger.Infof("gcs.fc_limit set to %d", limit)
+			a.fcLimit = limit
+		}
+	}
+	if desync != a.desynced || !a.desyncKnown {
+		value := "OFF"
+		if desync {
+			value = "ON"
+		}
+		if err := execSQL("SET GLOBAL wsrep_desync=" + value + ";\n"); err != nil {
+			a.logger.Errorf("failed to set wsrep_desync : %s", err.Error())
+			return
+		}
+		if desync != a.desynced {
+			a.logger.Infof("wsrep_desync set to %s", value)
+		}
+		a.desynced = desync
+		a.desyncKnown = true
+	}
+}
+
+// applyPCWeight brings pc.weight of the running server in line with spec, the
+// config rendered at startup only covers weights set before
+func (a *Agent) applyPCWeight(mdbc *components.MariaDBCluster) {
+	weight, ok := mdbc.GetPCWeight(a.Hostname)
+	if !ok {
+		// galera default
+		weight = 1
+	}
+	if weight == a.pcWeight || (!ok && a.pcWeight < 0) {
+		return
+	}
+	if err := execSQL(fmt.Sprintf("SET GLOBAL wsrep_provider_options='pc.weight=%d';\n", weight)); err != nil {
+		a.logger.Errorf("failed to set pc.weight : %s", err.Error())
+		return
+	}
+	a.logger.Infof("pc.weight set to %d", weight)
+	a.pcWeight = weight
+}
+
+// applyPCBootstrap has this pod form a new primary component when the operator
+// picked it after every pod lost quorum, once per request
+func (a *Agent) applyPCBootstrap(mdbc *components.MariaDBCluster) {
+	request := mdbc.Status.PCBootstrap
+	if request == nil || request.Pod != a.Hostname || request.Time.Equal(&a.pcBootstrapped) {
+		return
+	}
+	if a.clusterStatus == components.WSREPClusterStatusPrimary {
+		a.pcBootstrapped = request.Time
+		return
+	}
+	if err := execSQL("SET GLOBAL wsrep_provider_options='pc.bootstrap=YES';\n"); err != nil {
+		a.logger.Errorf("failed to bootstrap primary component : %s", err.Error())
+		return
+	}
+	a.logger.Warn("bootstrapped new primary component")
+	a.pcBootstrapped = request.Time
+}
+
+// ensureUsers creates the user mariabackup authenticates as when serving as
+// donor, the one Jobs of the operator log in as and the one of clients.
+// Statements replicate, a Synced pod creates them for the whole cluster.
+func (a *Agent) ensureUsers() {
+	if a.usersReady || a.sstPassword == "" {
+		return
+	}
+	user := "'" + components.SSTUser + "'@'localhost'"
+	statements := "CREATE USER IF NOT EXISTS " + user + " IDENTIFIED BY '" + a.sstPassword + "';\n" +
+		"ALTER USER " + user + " IDENTIFIED BY '" + a.sstPassword + "';\n" +
+		"GRANT RELOAD, PROCESS, LOCK TABLES, REPLICATION CLIENT ON *.* TO " + user + ";\n"
+	// pods started before the operator user existed have no password for it
+	if a.operatorPassword != "" {
+		user = "'" + components.OperatorUser + "'@'%'"
+		statements += "CREATE USER IF NOT EXISTS " + user + " IDENTIFIED BY '" + a.operatorPassword + "';\n" +
+			"ALTER USER " + user + " IDENTIFIED BY '" + a.operatorPassword + "';\n" +
+			"GRANT ALL PRIVILEGES ON *.* TO " + user + " WITH GRANT OPTION;\n"
+	}
+	// without SUPER the client user is refused writes while read_only is set
+	if a.clientPassword != "" {
+		user = "'" + components.ClientUser + "'@'%'"
+		statements += "CREATE USER IF NOT EXISTS " + user + " IDENTIFIED BY '" + a.clientPassword + "';\n" +
+			"ALTER USER " + user + " IDENTIFIED BY '" + a.clientPassword + "';\n" +
+			"GRANT SELECT, INSERT, UPDATE, DELETE, CREATE, DROP, INDEX, ALTER, REFERENCES, CREATE TEMPORARY TABLES, " +
+			"LOCK TABLES, EXECUTE, CREATE VIEW, SHOW VIEW, CREATE ROUTINE, ALTER ROUTINE, EVENT, TRIGGER ON *.* TO " + user + ";\n"
+	}
+	if err := execSQL(statements); err != nil {
+		a.logger.Errorf("failed to create users : %s", err.Error())
+		return
+	}
+	a.logger.Info("users ready")
+	a.usersReady = true
+}
+
+// execSQL runs statements on the local server, they are passed on stdin to
+// keep passwords out of the process list
+func execSQL(statements string) error {
+	cmd := exec.Command("mysql", "--protocol=tcp", "-h127.0.0.1")
+	cmd.Stdin = strings.NewReader(statements)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%s %s", err.Error(), strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// queryWSREPStatus reads wsrep status variables from the local server, along
+// with the counters it keeps since it started
+func queryWSREPStatus() (components.WSREPStatus, counters, error) {
+	var status components.WSREPStatus
+	var count counters
+	out, err := exec.Command("mysql", "--protocol=tcp", "-h127.0.0.1", "--skip-column-names", "-B",
+		"-e", `SHOW GLOBAL STATUS LIKE 'wsrep\_%'`).Output()
+	if err != nil {
+		return status, count, err
+	}
+	vars := parseStatus(string(out))
+	if vars["wsrep_cluster_status"] == "" {
+		return status, count, fmt.Errorf("wsrep provider not loaded")
+	}
+	status.ClusterStateUUID = vars["wsrep_cluster_state_uuid"]
+	status.ClusterStatus = vars["wsrep_cluster_status"]
+	status.ClusterConfID, _ = strconv.ParseInt(vars["wsrep_cluster_conf_id"], 10, 64)
+	status.ClusterSize, _ = strconv.Atoi(vars["wsrep_cluster_size"])
+	status.LocalState = vars["wsrep_local_state_comment"]
+	status.LastCommitted, _ = strconv.ParseInt(vars["wsrep_last_committed"], 10, 64)
+	replicated, _ := strconv.ParseInt(vars["wsrep_replicated_bytes"], 10, 64)
+	received, _ := strconv.ParseInt(vars["wsrep_received_bytes"], 10, 64)
+	count.bytes = replicated + received
+	count.pausedNs, _ = strconv.ParseInt(vars["wsrep_flow_control_paused_ns"], 10, 64)
+	count.fcSent, _ = strconv.ParseInt(vars["wsrep_flow_control_sent"], 10, 64)
+	count.recvQueue, _ = strconv.ParseInt(vars["wsrep_local_recv_queue"], 10, 64)
+	return status, count, nil
+}
+
+// dataAvailableBytes returns the space left on the data volume
+func dataAvailableBytes() int64 {
+	var fs syscall.Statfs_t
+	if err := syscall.Statfs(dataDir, &fs); err != nil {
+		return 0
+	}
+	return int64(fs.Bavail) * int64(fs.Bsize)
+}
+
+// parseStatus splits tab separated name and value rows of batch mode output
+func parseStatus(out string) map[string]string {
+	vars := make(map[string]string)
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.SplitN(line, "\t", 2)
+		if len(fields) == 2 {
+			vars[strings.ToLower(fields[0])] = strings.TrimSpace(fields[1])
+		}
+	}
+	return vars
+}
+
+// readSSTProgress returns the state transfer running into the data directory,
+// nil when there is none. It started when the sst script left its marker.
+func readSSTProgress() *components.SSTProgress {
+	for _, marker := range sstMarkers {
+		info, err := os.Stat(filepath.Join(dataDir, marker))
+		if err != nil {
+			continue
+		}
+		var bytes int64
+		filepath.Walk(dataDir, func(path string, info os.FileInfo, err error) error {
+			if err == nil && !info.IsDir() {
+				bytes += info.Size()
+			}
+			return nil
+		})
+		return &components.SSTProgress{StartTime: metav1.NewTime(info.ModTime()), Bytes: bytes}
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package agent
+
+import (
+	"io/ioutil"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	components "github.com/dansksupermarked/mariadb-galera-operator/pkg/apis/components/v1alpha1"
+)
+
+const (
+	// spec.config as rendered by the initializer when the pod started
+	serverConfigFile = "/etc/mysql/conf.d/config.cnf"
+	// hash of the configuration the initializer rendered
+	configHashFile = "/etc/mysql/conf.d/config-hash"
+)
+
+// my.cnf sizes, SET GLOBAL only takes them as plain numbers
+var sizeValueRegexp = regexp.MustCompile(`^([0-9]+)([kKmMgG]?)$`)
+
+// applyServerConfig brings dynamic variables of the running server in line
+// with spec.config as the operator copied it into status, by SET GLOBAL on
+// the options that changed since the server started or the last change. The
+// static ones that changed are kept as pending a restart, they are reported
+// along with the galera state.
+func (a *Agent) applyServerConfig(mdbc *components.MariaDBCluster) {
+	if a.serverConfig == nil {
+		content, err := ioutil.ReadFile(serverConfigFile)
+		if err != nil {
+			a.logger.Debugf("spec.config not applied at runtime : %s", err.Error())
+			return
+		}
+		if a.serverConfig, err = components.ParseServerConfig(string(content)); err != nil {
+			a.logger.Errorf("failed to parse %s : %s", serverConfigFile, err.Error())
+			return
+		}
+	}
+	rendered, err := mdbc.RenderServerConfig(mdbc.Status.ServerConfig, a.Hostname)
+	if err != nil {
+		a.logger.Errorf("Can't render spec.config : %s", err.Error())
+		return
+	}
+	wanted, err := components.ParseServerConfig(rendered)
+	if err != nil {
+		a.logger.Errorf("Can't parse spec.config : %s", err.Error())
+		return
+	}
+	var keys []string
+	for key := range wanted {
+		keys = append(keys, key)
+	}
+	for key := range a.serverConfig {
+		if _, ok := wanted[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	var pending []string
+	for _, key := range keys {
+		value, ok := wanted[key]
+		current, set := a.serverConfig[key]
+		if ok == set && value == current {
+			continue
+		}
+		if !components.IsDynamicServerVariable(key) {
+			pending = append(pending, key)
+			continue
+		}
+		expression := "DEFAULT"
+		if ok {
+			expression = sqlValue(value)
+		}
+		if err := execSQL("SET GLOBAL " + key + " = " + expression + ";\n"); err != nil {
+			a.logger.Errorf("failed to set %s : %s", key, err.Error())
+			pending = append(pending, key)
+			continue
+		}
+		a.logger.Infof("%s set to %s", key, expression)
+		if ok {
+			a.serverConfig[key] = value
+		} else {
+			delete(a.serverConfig, key)
+		}
+	}
+	a.pendingRestart = pending
+}
+
+// sqlValue turns the value of a my.cnf option into an SQL expression, a bare
+// boolean option being enabled
+func sqlValue(value string) string {
+	if value == "" {
+		return "ON"
+	}
+	if result := sizeValueRegexp.FindStringSubmatch(value); len(result) > 2 {
+		size, err := strconv.ParseInt(result[1], 10, 64)
+		if err == nil {
+			switch strings.ToUpper(result[2]) {
+			case "K":
+				size <<= 10
+			case "M":
+				size <<= 20
+			case "G":
+				size <<= 30
+			}
+			return strconv.FormatInt(size, 10)
+		}
+	}
+	if _, err := strconv.ParseFloat(value, 64); err == nil {
+		return value
+	}
+	if len(value) > 1 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		value = value[1 : len(value)-1]
+	}
+	return "'" + strings.Replace(strings.Replace(value, `\`, `\\`, -1), "'", `\'`, -1) + "'"
+}
+
+// readConfigHash returns the hash of the configuration this pod started with,
+// empty when the initializer did not write one
+func readConfigHash() string {
+	content, err := ioutil.ReadFile(configHashFile)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(content))
+}
+
+// checkPlugins looks up the plugins of spec.server in information_schema once
+// the server answers, keeping those that are not active along with their
+// status. The server loads plugins on startup only, they are checked again
+// once it restarted.
+func (a *Agent) checkPlugins(mdbc *components.MariaDBCluster) {
+	if a.inactivePlugins != nil {
+		return
+	}
+	plugins := mdbc.Spec.Server.GetPlugins()
+	out, err := exec.Command("mysql", "--protocol=tcp", "-h127.0.0.1", "--skip-column-names", "-B",
+		"-e", "SELECT plugin_name, plugin_status FROM information_schema.plugins").Output()
+	if err != nil {
+		a.logger.Errorf("failed to list plugins : %s", err.Error())
+		return
+	}
+	loaded := parseStatus(string(out))
+	inactive := []string{}
+	for _, plugin := range plugins {
+		status, ok := loaded[strings.ToLower(plugin.Name)]
+		if !ok {
+			status = "NOT LOADED"
+		}
+		if status != "ACTIVE" {
+			inactive = append(inactive, plugin.Name+" "+status)
+		}
+	}
+	if len(inactive) > 0 {
+		a.logger.Warnf("plugins not active : %s", strings.Join(inactive, ", "))
+	}
+	a.inactivePlugins = inactive
+}
@@ -0,0 +1,129 @@
+package agent
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+
+	"github.com/Sirupsen/logrus"
+	components "github.com/dansksupermarked/mariadb-galera-operator/pkg/apis/components/v1alpha1"
+)
+
+const grastatePath = "/var/lib/mysql/grastate.dat"
+
+// ReadGRAState returns the position of this pod, recovering the seqno through
+// --wsrep-recover when it was not saved. A pod with an empty volume reports no
+// UUID, one whose position can not be established reports seqno -1.
+func ReadGRAState(logger *logrus.Entry) components.GRAState {
+	var version, uuid string
+	var seqno int64 = -1
+	var safeToBootstrap int
+	if _, err := os.Stat(grastatePath); err == nil {
+		version, uuid, seqno, safeToBootstrap = parseGRAState()
+	} else {
+		logger.Warn("no grastate.dat found, reporting empty state")
+	}
+	if uuid != "" && seqno <= 0 {
+		// seqno is only saved on clean shutdown, recover it from the InnoDB logs
+		uuidRec, seqnoRec, err := recoverGRAStateUuidSeqNo()
+		if err != nil {
+			logger.Errorf("wsrep recovery failed : %s", err.Error())
+		} else if uuid == uuidRec || uuid == "00000000-0000-0000-0000-000000000000" {
+			uuid = uuidRec
+			seqno = seqnoRec
+		} else {
+			logger.Errorf("recovered cluster %s does not match grastate.dat cluster %s", uuidRec, uuid)
+			seqno = -1
+		}
+	}
+	return components.GRAState{Version: version, UUID: uuid, SeqNo: seqno, SafeToBootstrap: safeToBootstrap}
+}
+
+// SetSafeToBootstrap marks the pod to bootstrap a new primary component from
+func SetSafeToBootstrap() {
+	state := []byte(getStateString())
+	re := regexp.MustCompile(`safe_to_bootstrap:\s*0`)
+	newState := re.ReplaceAll(state, []byte(`safe_to_bootstrap: 1`))
+	// keep ownership and mode, mysqld rewrites the file on startup
+	info, err := os.Stat(grastatePath)
+	if err != nil {
+		panic(err.Error())
+	}
+	if err = ioutil.WriteFile(grastatePath, newState, info.Mode()); err != nil {
+		panic(err.Error())
+	}
+}
+
+func recoverGRAStateUuidSeqNo() (string, int64, error) {
+	logrus.Debug("Recovering wsrep state")
+	cmd := exec.Command("su", "mysql", "-c", "/usr/sbin/mysqld --wsrep-recover")
+	out, _ := cmd.CombinedOutput()
+	re := regexp.MustCompile(`WSREP: Recovered position:\s*([0-9a-z-]*):(\d+)`)
+	result := re.FindStringSubmatch(string(out))
+	if len(result) > 1 {
+		seqno, _ := strconv.ParseInt(result[2], 10, 64)
+		return result[1], seqno, nil
+	}
+	return "", int64(0), fmt.Errorf("failed to recover")
+}
+
+func getStateString() string {
+	stateString, err := ioutil.ReadFile(grastatePath)
+	if err != nil {
+		panic("missing grastate.dat : " + err.Error())
+	}
+	return string(stateString)
+}
+
+func parseGRAState() (string, string, int64, int) {
+	stateString := getStateString()
+	var safeToBootstrap int
+	var seqno int64
+	var uuid, version string
+	var re *regexp.Regexp
+	var result []string
+
+	logrus.Debug("stateString : " + stateString)
+
+	re = regexp.MustCompile(`version:\s*([0-9\.]*)`)
+	result = re.FindStringSubmatch(stateString)
+	if len(result) > 1 {
+		version = result[1]
+	} else {
+		panic("Version missing")
+	}
+
+	logrus.Debug("version " + version)
+
+	re = regexp.MustCompile(`uuid:\s*([A-Za-z0-9-]*)`)
+	result = re.FindStringSubmatch(stateString)
+	if len(result) > 1 {
+		uuid = result[1]
+	} else {
+		panic("UUID missing")
+	}
+	logrus.Debug("uuid " + uuid)
+
+	re = regexp.MustCompile(`seqno:\s*([-]?\d+)`)
+	result = re.FindStringSubmatch(stateString)
+	if len(result) > 1 {
+		seqno, _ = strconv.ParseInt(result[1], 10, 64)
+	} else {
+		panic("SeqNo missing")
+	}
+	logrus.Debugf("seqno %d", seqno)
+
+	re = regexp.MustCompile(`safe_to_bootstrap:\s*(\d)`)
+	result = re.FindStringSubmatch(string(stateString))
+	if len(result) > 1 {
+		safeToBootstrap, _ = strconv.Atoi(result[1])
+	} else {
+		logrus.Warn("safe_to_bootstrap missing")
+	}
+	logrus.Debugf("safeToBootstrap %d", safeToBootstrap)
+
+	return version, uuid, seqno, safeToBootstrap
+}
@@ -0,0 +1,167 @@
+package agent
+
+import (
+	"os"
+	"reflect"
+
+	"github.com/Sirupsen/logrus"
+	components "github.com/dansksupermarked/mariadb-galera-operator/pkg/apis/components/v1alpha1"
+	componentsclientset "github.com/dansksupermarked/mariadb-galera-operator/pkg/generated/clientset/versioned"
+	componentsclient "github.com/dansksupermarked/mariadb-galera-operator/pkg/generated/clientset/versioned/typed/components/v1alpha1"
+	"github.com/dansksupermarked/mariadb-galera-operator/pkg/util"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+)
+
+// Reporter publishes the state of the server pod it runs in into MariaDBCluster
+// status. Reports are signed with the key server pods get from the server
+// Secret, the operator ignores those it can not verify.
+type Reporter struct {
+	Hostname  string
+	name      string
+	namespace string
+	key       []byte
+	client    componentsclient.ComponentsV1alpha1Interface
+	logger    *logrus.Entry
+}
+
+// NewReporter sets up a Reporter from the environment of server pod containers
+func NewReporter() (*Reporter, error) {
+	r := &Reporter{
+		name:      os.Getenv("MARIADBCLUSTER_NAME"),
+		namespace: os.Getenv("MARIADBCLUSTER_NAMESPACE"),
+		key:       []byte(os.Getenv("MARIADBCLUSTER_REPORT_KEY")),
+	}
+	var err error
+	if r.Hostname, err = os.Hostname(); err != nil {
+		return nil, err
+	}
+	r.logger = logrus.WithField("namespace", r.namespace).WithField("name", r.name).WithField("pod", r.Hostname)
+	if len(r.key) == 0 {
+		r.logger.Warn("no report key set, the operator will ignore reports of this pod")
+	}
+
+	config, err := rest.InClusterConfig()
+	if err != nil {
+		return nil, err
+	}
+	config.Timeout = defaultKubeAPIRequestTimeout
+	clientset, err := componentsclientset.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	r.client = clientset.Components()
+	return r, nil
+}
+
+func (r *Reporter) Get() (*components.MariaDBCluster, error) {
+	return r.client.MariaDBClusters(r.namespace).Get(r.name, metav1.GetOptions{})
+}
+
+// ReportRecovery publishes the position of this pod while it is held back
+// during Recovery, only patching when it changed
+func (r *Reporter) ReportRecovery(state components.GRAState) {
+	current, err := r.Get()
+	if err != nil {
+		r.logger.Errorf("Error fetching object : %s", err.Error())
+		return
+	}
+	if last, ok := current.Status.RecoveryReports[r.Hostname]; ok && last.GRAState == state && last.Verify(r.Hostname, r.key) {
+		return
+	}
+	report := components.RecoveryReport{GRAState: state, Reported: metav1.Now()}
+	report.Sign(r.Hostname, r.key)
+	expected := current.DeepCopy()
+	if expected.Status.RecoveryReports == nil {
+		expected.Status.RecoveryReports = make(map[string]components.RecoveryReport)
+	}
+	expected.Status.RecoveryReports[r.Hostname] = report
+	util.CheckAndPatchMariaDBCluster(current, expected, r.client, r.logger)
+}
+
+// reportWSREP signs and publishes the live galera state of this pod
+func (r *Reporter) reportWSREP(current *components.MariaDBCluster, status components.WSREPStatus) {
+	status.Sign(r.Hostname, r.key)
+	expected := current.DeepCopy()
+	if expected.Status.WSREP == nil {
+		expected.Status.WSREP = make(map[string]components.WSREPStatus)
+	}
+	expected.Status.WSREP[r.Hostname] = status
+	util.CheckAndPatchMariaDBCluster(current, expected, r.client, r.logger)
+}
+
+// PrimaryPosition returns the cluster UUID and highest seqno other pods of
+// given color report as part of a primary component since given time, only
+// trusting signed reports. Returns false when no such report is found.
+func (r *Reporter) PrimaryPosition(mdbc *components.MariaDBCluster, color string, since metav1.Time) (string, int64, bool) {
+	var uuid string
+	var seqno int64 = -1
+	for name, status := range mdbc.Status.WSREP {
+		reportColor := status.Color
+		if reportColor == "" {
+			reportColor = components.ColorBlue
+		}
+		if name == r.Hostname || reportColor != color || status.ClusterStatus != components.WSREPClusterStatusPrimary ||
+			status.Reported.Before(&since) || !status.Verify(name, r.key) {
+			continue
+		}
+		if status.LastCommitted > seqno {
+			uuid, seqno = status.ClusterStateUUID, status.LastCommitted
+		}
+	}
+	return uuid, seqno, uuid != ""
+}
+
+// ReportDivergence publishes that this pod is held back from joining, only
+// patching when something changed
+func (r *Reporter) ReportDivergence(current *components.MariaDBCluster, state components.GRAState, uuid string, seqno int64) {
+	last, ok := current.Status.Diverged[r.Hostname]
+	if ok && last.GRAState == state && last.ClusterStateUUID == uuid && last.Verify(r.Hostname, r.key) {
+		return
+	}
+	report := components.DivergenceReport{GRAState: state, ClusterStateUUID: uuid, LastCommitted: seqno, Reported: metav1.Now()}
+	report.Sign(r.Hostname, r.key)
+	expected := current.DeepCopy()
+	if expected.Status.Diverged == nil {
+		expected.Status.Diverged = make(map[string]components.DivergenceReport)
+	}
+	expected.Status.Diverged[r.Hostname] = report
+	util.CheckAndPatchMariaDBCluster(current, expected, r.client, r.logger)
+}
+
+// ClearDivergence removes the divergence report of this pod, if any
+func (r *Reporter) ClearDivergence(current *components.MariaDBCluster) {
+	if _, ok := current.Status.Diverged[r.Hostname]; !ok {
+		return
+	}
+	expected := current.DeepCopy()
+	delete(expected.Status.Diverged, r.Hostname)
+	util.CheckAndPatchMariaDBCluster(current, expected, r.client, r.logger)
+}
+
+// ReportKernel publishes the sysctls of the node this pod runs on differing
+// from spec.kernelTuning, removing the report of this pod when none do. Only
+// patches when something changed.
+func (r *Reporter) ReportKernel(current *components.MariaDBCluster, node string, differing []components.KernelSetting) {
+	last, ok := current.Status.Kernel[r.Hostname]
+	if len(differing) == 0 {
+		if !ok {
+			return
+		}
+		expected := current.DeepCopy()
+		delete(expected.Status.Kernel, r.Hostname)
+		util.CheckAndPatchMariaDBCluster(current, expected, r.client, r.logger)
+		return
+	}
+	if ok && last.Node == node && reflect.DeepEqual(last.Differing, differing) && last.Verify(r.Hostname, r.key) {
+		return
+	}
+	report := components.KernelReport{Node: node, Differing: differing, Reported: metav1.Now()}
+	report.Sign(r.Hostname, r.key)
+	expected := current.DeepCopy()
+	if expected.Status.Kernel == nil {
+		expected.Status.Kernel = make(map[string]components.KernelReport)
+	}
+	expected.Status.Kernel[r.Hostname] = report
+	util.CheckAndPatchMariaDBCluster(current, expected, r.client, r.logger)
+}
@@ -0,0 +1,58 @@
+package v1alpha1
+
+import (
+	batch "k8s.io/api/batch/v1"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// BackupJobTransform renders a Job dumping the whole cluster through the client
+// facing service into the snapshot volume as <name>.sql
+func (mdbc *MariaDBCluster) BackupJobTransform(job *batch.Job, name string) error {
+	labels := mdbc.GetBackupLabels()
+	backoffLimit := int32(2)
+
+	job.SetName(name)
+	job.SetNamespace(mdbc.Namespace)
+	job.SetLabels(labels)
+	job.SetAnnotations(map[string]string{MariaDBClusterVersionAnnotation: mdbc.GetServerVersion()})
+	job.SetOwnerReferences([]metav1.OwnerReference{
+		*metav1.NewControllerRef(mdbc, schema.GroupVersionKind{
+			Group:   GroupName,
+			Version: Version,
+			Kind:    "MariaDBCluster",
+		}),
+	})
+	job.Spec.BackoffLimit = &backoffLimit
+	job.Spec.Template.ObjectMeta.Labels = labels
+	job.Spec.Template.Spec.RestartPolicy = v1.RestartPolicyNever
+	if len(job.Spec.Template.Spec.Containers) < 1 {
+		job.Spec.Template.Spec.Containers = append(job.Spec.Template.Spec.Containers, v1.Container{})
+	}
+	target := "/snapshot/" + name + ".sql"
+	job.Spec.Template.Spec.Containers[0].Name = MariaDBClusterBackupRole
+	job.Spec.Template.Spec.Containers[0].Image = mdbc.GetServerImage()
+	job.Spec.Template.Spec.Containers[0].ImagePullPolicy = mdbc.GetImagePullPolicy(v1.PullIfNotPresent)
+	host := mdbc.GetProxyServiceName()
+	if mdbc.Spec.Galera.Donor.AvoidBackupSource {
+		host = mdbc.GetBackupSourcePod() + "." + mdbc.GetServerServiceName()
+	}
+	job.Spec.Template.Spec.Containers[0].Command = []string{"bash", "-c",
+		"mysqldump -h " + host + " --all-databases --single-transaction --routines --events > " + target + ".tmp && mv " + target + ".tmp " + target}
+	job.Spec.Template.Spec.Containers[0].VolumeMounts = []v1.VolumeMount{
+		v1.VolumeMount{Name: "snapshot", MountPath: "/snapshot"},
+	}
+	job.Spec.Template.Spec.Volumes = []v1.Volume{
+		v1.Volume{
+			Name: "snapshot",
+			VolumeSource: v1.VolumeSource{
+				PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{ClaimName: mdbc.GetSnapshotPVC().Name},
+			},
+		},
+	}
+	mdbc.inheritMetadata(&job.ObjectMeta)
+	mdbc.inheritMetadata(&job.Spec.Template.ObjectMeta)
+	mdbc.confinePod(&job.Spec.Template)
+	return nil
+}
@@ -0,0 +1,157 @@
+package v1alpha1
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	batch "k8s.io/api/batch/v1"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	// has the clients below log in as the operator user, the password stays
+	// out of the process list as printf is a builtin
+	blueGreenCredentialsScript string = `
+export HOME=/tmp
+printf '[client]\nuser=%s\npassword=%s\n' "$OPERATOR_USER" "$OPERATOR_PASSWORD" > $HOME/.my.cnf
+for i in $(seq 60); do
+  mysql -h $SOURCE_HOST -e 'SELECT 1' >/dev/null 2>&1 && mysql -h $REPLICA_HOST -e 'SELECT 1' >/dev/null 2>&1 && break
+  [ $i = 60 ] && echo "$OPERATOR_USER can not log in to $SOURCE_HOST and $REPLICA_HOST" | tee /dev/termination-log && exit 1
+  sleep 5
+done
+`
+
+	// waits for the replica to catch up with its source, failing when the
+	// replication thread stops on an error
+	blueGreenCatchUpScript string = `
+while true; do
+  status=$(mysql -h $REPLICA_HOST -e 'SHOW SLAVE STATUS\G')
+  if echo "$status" | grep -q 'Slave_SQL_Running: No'; then
+    echo "$status" | grep 'Last_Error' | tee /dev/termination-log
+    exit 1
+  fi
+  [ "$(echo "$status" | awk '/Seconds_Behind_Master:/ {print $2}')" = "0" ] && break
+  sleep 5
+done
+`
+
+	// loads a consistent dump of the serving cluster into the first pod of the
+	// new one and has it replicate from there on, see blueGreenCatchUpScript
+	blueGreenSyncScript string = `
+set -eo pipefail
+` + blueGreenCredentialsScript + `
+if [ "$(mysql -h $SOURCE_HOST -N -B -e 'SELECT @@log_bin')" != "1" ]; then
+  echo "binary logging is disabled on $SOURCE_HOST" | tee /dev/termination-log
+  exit 1
+fi
+SOURCE_ID=$(mysql -h $SOURCE_HOST -N -B -e 'SELECT @@server_id')
+if [ "$(mysql -h $REPLICA_HOST -N -B -e 'SELECT @@server_id')" = "$SOURCE_ID" ]; then
+  mysql -h $REPLICA_HOST -e "SET GLOBAL server_id = $SOURCE_ID + 1"
+fi
+mysqldump -h $SOURCE_HOST --all-databases --single-transaction --routines --events --triggers --gtid --master-data=1 | mysql -h $REPLICA_HOST
+mysql_upgrade -h $REPLICA_HOST --force
+mysql -h $REPLICA_HOST -e "CHANGE MASTER TO MASTER_HOST='$SOURCE_HOST', MASTER_USER='$OPERATOR_USER', MASTER_PASSWORD='$OPERATOR_PASSWORD', MASTER_USE_GTID=slave_pos; START SLAVE"
+` + blueGreenCatchUpScript
+
+	// fences writes on every pod of the serving cluster with read_only, then
+	// waits for the replica to apply everything they committed. Writes are let
+	// through again unless the replica caught up, clients switch over after.
+	blueGreenSwitchScript string = `
+set -eo pipefail
+` + blueGreenCredentialsScript + `
+unfence() {
+  for h in $SOURCE_HOSTS; do mysql -h $h -e 'SET GLOBAL read_only = OFF' || true; done
+}
+trap unfence EXIT
+trap 'exit 1' TERM
+for h in $SOURCE_HOSTS; do mysql -h $h -e 'SET GLOBAL read_only = ON'; done
+# the causality check has the source apply what other pods committed first
+pos=$(mysql -h $SOURCE_HOST -N -B -e 'SET SESSION wsrep_sync_wait = 1; SELECT @@gtid_binlog_pos')
+if [ "$(mysql -h $REPLICA_HOST -N -B -e "SELECT MASTER_GTID_WAIT('$pos', $CATCH_UP_TIMEOUT)")" != "0" ]; then
+  echo "$REPLICA_HOST did not reach $pos within $CATCH_UP_TIMEOUT seconds" | tee /dev/termination-log
+  exit 1
+fi
+trap - EXIT
+`
+
+	// drains replication once clients moved over and detaches the new cluster
+	blueGreenFinalizeScript string = `
+set -eo pipefail
+` + blueGreenCredentialsScript + blueGreenCatchUpScript + `
+mysql -h $REPLICA_HOST -e 'STOP SLAVE; RESET SLAVE ALL'
+`
+)
+
+// BlueGreenSyncJobTransform renders a Job seeding the standby cluster of a
+// blue/green upgrade from the serving one and setting up async replication
+func (mdbc *MariaDBCluster) BlueGreenSyncJobTransform(job *batch.Job) error {
+	return mdbc.blueGreenJobTransform(job, mdbc.GetBlueGreenSyncJobName(), blueGreenSyncScript)
+}
+
+// BlueGreenSwitchJobTransform renders a Job fencing writes on the serving
+// cluster of a blue/green upgrade until the standby one caught up with it
+func (mdbc *MariaDBCluster) BlueGreenSwitchJobTransform(job *batch.Job) error {
+	return mdbc.blueGreenJobTransform(job, mdbc.GetBlueGreenSwitchJobName(), blueGreenSwitchScript)
+}
+
+// BlueGreenFinalizeJobTransform renders a Job stopping replication into the new
+// cluster of a blue/green upgrade after services have been switched over to it
+func (mdbc *MariaDBCluster) BlueGreenFinalizeJobTransform(job *batch.Job) error {
+	return mdbc.blueGreenJobTransform(job, mdbc.GetBlueGreenFinalizeJobName(), blueGreenFinalizeScript)
+}
+
+func (mdbc *MariaDBCluster) blueGreenJobTransform(job *batch.Job, name, script string) error {
+	bg := mdbc.Status.BlueGreen
+	if bg == nil {
+		return fmt.Errorf("no blue/green upgrade in progress")
+	}
+	source := ColorBlue
+	if bg.Color == ColorBlue {
+		source = ColorGreen
+	}
+	labels := mdbc.GetServerLabels()
+	labels[MariaDBClusterRoleLabel] = MariaDBClusterBlueGreenRole
+	backoffLimit := int32(1)
+
+	job.SetName(name)
+	job.SetNamespace(mdbc.Namespace)
+	job.SetLabels(labels)
+	job.SetAnnotations(map[string]string{MariaDBClusterVersionAnnotation: bg.Version})
+	job.SetOwnerReferences([]metav1.OwnerReference{
+		*metav1.NewControllerRef(mdbc, schema.GroupVersionKind{
+			Group:   GroupName,
+			Version: Version,
+			Kind:    "MariaDBCluster",
+		}),
+	})
+	job.Spec.BackoffLimit = &backoffLimit
+	job.Spec.Template.ObjectMeta.Labels = labels
+	job.Spec.Template.Spec.RestartPolicy = v1.RestartPolicyNever
+	if len(job.Spec.Template.Spec.Containers) < 1 {
+		job.Spec.Template.Spec.Containers = append(job.Spec.Template.Spec.Containers, v1.Container{})
+	}
+	job.Spec.Template.Spec.Containers[0].Name = MariaDBClusterBlueGreenRole
+	job.Spec.Template.Spec.Containers[0].Image = mdbc.GetServerImageForVersion(bg.Version)
+	job.Spec.Template.Spec.Containers[0].ImagePullPolicy = mdbc.GetImagePullPolicy(v1.PullIfNotPresent)
+	job.Spec.Template.Spec.Containers[0].TerminationMessagePolicy = v1.TerminationMessageReadFile
+	sourceHosts := make([]string, mdbc.Spec.Replicas)
+	for i := range sourceHosts {
+		sourceHosts[i] = fmt.Sprintf("%s-%d.%s", mdbc.GetServerNameForColor(source), i, mdbc.GetServerServiceNameForColor(source))
+	}
+	job.Spec.Template.Spec.Containers[0].Env = []v1.EnvVar{
+		v1.EnvVar{Name: "SOURCE_HOST", Value: sourceHosts[0]},
+		v1.EnvVar{Name: "SOURCE_HOSTS", Value: strings.Join(sourceHosts, " ")},
+		v1.EnvVar{Name: "REPLICA_HOST", Value: mdbc.GetServerNameForColor(bg.Color) + "-0." + mdbc.GetServerServiceNameForColor(bg.Color)},
+		v1.EnvVar{Name: "CATCH_UP_TIMEOUT", Value: strconv.Itoa(DefaultBlueGreenCatchUpTimeoutSeconds)},
+		v1.EnvVar{Name: "OPERATOR_USER", Value: OperatorUser},
+		mdbc.serverSecretEnvVar("OPERATOR_PASSWORD", OperatorPasswordKey),
+	}
+	job.Spec.Template.Spec.Containers[0].Command = []string{"bash", "-c", script}
+	mdbc.inheritMetadata(&job.ObjectMeta)
+	mdbc.inheritMetadata(&job.Spec.Template.ObjectMeta)
+	mdbc.confinePod(&job.Spec.Template)
+	return nil
+}
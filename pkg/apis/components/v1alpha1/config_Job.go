@@ -0,0 +1,123 @@
+package v1alpha1
+
+import (
+	"fmt"
+	"strings"
+
+	batch "k8s.io/api/batch/v1"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// ConfigCheckJobTransform renders a Job having the initializer write the
+// configuration of a server pod and mysqld parse it, with --validate-config
+// where the server knows it and --verbose --help otherwise. It fails on
+// options the server refuses, reporting its output through the termination
+// message.
+func (mdbc *MariaDBCluster) ConfigCheckJobTransform(job *batch.Job, hash string) error {
+	labels := mdbc.GetServerLabels()
+	labels[MariaDBClusterRoleLabel] = MariaDBClusterConfigCheckRole
+	backoffLimit := int32(0)
+
+	job.SetName(mdbc.GetConfigCheckJobName(hash))
+	job.SetNamespace(mdbc.Namespace)
+	job.SetLabels(labels)
+	job.SetAnnotations(map[string]string{MariaDBClusterConfigAnnotation: hash})
+	job.SetOwnerReferences([]metav1.OwnerReference{
+		*metav1.NewControllerRef(mdbc, schema.GroupVersionKind{
+			Group:   GroupName,
+			Version: Version,
+			Kind:    "MariaDBCluster",
+		}),
+	})
+	job.Spec.BackoffLimit = &backoffLimit
+	job.Spec.Template.ObjectMeta.Labels = labels
+	job.Spec.Template.Spec.RestartPolicy = v1.RestartPolicyNever
+	job.Spec.Template.Spec.ServiceAccountName = mdbc.GetServiceAccountName()
+	job.Spec.Template.Spec.Volumes = []v1.Volume{
+		v1.Volume{Name: "config", VolumeSource: v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{}}},
+	}
+	if len(job.Spec.Template.Spec.InitContainers) < 1 {
+		job.Spec.Template.Spec.InitContainers = append(job.Spec.Template.Spec.InitContainers, v1.Container{})
+	}
+	job.Spec.Template.Spec.InitContainers[0].Name = "init"
+	job.Spec.Template.Spec.InitContainers[0].Image = mdbc.GetInitImage()
+	job.Spec.Template.Spec.InitContainers[0].ImagePullPolicy = mdbc.GetImagePullPolicy(v1.PullAlways)
+	job.Spec.Template.Spec.InitContainers[0].Command = []string{"/mdbc"}
+	job.Spec.Template.Spec.InitContainers[0].Args = []string{"init"}
+	job.Spec.Template.Spec.InitContainers[0].Env = []v1.EnvVar{
+		v1.EnvVar{Name: "MARIADBCLUSTER_NAME", Value: mdbc.Name},
+		v1.EnvVar{Name: "MARIADBCLUSTER_NAMESPACE", Value: mdbc.Namespace},
+		v1.EnvVar{Name: "MARIADBCLUSTER_CONFIG_CHECK", Value: "true"},
+		mdbc.serverSecretEnvVar("MARIADBCLUSTER_SST_PASSWORD", SSTPasswordKey),
+	}
+	job.Spec.Template.Spec.InitContainers[0].VolumeMounts = []v1.VolumeMount{
+		v1.VolumeMount{Name: "config", MountPath: "/etc/mysql/conf.d"},
+	}
+	if len(job.Spec.Template.Spec.Containers) < 1 {
+		job.Spec.Template.Spec.Containers = append(job.Spec.Template.Spec.Containers, v1.Container{})
+	}
+	job.Spec.Template.Spec.Containers[0].Name = MariaDBClusterConfigCheckRole
+	job.Spec.Template.Spec.Containers[0].Image = mdbc.GetServerImage()
+	job.Spec.Template.Spec.Containers[0].ImagePullPolicy = mdbc.GetImagePullPolicy(v1.PullIfNotPresent)
+	job.Spec.Template.Spec.Containers[0].TerminationMessagePolicy = v1.TerminationMessageReadFile
+	job.Spec.Template.Spec.Containers[0].VolumeMounts = []v1.VolumeMount{
+		v1.VolumeMount{Name: "config", MountPath: "/etc/mysql/conf.d/operator.cnf", SubPath: "operator.cnf"},
+		v1.VolumeMount{Name: "config", MountPath: "/etc/mysql/conf.d/user.cnf", SubPath: "user.cnf"},
+		v1.VolumeMount{Name: "config", MountPath: "/etc/mysql/conf.d/config.cnf", SubPath: "config.cnf"},
+	}
+	job.Spec.Template.Spec.Containers[0].Command = []string{"bash", "-c",
+		"set -o pipefail; " +
+			"if /usr/sbin/mysqld --verbose --help 2>/dev/null | grep -q -- '--validate-config'; " +
+			"then /usr/sbin/mysqld --user=mysql --validate-config; " +
+			"else /usr/sbin/mysqld --user=mysql --verbose --help >/dev/null; fi 2>&1 | " +
+			"grep -v '^$' | tail -c 2048 | tee /dev/termination-log"}
+	mdbc.inheritMetadata(&job.ObjectMeta)
+	mdbc.inheritMetadata(&job.Spec.Template.ObjectMeta)
+	mdbc.confinePod(&job.Spec.Template)
+	return nil
+}
+
+// TimeZoneJobTransform renders a Job loading the time zone tables of the
+// server image into every server pod. The tables are not InnoDB, galera does
+// not replicate them, so each pod is loaded with wsrep off for the session.
+func (mdbc *MariaDBCluster) TimeZoneJobTransform(job *batch.Job) error {
+	labels := mdbc.GetServerLabels()
+	labels[MariaDBClusterRoleLabel] = MariaDBClusterTimeZoneRole
+	backoffLimit := int32(1)
+	color := mdbc.GetActiveColor()
+	var hosts []string
+	for ordinal := int32(0); ordinal < mdbc.Spec.Replicas; ordinal++ {
+		hosts = append(hosts, fmt.Sprintf("%s-%d.%s", mdbc.GetServerNameForColor(color), ordinal, mdbc.GetServerServiceNameForColor(color)))
+	}
+
+	job.SetName(mdbc.GetTimeZoneJobName())
+	job.SetNamespace(mdbc.Namespace)
+	job.SetLabels(labels)
+	job.SetOwnerReferences([]metav1.OwnerReference{
+		*metav1.NewControllerRef(mdbc, schema.GroupVersionKind{
+			Group:   GroupName,
+			Version: Version,
+			Kind:    "MariaDBCluster",
+		}),
+	})
+	job.Spec.BackoffLimit = &backoffLimit
+	job.Spec.Template.ObjectMeta.Labels = labels
+	job.Spec.Template.Spec.RestartPolicy = v1.RestartPolicyNever
+	if len(job.Spec.Template.Spec.Containers) < 1 {
+		job.Spec.Template.Spec.Containers = append(job.Spec.Template.Spec.Containers, v1.Container{})
+	}
+	job.Spec.Template.Spec.Containers[0].Name = MariaDBClusterTimeZoneRole
+	job.Spec.Template.Spec.Containers[0].Image = mdbc.GetServerImage()
+	job.Spec.Template.Spec.Containers[0].ImagePullPolicy = mdbc.GetImagePullPolicy(v1.PullIfNotPresent)
+	job.Spec.Template.Spec.Containers[0].TerminationMessagePolicy = v1.TerminationMessageReadFile
+	job.Spec.Template.Spec.Containers[0].Command = []string{"bash", "-c",
+		"set -o pipefail; for h in " + strings.Join(hosts, " ") + "; do " +
+			"(echo 'SET SESSION wsrep_on=OFF;'; mysql_tzinfo_to_sql /usr/share/zoneinfo 2>/dev/null) | mysql -h $h mysql 2>/tmp/error.log || " +
+			"{ echo \"$h: $(tail -c 2000 /tmp/error.log)\" | tee /dev/termination-log; exit 1; }; done"}
+	mdbc.inheritMetadata(&job.ObjectMeta)
+	mdbc.inheritMetadata(&job.Spec.Template.ObjectMeta)
+	mdbc.confinePod(&job.Spec.Template)
+	return nil
+}
@@ -0,0 +1,65 @@
+package v1alpha1
+
+import (
+	"fmt"
+
+	batch "k8s.io/api/batch/v1"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// InitSQLJobTransform renders a Job running a script of Spec.InitSQL through
+// the first server pod, reporting the output of mysql through its
+// termination message. It is not retried, a script failing half way may not
+// be safe to run again.
+func (mdbc *MariaDBCluster) InitSQLJobTransform(job *batch.Job, script InitSQLScript) error {
+	labels := mdbc.GetServerLabels()
+	labels[MariaDBClusterRoleLabel] = MariaDBClusterInitSQLRole
+	backoffLimit := int32(0)
+	color := mdbc.GetActiveColor()
+	host := fmt.Sprintf("%s-0.%s", mdbc.GetServerNameForColor(color), mdbc.GetServerServiceNameForColor(color))
+
+	job.SetName(mdbc.GetInitSQLJobName(script.Name))
+	job.SetNamespace(mdbc.Namespace)
+	job.SetLabels(labels)
+	job.SetOwnerReferences([]metav1.OwnerReference{
+		*metav1.NewControllerRef(mdbc, schema.GroupVersionKind{
+			Group:   GroupName,
+			Version: Version,
+			Kind:    "MariaDBCluster",
+		}),
+	})
+	job.Spec.BackoffLimit = &backoffLimit
+	job.Spec.Template.ObjectMeta.Labels = labels
+	job.Spec.Template.Spec.RestartPolicy = v1.RestartPolicyNever
+	source := v1.VolumeSource{}
+	if ref := script.ConfigMapKeyRef; ref != nil {
+		source.ConfigMap = &v1.ConfigMapVolumeSource{
+			LocalObjectReference: ref.LocalObjectReference,
+			Items:                []v1.KeyToPath{v1.KeyToPath{Key: ref.Key, Path: "script.sql"}},
+		}
+	} else if ref := script.SecretKeyRef; ref != nil {
+		source.Secret = &v1.SecretVolumeSource{
+			SecretName: ref.Name,
+			Items:      []v1.KeyToPath{v1.KeyToPath{Key: ref.Key, Path: "script.sql"}},
+		}
+	}
+	job.Spec.Template.Spec.Volumes = []v1.Volume{v1.Volume{Name: "script", VolumeSource: source}}
+	if len(job.Spec.Template.Spec.Containers) < 1 {
+		job.Spec.Template.Spec.Containers = append(job.Spec.Template.Spec.Containers, v1.Container{})
+	}
+	job.Spec.Template.Spec.Containers[0].Name = MariaDBClusterInitSQLRole
+	job.Spec.Template.Spec.Containers[0].Image = mdbc.GetServerImage()
+	job.Spec.Template.Spec.Containers[0].ImagePullPolicy = mdbc.GetImagePullPolicy(v1.PullIfNotPresent)
+	job.Spec.Template.Spec.Containers[0].TerminationMessagePolicy = v1.TerminationMessageReadFile
+	job.Spec.Template.Spec.Containers[0].VolumeMounts = []v1.VolumeMount{
+		v1.VolumeMount{Name: "script", MountPath: "/initsql", ReadOnly: true},
+	}
+	job.Spec.Template.Spec.Containers[0].Command = []string{"bash", "-c",
+		"set -o pipefail; mysql -h " + host + " < /initsql/script.sql 2>&1 | tail -c 2048 | tee /dev/termination-log"}
+	mdbc.inheritMetadata(&job.ObjectMeta)
+	mdbc.inheritMetadata(&job.Spec.Template.ObjectMeta)
+	mdbc.confinePod(&job.Spec.Template)
+	return nil
+}
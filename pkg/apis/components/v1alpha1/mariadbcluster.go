@@ -1,11 +1,20 @@
 package v1alpha1
 
 import (
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
 	"k8s.io/api/core/v1"
 	apiextensionsv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation"
 )
 
 const (
@@ -13,8 +22,151 @@ const (
 	MariaDBClusterNameLabel   string = MariaDBClusterLabelPrefix + "cluster-name"
 	MariaDBClusterRoleLabel   string = MariaDBClusterLabelPrefix + "role"
 
+	// zone labels of nodes, the legacy one is used by Kubernetes before 1.17
+	ZoneLabel       string = "topology.kubernetes.io/zone"
+	LegacyZoneLabel string = "failure-domain.beta.kubernetes.io/zone"
+	// node name label of nodes
+	HostnameLabel string = "kubernetes.io/hostname"
+
+	MariaDBClusterVersionAnnotation string = MariaDBClusterLabelPrefix + "version"
+	MariaDBClusterGCacheAnnotation  string = MariaDBClusterLabelPrefix + "gcache-size"
+	MariaDBClusterSSTAnnotation     string = MariaDBClusterLabelPrefix + "sst-method"
+	// hash of the configuration pods only read on startup, see GetConfigHash
+	MariaDBClusterConfigAnnotation string = MariaDBClusterLabelPrefix + "config-hash"
+	// hash of the data of the server ConfigMap, see GetConfigMapContentHash
+	MariaDBClusterContentAnnotation string = MariaDBClusterLabelPrefix + "content-hash"
+	// hash of what the operator last rendered for a StatefulSet or Service,
+	// set apart from edits of others like the content hash of the ConfigMap
+	MariaDBClusterRenderedAnnotation string = MariaDBClusterLabelPrefix + "rendered-hash"
+	// "true" on a MariaDBCluster has the webhook refuse its deletion
+	MariaDBClusterDeletionProtectionAnnotation string = MariaDBClusterLabelPrefix + "deletion-protection"
+	// "true" on a MariaDBCluster has it adopt a StatefulSet and ConfigMap
+	// found under its names before bootstrap
+	MariaDBClusterAdoptAnnotation string = MariaDBClusterLabelPrefix + "adopt"
+	// API version a MariaDBCluster was last written in by the operator
+	MariaDBClusterStorageVersionAnnotation string = MariaDBClusterLabelPrefix + "storage-version"
+	// annotations below it switch experimental behaviour on for one cluster,
+	// as mariadb.galera/feature.parallel-join: "true"
+	FeatureAnnotationPrefix string = "mariadb.galera/feature."
+	// "true" on a MariaDBCluster puts it in maintenance as spec.maintenance
+	MariaDBClusterMaintenanceAnnotation string = MariaDBClusterLabelPrefix + "maintenance"
+	// "true" on a MariaDBCluster has the operator only tell what it would
+	// change, as --dry-run does for every cluster
+	MariaDBClusterDryRunAnnotation string = MariaDBClusterLabelPrefix + "dry-run"
+
 	MariaDBClusterServerRole string = "server"
 	MariaDBClusterProxyRole  string = "proxy"
+	MariaDBClusterBackupRole string = "backup"
+
+	MariaDBClusterProviderCheckRole string = "provider-check"
+	MariaDBClusterImageResolveRole  string = "image-resolve"
+	MariaDBClusterBlueGreenRole     string = "bluegreen"
+	MariaDBClusterWriteRateRole     string = "write-rate"
+	MariaDBClusterSSTCheckRole      string = "sst-check"
+	MariaDBClusterWSREPRecoverRole  string = "wsrep-recover"
+	MariaDBClusterConfigCheckRole   string = "config-check"
+	MariaDBClusterTimeZoneRole      string = "tzinfo"
+	MariaDBClusterInitSQLRole       string = "init-sql"
+	// carried by no pod, services selecting it are left without endpoints
+	MariaDBClusterFencedRole string = "fenced"
+
+	// held by clusters until the operator ran their teardown
+	MariaDBClusterFinalizer string = "components.dsg.dk/teardown"
+
+	// storage retention and cluster reclaim policies, applied when the cluster is deleted
+	RetentionPolicyRetain string = "Retain"
+	RetentionPolicyDelete string = "Delete"
+
+	UpgradeStrategyRolling   string = "Rolling"
+	UpgradeStrategyBlueGreen string = "BlueGreen"
+
+	// StatefulSet controller replaces pods as soon as their template changes
+	UpdateStrategyRollingUpdate string = "RollingUpdate"
+	// Operator replaces one pod at a time, once the rest of the cluster is Synced
+	UpdateStrategyOperator string = "Operator"
+	// Pods are replaced only when deleted by hand
+	UpdateStrategyOnDelete string = "OnDelete"
+
+	// PodDisruptionBudget letting one server pod be evicted at a time
+	DisruptionBudgetMaxUnavailable string = "MaxUnavailable"
+	// PodDisruptionBudget letting server pods be evicted while a majority of
+	// them is left
+	DisruptionBudgetQuorum string = "Quorum"
+	// No PodDisruptionBudget
+	DisruptionBudgetNone string = "None"
+
+	SSTMethodRsync       string = "rsync"
+	SSTMethodMariaBackup string = "mariabackup"
+	// database user mariabackup authenticates as on the donor
+	SSTUser string = "mdbc_sst"
+	// key of the SST user password in the server Secret
+	SSTPasswordKey string = "sst-password"
+	// key of the key server pods sign their reports with in the server Secret
+	ReportKeyKey string = "report-key"
+	// database user the Jobs of the operator log in as over the network
+	OperatorUser string = "mdbc_operator"
+	// key of the operator user password in the server Secret
+	OperatorPasswordKey string = "operator-password"
+	// database user clients log in as, published in the client Secret. It has
+	// no administrative privileges, read_only fences it like any client.
+	ClientUser string = "mdbc_client"
+
+	// A split brain is reported and left to be resolved by hand
+	SplitBrainPolicyManual string = "Manual"
+	// Pods outside of the largest primary component are restarted to rejoin it
+	SplitBrainPolicyKeepLargest string = "KeepLargest"
+	// A cluster without any primary component goes through Recovery
+	NonPrimaryPolicyRecover string = "Recover"
+	// The most advanced pod of a cluster without primary component bootstraps one
+	NonPrimaryPolicyBootstrap string = "Bootstrap"
+
+	// performance_schema disabled altogether
+	InstrumentationOff string = "Off"
+	// Statement digests only, for query analysis at little memory
+	InstrumentationMinimal string = "Minimal"
+	// Every instrument and consumer, memory instrumentation included
+	InstrumentationFull string = "Full"
+
+	// Blue is the original generation of server objects, green the parallel one
+	// built during a blue/green upgrade, they swap roles after each such upgrade
+	ColorBlue  string = "blue"
+	ColorGreen string = "green"
+
+	DefaultVersion               string = "10.2"
+	MinimumVersion               string = "10.2.8"
+	DefaultServerImage           string = "mariadb"
+	DefaultOperatorImage         string = "goblain/mdbc:dev"
+	DefaultBackupMaxAge                 = time.Hour
+	DefaultImageDigestRefresh           = time.Hour
+	DefaultISTWindow                    = 10 * time.Minute
+	DefaultSSTTimeout                   = time.Hour
+	DefaultBootstrapTimeout             = 30 * time.Minute
+	DefaultRecoveryTimeout              = time.Hour
+	DefaultReportRetryTimeout           = 30 * time.Minute
+	DefaultUnsyncedTimeout              = 15 * time.Minute
+	DefaultBootstrapReadyTimeout        = 10 * time.Minute
+	DefaultSuspectTimeout               = 10 * time.Second
+	DefaultInactiveTimeout              = 30 * time.Second
+	DefaultInstallTimeout               = 15 * time.Second
+	DefaultSSTMethod             string = SSTMethodRsync
+	// galera default of gcache.size
+	DefaultGCacheSizeMB int64 = 128
+	// galera default of gcs.fc_limit
+	DefaultFlowControlLimit         = 16
+	DefaultFlowControlMaxLimit      = 256
+	DefaultFlowControlPausedPercent = 10
+	DefaultBufferPoolMemoryPercent  = 60
+	// wait of a blue/green switch for the standby cluster to catch up with
+	// writes fenced on the serving one
+	DefaultBlueGreenCatchUpTimeoutSeconds = 300
+	// galera ports, the group communication one is not configurable
+	DefaultMySQLPort int32 = 3306
+	DefaultWSREPPort int32 = 4567
+	DefaultISTPort   int32 = 4568
+	DefaultSSTPort   int32 = 4444
+	// filled in by the defaulting webhook when left out of the spec
+	DefaultReplicas    int32  = 3
+	DefaultStorageSize string = "10Gi"
 )
 
 var ()
@@ -38,6 +190,10 @@ func GetCRDs() []*apiextensionsv1beta1.CustomResourceDefinition {
 				Plural: ResourcePlural,
 				Kind:   ResourceKind,
 			},
+			// status is written apart from the spec, by the operator and agents
+			Subresources: &apiextensionsv1beta1.CustomResourceSubresources{
+				Status: &apiextensionsv1beta1.CustomResourceSubresourceStatus{},
+			},
 		},
 	}
 	return []*apiextensionsv1beta1.CustomResourceDefinition{mariadbcluster}
@@ -64,6 +220,20 @@ type MariaDBCluster struct {
 type MariaDBClusterSpec struct {
 	// MariaDB container/engine version, no less then 10.2.8
 	Version string `json:"version"`
+	// Server image digest (sha256:...) pods are pinned to, regardless of the version tag
+	ImageDigest string `json:"imageDigest,omitempty"`
+	// Resolve the digest behind the version tag periodically and pin pods to it,
+	// so pods only roll when the tag actually moved to a different image
+	PinImageDigest bool `json:"pinImageDigest,omitempty"`
+	// Repository of the server image, tagged with Version. Defaults to mariadb.
+	Image string `json:"image,omitempty"`
+	// Image of the init container preparing server pods and of config Jobs
+	InitImage string `json:"initImage,omitempty"`
+	// Image of the agent container reporting the galera state of each pod
+	AgentImage string `json:"agentImage,omitempty"`
+	// Pull policy of every container run from the images above, each keeps
+	// its own default when unset
+	ImagePullPolicy v1.PullPolicy `json:"imagePullPolicy,omitempty"`
 	// Pause any control from operator on this resource
 	Paused        bool                    `json:"paused"`
 	Replicas      int32                   `json:"replicas"`
@@ -71,12 +241,737 @@ type MariaDBClusterSpec struct {
 	Resources     v1.ResourceRequirements `json:"resources"`
 	Storages      Storages                `json:"storages"`
 	ServerConfig  string                  `json:"serverConfig"`
-	Proxy         bool                    `json:"proxy"`
+	// my.cnf fragment added to the server configuration, rendered as a Go
+	// template for each pod. It may not set keys the operator manages.
+	Config ServerConfigSource `json:"config,omitempty"`
+	// Sizing of the InnoDB buffer pool from the memory limit
+	BufferPool BufferPoolPolicy `json:"bufferPool,omitempty"`
+	// Server defaults rendered by the operator, spec.config may not set them
+	Server ServerSettings `json:"server,omitempty"`
+	// SQL scripts run once each, in order, once the cluster is Operational
+	InitSQL []InitSQLScript `json:"initSQL,omitempty"`
+	Proxy   bool            `json:"proxy"`
+	// Policies applied when Version changes on a running cluster
+	Upgrade UpgradePolicy `json:"upgrade,omitempty"`
+	// How server pods pick up changes once the cluster is Operational, one of
+	// RollingUpdate (default), Operator or OnDelete
+	UpdateStrategy string `json:"updateStrategy,omitempty"`
+	// Policies applied when the cluster lost all of its ready pods
+	Recovery RecoveryPolicy `json:"recovery,omitempty"`
+	// Galera replication settings
+	Galera GaleraConfig `json:"galera,omitempty"`
+	// Timings of the probes of the server container
+	Probes ProbeSettings `json:"probes,omitempty"`
+	// Clean stop of the server container, so that a pod coming back rejoins
+	// through IST rather than SST
+	Shutdown ShutdownPolicy `json:"shutdown,omitempty"`
+	// Kernel parameters set or checked on the nodes of server pods
+	KernelTuning KernelTuning `json:"kernelTuning,omitempty"`
+	// Steps run on deletion of the cluster, before its objects go away
+	Teardown TeardownPolicy `json:"teardown,omitempty"`
+	// Retain or Delete the volumes, backups and Secret once the cluster is
+	// deleted, the retentionPolicy of a storage takes precedence
+	ReclaimPolicy string `json:"reclaimPolicy,omitempty"`
+	// Time the cluster may spend in a phase before it is reported as stalled,
+	// keyed by phase. 30m for bootstrap phases and 1h for Recovery unless set,
+	// a zero duration disables the timeout of a phase.
+	PhaseTimeouts map[string]metav1.Duration `json:"phaseTimeouts,omitempty"`
+	// Automation of the operator held back while the cluster is otherwise
+	// reconciled, unlike Paused
+	Suspend SuspendPolicy `json:"suspend,omitempty"`
+	// Labels and annotations stamped onto every object the operator creates
+	// for the cluster and onto the templates of its pods
+	InheritMetadata MetadataTemplate `json:"inheritMetadata,omitempty"`
+	// Labels and annotations of server pods only, as scrape annotations or
+	// mesh injection toggles, taking precedence over InheritMetadata
+	PodMetadata MetadataTemplate `json:"podMetadata,omitempty"`
+	// Seccomp and AppArmor profiles confining the containers of every pod of
+	// the cluster
+	SecurityProfiles SecurityProfiles `json:"securityProfiles,omitempty"`
+	// Existing ServiceAccount server pods and Jobs run as, the operator
+	// creates one named after the cluster when unset
+	ServiceAccountName string `json:"serviceAccountName,omitempty"`
+	// Create no Role and RoleBinding, the ServiceAccount is then granted what
+	// the agents need by other means
+	SkipRBAC bool `json:"skipRBAC,omitempty"`
+	// Keep checking and reporting health but take no corrective action, no
+	// pod is restarted, recovered or upgraded by the operator
+	Maintenance bool `json:"maintenance,omitempty"`
+	// Affinity of server pods, set on their template as is. Unset, pods of a
+	// color are required to run on distinct nodes and preferably in distinct
+	// zones, an empty affinity drops that default.
+	Affinity *v1.Affinity `json:"affinity,omitempty"`
+	// Topology spread constraints of server pods, a constraint without a
+	// selector counting the pods of its color. Unset, pods of a color are
+	// spread over zones as far as capacity allows, an empty list drops that
+	// default. Not omitted when empty, so that such a list survives conversion.
+	TopologySpreadConstraints []TopologySpreadConstraint `json:"topologySpreadConstraints"`
+	// Tolerations and node selector of server pods, set on their template as
+	// they are, to pin a cluster to nodes dedicated to databases
+	Tolerations  []v1.Toleration   `json:"tolerations,omitempty"`
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+	// PodDisruptionBudget keeping evictions, as of node drains, from taking
+	// away quorum, one of MaxUnavailable (default), Quorum or None
+	DisruptionBudget string `json:"disruptionBudget,omitempty"`
+	// PriorityClass of server pods, and of proxy pods, so that databases are
+	// not the first pods preempted when nodes run short
+	PriorityClassName      string `json:"priorityClassName,omitempty"`
+	ProxyPriorityClassName string `json:"proxyPriorityClassName,omitempty"`
+	// Host aliases and DNS settings of server pods, as for split-horizon DNS
+	// or replication peers outside of the cluster addressed by hostname
+	HostAliases []v1.HostAlias   `json:"hostAliases,omitempty"`
+	DNSPolicy   v1.DNSPolicy     `json:"dnsPolicy,omitempty"`
+	DNSConfig   *v1.PodDNSConfig `json:"dnsConfig,omitempty"`
+	// Containers of the user run in server pods after the ones of the
+	// operator, as log shippers or agents. They can mount the data and config
+	// volumes.
+	Sidecars []v1.Container `json:"sidecars,omitempty"`
+	// Init containers of the user run in order before the initializer of the
+	// operator, as to prepare data or set sysctls
+	InitContainers []v1.Container `json:"initContainers,omitempty"`
+	// Volumes of the user added to server pods, as CA bundles or UDF
+	// libraries, mounted into containers of the operator by VolumeMounts and
+	// into sidecars and init containers of the user by their own mounts
+	Volumes      []v1.Volume            `json:"volumes,omitempty"`
+	VolumeMounts []ContainerVolumeMount `json:"volumeMounts,omitempty"`
+	// Resources of the initializer and agent containers, spec.resources
+	// being the ones of the server container
+	ContainerResources ContainerResources `json:"containerResources,omitempty"`
+	// Environment of the user set on the server and agent containers, after
+	// the variables of the operator, which it may not set
+	Env     []v1.EnvVar        `json:"env,omitempty"`
+	EnvFrom []v1.EnvFromSource `json:"envFrom,omitempty"`
 	// Notifications
 	//   slack
 	//   email
 }
 
+// MetadataTemplate holds metadata added to the objects of a cluster, as needed
+// by cost allocation, backup selectors or policy engines. Keys under
+// MariaDBClusterLabelPrefix belong to the operator and can not be set.
+type MetadataTemplate struct {
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// SuspendPolicy turns off parts of what the operator does on its own, as
+// during an incident where one of them is not to be trusted
+type SuspendPolicy struct {
+	// Start no backup Jobs: upgrades requiring a backup wait for one taken
+	// elsewhere, and no final backup is taken on deletion
+	Backups bool `json:"backups,omitempty"`
+	// Delete no server pods on what their agents report, nor to roll them out
+	// with the Operator update strategy
+	Restarts bool `json:"restarts,omitempty"`
+	// Leave a cluster without ready pods as it is, and hold a Recovery under way
+	Recovery bool `json:"recovery,omitempty"`
+}
+
+type TeardownPolicy struct {
+	// Dump the cluster into the snapshot volume before its pods are removed,
+	// the snapshot volume is then retained unless its policy says otherwise
+	FinalBackup bool `json:"finalBackup,omitempty"`
+}
+
+type ProbeSettings struct {
+	// mysqladmin ping, the container restarts when it fails
+	Liveness ProbeTimings `json:"liveness,omitempty"`
+	// wsrep_local_state_comment being Synced, pods only serve once it passes
+	Readiness ProbeTimings `json:"readiness,omitempty"`
+}
+
+// ProbeTimings of a probe, zero fields take those of DefaultLivenessProbe or
+// DefaultReadinessProbe
+type ProbeTimings struct {
+	InitialDelaySeconds int32 `json:"initialDelaySeconds,omitempty"`
+	PeriodSeconds       int32 `json:"periodSeconds,omitempty"`
+	TimeoutSeconds      int32 `json:"timeoutSeconds,omitempty"`
+	// Failures in a row after which the probe fails, a server starting on a
+	// large dataset gets failureThreshold times periodSeconds past the initial
+	// delay before liveness restarts it
+	FailureThreshold int32 `json:"failureThreshold,omitempty"`
+}
+
+var (
+	DefaultLivenessProbe  = ProbeTimings{InitialDelaySeconds: 30, PeriodSeconds: 5, TimeoutSeconds: 2, FailureThreshold: 3}
+	DefaultReadinessProbe = ProbeTimings{InitialDelaySeconds: 10, PeriodSeconds: 2, TimeoutSeconds: 2, FailureThreshold: 3}
+)
+
+func (p *ProbeSettings) GetLiveness() ProbeTimings {
+	return p.Liveness.withDefaults(DefaultLivenessProbe)
+}
+
+func (p *ProbeSettings) GetReadiness() ProbeTimings {
+	return p.Readiness.withDefaults(DefaultReadinessProbe)
+}
+
+func (t ProbeTimings) withDefaults(defaults ProbeTimings) ProbeTimings {
+	if t.InitialDelaySeconds == 0 {
+		t.InitialDelaySeconds = defaults.InitialDelaySeconds
+	}
+	if t.PeriodSeconds == 0 {
+		t.PeriodSeconds = defaults.PeriodSeconds
+	}
+	if t.TimeoutSeconds == 0 {
+		t.TimeoutSeconds = defaults.TimeoutSeconds
+	}
+	if t.FailureThreshold == 0 {
+		t.FailureThreshold = defaults.FailureThreshold
+	}
+	return t
+}
+
+// ShutdownPolicy of the server container. Its preStop hook waits for the
+// transactions in progress, sets innodb_fast_shutdown and shuts the server
+// down, which leaves the seqno in grastate.dat for the pod to rejoin by IST.
+type ShutdownPolicy struct {
+	// Seconds a terminating server pod is given before it is killed, 120 by
+	// default
+	TerminationGracePeriodSeconds int64 `json:"terminationGracePeriodSeconds,omitempty"`
+	// Seconds the transactions in progress are waited for, 60 by default
+	TransactionTimeoutSeconds int32 `json:"transactionTimeoutSeconds,omitempty"`
+	// innodb_fast_shutdown of the stop, 1 by default, 0 for a slow shutdown
+	// purging and merging the change buffer
+	InnoDBFastShutdown *int32 `json:"innodbFastShutdown,omitempty"`
+}
+
+var (
+	DefaultTerminationGracePeriodSeconds = int64(120)
+	DefaultTransactionTimeoutSeconds     = int32(60)
+	DefaultInnoDBFastShutdown            = int32(1)
+)
+
+func (p *ShutdownPolicy) GetTerminationGracePeriodSeconds() int64 {
+	if p.TerminationGracePeriodSeconds == 0 {
+		return DefaultTerminationGracePeriodSeconds
+	}
+	return p.TerminationGracePeriodSeconds
+}
+
+func (p *ShutdownPolicy) GetTransactionTimeoutSeconds() int32 {
+	if p.TransactionTimeoutSeconds == 0 {
+		return DefaultTransactionTimeoutSeconds
+	}
+	return p.TransactionTimeoutSeconds
+}
+
+func (p *ShutdownPolicy) GetInnoDBFastShutdown() int32 {
+	if p.InnoDBFastShutdown == nil {
+		return DefaultInnoDBFastShutdown
+	}
+	return *p.InnoDBFastShutdown
+}
+
+// Validate refuses a wait for transactions leaving the server no time to stop
+// within the grace period, and innodb_fast_shutdown values of no server
+func (p *ShutdownPolicy) Validate() error {
+	if p.TerminationGracePeriodSeconds < 0 || p.TransactionTimeoutSeconds < 0 {
+		return fmt.Errorf("shutdown timings can not be negative")
+	}
+	if int64(p.GetTransactionTimeoutSeconds()) >= p.GetTerminationGracePeriodSeconds() {
+		return fmt.Errorf("shutdown transactionTimeoutSeconds %d leaves no time to stop within terminationGracePeriodSeconds %d", p.GetTransactionTimeoutSeconds(), p.GetTerminationGracePeriodSeconds())
+	}
+	if fast := p.GetInnoDBFastShutdown(); fast < 0 || fast > 3 {
+		return fmt.Errorf("shutdown innodbFastShutdown %d is not one of 0, 1, 2, 3", fast)
+	}
+	return nil
+}
+
+const (
+	// a privileged init container sets the sysctls on the node
+	KernelTuningApply = "Apply"
+	// an init container only reports the sysctls of the node differing
+	KernelTuningValidate = "Validate"
+)
+
+// RecommendedSysctls are the kernel parameters KernelTuning sets or checks
+// unless replaced: the buffer pool kept out of swap, and TCP buffers large
+// enough for replication and state transfers across zones. The latter belong
+// to the network namespace of the pod, net.core.rmem_max and wmem_max can not
+// be reached from it.
+var RecommendedSysctls = map[string]string{
+	"vm.swappiness":     "1",
+	"net.ipv4.tcp_rmem": "4096 87380 16777216",
+	"net.ipv4.tcp_wmem": "4096 65536 16777216",
+}
+
+// KernelTuning has an init container of server pods set or check kernel
+// parameters of the node they run on, ahead of the initializer. A node is
+// shared with whatever else runs on it, Apply changes it for all of that.
+type KernelTuning struct {
+	// Apply or Validate, nodes are left alone when empty
+	Mode string `json:"mode,omitempty"`
+	// Values by sysctl name over the recommended ones, an empty value leaves
+	// a recommended one out
+	Sysctls map[string]string `json:"sysctls,omitempty"`
+}
+
+// GetSysctls returns the sysctls set or checked as name=value, sorted by name
+func (k *KernelTuning) GetSysctls() []string {
+	values := make(map[string]string)
+	for name, value := range RecommendedSysctls {
+		values[name] = value
+	}
+	for name, value := range k.Sysctls {
+		values[name] = value
+	}
+	var sysctls []string
+	for name, value := range values {
+		if value != "" {
+			sysctls = append(sysctls, name+"="+value)
+		}
+	}
+	sort.Strings(sysctls)
+	return sysctls
+}
+
+func (k *KernelTuning) Validate() error {
+	switch k.Mode {
+	case "", KernelTuningApply, KernelTuningValidate:
+	default:
+		return fmt.Errorf("kernelTuning mode %q is not one of %s, %s", k.Mode, KernelTuningApply, KernelTuningValidate)
+	}
+	for name, value := range k.Sysctls {
+		if !sysctlNameRegexp.MatchString(name) {
+			return fmt.Errorf("kernelTuning sysctl %q is not a sysctl name", name)
+		}
+		if value != "" && isNodeNetSysctl(name) {
+			return fmt.Errorf("kernelTuning sysctl %q belongs to the network namespace of the node, pods can neither read nor set it", name)
+		}
+	}
+	return nil
+}
+
+// isNodeNetSysctl tells the net.core sysctls kept by the network namespace of
+// the node only, which the tune container does not run in. The other net ones
+// are those of the pod, which the server shares.
+func isNodeNetSysctl(name string) bool {
+	return strings.HasPrefix(name, "net.core.") && name != "net.core.somaxconn"
+}
+
+const (
+	// pods exceeding maxSkew are left pending
+	TopologySpreadDoNotSchedule = "DoNotSchedule"
+	// pods exceeding maxSkew are scheduled on the nodes skewing least
+	TopologySpreadScheduleAnyway = "ScheduleAnyway"
+)
+
+// TopologySpreadConstraint mirrors the pod spec field the vendored API
+// predates, so that it is added to the server StatefulSets as raw JSON.
+// Kubernetes 1.18 is needed for it to take effect.
+type TopologySpreadConstraint struct {
+	MaxSkew           int32                 `json:"maxSkew"`
+	TopologyKey       string                `json:"topologyKey"`
+	WhenUnsatisfiable string                `json:"whenUnsatisfiable"`
+	LabelSelector     *metav1.LabelSelector `json:"labelSelector,omitempty"`
+}
+
+func (c *TopologySpreadConstraint) Validate() error {
+	if c.MaxSkew < 1 {
+		return fmt.Errorf("topologySpreadConstraint maxSkew %d is not positive", c.MaxSkew)
+	}
+	if c.TopologyKey == "" {
+		return fmt.Errorf("topologySpreadConstraint has no topologyKey")
+	}
+	switch c.WhenUnsatisfiable {
+	case TopologySpreadDoNotSchedule, TopologySpreadScheduleAnyway:
+	default:
+		return fmt.Errorf("topologySpreadConstraint whenUnsatisfiable %q is not one of %s, %s", c.WhenUnsatisfiable, TopologySpreadDoNotSchedule, TopologySpreadScheduleAnyway)
+	}
+	return nil
+}
+
+type ServerConfigSource struct {
+	Inline string `json:"inline,omitempty"`
+	// Key of a ConfigMap in the namespace of the cluster
+	ConfigMapKeyRef *v1.ConfigMapKeySelector `json:"configMapKeyRef,omitempty"`
+}
+
+type InitSQLScript struct {
+	// Name the script is tracked by in status, runs again under another name only
+	Name string `json:"name"`
+	// Key of a ConfigMap or Secret in the namespace of the cluster holding the SQL
+	ConfigMapKeyRef *v1.ConfigMapKeySelector `json:"configMapKeyRef,omitempty"`
+	SecretKeyRef    *v1.SecretKeySelector    `json:"secretKeyRef,omitempty"`
+}
+
+// ContainerResources are the resources of the containers of the operator
+// running along the server, small requests unless set
+type ContainerResources struct {
+	Init  *v1.ResourceRequirements `json:"init,omitempty"`
+	Agent *v1.ResourceRequirements `json:"agent,omitempty"`
+}
+
+// ContainerVolumeMount mounts a volume of spec.volumes into containers of the
+// operator
+type ContainerVolumeMount struct {
+	// Names of the containers, among mariadb, init, debug and agent
+	Containers     []string `json:"containers"`
+	v1.VolumeMount `json:",inline"`
+}
+
+type ServerSettings struct {
+	// character_set_server, e.g. utf8mb4
+	CharacterSet string `json:"characterSet,omitempty"`
+	// collation_server, of the character set when both are set
+	Collation string `json:"collation,omitempty"`
+	// sql_mode as a comma separated list of modes, empty to clear every mode
+	SQLMode *string `json:"sqlMode,omitempty"`
+	// default_time_zone, SYSTEM, an offset such as +00:00 or a named zone
+	// such as Europe/Copenhagen, the latter needing the time zone tables
+	TimeZone string `json:"timeZone,omitempty"`
+	// Load the time zone tables once the cluster is first Operational, also
+	// done for a named TimeZone
+	LoadTimeZones bool `json:"loadTimeZones,omitempty"`
+	// Plugins loaded with plugin_load_add, their library has to be shipped
+	// with the server image
+	Plugins []ServerPlugin `json:"plugins,omitempty"`
+	// performance_schema profile, one of Off, Minimal or Full, MariaDB
+	// defaults apply unless set
+	Instrumentation string `json:"instrumentation,omitempty"`
+	// Binary logging, for point in time recovery and replicas outside of
+	// the cluster
+	Binlog BinlogSettings `json:"binlog,omitempty"`
+}
+
+type BinlogSettings struct {
+	// Write binary logs into the data volume, log_bin
+	Enabled bool `json:"enabled,omitempty"`
+	// binlog_format, ROW unless set. Galera only replicates ROW events
+	// reliably, MIXED and STATEMENT are for replicas that need them.
+	Format string `json:"format,omitempty"`
+	// How long binary logs are kept, rendered as binlog_expire_logs_seconds
+	// and expire_logs_days in whole days for servers without the former
+	ExpireLogs *metav1.Duration `json:"expireLogs,omitempty"`
+	// server_id of the first pod, the others follow by ordinal and pods of
+	// the green color start serverIDColorOffset above. Defaults to 1 with
+	// binary logging enabled.
+	ServerIDBase *int64 `json:"serverIDBase,omitempty"`
+	// log_slave_updates, have pods log the writes galera replicates into
+	// them so that a replica of any pod gets all of them
+	LogSlaveUpdates bool `json:"logSlaveUpdates,omitempty"`
+}
+
+type ServerPlugin struct {
+	// audit, cracklib, spider, connect or the name of the plugin as listed
+	// by information_schema.plugins
+	Name string `json:"name"`
+	// Library of the plugin without extension, only needed for plugins the
+	// operator does not know
+	Library string `json:"library,omitempty"`
+}
+
+type BufferPoolPolicy struct {
+	// Share of the memory limit of the server container given to the buffer
+	// pool, defaults to 60
+	MemoryPercent int `json:"memoryPercent,omitempty"`
+	// Leave innodb_buffer_pool_size to MariaDB or the server configuration
+	Disabled bool `json:"disabled,omitempty"`
+}
+
+func (b *BufferPoolPolicy) GetMemoryPercent() int {
+	if b.MemoryPercent == 0 {
+		return DefaultBufferPoolMemoryPercent
+	}
+	return b.MemoryPercent
+}
+
+type UpgradePolicy struct {
+	// Hold the upgrade until a successful backup of the current version exists
+	RequireBackup bool `json:"requireBackup,omitempty"`
+	// Age after which a backup is no longer considered fresh, defaults to 1h
+	BackupMaxAge *metav1.Duration `json:"backupMaxAge,omitempty"`
+	// Start a backup Job automatically instead of waiting for one to appear
+	TriggerBackup bool `json:"triggerBackup,omitempty"`
+	// Roll out without comparing galera providers of the current and new image
+	SkipProviderCheck bool `json:"skipProviderCheck,omitempty"`
+	// Rolling (default) restarts pods in place, BlueGreen builds a parallel
+	// cluster on the new version, replicates into it and switches services over
+	Strategy string `json:"strategy,omitempty"`
+	// Grow gcache ahead of a rolling upgrade to hold the writes expected while
+	// a pod is restarting, so it rejoins through IST rather than a full SST
+	PresizeGCache bool `json:"presizeGCache,omitempty"`
+	// Time a pod is expected to be away during the upgrade, defaults to 10m
+	ISTWindow *metav1.Duration `json:"istWindow,omitempty"`
+}
+
+func (u *UpgradePolicy) GetBackupMaxAge() time.Duration {
+	if u.BackupMaxAge == nil {
+		return DefaultBackupMaxAge
+	}
+	return u.BackupMaxAge.Duration
+}
+
+func (u *UpgradePolicy) GetISTWindow() time.Duration {
+	if u.ISTWindow == nil {
+		return DefaultISTWindow
+	}
+	return u.ISTWindow.Duration
+}
+
+type RecoveryPolicy struct {
+	// Server pod to bootstrap the primary component from, overriding the seqno
+	// comparison. Applied once, unset it after recovery to be able to force
+	// the same pod again. Any transactions more advanced pods hold are lost.
+	ForceBootstrapFrom string `json:"forceBootstrapFrom,omitempty"`
+	// How a split into several primary components is resolved, Manual by default
+	SplitBrainPolicy string `json:"splitBrainPolicy,omitempty"`
+	// What is done when every pod lost quorum while still running, Recover
+	// (default) restarts all of them through the Recovery phase, Bootstrap
+	// has the most advanced one form a new primary component with pc.bootstrap
+	NonPrimaryPolicy string `json:"nonPrimaryPolicy,omitempty"`
+	// Time spent restarting pods for new reports when those received do not
+	// allow picking a pod to bootstrap from, before waiting on
+	// forceBootstrapFrom. Defaults to 30m, zero waits right away.
+	ReportRetryTimeout *metav1.Duration `json:"reportRetryTimeout,omitempty"`
+	// Time a pod may stay out of Synced while all others are, before it is
+	// restarted. Defaults to 15m, zero never restarts.
+	UnsyncedTimeout *metav1.Duration `json:"unsyncedTimeout,omitempty"`
+	// Time the pod picked to bootstrap from gets to become ready, before it is
+	// excluded and the next best one is picked. Defaults to 10m, zero waits on
+	// it for good.
+	BootstrapReadyTimeout *metav1.Duration `json:"bootstrapReadyTimeout,omitempty"`
+	// Pods held back as their data diverged from the primary component that
+	// may join it anyway, galera then replaces their data through SST
+	OverwriteDiverged []string `json:"overwriteDiverged,omitempty"`
+}
+
+func (r *RecoveryPolicy) GetUnsyncedTimeout() time.Duration {
+	if r.UnsyncedTimeout == nil {
+		return DefaultUnsyncedTimeout
+	}
+	return r.UnsyncedTimeout.Duration
+}
+
+func (r *RecoveryPolicy) GetBootstrapReadyTimeout() time.Duration {
+	if r.BootstrapReadyTimeout == nil {
+		return DefaultBootstrapReadyTimeout
+	}
+	return r.BootstrapReadyTimeout.Duration
+}
+
+func (r *RecoveryPolicy) GetReportRetryTimeout() time.Duration {
+	if r.ReportRetryTimeout == nil {
+		return DefaultReportRetryTimeout
+	}
+	return r.ReportRetryTimeout.Duration
+}
+
+func (r *RecoveryPolicy) GetNonPrimaryPolicy() string {
+	if r.NonPrimaryPolicy == "" {
+		return NonPrimaryPolicyRecover
+	}
+	return r.NonPrimaryPolicy
+}
+
+func (r *RecoveryPolicy) GetSplitBrainPolicy() string {
+	if r.SplitBrainPolicy == "" {
+		return SplitBrainPolicyManual
+	}
+	return r.SplitBrainPolicy
+}
+
+type GaleraConfig struct {
+	// wsrep_sst_method, rsync (default) or mariabackup which keeps the donor
+	// serving writes during the transfer
+	SSTMethod string `json:"sstMethod,omitempty"`
+	// Time a joiner may spend receiving a state transfer before it is restarted
+	// to get it from another donor, defaults to 1h
+	SSTTimeout *metav1.Duration `json:"sstTimeout,omitempty"`
+	// Preferences rendered into wsrep_sst_donor
+	Donor DonorPolicy `json:"donor,omitempty"`
+	// Sizing of gcache.size from observed writes
+	GCache GCachePolicy `json:"gcache,omitempty"`
+	// Set gmcast.segment of each pod from the zone of its node, so that
+	// replication traffic crosses zones once per write set only
+	ZoneSegments bool `json:"zoneSegments,omitempty"`
+	// Detection and mitigation of flow control pauses
+	FlowControl FlowControlPolicy `json:"flowControl,omitempty"`
+	// Quorum weights of pods, galera defaults to 1
+	Weights PCWeights `json:"weights,omitempty"`
+	// Group communication timeouts, defaults are more tolerant than those of
+	// galera so that short network hiccups of pods do not reconfigure the cluster
+	Resilience ResiliencePolicy `json:"resilience,omitempty"`
+	// Addresses incremental state transfers are received on
+	IST ISTConfig `json:"ist,omitempty"`
+	// Ports state transfers are received on
+	Ports GaleraPorts `json:"ports,omitempty"`
+	// Further wsrep_provider_options by name, validated against the options
+	// galera knows and rendered after those the operator sets itself
+	ProviderOptions map[string]string `json:"providerOptions,omitempty"`
+}
+
+type GaleraPorts struct {
+	// Port joiners receive full state transfers on, 4444 by default
+	SST int32 `json:"sst,omitempty"`
+	// Port joiners receive incremental state transfers on, 4568 by default.
+	// A port given in spec.galera.ist.recvAddr takes precedence.
+	IST int32 `json:"ist,omitempty"`
+}
+
+func (p *GaleraPorts) GetSST() int32 {
+	if p.SST == 0 {
+		return DefaultSSTPort
+	}
+	return p.SST
+}
+
+func (p *GaleraPorts) GetIST() int32 {
+	if p.IST == 0 {
+		return DefaultISTPort
+	}
+	return p.IST
+}
+
+func (p *GaleraPorts) Validate() error {
+	sst, ist := p.GetSST(), p.GetIST()
+	for _, port := range []int32{sst, ist} {
+		if port < 1 || port > 65535 {
+			return fmt.Errorf("galera port %d is not a port number", port)
+		}
+		if port == DefaultMySQLPort || port == DefaultWSREPPort {
+			return fmt.Errorf("galera port %d is taken by mysql or group communication", port)
+		}
+	}
+	if sst == ist {
+		return fmt.Errorf("galera sst and ist ports can not both be %d", sst)
+	}
+	return nil
+}
+
+type ISTConfig struct {
+	// ist.recv_addr by pod name, host[:port] other pods reach the pod at when
+	// that is not its own address, as behind NAT or a service mesh. Only
+	// rendered once the operator resolved the host.
+	RecvAddr map[string]string `json:"recvAddr,omitempty"`
+	// ist.recv_bind, local address to listen on, for when recvAddr is not one
+	RecvBind string `json:"recvBind,omitempty"`
+}
+
+type ResiliencePolicy struct {
+	// pc.recovery, pods restore the primary component they were part of by
+	// themselves after all of them restarted, true by default
+	PCRecovery *bool `json:"pcRecovery,omitempty"`
+	// evs.suspect_timeout, silence after which a pod is suspected to be gone, 10s by default
+	SuspectTimeout *metav1.Duration `json:"suspectTimeout,omitempty"`
+	// evs.inactive_timeout, silence after which a pod is dropped whatever
+	// others think, 30s by default
+	InactiveTimeout *metav1.Duration `json:"inactiveTimeout,omitempty"`
+	// evs.install_timeout, time allowed to agree on a new membership, 15s by default
+	InstallTimeout *metav1.Duration `json:"installTimeout,omitempty"`
+}
+
+func (r *ResiliencePolicy) GetPCRecovery() bool {
+	if r.PCRecovery == nil {
+		return true
+	}
+	return *r.PCRecovery
+}
+
+func (r *ResiliencePolicy) GetSuspectTimeout() time.Duration {
+	if r.SuspectTimeout == nil {
+		return DefaultSuspectTimeout
+	}
+	return r.SuspectTimeout.Duration
+}
+
+func (r *ResiliencePolicy) GetInactiveTimeout() time.Duration {
+	if r.InactiveTimeout == nil {
+		return DefaultInactiveTimeout
+	}
+	return r.InactiveTimeout.Duration
+}
+
+func (r *ResiliencePolicy) GetInstallTimeout() time.Duration {
+	if r.InstallTimeout == nil {
+		return DefaultInstallTimeout
+	}
+	return r.InstallTimeout.Duration
+}
+
+// PCWeights sets pc.weight, so that pods of a primary datacenter keep quorum
+// when losing the connection to the others. A pod listed in Pods takes that
+// weight, others that of the zone of their node.
+type PCWeights struct {
+	Pods  map[string]int `json:"pods,omitempty"`
+	Zones map[string]int `json:"zones,omitempty"`
+}
+
+type FlowControlPolicy struct {
+	// Share of time replication may be paused before the cluster is reported
+	// as throttled, defaults to 10
+	PausedThresholdPercent int `json:"pausedThresholdPercent,omitempty"`
+	// Double gcs.fc_limit of the pod requesting the pauses, up to MaxLimit
+	RaiseLimit bool `json:"raiseLimit,omitempty"`
+	// Upper bound of raised limits, defaults to 256
+	MaxLimit int `json:"maxLimit,omitempty"`
+	// Desync the pod requesting the pauses until it caught up, which also
+	// takes it out of traffic. Only done while two other pods are Synced.
+	DesyncLagging bool `json:"desyncLagging,omitempty"`
+}
+
+func (f *FlowControlPolicy) GetPausedThresholdPercent() int {
+	if f.PausedThresholdPercent == 0 {
+		return DefaultFlowControlPausedPercent
+	}
+	return f.PausedThresholdPercent
+}
+
+func (f *FlowControlPolicy) GetMaxLimit() int {
+	if f.MaxLimit == 0 {
+		return DefaultFlowControlMaxLimit
+	}
+	return f.MaxLimit
+}
+
+type GCachePolicy struct {
+	// Size gcache to hold twice the writes of Window, at the busiest time of
+	// the last day, restarting pods whenever the size changes notably
+	Auto bool `json:"auto,omitempty"`
+	// Bounds of the size, MinSizeMB defaults to the galera default of 128M
+	MinSizeMB int64 `json:"minSizeMB,omitempty"`
+	MaxSizeMB int64 `json:"maxSizeMB,omitempty"`
+	// Time a pod may be away and still rejoin through IST, defaults to 10m
+	Window *metav1.Duration `json:"window,omitempty"`
+	// gcache.page_size, size of the overflow pages written when a write set
+	// does not fit into gcache, galera defaults to 128M
+	PageSizeMB int64 `json:"pageSizeMB,omitempty"`
+	// gcache.keep_pages_size, overflow pages kept for IST once written, galera
+	// defaults to none
+	KeepPagesSizeMB int64 `json:"keepPagesSizeMB,omitempty"`
+}
+
+func (g *GCachePolicy) GetWindow() time.Duration {
+	if g.Window == nil {
+		return DefaultISTWindow
+	}
+	return g.Window.Duration
+}
+
+type DonorPolicy struct {
+	// Server pods tried first as donor, in order
+	Preferred []string `json:"preferred,omitempty"`
+	// Prefer donors running in the zone of the joiner
+	PreferSameZone bool `json:"preferSameZone,omitempty"`
+	// Take backups from the last pod and never pick it as donor, so a
+	// state transfer and a backup do not load the same node
+	AvoidBackupSource bool `json:"avoidBackupSource,omitempty"`
+}
+
+func (g *GaleraConfig) GetSSTMethod() string {
+	if g.SSTMethod == "" {
+		return DefaultSSTMethod
+	}
+	return g.SSTMethod
+}
+
+func (g *GaleraConfig) GetSSTTimeout() time.Duration {
+	if g.SSTTimeout == nil {
+		return DefaultSSTTimeout
+	}
+	return g.SSTTimeout.Duration
+}
+
 type Storages struct {
 	Data     Storage `json:"data,omitempty"`
 	Snapshot Storage `json:"snapshot,omitempty"`
@@ -88,7 +983,46 @@ type Storage struct {
 	MaximumSize      string `json:"maxSize"`
 	GrowBy           string `json:"growBy"`
 	GrowThreshold    string `json:"growThreshold"`
-	RetentionPolicy  string //keep data after cluster deleted ?
+	// Retain or Delete the volume when the cluster is deleted
+	RetentionPolicy string `json:"retentionPolicy,omitempty"`
+}
+
+// GetRetentionPolicy returns the retention policy of the storage, or given
+// default when it is not set
+func (s *Storage) GetRetentionPolicy(defaultPolicy string) string {
+	if s.RetentionPolicy == "" {
+		return defaultPolicy
+	}
+	return s.RetentionPolicy
+}
+
+// Validate checks the sizes of a storage are quantities, the initial one being
+// required for the claims rendered from it
+func (s *Storage) Validate(name string) error {
+	initial, err := resource.ParseQuantity(s.InitialSize)
+	if err != nil || initial.Sign() <= 0 {
+		return fmt.Errorf("storages %s initSize %q is not a size", name, s.InitialSize)
+	}
+	if s.MaximumSize != "" {
+		maximum, err := resource.ParseQuantity(s.MaximumSize)
+		if err != nil {
+			return fmt.Errorf("storages %s maxSize %q is not a size", name, s.MaximumSize)
+		}
+		if maximum.Cmp(initial) < 0 {
+			return fmt.Errorf("storages %s maxSize %s is below initSize %s", name, s.MaximumSize, s.InitialSize)
+		}
+	}
+	switch s.RetentionPolicy {
+	case "", RetentionPolicyRetain, RetentionPolicyDelete:
+	default:
+		return fmt.Errorf("storages %s retentionPolicy %q is not one of %s, %s", name, s.RetentionPolicy, RetentionPolicyRetain, RetentionPolicyDelete)
+	}
+	if s.GrowBy != "" {
+		if _, err := resource.ParseQuantity(s.GrowBy); err != nil {
+			return fmt.Errorf("storages %s growBy %q is not a size", name, s.GrowBy)
+		}
+	}
+	return nil
 }
 
 func (s *Storage) GetResourceRequirements() v1.ResourceRequirements {
@@ -110,10 +1044,10 @@ func (s *Storage) GetPersistentVolumeClaimSpecWithMode(mode v1.PersistentVolumeA
 func (mdbc *MariaDBCluster) GetWSREPEndpoints() []string {
 	var wsrep []string
 
-	statefulSetName := mdbc.GetServerName()
+	statefulSetName := mdbc.GetServerStatefulSetName()
 	serviceName := mdbc.GetServerServiceName()
 
-	if mdbc.Status.Phase == PhaseBootstrapFirst || mdbc.Status.Phase == PhaseBootstrapFirstRestart {
+	if mdbc.Status.Phase == PhaseBootstrapFirst || mdbc.Status.Phase == PhaseBootstrapFirstRestart || mdbc.IsSingleNode() {
 		wsrep = []string{}
 	} else if mdbc.Status.Phase == PhaseBootstrapSecond {
 		wsrep = []string{statefulSetName + "-0." + serviceName}
@@ -125,8 +1059,28 @@ func (mdbc *MariaDBCluster) GetWSREPEndpoints() []string {
 	return wsrep
 }
 
+// GetWSREPEndpointsForColor returns peers for pods of given color. The standby
+// cluster of a blue/green upgrade runs a single bootstrapped pod until it is
+// loaded with data, pods added after that join all of their siblings.
+func (mdbc *MariaDBCluster) GetWSREPEndpointsForColor(color string) []string {
+	if color == mdbc.GetActiveColor() {
+		return mdbc.GetWSREPEndpoints()
+	}
+	wsrep := []string{}
+	bg := mdbc.Status.BlueGreen
+	if bg == nil || bg.Stage == BlueGreenStageProvisioning || bg.Stage == BlueGreenStageSyncing {
+		return wsrep
+	}
+	statefulSetName := mdbc.GetServerNameForColor(color)
+	serviceName := mdbc.GetServerServiceNameForColor(color)
+	for i := int32(0); i < mdbc.Spec.Replicas; i++ {
+		wsrep = append(wsrep, fmt.Sprintf("%s-%d.%s", statefulSetName, i, serviceName))
+	}
+	return wsrep
+}
+
 func (mdbc *MariaDBCluster) GetSnapshotPVC() *v1.PersistentVolumeClaim {
-	return &v1.PersistentVolumeClaim{
+	pvc := &v1.PersistentVolumeClaim{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      mdbc.Name,
 			Namespace: mdbc.Namespace,
@@ -143,63 +1097,1210 @@ func (mdbc *MariaDBCluster) GetSnapshotPVC() *v1.PersistentVolumeClaim {
 		},
 		Spec: mdbc.Spec.Storages.Snapshot.GetPersistentVolumeClaimSpecWithMode(v1.ReadWriteMany),
 	}
+	mdbc.inheritMetadata(&pvc.ObjectMeta)
+	return pvc
 }
 
-func (mdb *MariaDBCluster) SetDefaults() {
-
-}
-
-func (mdb *MariaDBCluster) Validate() error {
+func (m *MetadataTemplate) Validate(field string) error {
+	for key, value := range m.Labels {
+		if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+			return fmt.Errorf("%s label %q : %s", field, key, strings.Join(errs, ", "))
+		}
+		if errs := validation.IsValidLabelValue(value); len(errs) > 0 {
+			return fmt.Errorf("%s label %q value %q : %s", field, key, value, strings.Join(errs, ", "))
+		}
+	}
+	for key := range m.Annotations {
+		if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+			return fmt.Errorf("%s annotation %q : %s", field, key, strings.Join(errs, ", "))
+		}
+	}
+	for _, keys := range []map[string]string{m.Labels, m.Annotations} {
+		for key := range keys {
+			if strings.HasPrefix(key, MariaDBClusterLabelPrefix) {
+				return fmt.Errorf("%s key %q is reserved to the operator", field, key)
+			}
+		}
+	}
 	return nil
 }
 
-func (mdb *MariaDBCluster) AsOwner() metav1.OwnerReference {
-	trueVar := true
-	return metav1.OwnerReference{
-		APIVersion: SchemeGroupVersion.String(),
-		Kind:       ResourceKind,
-		Name:       mdb.Name,
-		UID:        mdb.UID,
-		Controller: &trueVar,
-	}
+// inheritMetadata stamps spec.inheritMetadata onto the metadata of a generated
+// object. Labels of objects are often shared with their selectors, the maps
+// are copied so that selectors never pick up inherited labels.
+func (mdbc *MariaDBCluster) inheritMetadata(meta *metav1.ObjectMeta) {
+	meta.Labels = mergeMetadata(meta.Labels, mdbc.Spec.InheritMetadata.Labels)
+	meta.Annotations = mergeMetadata(meta.Annotations, mdbc.Spec.InheritMetadata.Annotations)
 }
 
-// Name getters
+// AppArmor profile annotation of a container, followed by its name
+const AppArmorContainerAnnotationKeyPrefix = "container.apparmor.security.beta.kubernetes.io/"
 
-func (mdbc *MariaDBCluster) GetServerName() string {
-	return mdbc.Name + "-" + MariaDBClusterServerRole
+// SecurityProfiles confine the containers of server pods, Jobs and proxies.
+// The Kubernetes API the operator is built against reads them from
+// annotations of the pods, set over inherited and pod metadata.
+type SecurityProfiles struct {
+	// Seccomp profile of the pods: runtime/default, docker/default,
+	// unconfined or localhost/<profile> under the seccomp root of the kubelet
+	Seccomp string `json:"seccomp,omitempty"`
+	// AppArmor profile of every container: runtime/default, unconfined or
+	// localhost/<profile> loaded on the node
+	AppArmor string `json:"appArmor,omitempty"`
 }
 
-func (mdbc *MariaDBCluster) GetServerServiceName() string {
-	return mdbc.GetServerName()
+func (p *SecurityProfiles) Validate() error {
+	if p.Seccomp != "" && !isSecurityProfile(p.Seccomp, "runtime/default", "docker/default", "unconfined") {
+		return fmt.Errorf("securityProfiles seccomp %q is not one of runtime/default, docker/default, unconfined, localhost/<profile>", p.Seccomp)
+	}
+	if p.AppArmor != "" && !isSecurityProfile(p.AppArmor, "runtime/default", "unconfined") {
+		return fmt.Errorf("securityProfiles appArmor %q is not one of runtime/default, unconfined, localhost/<profile>", p.AppArmor)
+	}
+	return nil
 }
 
-func (mdbc *MariaDBCluster) GetServerConfigMapName() string {
-	return mdbc.GetServerName()
+// isSecurityProfile tells whether a profile is one of given ones or a
+// localhost one naming a profile without leaving the root of profiles
+func isSecurityProfile(profile string, profiles ...string) bool {
+	for _, p := range profiles {
+		if profile == p {
+			return true
+		}
+	}
+	name := strings.TrimPrefix(profile, "localhost/")
+	if name == profile || name == "" {
+		return false
+	}
+	for _, part := range strings.Split(name, "/") {
+		if part == "" || part == "." || part == ".." {
+			return false
+		}
+	}
+	return true
 }
 
-func (mdbc *MariaDBCluster) GetProxyName() string {
-	return mdbc.Name + "-" + MariaDBClusterProxyRole
+// confinePod annotates a pod template with spec.securityProfiles, the
+// annotations map is copied as it may be shared with inherited metadata
+func (mdbc *MariaDBCluster) confinePod(template *v1.PodTemplateSpec) {
+	profiles := mdbc.Spec.SecurityProfiles
+	if profiles.Seccomp == "" && profiles.AppArmor == "" {
+		return
+	}
+	annotations := make(map[string]string, len(template.Annotations))
+	for key, value := range template.Annotations {
+		annotations[key] = value
+	}
+	if profiles.Seccomp != "" {
+		annotations[v1.SeccompPodAnnotationKey] = profiles.Seccomp
+	}
+	if profiles.AppArmor != "" {
+		for _, containers := range [][]v1.Container{template.Spec.InitContainers, template.Spec.Containers} {
+			for _, container := range containers {
+				annotations[AppArmorContainerAnnotationKeyPrefix+container.Name] = profiles.AppArmor
+			}
+		}
+	}
+	template.Annotations = annotations
 }
 
-func (mdbc *MariaDBCluster) GetProxyServiceName() string {
-	return mdbc.Name
+func mergeMetadata(base, top map[string]string) map[string]string {
+	if len(top) == 0 {
+		return base
+	}
+	merged := make(map[string]string, len(base)+len(top))
+	for key, value := range base {
+		merged[key] = value
+	}
+	for key, value := range top {
+		merged[key] = value
+	}
+	return merged
 }
 
-func (mdbc *MariaDBCluster) GetProxyConfigMapName() string {
-	return mdbc.GetProxyName()
-}
+func (mdb *MariaDBCluster) SetDefaults() {
 
-func (mdbc *MariaDBCluster) isProxyEnabled() bool {
-	return mdbc.Spec.Proxy
 }
 
-// Label getters
+// prefix of the variables the operator sets in server pods
+const operatorEnvPrefix = "MARIADBCLUSTER_"
 
-func (mdbc *MariaDBCluster) GetServerLabels() map[string]string {
-	labels := make(map[string]string)
-	labels[MariaDBClusterNameLabel] = mdbc.Name
-	labels[MariaDBClusterRoleLabel] = MariaDBClusterServerRole
+var (
+	imageDigestRegexp = regexp.MustCompile(`^sha256:[a-f0-9]{64}$`)
+	// an image repository, optionally on a registry host
+	imageRepositoryRegexp = regexp.MustCompile(`^([A-Za-z0-9.-]+(:[0-9]+)?/)?[a-z0-9]+([._-]+[a-z0-9]+)*(/[a-z0-9]+([._-]+[a-z0-9]+)*)*$`)
+	// a tag, a digest or both following a repository
+	imageSuffixRegexp = regexp.MustCompile(`^(:[A-Za-z0-9_][A-Za-z0-9_.-]{0,127})?(@sha256:[a-f0-9]{64})?$`)
+	initSQLNameRegexp = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]{0,38}[a-z0-9])?$`)
+	// a series or release of the server image, with an optional tag suffix
+	versionRegexp = regexp.MustCompile(`^([0-9]+)\.([0-9]+)(\.([0-9]+))?(-[A-Za-z0-9._]+)?$`)
+	// names of the containers and init containers of server pods
+	reservedContainerNames = map[string]bool{"init": true, "tune": true, "mariadb": true, "debug": true, "agent": true}
+	// variables of the operator in server pods, along with those prefixed
+	// by operatorEnvPrefix
+	operatorEnvNames = map[string]bool{"MYSQL_ALLOW_EMPTY_PASSWORD": true, "MYSQL_INITDB_SKIP_TZINFO": true}
+	// a sysctl as vm.swappiness, naming a file under /proc/sys
+	sysctlNameRegexp = regexp.MustCompile(`^[a-z0-9_]+(\.[a-z0-9_-]+)+$`)
+)
+
+func (mdb *MariaDBCluster) Validate() error {
+	if mdb.Spec.Replicas < 1 {
+		return fmt.Errorf("replicas %d is below 1", mdb.Spec.Replicas)
+	}
+	if err := validateVersion(mdb.GetVersion()); err != nil {
+		return err
+	}
+	if err := mdb.Spec.Storages.Data.Validate("data"); err != nil {
+		return err
+	}
+	if err := mdb.Spec.Storages.Snapshot.Validate("snapshot"); err != nil {
+		return err
+	}
+	switch mdb.Spec.ReclaimPolicy {
+	case "", RetentionPolicyRetain, RetentionPolicyDelete:
+	default:
+		return fmt.Errorf("reclaimPolicy %q is not one of %s, %s", mdb.Spec.ReclaimPolicy, RetentionPolicyRetain, RetentionPolicyDelete)
+	}
+	for name, probe := range map[string]ProbeTimings{"liveness": mdb.Spec.Probes.Liveness, "readiness": mdb.Spec.Probes.Readiness} {
+		if probe.InitialDelaySeconds < 0 || probe.PeriodSeconds < 0 || probe.TimeoutSeconds < 0 || probe.FailureThreshold < 0 {
+			return fmt.Errorf("probes %s timings can not be negative", name)
+		}
+	}
+	if mdb.Spec.ImageDigest != "" && !imageDigestRegexp.MatchString(mdb.Spec.ImageDigest) {
+		return fmt.Errorf("imageDigest %q is not a sha256 digest", mdb.Spec.ImageDigest)
+	}
+	if mdb.Spec.Image != "" && !imageRepositoryRegexp.MatchString(mdb.Spec.Image) {
+		return fmt.Errorf("image %q is not an image repository, its tag is the version", mdb.Spec.Image)
+	}
+	for name, image := range map[string]string{"initImage": mdb.Spec.InitImage, "agentImage": mdb.Spec.AgentImage} {
+		if image != "" && !isImageReference(image) {
+			return fmt.Errorf("%s %q is not an image reference", name, image)
+		}
+	}
+	if name := mdb.Spec.ServiceAccountName; name != "" {
+		if errs := validation.IsDNS1123Subdomain(name); len(errs) > 0 {
+			return fmt.Errorf("serviceAccountName %q : %s", name, strings.Join(errs, ", "))
+		}
+	}
+	if err := mdb.Spec.InheritMetadata.Validate("inheritMetadata"); err != nil {
+		return err
+	}
+	if err := mdb.Spec.PodMetadata.Validate("podMetadata"); err != nil {
+		return err
+	}
+	if err := mdb.Spec.SecurityProfiles.Validate(); err != nil {
+		return err
+	}
+	if err := mdb.Spec.Shutdown.Validate(); err != nil {
+		return err
+	}
+	if err := mdb.Spec.KernelTuning.Validate(); err != nil {
+		return err
+	}
+	for _, constraint := range mdb.Spec.TopologySpreadConstraints {
+		if err := constraint.Validate(); err != nil {
+			return err
+		}
+	}
+	switch mdb.Spec.DNSPolicy {
+	case "", v1.DNSClusterFirst, v1.DNSClusterFirstWithHostNet, v1.DNSDefault:
+	case v1.DNSNone:
+		if mdb.Spec.DNSConfig == nil || len(mdb.Spec.DNSConfig.Nameservers) == 0 {
+			return fmt.Errorf("dnsPolicy %s needs dnsConfig nameservers", v1.DNSNone)
+		}
+	default:
+		return fmt.Errorf("dnsPolicy %q is not one of %s, %s, %s, %s", mdb.Spec.DNSPolicy, v1.DNSClusterFirst, v1.DNSClusterFirstWithHostNet, v1.DNSDefault, v1.DNSNone)
+	}
+	if _, err := mdb.GetFeatures(); err != nil {
+		return err
+	}
+	switch mdb.Spec.ImagePullPolicy {
+	case "", v1.PullAlways, v1.PullIfNotPresent, v1.PullNever:
+	default:
+		return fmt.Errorf("imagePullPolicy %q is not one of %s, %s, %s", mdb.Spec.ImagePullPolicy, v1.PullAlways, v1.PullIfNotPresent, v1.PullNever)
+	}
+	if pod := mdb.Spec.Recovery.ForceBootstrapFrom; pod != "" && !mdb.IsServerPod(pod) {
+		return fmt.Errorf("forceBootstrapFrom %q is not a server pod of this cluster", pod)
+	}
+	switch mdb.Spec.UpdateStrategy {
+	case "", UpdateStrategyRollingUpdate, UpdateStrategyOperator, UpdateStrategyOnDelete:
+	default:
+		return fmt.Errorf("unknown updateStrategy %q", mdb.Spec.UpdateStrategy)
+	}
+	// names of containers and init containers are unique within a pod
+	containers := make(map[string]bool)
+	for _, container := range append(append([]v1.Container{}, mdb.Spec.InitContainers...), mdb.Spec.Sidecars...) {
+		if container.Name == "" || container.Image == "" {
+			return fmt.Errorf("sidecars and initContainers need a name and an image")
+		}
+		if reservedContainerNames[container.Name] {
+			return fmt.Errorf("container name %q is taken by a container of the operator", container.Name)
+		}
+		if containers[container.Name] {
+			return fmt.Errorf("container %s listed twice in sidecars and initContainers", container.Name)
+		}
+		containers[container.Name] = true
+	}
+	envs := make(map[string]bool)
+	for _, env := range mdb.Spec.Env {
+		if errs := validation.IsEnvVarName(env.Name); len(errs) > 0 {
+			return fmt.Errorf("env %q : %s", env.Name, strings.Join(errs, ", "))
+		}
+		if operatorEnvNames[env.Name] || strings.HasPrefix(env.Name, operatorEnvPrefix) {
+			return fmt.Errorf("env %s is set by the operator", env.Name)
+		}
+		if envs[env.Name] {
+			return fmt.Errorf("env %s listed twice", env.Name)
+		}
+		envs[env.Name] = true
+	}
+	for _, source := range mdb.Spec.EnvFrom {
+		if (source.ConfigMapRef == nil) == (source.SecretRef == nil) {
+			return fmt.Errorf("envFrom needs either a configMapRef or a secretRef")
+		}
+		if source.Prefix == "" {
+			continue
+		}
+		if errs := validation.IsEnvVarName(source.Prefix); len(errs) > 0 {
+			return fmt.Errorf("envFrom prefix %q : %s", source.Prefix, strings.Join(errs, ", "))
+		}
+		if strings.HasPrefix(source.Prefix, operatorEnvPrefix) {
+			return fmt.Errorf("envFrom prefix %s is taken by variables of the operator", source.Prefix)
+		}
+	}
+	volumes := make(map[string]bool)
+	for _, volume := range mdb.Spec.Volumes {
+		if volume.Name == "config" || volume.Name == "data" {
+			return fmt.Errorf("volume name %q is taken by a volume of the operator", volume.Name)
+		}
+		if volumes[volume.Name] {
+			return fmt.Errorf("volume %s listed twice", volume.Name)
+		}
+		volumes[volume.Name] = true
+	}
+	for _, mount := range mdb.Spec.VolumeMounts {
+		if !volumes[mount.Name] {
+			return fmt.Errorf("volumeMount of %q which is not one of volumes", mount.Name)
+		}
+		if len(mount.Containers) == 0 {
+			return fmt.Errorf("volumeMount of %s names no containers", mount.Name)
+		}
+		for _, container := range mount.Containers {
+			if !reservedContainerNames[container] {
+				return fmt.Errorf("volumeMount of %s into %q which is not a container of the operator", mount.Name, container)
+			}
+		}
+	}
+	switch mdb.Spec.DisruptionBudget {
+	case "", DisruptionBudgetMaxUnavailable, DisruptionBudgetQuorum, DisruptionBudgetNone:
+	default:
+		return fmt.Errorf("disruptionBudget %q is not one of %s, %s, %s", mdb.Spec.DisruptionBudget, DisruptionBudgetMaxUnavailable, DisruptionBudgetQuorum, DisruptionBudgetNone)
+	}
+	if mdb.Spec.Config.Inline != "" && mdb.Spec.Config.ConfigMapKeyRef != nil {
+		return fmt.Errorf("config can not be both inline and from a ConfigMap")
+	}
+	if err := mdb.ValidateServerConfig(mdb.Spec.Config.Inline); err != nil {
+		return err
+	}
+	if err := mdb.Spec.Server.Validate(); err != nil {
+		return err
+	}
+	if err := mdb.Spec.Server.Binlog.Validate(mdb.Spec.Replicas); err != nil {
+		return err
+	}
+	scripts := make(map[string]bool)
+	for _, script := range mdb.Spec.InitSQL {
+		if !initSQLNameRegexp.MatchString(script.Name) {
+			return fmt.Errorf("initSQL name %q is not a lower case name of letters, digits and dashes", script.Name)
+		}
+		if scripts[script.Name] {
+			return fmt.Errorf("initSQL %s listed twice", script.Name)
+		}
+		scripts[script.Name] = true
+		if (script.ConfigMapKeyRef == nil) == (script.SecretKeyRef == nil) {
+			return fmt.Errorf("initSQL %s needs either a configMapKeyRef or a secretKeyRef", script.Name)
+		}
+	}
+	if percent := mdb.Spec.BufferPool.MemoryPercent; percent != 0 && (percent < 10 || percent > 90) {
+		return fmt.Errorf("bufferPool memoryPercent %d is not from 10 to 90", percent)
+	}
+	if gcache := mdb.Spec.Galera.GCache; gcache.PageSizeMB < 0 || gcache.KeepPagesSizeMB < 0 {
+		return fmt.Errorf("gcache pageSizeMB and keepPagesSizeMB can not be negative")
+	}
+	if err := ValidateProviderOptions(mdb.Spec.Galera.ProviderOptions); err != nil {
+		return err
+	}
+	for pod, addr := range mdb.Spec.Galera.IST.RecvAddr {
+		if !mdb.IsServerPod(pod) {
+			return fmt.Errorf("ist recvAddr set for %q which is not a server pod of this cluster", pod)
+		}
+		if _, err := ParseISTAddr(addr); err != nil {
+			return fmt.Errorf("ist recvAddr of %s : %s", pod, err.Error())
+		}
+	}
+	if bind := mdb.Spec.Galera.IST.RecvBind; bind != "" && net.ParseIP(bind) == nil {
+		return fmt.Errorf("ist recvBind %q is not an IP address", bind)
+	}
+	if err := mdb.Spec.Galera.Ports.Validate(); err != nil {
+		return err
+	}
+	if gcache := mdb.Spec.Galera.GCache; gcache.MaxSizeMB > 0 && gcache.MaxSizeMB < gcache.MinSizeMB {
+		return fmt.Errorf("gcache maxSizeMB %d is below minSizeMB %d", gcache.MaxSizeMB, gcache.MinSizeMB)
+	}
+	if fc := mdb.Spec.Galera.FlowControl; fc.PausedThresholdPercent < 0 || fc.PausedThresholdPercent > 100 {
+		return fmt.Errorf("flowControl pausedThresholdPercent %d is not a percentage", fc.PausedThresholdPercent)
+	}
+	if limit := mdb.Spec.Galera.FlowControl.MaxLimit; limit != 0 && limit < DefaultFlowControlLimit {
+		return fmt.Errorf("flowControl maxLimit %d is below the galera default of %d", limit, DefaultFlowControlLimit)
+	}
+	resilience := mdb.Spec.Galera.Resilience
+	if resilience.GetSuspectTimeout() < time.Second {
+		return fmt.Errorf("suspectTimeout %s is below 1s", resilience.GetSuspectTimeout())
+	}
+	if resilience.GetInactiveTimeout() <= resilience.GetSuspectTimeout() {
+		return fmt.Errorf("inactiveTimeout %s has to be longer than suspectTimeout %s",
+			resilience.GetInactiveTimeout(), resilience.GetSuspectTimeout())
+	}
+	if resilience.GetInstallTimeout() <= 0 || resilience.GetInstallTimeout() > resilience.GetInactiveTimeout() {
+		return fmt.Errorf("installTimeout %s has to be positive and at most inactiveTimeout %s",
+			resilience.GetInstallTimeout(), resilience.GetInactiveTimeout())
+	}
+	for pod, weight := range mdb.Spec.Galera.Weights.Pods {
+		if !mdb.IsServerPod(pod) {
+			return fmt.Errorf("weight set for %q which is not a server pod of this cluster", pod)
+		}
+		if weight < 0 || weight > 255 {
+			return fmt.Errorf("weight %d of %s is out of 0-255", weight, pod)
+		}
+	}
+	for zone, weight := range mdb.Spec.Galera.Weights.Zones {
+		if weight < 0 || weight > 255 {
+			return fmt.Errorf("weight %d of zone %s is out of 0-255", weight, zone)
+		}
+	}
+	for _, pod := range mdb.Spec.Galera.Donor.Preferred {
+		if !mdb.IsServerPod(pod) {
+			return fmt.Errorf("preferred donor %q is not a server pod of this cluster", pod)
+		}
+	}
+	switch mdb.Spec.Galera.SSTMethod {
+	case "", SSTMethodRsync, SSTMethodMariaBackup:
+	default:
+		return fmt.Errorf("unknown sstMethod %q", mdb.Spec.Galera.SSTMethod)
+	}
+	for phase := range mdb.Spec.PhaseTimeouts {
+		switch phase {
+		case PhasePreFlight, PhaseBootstrapFirst, PhaseBootstrapFirstRestart, PhaseBootstrapSecond, PhaseBootstrapThird, PhaseRecovery:
+		default:
+			return fmt.Errorf("phaseTimeouts can not be set for phase %q", phase)
+		}
+	}
+	switch mdb.Spec.Recovery.NonPrimaryPolicy {
+	case "", NonPrimaryPolicyRecover, NonPrimaryPolicyBootstrap:
+	default:
+		return fmt.Errorf("unknown nonPrimaryPolicy %q", mdb.Spec.Recovery.NonPrimaryPolicy)
+	}
+	if mdb.Spec.Recovery.GetReportRetryTimeout() < 0 {
+		return fmt.Errorf("reportRetryTimeout can not be negative")
+	}
+	for _, pod := range mdb.Spec.Recovery.OverwriteDiverged {
+		if !mdb.IsServerPod(pod) {
+			return fmt.Errorf("overwriteDiverged %q is not a server pod of this cluster", pod)
+		}
+	}
+	if mdb.Spec.Recovery.GetUnsyncedTimeout() < 0 {
+		return fmt.Errorf("unsyncedTimeout can not be negative")
+	}
+	if mdb.Spec.Recovery.GetBootstrapReadyTimeout() < 0 {
+		return fmt.Errorf("bootstrapReadyTimeout can not be negative")
+	}
+	switch mdb.Spec.Recovery.SplitBrainPolicy {
+	case "", SplitBrainPolicyManual, SplitBrainPolicyKeepLargest:
+	default:
+		return fmt.Errorf("unknown splitBrainPolicy %q", mdb.Spec.Recovery.SplitBrainPolicy)
+	}
+	return nil
+}
+
+// ValidateUpdate checks a changed spec against the previous version of the
+// cluster for what can not change once created: the storage class and size of
+// the data volumes, StatefulSet claim templates being immutable, the storage
+// class of the snapshot volume, which may only grow, and the server version,
+// MariaDB not supporting downgrades to an older series than the running one.
+func (mdb *MariaDBCluster) ValidateUpdate(old *MariaDBCluster) error {
+	if err := mdb.Validate(); err != nil {
+		return err
+	}
+	data, oldData := mdb.Spec.Storages.Data, old.Spec.Storages.Data
+	if data.StorageClassName != oldData.StorageClassName {
+		return fmt.Errorf("storages data storageClassName can not be changed from %q", oldData.StorageClassName)
+	}
+	if size, err := resource.ParseQuantity(oldData.InitialSize); err == nil && size.Cmp(resource.MustParse(data.InitialSize)) != 0 {
+		return fmt.Errorf("storages data initSize can not be changed from %s", oldData.InitialSize)
+	}
+	snapshot, oldSnapshot := mdb.Spec.Storages.Snapshot, old.Spec.Storages.Snapshot
+	if snapshot.StorageClassName != oldSnapshot.StorageClassName {
+		return fmt.Errorf("storages snapshot storageClassName can not be changed from %q", oldSnapshot.StorageClassName)
+	}
+	if size, err := resource.ParseQuantity(oldSnapshot.InitialSize); err == nil && size.Cmp(resource.MustParse(snapshot.InitialSize)) > 0 {
+		return fmt.Errorf("storages snapshot initSize can not shrink below %s", oldSnapshot.InitialSize)
+	}
+	running := old.GetServerVersion()
+	if current, err := parseVersion(running); err == nil {
+		target, _ := parseVersion(mdb.GetVersion())
+		if target[0] < current[0] || target[0] == current[0] && target[1] < current[1] {
+			return fmt.Errorf("version %s is of an older series than %s the cluster runs, downgrades are not supported", mdb.GetVersion(), running)
+		}
+	}
+	return nil
+}
+
+// validateVersion checks a server version is one the operator can run, a
+// series such as 10.3 or a release of at least MinimumVersion
+func validateVersion(version string) error {
+	parsed, err := parseVersion(version)
+	if err != nil {
+		return err
+	}
+	minimum, _ := parseVersion(MinimumVersion)
+	for i := range parsed {
+		// a series stands for its latest release
+		if parsed[i] < 0 || parsed[i] > minimum[i] {
+			return nil
+		}
+		if parsed[i] < minimum[i] {
+			return fmt.Errorf("version %s is below %s, the oldest one supported", version, MinimumVersion)
+		}
+	}
+	return nil
+}
+
+// parseVersion returns major, minor and patch of a version such as 10.2 or
+// 10.2.14-bionic, the patch being -1 for a series
+func parseVersion(version string) ([3]int, error) {
+	match := versionRegexp.FindStringSubmatch(version)
+	if match == nil {
+		return [3]int{}, fmt.Errorf("version %q is not a server version such as 10.3 or 10.3.9", version)
+	}
+	parsed := [3]int{0, 0, -1}
+	parsed[0], _ = strconv.Atoi(match[1])
+	parsed[1], _ = strconv.Atoi(match[2])
+	if match[4] != "" {
+		parsed[2], _ = strconv.Atoi(match[4])
+	}
+	return parsed, nil
+}
+
+// GetPhaseTimeout returns the time the cluster may spend in given phase, zero
+// when it may stay there for good
+func (mdbc *MariaDBCluster) GetPhaseTimeout(phase string) time.Duration {
+	if timeout, ok := mdbc.Spec.PhaseTimeouts[phase]; ok {
+		return timeout.Duration
+	}
+	switch phase {
+	case PhasePreFlight, PhaseBootstrapFirst, PhaseBootstrapFirstRestart, PhaseBootstrapSecond, PhaseBootstrapThird:
+		return DefaultBootstrapTimeout
+	case PhaseRecovery:
+		return DefaultRecoveryTimeout
+	}
+	return 0
+}
+
+// IsSingleNode tells whether the cluster runs a single pod, which bootstraps
+// itself whenever it starts. Once scaled up the pod joins the others on its
+// next restart.
+func (mdbc *MariaDBCluster) IsSingleNode() bool {
+	return mdbc.Spec.Replicas == 1
+}
+
+// IsServerPod tells whether name is one of the pods of the serving StatefulSet
+func (mdbc *MariaDBCluster) IsServerPod(name string) bool {
+	prefix := mdbc.GetServerStatefulSetName() + "-"
+	if !strings.HasPrefix(name, prefix) {
+		return false
+	}
+	ordinal, err := strconv.Atoi(strings.TrimPrefix(name, prefix))
+	return err == nil && ordinal >= 0 && int32(ordinal) < mdbc.Spec.Replicas
+}
+
+func (mdbc *MariaDBCluster) GetUpdateStrategy() string {
+	if mdbc.Spec.UpdateStrategy == "" {
+		return UpdateStrategyRollingUpdate
+	}
+	return mdbc.Spec.UpdateStrategy
+}
+
+// GetServerResources returns Spec.Resources, or requests growing with the
+// data volume when it sets neither requests nor limits: 250m of cpu and an
+// eighth of the volume size of memory, within 512Mi and 8Gi. Limits are left
+// to be set, the buffer pool is sized from the memory limit.
+func (mdbc *MariaDBCluster) GetServerResources() v1.ResourceRequirements {
+	if len(mdbc.Spec.Resources.Requests) > 0 || len(mdbc.Spec.Resources.Limits) > 0 {
+		return *mdbc.Spec.Resources.DeepCopy()
+	}
+	memory := int64(512 << 20)
+	if size, err := resource.ParseQuantity(mdbc.Spec.Storages.Data.InitialSize); err == nil && size.Value()/8 > memory {
+		memory = size.Value() / 8
+	}
+	if memory > 8<<30 {
+		memory = 8 << 30
+	}
+	// whole MiB, as quantities are shown
+	memory = memory >> 20 << 20
+	return v1.ResourceRequirements{
+		Requests: v1.ResourceList{
+			v1.ResourceCPU:    resource.MustParse("250m"),
+			v1.ResourceMemory: *resource.NewQuantity(memory, resource.BinarySI),
+		},
+	}
+}
+
+// GetInitResources returns the resources of the initializer container, which
+// renders the config and is done
+func (mdbc *MariaDBCluster) GetInitResources() v1.ResourceRequirements {
+	if init := mdbc.Spec.ContainerResources.Init; init != nil {
+		return *init.DeepCopy()
+	}
+	return v1.ResourceRequirements{
+		Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("50m"), v1.ResourceMemory: resource.MustParse("64Mi")},
+	}
+}
+
+// GetAgentResources returns the resources of the agent container, which polls
+// the server and reports its state
+func (mdbc *MariaDBCluster) GetAgentResources() v1.ResourceRequirements {
+	if agent := mdbc.Spec.ContainerResources.Agent; agent != nil {
+		return *agent.DeepCopy()
+	}
+	return v1.ResourceRequirements{
+		Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("10m"), v1.ResourceMemory: resource.MustParse("32Mi")},
+	}
+}
+
+func (mdbc *MariaDBCluster) GetDisruptionBudget() string {
+	if mdbc.Spec.DisruptionBudget == "" {
+		return DisruptionBudgetMaxUnavailable
+	}
+	return mdbc.Spec.DisruptionBudget
+}
+
+func (mdb *MariaDBCluster) AsOwner() metav1.OwnerReference {
+	trueVar := true
+	return metav1.OwnerReference{
+		APIVersion: SchemeGroupVersion.String(),
+		Kind:       ResourceKind,
+		Name:       mdb.Name,
+		UID:        mdb.UID,
+		Controller: &trueVar,
+	}
+}
+
+// Version getters
+
+// GetVersion returns the version requested in spec
+func (mdbc *MariaDBCluster) GetVersion() string {
+	if mdbc.Spec.Version == "" {
+		return DefaultVersion
+	}
+	return mdbc.Spec.Version
+}
+
+// GetServerVersion returns the version that server pods should be running,
+// which lags behind spec while an upgrade is held by its gates
+func (mdbc *MariaDBCluster) GetServerVersion() string {
+	if mdbc.Status.CurrentVersion == "" {
+		return mdbc.GetVersion()
+	}
+	return mdbc.Status.CurrentVersion
+}
+
+// GetServerImage returns the server image reference, pinned to a digest if one is
+// set in spec or has been resolved for the running version
+func (mdbc *MariaDBCluster) GetServerImage() string {
+	return mdbc.GetServerImageForVersion(mdbc.GetServerVersion())
+}
+
+// GetServerImageForVersion returns the server image of given version, pinned to
+// a digest when one is set in spec or has been resolved for that version
+func (mdbc *MariaDBCluster) GetServerImageForVersion(version string) string {
+	image := mdbc.GetImage(version)
+	if mdbc.Spec.ImageDigest != "" {
+		return image + "@" + mdbc.Spec.ImageDigest
+	}
+	pinned := mdbc.Status.PinnedImage
+	if mdbc.Spec.PinImageDigest && pinned != nil && pinned.GetImage() == image {
+		return image + "@" + pinned.Digest
+	}
+	return image
+}
+
+// GetImage returns the server image tag of given version, without any digest
+func (mdbc *MariaDBCluster) GetImage(version string) string {
+	return mdbc.GetImageRepository() + ":" + version
+}
+
+func (mdbc *MariaDBCluster) GetImageRepository() string {
+	if mdbc.Spec.Image == "" {
+		return DefaultServerImage
+	}
+	return mdbc.Spec.Image
+}
+
+func (mdbc *MariaDBCluster) GetInitImage() string {
+	if mdbc.Spec.InitImage == "" {
+		return DefaultOperatorImage
+	}
+	return mdbc.Spec.InitImage
+}
+
+func (mdbc *MariaDBCluster) GetAgentImage() string {
+	if mdbc.Spec.AgentImage == "" {
+		return DefaultOperatorImage
+	}
+	return mdbc.Spec.AgentImage
+}
+
+// GetImagePullPolicy returns the pull policy set in spec, or given default of
+// the container
+func (mdbc *MariaDBCluster) GetImagePullPolicy(def v1.PullPolicy) v1.PullPolicy {
+	if mdbc.Spec.ImagePullPolicy == "" {
+		return def
+	}
+	return mdbc.Spec.ImagePullPolicy
+}
+
+// isImageReference reports whether image is a repository with an optional tag
+// and digest, a colon past the last slash starts the tag
+func isImageReference(image string) bool {
+	repository, suffix := image, ""
+	if i := strings.Index(repository, "@"); i >= 0 {
+		repository, suffix = repository[:i], repository[i:]
+	}
+	if i := strings.LastIndex(repository, ":"); i > strings.LastIndex(repository, "/") {
+		repository, suffix = repository[:i], repository[i:]+suffix
+	}
+	return imageRepositoryRegexp.MatchString(repository) && imageSuffixRegexp.MatchString(suffix)
+}
+
+// Name getters
+
+func (mdbc *MariaDBCluster) GetServerName() string {
+	return mdbc.Name + "-" + MariaDBClusterServerRole
+}
+
+// GetActiveColor returns the color of server objects currently serving clients
+func (mdbc *MariaDBCluster) GetActiveColor() string {
+	if mdbc.Status.ActiveColor == "" {
+		return ColorBlue
+	}
+	return mdbc.Status.ActiveColor
+}
+
+func (mdbc *MariaDBCluster) GetStandbyColor() string {
+	if mdbc.GetActiveColor() == ColorBlue {
+		return ColorGreen
+	}
+	return ColorBlue
+}
+
+// GetServerNameForColor keeps blue objects named as they were before blue/green
+// upgrades existed, so that existing clusters are not renamed
+func (mdbc *MariaDBCluster) GetServerNameForColor(color string) string {
+	if color == ColorBlue {
+		return mdbc.GetServerName()
+	}
+	return mdbc.GetServerName() + "-" + color
+}
+
+func (mdbc *MariaDBCluster) GetServerStatefulSetName() string {
+	return mdbc.GetServerNameForColor(mdbc.GetActiveColor())
+}
+
+func (mdbc *MariaDBCluster) GetServerServiceName() string {
+	return mdbc.GetServerServiceNameForColor(mdbc.GetActiveColor())
+}
+
+func (mdbc *MariaDBCluster) GetServerServiceNameForColor(color string) string {
+	return mdbc.GetServerNameForColor(color)
+}
+
+// GetServiceAccountName returns the ServiceAccount of server pods and Jobs
+func (mdbc *MariaDBCluster) GetServiceAccountName() string {
+	if mdbc.Spec.ServiceAccountName != "" {
+		return mdbc.Spec.ServiceAccountName
+	}
+	return mdbc.GetServerName()
+}
+
+// GetAffinityForColor returns the affinity of server pods of a color,
+// Spec.Affinity or one keeping them on distinct nodes, required as a node
+// failing would take more than one member of the cluster along, and in
+// distinct zones where there are enough of them
+func (mdbc *MariaDBCluster) GetAffinityForColor(color string) *v1.Affinity {
+	if mdbc.Spec.Affinity != nil {
+		return mdbc.Spec.Affinity.DeepCopy()
+	}
+	selector := &metav1.LabelSelector{MatchLabels: mdbc.GetServerLabelsForColor(color)}
+	return &v1.Affinity{
+		PodAntiAffinity: &v1.PodAntiAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: []v1.PodAffinityTerm{
+				{LabelSelector: selector, TopologyKey: HostnameLabel},
+			},
+			PreferredDuringSchedulingIgnoredDuringExecution: []v1.WeightedPodAffinityTerm{
+				{Weight: 100, PodAffinityTerm: v1.PodAffinityTerm{LabelSelector: selector, TopologyKey: ZoneLabel}},
+			},
+		},
+	}
+}
+
+// GetTopologySpreadConstraintsForColor returns the topology spread constraints
+// of server pods of a color, Spec.TopologySpreadConstraints or one spreading
+// them evenly over zones, as a preference so that clusters still schedule with
+// fewer zones than pods or a zone out of capacity
+func (mdbc *MariaDBCluster) GetTopologySpreadConstraintsForColor(color string) []TopologySpreadConstraint {
+	constraints := []TopologySpreadConstraint{
+		{MaxSkew: 1, TopologyKey: ZoneLabel, WhenUnsatisfiable: TopologySpreadScheduleAnyway},
+	}
+	if mdbc.Spec.TopologySpreadConstraints != nil {
+		constraints = make([]TopologySpreadConstraint, len(mdbc.Spec.TopologySpreadConstraints))
+		for i := range mdbc.Spec.TopologySpreadConstraints {
+			mdbc.Spec.TopologySpreadConstraints[i].DeepCopyInto(&constraints[i])
+		}
+	}
+	for i := range constraints {
+		if constraints[i].LabelSelector == nil {
+			constraints[i].LabelSelector = &metav1.LabelSelector{MatchLabels: mdbc.GetServerLabelsForColor(color)}
+		}
+	}
+	return constraints
+}
+
+func (mdbc *MariaDBCluster) GetServerConfigMapName() string {
+	return mdbc.GetServerName()
+}
+
+func (mdbc *MariaDBCluster) GetProxyName() string {
+	return mdbc.Name + "-" + MariaDBClusterProxyRole
+}
+
+func (mdbc *MariaDBCluster) GetProxyServiceName() string {
+	return mdbc.Name
+}
+
+// GetPrimaryHost returns the DNS name of the Service clients write through
+func (mdbc *MariaDBCluster) GetPrimaryHost() string {
+	return mdbc.GetProxyServiceName() + "." + mdbc.Namespace + ".svc"
+}
+
+// GetReaderHost returns the DNS name of the headless Service of the serving
+// pods, resolving to every one of them
+func (mdbc *MariaDBCluster) GetReaderHost() string {
+	return mdbc.GetServerServiceName() + "." + mdbc.Namespace + ".svc"
+}
+
+func (mdbc *MariaDBCluster) GetProxyConfigMapName() string {
+	return mdbc.GetProxyName()
+}
+
+// GetReclaimPolicy returns Spec.ReclaimPolicy, or given default when it is
+// not set
+func (mdbc *MariaDBCluster) GetReclaimPolicy(defaultPolicy string) string {
+	if mdbc.Spec.ReclaimPolicy == "" {
+		return defaultPolicy
+	}
+	return mdbc.Spec.ReclaimPolicy
+}
+
+// GetDataRetentionPolicy tells whether the data volumes of server pods are
+// kept once the cluster is deleted, they are unless set to Delete
+func (mdbc *MariaDBCluster) GetDataRetentionPolicy() string {
+	return mdbc.Spec.Storages.Data.GetRetentionPolicy(mdbc.GetReclaimPolicy(RetentionPolicyRetain))
+}
+
+// GetSnapshotRetentionPolicy tells whether the snapshot volume is kept once
+// the cluster is deleted, only by default when it holds a final backup
+func (mdbc *MariaDBCluster) GetSnapshotRetentionPolicy() string {
+	if mdbc.Spec.Teardown.FinalBackup {
+		return mdbc.Spec.Storages.Snapshot.GetRetentionPolicy(RetentionPolicyRetain)
+	}
+	return mdbc.Spec.Storages.Snapshot.GetRetentionPolicy(mdbc.GetReclaimPolicy(RetentionPolicyDelete))
+}
+
+// GetSecretRetentionPolicy tells whether the server and client Secrets are
+// kept once the cluster is deleted. They are along with retained data volumes,
+// as their data can only be served again with its credentials.
+func (mdbc *MariaDBCluster) GetSecretRetentionPolicy() string {
+	if mdbc.GetDataRetentionPolicy() == RetentionPolicyRetain {
+		return RetentionPolicyRetain
+	}
+	return mdbc.GetReclaimPolicy(RetentionPolicyDelete)
+}
+
+// GetBackupRetentionPolicy tells whether the backup Jobs of the cluster are
+// kept once it is deleted, so that the backups they wrote can be found again
+func (mdbc *MariaDBCluster) GetBackupRetentionPolicy() string {
+	return mdbc.GetReclaimPolicy(RetentionPolicyDelete)
+}
+
+// IsDeletionProtected tells whether the deletion protection annotation is set
+func (mdbc *MariaDBCluster) IsDeletionProtected() bool {
+	protected, _ := strconv.ParseBool(mdbc.Annotations[MariaDBClusterDeletionProtectionAnnotation])
+	return protected
+}
+
+// IsAdoptionEnabled tells whether the adopt annotation is set
+func (mdbc *MariaDBCluster) IsAdoptionEnabled() bool {
+	adopt, _ := strconv.ParseBool(mdbc.Annotations[MariaDBClusterAdoptAnnotation])
+	return adopt
+}
+
+// IsInMaintenance tells whether spec.maintenance or the maintenance annotation
+// is set
+func (mdbc *MariaDBCluster) IsInMaintenance() bool {
+	if mdbc.Spec.Maintenance {
+		return true
+	}
+	maintenance, _ := strconv.ParseBool(mdbc.Annotations[MariaDBClusterMaintenanceAnnotation])
+	return maintenance
+}
+
+// IsDryRun tells whether the operator is to leave the cluster and its objects
+// as they are, through MariaDBClusterDryRunAnnotation
+func (mdbc *MariaDBCluster) IsDryRun() bool {
+	dryRun, _ := strconv.ParseBool(mdbc.Annotations[MariaDBClusterDryRunAnnotation])
+	return dryRun
+}
+
+// experimental features, switched on through FeatureAnnotationPrefix
+const (
+	// pods joining the first one during bootstrap all start at once
+	FeatureParallelJoin = "parallel-join"
+	// Recovery bootstraps once a majority of pods reported, without waiting
+	// for the rest
+	FeatureAggressiveRecovery = "aggressive-recovery"
+)
+
+var Features = []string{FeatureParallelJoin, FeatureAggressiveRecovery}
+
+// GetFeatures returns the feature flags set through annotations, failing on
+// the first one of an unknown feature or not holding a boolean
+func (mdbc *MariaDBCluster) GetFeatures() (map[string]bool, error) {
+	var keys []string
+	for key := range mdbc.Annotations {
+		if strings.HasPrefix(key, FeatureAnnotationPrefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	flags := make(map[string]bool)
+	for _, key := range keys {
+		name := strings.TrimPrefix(key, FeatureAnnotationPrefix)
+		known := false
+		for _, feature := range Features {
+			known = known || feature == name
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown feature %q, not one of %s", name, strings.Join(Features, ", "))
+		}
+		enabled, err := strconv.ParseBool(mdbc.Annotations[key])
+		if err != nil {
+			return nil, fmt.Errorf("feature %s : %q is not a boolean", name, mdbc.Annotations[key])
+		}
+		flags[name] = enabled
+	}
+	return flags, nil
+}
+
+// IsFeatureEnabled tells whether the annotation of an experimental feature is
+// set, one that does not parse leaves it off
+func (mdbc *MariaDBCluster) IsFeatureEnabled(name string) bool {
+	enabled, _ := strconv.ParseBool(mdbc.Annotations[FeatureAnnotationPrefix+name])
+	return enabled
+}
+
+func (mdbc *MariaDBCluster) GetFinalBackupJobName() string {
+	return mdbc.Name + "-" + MariaDBClusterBackupRole + "-final"
+}
+
+// GetUpgradeBackupJobName returns a name unique to the upgrade target so that
+// retries of the same upgrade reuse the backup Job
+func (mdbc *MariaDBCluster) GetUpgradeBackupJobName() string {
+	return mdbc.Name + "-" + MariaDBClusterBackupRole + "-" + strings.Replace(mdbc.Status.TargetVersion, ".", "-", -1)
+}
+
+func (mdbc *MariaDBCluster) GetProviderCheckJobName(version string) string {
+	return mdbc.Name + "-" + MariaDBClusterProviderCheckRole + "-" + strings.Replace(version, ".", "-", -1)
+}
+
+func (mdbc *MariaDBCluster) GetImageResolveJobName(version string) string {
+	return mdbc.Name + "-" + MariaDBClusterImageResolveRole + "-" + strings.Replace(version, ".", "-", -1)
+}
+
+func (mdbc *MariaDBCluster) GetSSTCheckJobName(version string) string {
+	return mdbc.Name + "-" + MariaDBClusterSSTCheckRole + "-" + strings.Replace(version, ".", "-", -1)
+}
+
+// GetWSREPRecoverJobName returns the name of the Job recovering the position
+// of given server pod from its volume
+func (mdbc *MariaDBCluster) GetWSREPRecoverJobName(pod string) string {
+	return pod + "-" + MariaDBClusterWSREPRecoverRole
+}
+
+// GetConfigCheckJobName returns the name of the Job checking the server
+// configuration of given hash
+func (mdbc *MariaDBCluster) GetConfigCheckJobName(hash string) string {
+	return mdbc.Name + "-" + MariaDBClusterConfigCheckRole + "-" + hash
+}
+
+func (mdbc *MariaDBCluster) GetTimeZoneJobName() string {
+	return mdbc.Name + "-" + MariaDBClusterTimeZoneRole
+}
+
+// GetInitSQLJobName returns the name of the Job running given script of
+// Spec.InitSQL
+func (mdbc *MariaDBCluster) GetInitSQLJobName(script string) string {
+	return mdbc.Name + "-" + MariaDBClusterInitSQLRole + "-" + script
+}
+
+func (mdbc *MariaDBCluster) GetServerSecretName() string {
+	return mdbc.GetServerName()
+}
+
+func (mdbc *MariaDBCluster) GetClientSecretName() string {
+	return mdbc.Name + "-client"
+}
+
+func (mdbc *MariaDBCluster) GetWriteRateJobName(version string) string {
+	return mdbc.Name + "-" + MariaDBClusterWriteRateRole + "-" + strings.Replace(version, ".", "-", -1)
+}
+
+// GetGCacheSizeMB returns the gcache size set by the operator, zero when the
+// galera default is used
+func (mdbc *MariaDBCluster) GetGCacheSizeMB() int64 {
+	if mdbc.Status.GCache == nil {
+		return 0
+	}
+	return mdbc.Status.GCache.SizeMB
+}
+
+// GetWSREPProviderOptions returns wsrep_provider_options for given server pod,
+// bootstrap marks the pod starting a new primary component
+func (mdbc *MariaDBCluster) GetWSREPProviderOptions(hostname string, bootstrap bool) string {
+	var options []string
+	if bootstrap {
+		options = append(options, "pc.bootstrap=true")
+	}
+	if size := mdbc.GetGCacheSizeMB(); size > 0 {
+		options = append(options, fmt.Sprintf("gcache.size=%dM", size))
+	}
+	if size := mdbc.Spec.Galera.GCache.PageSizeMB; size > 0 {
+		options = append(options, fmt.Sprintf("gcache.page_size=%dM", size))
+	}
+	if size := mdbc.Spec.Galera.GCache.KeepPagesSizeMB; size > 0 {
+		options = append(options, fmt.Sprintf("gcache.keep_pages_size=%dM", size))
+	}
+	if addr, ok := mdbc.Status.ISTRecvAddr[hostname]; ok {
+		if port := mdbc.Spec.Galera.Ports.GetIST(); port != DefaultISTPort && !strings.Contains(addr, ":") {
+			addr = fmt.Sprintf("%s:%d", addr, port)
+		}
+		options = append(options, "ist.recv_addr="+addr)
+	} else if port := mdbc.Spec.Galera.Ports.GetIST(); port != DefaultISTPort {
+		options = append(options, fmt.Sprintf("ist.recv_addr=%s:%d", mdbc.GetReceiveHost(hostname), port))
+	}
+	if bind := mdbc.Spec.Galera.IST.RecvBind; bind != "" {
+		options = append(options, "ist.recv_bind="+bind)
+	}
+	resilience := mdbc.Spec.Galera.Resilience
+	options = append(options,
+		fmt.Sprintf("pc.recovery=%t", resilience.GetPCRecovery()),
+		"evs.suspect_timeout="+isoDuration(resilience.GetSuspectTimeout()),
+		"evs.inactive_timeout="+isoDuration(resilience.GetInactiveTimeout()),
+		"evs.install_timeout="+isoDuration(resilience.GetInstallTimeout()),
+	)
+	var keys []string
+	for key := range mdbc.Spec.Galera.ProviderOptions {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		options = append(options, key+"="+mdbc.Spec.Galera.ProviderOptions[key])
+	}
+	if mdbc.Spec.Galera.ZoneSegments {
+		if segment, ok := mdbc.Status.Segments[mdbc.Status.Placement[hostname].Zone]; ok {
+			options = append(options, fmt.Sprintf("gmcast.segment=%d", segment))
+		}
+	}
+	if weight, ok := mdbc.GetPCWeight(hostname); ok {
+		options = append(options, fmt.Sprintf("pc.weight=%d", weight))
+	}
+	if fc := mdbc.Status.FlowControl; fc != nil && fc.Limits[hostname] > 0 {
+		options = append(options, fmt.Sprintf("gcs.fc_limit=%d", fc.Limits[hostname]))
+	}
+	return strings.Join(options, ";")
+}
+
+// GetReceiveHost returns the host other pods reach a server pod at for state
+// transfers: that of its ist.recv_addr when set, its name in the headless
+// service of its color otherwise
+func (mdbc *MariaDBCluster) GetReceiveHost(pod string) string {
+	if addr, ok := mdbc.Status.ISTRecvAddr[pod]; ok {
+		if host, err := ParseISTAddr(addr); err == nil {
+			return host
+		}
+	}
+	service := pod
+	if i := strings.LastIndex(pod, "-"); i >= 0 {
+		service = pod[:i]
+	}
+	return pod + "." + service
+}
+
+// GetSSTReceiveAddress returns wsrep_sst_receive_address of a server pod, empty
+// when the SST port is the default one and the server finds it by itself
+func (mdbc *MariaDBCluster) GetSSTReceiveAddress(pod string) string {
+	port := mdbc.Spec.Galera.Ports.GetSST()
+	if port == DefaultSSTPort {
+		return ""
+	}
+	return net.JoinHostPort(mdbc.GetReceiveHost(pod), strconv.Itoa(int(port)))
+}
+
+// ParseISTAddr returns the host of an ist.recv_addr, which may carry a port
+func ParseISTAddr(addr string) (string, error) {
+	host := addr
+	if strings.Contains(addr, ":") {
+		var port string
+		var err error
+		if host, port, err = net.SplitHostPort(addr); err != nil {
+			return "", err
+		}
+		if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+			return "", fmt.Errorf("invalid port %q", port)
+		}
+	}
+	if host == "" {
+		return "", fmt.Errorf("no host in %q", addr)
+	}
+	return host, nil
+}
+
+// isoDuration formats d the way galera takes periods, as ISO 8601 duration
+func isoDuration(d time.Duration) string {
+	return "PT" + strconv.FormatFloat(d.Seconds(), 'f', -1, 64) + "S"
+}
+
+// GetSSTDonor returns wsrep_sst_donor for given pod, listing its siblings by
+// Spec.Galera.Donor: preferred pods first, then those in the same zone. Donors
+// of timed out state transfers are left out, the trailing comma lets galera
+// fall back to them when nothing else is available. The backup source, when
+// avoided, is never used, so there is no fallback then.
+func (mdbc *MariaDBCluster) GetSSTDonor(hostname, color string) string {
+	policy := mdbc.Spec.Galera.Donor
+	if len(mdbc.Status.SSTExcludedDonors) == 0 && len(policy.Preferred) == 0 &&
+		!policy.PreferSameZone && !policy.AvoidBackupSource {
+		return ""
+	}
+	excluded := map[string]bool{hostname: true}
+	for _, donor := range mdbc.Status.SSTExcludedDonors {
+		excluded[donor] = true
+	}
+	if policy.AvoidBackupSource {
+		excluded[mdbc.GetBackupSourcePod()] = true
+	}
+	var donors, sameZone, otherZones []string
+	listed := make(map[string]bool)
+	if color == mdbc.GetActiveColor() {
+		for _, pod := range policy.Preferred {
+			if !excluded[pod] && !listed[pod] {
+				donors = append(donors, pod)
+				listed[pod] = true
+			}
+		}
+	}
+	zone := mdbc.Status.Placement[hostname].Zone
+	for i := int32(0); i < mdbc.Spec.Replicas; i++ {
+		pod := fmt.Sprintf("%s-%d", mdbc.GetServerNameForColor(color), i)
+		if excluded[pod] || listed[pod] {
+			continue
+		}
+		if policy.PreferSameZone && zone != "" && mdbc.Status.Placement[pod].Zone == zone {
+			sameZone = append(sameZone, pod)
+		} else {
+			otherZones = append(otherZones, pod)
+		}
+	}
+	donors = append(append(donors, sameZone...), otherZones...)
+	if len(donors) == 0 {
+		return ""
+	}
+	if policy.AvoidBackupSource {
+		return strings.Join(donors, ",")
+	}
+	return strings.Join(donors, ",") + ","
+}
+
+// NeedsPlacement tells whether pods depend on the zones recorded in status
+func (mdbc *MariaDBCluster) NeedsPlacement() bool {
+	return mdbc.Spec.Galera.Donor.PreferSameZone || mdbc.Spec.Galera.ZoneSegments ||
+		len(mdbc.Spec.Galera.Weights.Zones) > 0
+}
+
+// GetPCWeight returns pc.weight of given pod, false when left to galera
+func (mdbc *MariaDBCluster) GetPCWeight(hostname string) (int, bool) {
+	weights := mdbc.Spec.Galera.Weights
+	if weight, ok := weights.Pods[hostname]; ok {
+		return weight, true
+	}
+	if placement, ok := mdbc.Status.Placement[hostname]; ok && placement.Zone != "" {
+		if weight, ok := weights.Zones[placement.Zone]; ok {
+			return weight, true
+		}
+	}
+	return 0, false
+}
+
+// GetBackupSourcePod returns the pod backups are taken from when it is kept
+// out of state transfers, the last one as it is the last to be scaled away
+func (mdbc *MariaDBCluster) GetBackupSourcePod() string {
+	return fmt.Sprintf("%s-%d", mdbc.GetServerStatefulSetName(), mdbc.Spec.Replicas-1)
+}
+
+func (mdbc *MariaDBCluster) GetBlueGreenSyncJobName() string {
+	return mdbc.Name + "-" + MariaDBClusterBlueGreenRole + "-sync"
+}
+
+func (mdbc *MariaDBCluster) GetBlueGreenSwitchJobName() string {
+	return mdbc.Name + "-" + MariaDBClusterBlueGreenRole + "-switch"
+}
+
+func (mdbc *MariaDBCluster) GetBlueGreenFinalizeJobName() string {
+	return mdbc.Name + "-" + MariaDBClusterBlueGreenRole + "-finalize"
+}
+
+func (mdbc *MariaDBCluster) isProxyEnabled() bool {
+	return mdbc.Spec.Proxy
+}
+
+// Label getters
+
+func (mdbc *MariaDBCluster) GetServerLabels() map[string]string {
+	return mdbc.GetServerLabelsForColor(mdbc.GetActiveColor())
+}
+
+// GetServerLabelsForColor tells colors apart by role so that selectors of blue
+// objects, which are immutable on StatefulSets, never have to change
+func (mdbc *MariaDBCluster) GetServerLabelsForColor(color string) map[string]string {
+	labels := make(map[string]string)
+	labels[MariaDBClusterNameLabel] = mdbc.Name
+	labels[MariaDBClusterRoleLabel] = MariaDBClusterServerRole
+	if color != ColorBlue {
+		labels[MariaDBClusterRoleLabel] = MariaDBClusterServerRole + "-" + color
+	}
+	return labels
+}
+
+func (mdbc *MariaDBCluster) GetBackupLabels() map[string]string {
+	labels := make(map[string]string)
+	labels[MariaDBClusterNameLabel] = mdbc.Name
+	labels[MariaDBClusterRoleLabel] = MariaDBClusterBackupRole
 	return labels
 }
 
@@ -1,6 +1,11 @@
 package v1alpha1
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -14,9 +19,56 @@ const (
 	StageSynced                = "Synced"
 	StageDegraded              = "Degraded"
 	PhaseRecovery              = "Recovery"
+	StageRestarting            = "Restarting"
+	StageReporting             = "Reporting"
 	StagePrimaryRecovered      = "PrimaryRecovered"
 	StageInvalidReport         = "InvalidReport"
-	ConditionScaling           = "Scaling"
+	// reports were retried for Spec.Recovery.ReportRetryTimeout, waiting on forceBootstrapFrom
+	StageManualRecovery = "ManualRecovery"
+	// the phase timed out, the stage it was in is kept in Stalled
+	StageStalled = "Stalled"
+	// the cluster was deleted, its finalizer is removed once the stages ran
+	PhaseTeardown          = "Teardown"
+	StageFinalBackup       = "FinalBackup"
+	StageFinalBackupFailed = "FinalBackupFailed"
+	StageRemovingClients   = "RemovingClients"
+	StageRemovingServers   = "RemovingServers"
+	StageReleasingStorage  = "ReleasingStorage"
+	ConditionScaling       = "Scaling"
+	ConditionUpgrading     = "Upgrading"
+	ConditionUpdatePending = "UpdatePending"
+	ConditionSplitBrain    = "SplitBrain"
+	ConditionStateTransfer = "StateTransfer"
+	ConditionSSTMethod     = "SSTMethod"
+	ConditionFlowControl   = "FlowControl"
+	ConditionStalled       = "Stalled"
+	ConditionDiverged      = "Diverged"
+	ConditionKernelTuning  = "KernelTuning"
+	ConditionISTAddress    = "ISTAddress"
+	ConditionServerConfig  = "ServerConfig"
+	ConditionConfigPending = "ConfigPendingRestart"
+	ConditionConfigDrift   = "ConfigDrift"
+	ConditionConfigCheck   = "ConfigCheck"
+	ConditionTimeZone      = "TimeZone"
+	ConditionImageResolve  = "ImageResolve"
+	ConditionPlugins       = "Plugins"
+	ConditionInitSQL       = "InitSQL"
+	ConditionSuspended     = "Suspended"
+	ConditionConflict      = "ResourceConflict"
+	ConditionFeatures      = "ExperimentalFeatures"
+	ConditionMaintenance   = "Maintenance"
+	ConditionReconcile     = "ReconcileFailed"
+	// the last reconcile panicked, the message holds where
+	ConditionReconcileError = "ReconcileError"
+
+	// wsrep_cluster_status of a node belonging to a primary component
+	WSREPClusterStatusPrimary = "Primary"
+
+	BlueGreenStageProvisioning = "Provisioning"
+	BlueGreenStageSyncing      = "Syncing"
+	BlueGreenStageScaling      = "Scaling"
+	BlueGreenStageSwitching    = "Switching"
+	BlueGreenStageRetiring     = "Retiring"
 )
 
 type MariaDBClusterCondition struct {
@@ -31,24 +83,489 @@ type MariaDBClusterCondition struct {
 type MariaDBClusterStatus struct {
 	Phase                         string                    `json:"phase"`
 	Stage                         string                    `json:"stage"`
+	PhaseStartTime                metav1.Time               `json:"phaseStartTime,omitempty"`
 	Conditions                    []MariaDBClusterCondition `json:"conditions"`
 	CurrentVersion                string                    `json:"currentVersion"`
 	TargetVersion                 string                    `json:"targetVersion"`
 	StatefulSetObservedGeneration int64                     `json:"statefulSetObservedGeneration"`
-	StatefulSetPodConditions      []PodCondition            `json:"statefulSetPodConditions"`
-	BootstrapFrom                 string                    `json:"bootstrapFrom,omitempty"`
+	// metadata.generation the last reconciliation without error went through,
+	// the spec is acted upon once it matches
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Ready pods of the active StatefulSet
+	ReadyReplicas int32 `json:"readyReplicas"`
+	// Pod of the primary component furthest ahead, the lowest ordinal on a
+	// tie, empty while no serving pod reports being part of one
+	Primary string `json:"primary,omitempty"`
+	// Fragment of Spec.Config last found valid, pods render it into their
+	// configuration when they start
+	ServerConfig string `json:"serverConfig,omitempty"`
+	// Configuration hash a check Job last accepted, StatefulSet changes are
+	// held back until the hash pods would roll to is accepted
+	CheckedConfigHash string `json:"checkedConfigHash,omitempty"`
+	// Time zone tables were loaded on every server pod
+	TimeZonesLoaded bool `json:"timeZonesLoaded,omitempty"`
+	// Spec.Server.TimeZone once the tables it needs are loaded, pods render it
+	// as default_time_zone
+	TimeZone string `json:"timeZone,omitempty"`
+	// Spec.InitSQL scripts that completed, keyed by name, they never run again
+	InitSQL map[string]InitSQLStatus `json:"initSQL,omitempty"`
+	// Position of server pods during Recovery, keyed by pod name
+	RecoveryReports map[string]RecoveryReport `json:"recoveryReports,omitempty"`
+	BootstrapFrom   string                    `json:"bootstrapFrom,omitempty"`
+	// Position server pods still answering over SQL were at when Recovery
+	// started, keyed by pod name, their grastate.dat is checked against it
+	QueriedPositions map[string]GRAState `json:"queriedPositions,omitempty"`
+	// When BootstrapFrom was picked
+	BootstrapFromTime metav1.Time `json:"bootstrapFromTime,omitempty"`
+	// Pods that did not become ready within Spec.Recovery.BootstrapReadyTimeout
+	// once picked during this Recovery, they are not picked again
+	BootstrapExcluded []string `json:"bootstrapExcluded,omitempty"`
+	// Last Spec.Recovery.ForceBootstrapFrom acted upon
+	ForcedBootstrapFrom string `json:"forcedBootstrapFrom,omitempty"`
+	// Pods restarted for new reports since the first invalid one of this Recovery
+	ReportRetry *ReportRetryStatus `json:"reportRetry,omitempty"`
+	// Most recent successful backup Job of this cluster
+	LastBackup *BackupStatus `json:"lastBackup,omitempty"`
+	// Backup taken before the last version upgrade, to be used for rollback
+	UpgradeRollbackPoint string `json:"upgradeRollbackPoint,omitempty"`
+	// Galera provider package shipped with each probed server version
+	ProviderVersions map[string]string `json:"providerVersions,omitempty"`
+	// Digest the version tag was last resolved to when image pinning is enabled
+	PinnedImage *PinnedImageStatus `json:"pinnedImage,omitempty"`
+	// Resolve Jobs that failed in a row, retries back off accordingly
+	ImageResolveAttempts int `json:"imageResolveAttempts,omitempty"`
+	// Color of the server objects serving clients, blue when empty
+	ActiveColor string `json:"activeColor,omitempty"`
+	// Progress of a blue/green upgrade in flight
+	BlueGreen *BlueGreenStatus `json:"blueGreen,omitempty"`
+	// gcache sizing done ahead of the last rolling upgrade
+	GCache *GCacheStatus `json:"gcache,omitempty"`
+	// Live galera state of server pods reported by their agent, keyed by pod name
+	WSREP map[string]WSREPStatus `json:"wsrep,omitempty"`
+	// Donors of state transfers that timed out, avoided until the cluster is Synced
+	SSTExcludedDonors []string `json:"sstExcludedDonors,omitempty"`
+	// wsrep_sst_method rendered into server config, set once the server image
+	// was found to ship the tools it needs
+	SSTMethod string `json:"sstMethod,omitempty"`
+	// SST methods each probed server version ships the tools for
+	SSTMethods map[string][]string `json:"sstMethods,omitempty"`
+	// Node and zone of server pods, kept while donor preferences need them
+	Placement map[string]PodPlacement `json:"placement,omitempty"`
+	// gmcast.segment of each zone, a number is never handed to another zone
+	Segments map[string]int `json:"segments,omitempty"`
+	// Spec.Galera.IST.RecvAddr entries the operator resolved, rendered into
+	// ist.recv_addr of the pods
+	ISTRecvAddr map[string]string `json:"istRecvAddr,omitempty"`
+	// Flow control mitigations applied by the agents
+	FlowControl *FlowControlStatus `json:"flowControl,omitempty"`
+	// Pod told to bootstrap a primary component after all of them lost quorum
+	PCBootstrap *PCBootstrapStatus `json:"pcBootstrap,omitempty"`
+	// Phase exceeding its timeout, set along with the Stalled stage
+	Stalled *StalledStatus `json:"stalled,omitempty"`
+	// Pods held back from joining by their initializer as their data diverged
+	// from the running primary component, keyed by pod name
+	Diverged map[string]DivergenceReport `json:"diverged,omitempty"`
+	// Kernel parameters of nodes differing from spec.kernelTuning, as tune
+	// init containers found them, keyed by pod name
+	Kernel map[string]KernelReport `json:"kernel,omitempty"`
+	// Galera cluster the pods hold and primary components bootstrapped for it
+	Lineage *LineageStatus `json:"lineage,omitempty"`
+	// What happened during the last Recovery phase, for post-incident review
+	RecoveryTimeline *RecoveryTimeline `json:"recoveryTimeline,omitempty"`
+	// How clients reach the cluster, published once it is first Operational
+	Connection *ConnectionStatus `json:"connection,omitempty"`
+	// Kinds of pre-existing objects the cluster took ownership of
+	Adopted []string `json:"adopted,omitempty"`
+	// Latest phase and stage transitions, oldest first
+	Transitions []PhaseTransition `json:"transitions,omitempty"`
+	// Whether the cluster is in maintenance, independently of its phase
+	Maintenance bool `json:"maintenance,omitempty"`
+}
+
+type RecoveryTimeline struct {
+	StartTime metav1.Time `json:"startTime"`
+	// Set once the cluster is Operational again
+	EndTime metav1.Time `json:"endTime,omitempty"`
+	// Recovered, or the stage the phase waits in when it did not complete:
+	// ManualRecovery or Stalled
+	Result string `json:"result,omitempty"`
+	// Positions pods reported, as they were when the bootstrap pod was chosen
+	Positions     map[string]GRAState `json:"positions,omitempty"`
+	BootstrapFrom string              `json:"bootstrapFrom,omitempty"`
+	Events        []RecoveryEvent     `json:"events,omitempty"`
+	// State transfers of pods joining the recovered primary component
+	Transfers []RecoveryTransfer `json:"transfers,omitempty"`
+}
+
+type RecoveryEvent struct {
+	Time    metav1.Time `json:"time"`
+	Message string      `json:"message"`
+}
+
+type RecoveryTransfer struct {
+	Pod       string      `json:"pod"`
+	StartTime metav1.Time `json:"startTime"`
+	// Zero while in progress
+	Duration metav1.Duration `json:"duration,omitempty"`
+	Bytes    int64           `json:"bytes"`
+}
+
+type LineageStatus struct {
+	// Cluster UUID and highest seqno serving pods reported, only ever growing
+	// for a given UUID
+	ClusterStateUUID string `json:"clusterStateUUID,omitempty"`
+	LastCommitted    int64  `json:"lastCommitted"`
+	// Primary components bootstrapped by the operator, oldest first
+	Bootstraps []BootstrapRecord `json:"bootstraps,omitempty"`
+}
+
+type BootstrapRecord struct {
+	Time metav1.Time `json:"time"`
+	Pod  string      `json:"pod"`
+	// What led to it: BootstrapFirst, Recovery, Forced or PCBootstrap
+	Reason string `json:"reason"`
+	// Position of the pod, seqno -1 when not known
+	ClusterStateUUID string `json:"clusterStateUUID,omitempty"`
+	SeqNo            int64  `json:"seqno"`
+}
+
+// DivergenceReport is what the initializer of a pod held back from joining
+// tells about its data and the primary component it would have joined
+type DivergenceReport struct {
+	GRAState GRAState `json:"graState"`
+	// Cluster UUID and highest seqno of the primary component
+	ClusterStateUUID string      `json:"clusterStateUUID"`
+	LastCommitted    int64       `json:"lastCommitted"`
+	Reported         metav1.Time `json:"reported"`
+	// HMAC of the report under the report key of the server Secret
+	Signature string `json:"signature,omitempty"`
+}
+
+// KernelReport is what the tune init container of a pod tells about the node
+// it ran on, only while some sysctls differ from spec.kernelTuning
+type KernelReport struct {
+	Node      string          `json:"node"`
+	Differing []KernelSetting `json:"differing"`
+	Reported  metav1.Time     `json:"reported"`
+	// HMAC of the report under the report key of the server Secret
+	Signature string `json:"signature,omitempty"`
+}
+
+type KernelSetting struct {
+	Name string `json:"name"`
+	// Empty when the sysctl could not be read
+	Value    string `json:"value"`
+	Expected string `json:"expected"`
+}
+
+type PCBootstrapStatus struct {
+	Pod string `json:"pod"`
+	// Position of the pod when it was picked
+	LastCommitted int64       `json:"lastCommitted"`
+	Time          metav1.Time `json:"time"`
+}
+
+type ReportRetryStatus struct {
+	Attempts int `json:"attempts"`
+	// Why the reports did not allow selecting a pod, last time they were checked
+	Reason string `json:"reason"`
+	// First invalid report, retries stop after Spec.Recovery.ReportRetryTimeout
+	StartTime metav1.Time `json:"startTime"`
+	LastTime  metav1.Time `json:"lastTime"`
+}
+
+type StalledStatus struct {
+	// Stage the phase is actually in, transitions carry on from there
+	Stage string `json:"stage,omitempty"`
+	// What holds the phase back, as far as the operator can tell
+	Diagnostics []string `json:"diagnostics,omitempty"`
+}
+
+type FlowControlStatus struct {
+	// gcs.fc_limit raised for each pod, kept across restarts
+	Limits map[string]int `json:"limits,omitempty"`
+	// Pod desynced until its receive queue drained
+	Desynced string `json:"desynced,omitempty"`
+	// Last mitigation applied, the next one waits for its effect
+	LastChange metav1.Time `json:"lastChange,omitempty"`
+}
+
+type PodPlacement struct {
+	NodeName string `json:"nodeName"`
+	Zone     string `json:"zone,omitempty"`
+}
+
+type InitSQLStatus struct {
+	CompletionTime metav1.Time `json:"completionTime"`
+	// Job that ran the script, until it is removed
+	Job string `json:"job,omitempty"`
+}
+
+type WSREPStatus struct {
+	// Color of the StatefulSet the pod belongs to, blue when empty
+	Color            string `json:"color,omitempty"`
+	ClusterStateUUID string `json:"clusterStateUUID"`
+	ClusterStatus    string `json:"clusterStatus"`
+	// When ClusterStatus last changed, as far as the agent saw
+	ClusterStatusSince metav1.Time `json:"clusterStatusSince,omitempty"`
+	ClusterConfID      int64       `json:"clusterConfID"`
+	ClusterSize        int         `json:"clusterSize"`
+	LocalState         string      `json:"localState"`
+	// When LocalState last changed, as far as the agent saw
+	LocalStateSince metav1.Time `json:"localStateSince,omitempty"`
+	LastCommitted   int64       `json:"lastCommitted"`
+	// Bytes per second replicated and received since the previous report
+	WriteRate int64 `json:"writeRate,omitempty"`
+	// Space left on the data volume
+	DataAvailableBytes int64 `json:"dataAvailableBytes,omitempty"`
+	// State transfer the pod is receiving, if any
+	SST *SSTProgress `json:"sst,omitempty"`
+	// Share of time replication was paused by flow control since the previous report
+	FlowControlPausedPercent int `json:"flowControlPausedPercent,omitempty"`
+	// Pause requests the pod sent since the previous report
+	FlowControlSent int64 `json:"flowControlSent,omitempty"`
+	// Write sets waiting to be applied
+	RecvQueue int64 `json:"recvQueue,omitempty"`
+	// wsrep_desync was set by the agent as flow control mitigation
+	Desynced bool `json:"desynced,omitempty"`
+	// Static options of spec.config the server did not start with, applied
+	// on its next restart. Dynamic ones are set by the agent right away.
+	PendingRestart []string `json:"pendingRestart,omitempty"`
+	// Plugins of spec.server not active on the server, with their status
+	InactivePlugins []string `json:"inactivePlugins,omitempty"`
+	// GetConfigHash of the configuration the pod was started with
+	ConfigHash string      `json:"configHash,omitempty"`
+	Reported   metav1.Time `json:"reported"`
+	// HMAC of the report under the report key of the server Secret
+	Signature string `json:"signature,omitempty"`
+}
+
+type SSTProgress struct {
+	StartTime metav1.Time `json:"startTime"`
+	// Size of the data directory received so far
+	Bytes int64 `json:"bytes"`
+}
+
+type GCacheStatus struct {
+	// Upgrade target the estimate was made for, empty for automatic sizing
+	Version string `json:"version"`
+	// Replicated bytes per second observed across the cluster
+	WriteRate int64 `json:"writeRate"`
+	// When WriteRate was observed, automatic sizing keeps the peak of a day
+	MeasuredTime metav1.Time `json:"measuredTime,omitempty"`
+	// gcache.size rendered into server config, zero for the galera default
+	SizeMB int64 `json:"sizeMB,omitempty"`
+}
+
+type BlueGreenStatus struct {
+	// Color of the parallel cluster being built
+	Color     string      `json:"color"`
+	Version   string      `json:"version"`
+	Stage     string      `json:"stage"`
+	StartTime metav1.Time `json:"startTime"`
+}
+
+type PinnedImageStatus struct {
+	Version      string      `json:"version"`
+	Digest       string      `json:"digest"`
+	ResolvedTime metav1.Time `json:"resolvedTime"`
+	// Tagged image the digest was resolved from
+	Image string `json:"image,omitempty"`
+}
+
+type PhaseTransition struct {
+	Time      metav1.Time `json:"time"`
+	Phase     string      `json:"phase"`
+	Stage     string      `json:"stage,omitempty"`
+	FromPhase string      `json:"fromPhase,omitempty"`
+	FromStage string      `json:"fromStage,omitempty"`
+	// Diagnostics, recovery event or conditions that came with the transition
+	Reason string `json:"reason,omitempty"`
+}
+
+// ConnectionStatus tells other controllers where to connect, so that they need
+// not rely on the naming of the cluster objects
+type ConnectionStatus struct {
+	// Service taking writes, routed to the proxy or to serving server pods
+	PrimaryHost string `json:"primaryHost"`
+	// Service of the server pods, resolving to each of them
+	ReaderHost string `json:"readerHost"`
+	Port       int32  `json:"port"`
+	// Secret holding the username and password of the client user
+	SecretName string `json:"secretName"`
+}
+
+type BackupStatus struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version"`
+	CompletionTime metav1.Time `json:"completionTime"`
+}
+
+// GetImage returns the tagged image the digest belongs to, digests pinned before
+// the image was recorded were resolved from the default repository
+func (p *PinnedImageStatus) GetImage() string {
+	if p.Image == "" {
+		return DefaultServerImage + ":" + p.Version
+	}
+	return p.Image
+}
+
+// IsAdopted tells whether an existing object of given kind was adopted
+func (s *MariaDBClusterStatus) IsAdopted(kind string) bool {
+	for _, adopted := range s.Adopted {
+		if adopted == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// GetStage returns the stage of the current phase, also while it is stalled
+func (s *MariaDBClusterStatus) GetStage() string {
+	if s.Stage == StageStalled && s.Stalled != nil {
+		return s.Stalled.Stage
+	}
+	return s.Stage
+}
+
+func (s *MariaDBClusterStatus) GetCondition(conditionType string) *MariaDBClusterCondition {
+	for i := range s.Conditions {
+		if s.Conditions[i].Type == conditionType {
+			return &s.Conditions[i]
+		}
+	}
+	return nil
+}
+
+// SetCondition adds or updates a condition, timestamps are only bumped on
+// actual changes so that repeated reconciles do not produce status patches
+func (s *MariaDBClusterStatus) SetCondition(conditionType string, status bool, reason, message string) {
+	now := metav1.Now()
+	cond := s.GetCondition(conditionType)
+	if cond == nil {
+		s.Conditions = append(s.Conditions, MariaDBClusterCondition{
+			Type:               conditionType,
+			Status:             status,
+			Reason:             reason,
+			Message:            message,
+			LastTransitionTime: now,
+			LastUpdateTime:     now,
+		})
+		return
+	}
+	if cond.Status != status {
+		cond.LastTransitionTime = now
+	}
+	if cond.Status != status || cond.Reason != reason || cond.Message != message {
+		cond.Status = status
+		cond.Reason = reason
+		cond.Message = message
+		cond.LastUpdateTime = now
+	}
+}
+
+func (s *MariaDBClusterStatus) RemoveCondition(conditionType string) {
+	var conditions []MariaDBClusterCondition
+	for _, cond := range s.Conditions {
+		if cond.Type != conditionType {
+			conditions = append(conditions, cond)
+		}
+	}
+	s.Conditions = conditions
+}
+
+// RecoveryReport is what the agent of a server pod held back during Recovery
+// tells about the position of its data
+type RecoveryReport struct {
+	GRAState GRAState    `json:"graState"`
+	Reported metav1.Time `json:"reported"`
+	// Position a wsrep-recover Job found on the volume of a pod reporting
+	// seqno -1, added by the operator. Seqno -1 when the Job failed.
+	Recovered *GRAState `json:"recovered,omitempty"`
+	// HMAC of the report under the report key of the server Secret
+	Signature string `json:"signature,omitempty"`
+}
+
+// GRAState holds grastate.dat values, the seqno recovered through
+// --wsrep-recover when it was not saved
+type GRAState struct {
+	Version         string `json:"version"`
+	UUID            string `json:"uuid"`
+	SeqNo           int64  `json:"seqno"`
+	SafeToBootstrap int    `json:"safeToBootstrap"`
+}
+
+// GetGRAState returns the reported position, completed with the one recovered
+// by a wsrep-recover Job when the pod could not tell its seqno
+func (r RecoveryReport) GetGRAState() GRAState {
+	state := r.GRAState
+	if state.SeqNo < 0 && r.Recovered != nil && r.Recovered.SeqNo >= 0 {
+		state.UUID = r.Recovered.UUID
+		state.SeqNo = r.Recovered.SeqNo
+	}
+	return state
+}
+
+// Sign sets the signature of the report pod name makes, the operator only
+// trusts reports signed with the key server pods get from the server Secret
+func (r *RecoveryReport) Sign(pod string, key []byte) {
+	r.Signature = ""
+	r.Signature = signReport(pod, key, r)
+}
+
+func (r RecoveryReport) Verify(pod string, key []byte) bool {
+	signature := r.Signature
+	r.Signature = ""
+	return verifyReport(pod, key, &r, signature)
+}
+
+func (d *DivergenceReport) Sign(pod string, key []byte) {
+	d.Signature = ""
+	d.Signature = signReport(pod, key, d)
+}
+
+func (d DivergenceReport) Verify(pod string, key []byte) bool {
+	signature := d.Signature
+	d.Signature = ""
+	return verifyReport(pod, key, &d, signature)
+}
+
+func (k *KernelReport) Sign(pod string, key []byte) {
+	k.Signature = ""
+	k.Signature = signReport(pod, key, k)
+}
+
+func (k KernelReport) Verify(pod string, key []byte) bool {
+	signature := k.Signature
+	k.Signature = ""
+	return verifyReport(pod, key, &k, signature)
+}
+
+func (w *WSREPStatus) Sign(pod string, key []byte) {
+	w.Signature = ""
+	w.Signature = signReport(pod, key, w)
+}
+
+func (w WSREPStatus) Verify(pod string, key []byte) bool {
+	signature := w.Signature
+	w.Signature = ""
+	return verifyReport(pod, key, &w, signature)
 }
 
-// PodCondition publishes grstate.dat values with some additional meta
-type PodCondition struct {
-	Hostname string
-	Reported metav1.Time
-	GRAState PodConditionGRAState
+// signReport returns the HMAC of the serialized report along with the pod it
+// is about, so that a report can not be replayed for another pod
+func signReport(pod string, key []byte, report interface{}) string {
+	payload, err := json.Marshal(report)
+	if err != nil || len(key) == 0 {
+		return ""
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(pod + "\n"))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
 }
 
-type PodConditionGRAState struct {
-	Version         string `json:"version" yaml:"version"`
-	UUID            string `json:"uuid" yaml:"uuid"`
-	SeqNo           int64  `json:"seqno" yaml:"seqno"`
-	SafeToBootstrap int    `json:"safe_to_bootstrap" yaml:"safe_to_bootstrap"`
+func verifyReport(pod string, key []byte, report interface{}, signature string) bool {
+	expected := signReport(pod, key, report)
+	return expected != "" && hmac.Equal([]byte(expected), []byte(signature))
 }
@@ -0,0 +1,73 @@
+package v1alpha1
+
+import (
+	"testing"
+)
+
+func TestReportSignature(t *testing.T) {
+	key := []byte("0123456789abcdef0123456789abcdef")
+	signed := func(pod string, signKey []byte) WSREPStatus {
+		status := WSREPStatus{
+			ClusterStateUUID: "6b3e2a1c-2f4d-11e8-9a7e-0a580a800004",
+			ClusterStatus:    WSREPClusterStatusPrimary,
+			ClusterSize:      3,
+			LastCommitted:    125,
+			LocalState:       "Synced",
+		}
+		status.Sign(pod, signKey)
+		return status
+	}
+	tampered := signed("mariadb-0", key)
+	tampered.LastCommitted = 9000
+	unsigned := signed("mariadb-0", key)
+	unsigned.Signature = ""
+	tests := []struct {
+		name   string
+		status WSREPStatus
+		pod    string
+		key    []byte
+		valid  bool
+	}{
+		{"good signature", signed("mariadb-0", key), "mariadb-0", key, true},
+		{"tampered field", tampered, "mariadb-0", key, false},
+		{"wrong key", signed("mariadb-0", []byte("fedcba9876543210fedcba9876543210")), "mariadb-0", key, false},
+		{"missing signature", unsigned, "mariadb-0", key, false},
+		{"signed for another pod", signed("mariadb-1", key), "mariadb-0", key, false},
+		{"signed without a key", signed("mariadb-0", nil), "mariadb-0", nil, false},
+	}
+	for _, test := range tests {
+		if valid := test.status.Verify(test.pod, test.key); valid != test.valid {
+			t.Errorf("%s: valid %t, expected %t", test.name, valid, test.valid)
+		}
+	}
+}
+
+func TestRecoveryReportSignature(t *testing.T) {
+	key := []byte("0123456789abcdef0123456789abcdef")
+	signed := func() RecoveryReport {
+		report := RecoveryReport{GRAState: GRAState{Version: "2.1", UUID: "6b3e2a1c-2f4d-11e8-9a7e-0a580a800004", SeqNo: -1}}
+		report.Sign("mariadb-0", key)
+		return report
+	}
+	safeToBootstrap := signed()
+	safeToBootstrap.GRAState.SafeToBootstrap = 1
+	recovered := signed()
+	recovered.Recovered = &GRAState{UUID: recovered.GRAState.UUID, SeqNo: 140}
+	resigned := recovered
+	resigned.Sign("mariadb-0", key)
+	tests := []struct {
+		name   string
+		report RecoveryReport
+		valid  bool
+	}{
+		{"good signature", signed(), true},
+		{"tampered safe_to_bootstrap", safeToBootstrap, false},
+		{"recovered position added without signing", recovered, false},
+		{"recovered position signed by the operator", resigned, true},
+	}
+	for _, test := range tests {
+		if valid := test.report.Verify("mariadb-0", key); valid != test.valid {
+			t.Errorf("%s: valid %t, expected %t", test.name, valid, test.valid)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package v1alpha1
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	// galera takes periods as ISO 8601 durations
+	isoDurationRegexp = regexp.MustCompile(`^P(T([0-9]+H)?([0-9]+M)?([0-9]+(\.[0-9]+)?S)?)$`)
+	sizeRegexp        = regexp.MustCompile(`^[0-9]+[KMGT]?$`)
+)
+
+// provider options the operator renders from other settings, spec.galera.providerOptions
+// may not set them
+var managedProviderOptions = map[string]string{
+	"pc.bootstrap":           "the operator when bootstrapping",
+	"pc.recovery":            "spec.galera.resilience.pcRecovery",
+	"pc.weight":              "spec.galera.weights",
+	"evs.suspect_timeout":    "spec.galera.resilience.suspectTimeout",
+	"evs.inactive_timeout":   "spec.galera.resilience.inactiveTimeout",
+	"evs.install_timeout":    "spec.galera.resilience.installTimeout",
+	"gcache.size":            "spec.galera.gcache",
+	"gcache.page_size":       "spec.galera.gcache.pageSizeMB",
+	"gcache.keep_pages_size": "spec.galera.gcache.keepPagesSizeMB",
+	"gcs.fc_limit":           "spec.galera.flowControl",
+	"gmcast.segment":         "spec.galera.zoneSegments",
+	"ist.recv_addr":          "spec.galera.ist.recvAddr",
+	"ist.recv_bind":          "spec.galera.ist.recvBind",
+	"base_host":              "the operator",
+	"base_port":              "the operator",
+}
+
+// provider options spec.galera.providerOptions may set, with a check of their value
+var providerOptions = map[string]func(string) error{
+	"cert.log_conflicts":       boolOption,
+	"debug":                    boolOption,
+	"evs.auto_evict":           intOption(0, 1<<16),
+	"evs.delayed_keep_period":  durationOption,
+	"evs.delayed_margin":       durationOption,
+	"evs.join_retrans_period":  durationOption,
+	"evs.keepalive_period":     durationOption,
+	"evs.max_install_timeouts": intOption(0, 1<<16),
+	"evs.send_window":          intOption(1, 1<<16),
+	"evs.stats_report_period":  durationOption,
+	"evs.user_send_window":     intOption(1, 1<<16),
+	"gcache.mem_size":          sizeOption,
+	"gcache.recover":           boolOption,
+	"gcs.fc_factor":            floatOption(0, 1),
+	"gcs.fc_master_slave":      boolOption,
+	"gcs.max_packet_size":      intOption(1024, 1<<30),
+	"gcs.max_throttle":         floatOption(0, 1),
+	"gcs.recv_q_hard_limit":    sizeOption,
+	"gcs.recv_q_soft_limit":    floatOption(0, 1),
+	"gcs.sync_donor":           boolOption,
+	"gmcast.peer_timeout":      durationOption,
+	"gmcast.time_wait":         durationOption,
+	"pc.announce_timeout":      durationOption,
+	"pc.checksum":              boolOption,
+	"pc.ignore_quorum":         boolOption,
+	"pc.ignore_sb":             boolOption,
+	"pc.linger":                durationOption,
+	"pc.npvo":                  boolOption,
+	"pc.wait_prim":             boolOption,
+	"pc.wait_prim_timeout":     durationOption,
+	"repl.causal_read_timeout": durationOption,
+	"repl.commit_order":        intOption(0, 3),
+	"repl.key_format":          enumOption("FLAT8", "FLAT8A", "FLAT16", "FLAT16A"),
+	"repl.max_ws_size":         sizeOption,
+	"socket.checksum":          intOption(0, 2),
+	"socket.ssl":               boolOption,
+	"socket.ssl_ca":            stringOption,
+	"socket.ssl_cert":          stringOption,
+	"socket.ssl_cipher":        stringOption,
+	"socket.ssl_key":           stringOption,
+}
+
+// ValidateProviderOptions checks spec.galera.providerOptions against the options
+// galera knows and the operator leaves alone
+func ValidateProviderOptions(options map[string]string) error {
+	var keys []string
+	for key := range options {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if owner, ok := managedProviderOptions[key]; ok {
+			return fmt.Errorf("providerOptions can not set %s, it is managed through %s", key, owner)
+		}
+		check, ok := providerOptions[key]
+		if !ok {
+			return fmt.Errorf("providerOptions %s is not a known galera option", key)
+		}
+		value := options[key]
+		if strings.ContainsAny(value, ";\"\n") {
+			return fmt.Errorf("providerOptions %s : value %q holds a separator", key, value)
+		}
+		if err := check(value); err != nil {
+			return fmt.Errorf("providerOptions %s : %s", key, err.Error())
+		}
+	}
+	return nil
+}
+
+func boolOption(value string) error {
+	switch strings.ToLower(value) {
+	case "yes", "no", "true", "false", "on", "off", "1", "0":
+		return nil
+	}
+	return fmt.Errorf("%q is not a boolean", value)
+}
+
+func intOption(min, max int64) func(string) error {
+	return func(value string) error {
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || n < min || n > max {
+			return fmt.Errorf("%q is not a number from %d to %d", value, min, max)
+		}
+		return nil
+	}
+}
+
+func floatOption(min, max float64) func(string) error {
+	return func(value string) error {
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil || f < min || f > max {
+			return fmt.Errorf("%q is not a number from %g to %g", value, min, max)
+		}
+		return nil
+	}
+}
+
+func enumOption(values ...string) func(string) error {
+	return func(value string) error {
+		for _, v := range values {
+			if value == v {
+				return nil
+			}
+		}
+		return fmt.Errorf("%q is not one of %s", value, strings.Join(values, ", "))
+	}
+}
+
+func durationOption(value string) error {
+	if value == "P" || value == "PT" || !isoDurationRegexp.MatchString(value) {
+		return fmt.Errorf("%q is not an ISO 8601 duration such as PT5S", value)
+	}
+	return nil
+}
+
+func sizeOption(value string) error {
+	if !sizeRegexp.MatchString(value) {
+		return fmt.Errorf("%q is not a size such as 128M", value)
+	}
+	return nil
+}
+
+func stringOption(value string) error {
+	if value == "" {
+		return fmt.Errorf("empty value")
+	}
+	return nil
+}
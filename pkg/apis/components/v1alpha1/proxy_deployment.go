@@ -26,6 +26,7 @@ func (cluster *MariaDBCluster) ProxyDeploymentTransform(obj *apps.Deployment) er
 	obj.Spec.Replicas = &replicas
 	obj.Spec.Selector = &metav1.LabelSelector{MatchLabels: labels}
 	obj.Spec.Template.ObjectMeta.Labels = labels
+	obj.Spec.Template.Spec.PriorityClassName = cluster.Spec.ProxyPriorityClassName
 	if len(obj.Spec.Template.Spec.Containers) < 1 {
 		obj.Spec.Template.Spec.Containers = append(obj.Spec.Template.Spec.Containers, v1.Container{})
 	}
@@ -64,6 +65,9 @@ func (cluster *MariaDBCluster) ProxyDeploymentTransform(obj *apps.Deployment) er
 	// obj.Spec.Template.Spec.Containers[0].ReadinessProbe.PeriodSeconds = 2
 	// obj.Spec.Template.Spec.Containers[0].ReadinessProbe.TimeoutSeconds = 2
 	// obj.Spec.Template.Spec.Volumes = cluster.proxySetVolumesTransform(obj.Spec.Template.Spec.Volumes)
+	cluster.inheritMetadata(&obj.ObjectMeta)
+	cluster.inheritMetadata(&obj.Spec.Template.ObjectMeta)
+	cluster.confinePod(&obj.Spec.Template)
 	return nil
 }
 
@@ -25,6 +25,11 @@ func (mdbc *MariaDBCluster) ProxyServiceTransform(svc *v1.Service) error {
 	} else {
 		svc.Spec.Selector = mdbc.GetServerLabels()
 	}
+	// pods of diverged primary components must not all take writes
+	if cond := mdbc.Status.GetCondition(ConditionSplitBrain); cond != nil && cond.Status {
+		svc.Spec.Selector = mdbc.GetServerLabels()
+		svc.Spec.Selector[MariaDBClusterRoleLabel] = MariaDBClusterFencedRole
+	}
 	svc.Spec.Ports = []v1.ServicePort{
 		v1.ServicePort{
 			Name:       "mysql",
@@ -33,5 +38,6 @@ func (mdbc *MariaDBCluster) ProxyServiceTransform(svc *v1.Service) error {
 			TargetPort: intstr.FromInt(3306),
 		},
 	}
+	mdbc.inheritMetadata(&svc.ObjectMeta)
 	return nil
 }
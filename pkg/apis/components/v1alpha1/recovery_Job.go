@@ -0,0 +1,60 @@
+package v1alpha1
+
+import (
+	batch "k8s.io/api/batch/v1"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// WSREPRecoverJobTransform renders a Job running mysqld --wsrep-recover on the
+// data volume of given server pod, reporting the recovered position through
+// its termination message formatted as <uuid>:<seqno>. The volume may only be
+// attachable to one node, the Job runs on the node of the pod, which is held
+// in its init container meanwhile.
+func (mdbc *MariaDBCluster) WSREPRecoverJobTransform(job *batch.Job, pod, node string) error {
+	labels := mdbc.GetServerLabels()
+	labels[MariaDBClusterRoleLabel] = MariaDBClusterWSREPRecoverRole
+	backoffLimit := int32(1)
+
+	job.SetName(mdbc.GetWSREPRecoverJobName(pod))
+	job.SetNamespace(mdbc.Namespace)
+	job.SetLabels(labels)
+	job.SetOwnerReferences([]metav1.OwnerReference{
+		*metav1.NewControllerRef(mdbc, schema.GroupVersionKind{
+			Group:   GroupName,
+			Version: Version,
+			Kind:    "MariaDBCluster",
+		}),
+	})
+	job.Spec.BackoffLimit = &backoffLimit
+	job.Spec.Template.ObjectMeta.Labels = labels
+	job.Spec.Template.Spec.RestartPolicy = v1.RestartPolicyNever
+	job.Spec.Template.Spec.NodeName = node
+	job.Spec.Template.Spec.Volumes = []v1.Volume{
+		v1.Volume{
+			Name: "data",
+			VolumeSource: v1.VolumeSource{
+				PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{ClaimName: "data-" + pod},
+			},
+		},
+	}
+	if len(job.Spec.Template.Spec.Containers) < 1 {
+		job.Spec.Template.Spec.Containers = append(job.Spec.Template.Spec.Containers, v1.Container{})
+	}
+	job.Spec.Template.Spec.Containers[0].Name = MariaDBClusterWSREPRecoverRole
+	job.Spec.Template.Spec.Containers[0].Image = mdbc.GetServerImage()
+	job.Spec.Template.Spec.Containers[0].ImagePullPolicy = mdbc.GetImagePullPolicy(v1.PullIfNotPresent)
+	job.Spec.Template.Spec.Containers[0].TerminationMessagePolicy = v1.TerminationMessageReadFile
+	job.Spec.Template.Spec.Containers[0].VolumeMounts = []v1.VolumeMount{
+		v1.VolumeMount{Name: "data", MountPath: "/var/lib/mysql"},
+	}
+	job.Spec.Template.Spec.Containers[0].Command = []string{"bash", "-c",
+		"/usr/sbin/mysqld --user=mysql --wsrep-on=ON --wsrep-provider=/usr/lib/galera/libgalera_smm.so " +
+			"--wsrep-recover --log-error=/tmp/wsrep-recover.log; " +
+			"sed -n 's/.*WSREP: Recovered position: *//p' /tmp/wsrep-recover.log | tail -n 1 | tee /dev/termination-log | grep -q ':'"}
+	mdbc.inheritMetadata(&job.ObjectMeta)
+	mdbc.inheritMetadata(&job.Spec.Template.ObjectMeta)
+	mdbc.confinePod(&job.Spec.Template)
+	return nil
+}
@@ -1,20 +1,36 @@
 package v1alpha1
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strings"
+
 	"k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
+// ServerConfigMapTransform renders the configuration shared by the server pods
+// of the active color into the server ConfigMap: operator.cnf without the
+// per-pod values the initializer adds (donor, bootstrap, placement, server_id
+// and SST credentials), user.cnf and the spec.config fragment before it is rendered for
+// each pod. Pods render their configuration from status, the ConfigMap is the
+// copy of it to inspect. While a running cluster waits on the config check of
+// a new configuration the ConfigMap keeps the one the pods run with, like the
+// StatefulSet does.
 func (mdbc *MariaDBCluster) ServerConfigMapTransform(cmap *v1.ConfigMap) error {
-	serviceName := mdbc.GetServerServiceName()
-	configMapName := mdbc.GetServerConfigMapName()
-	statefulSetName := mdbc.GetServerName()
-	labels := mdbc.GetServerLabels()
+	color := mdbc.GetActiveColor()
+	hash := mdbc.GetConfigHash(color)
+	if current := cmap.Annotations[MariaDBClusterConfigAnnotation]; current != "" && mdbc.Status.Phase == PhaseOperational {
+		if hash != current && hash != mdbc.Status.CheckedConfigHash {
+			return nil
+		}
+	}
 
-	cmap.SetName(configMapName)
+	cmap.SetName(mdbc.GetServerConfigMapName())
 	cmap.SetNamespace(mdbc.Namespace)
-	cmap.SetLabels(labels)
+	cmap.SetLabels(mdbc.GetServerLabels())
 	cmap.SetOwnerReferences([]metav1.OwnerReference{
 		*metav1.NewControllerRef(mdbc, schema.GroupVersionKind{
 			Group:   GroupName,
@@ -22,24 +38,61 @@ func (mdbc *MariaDBCluster) ServerConfigMapTransform(cmap *v1.ConfigMap) error {
 			Kind:    "MariaDBCluster",
 		}),
 	})
-	var wsrep []string
-	if mdbc.Status.Phase == PhaseBootstrapFirst || mdbc.Status.Phase == PhaseBootstrapFirstRestart {
-		wsrep = []string{}
-	} else if mdbc.Status.Phase == PhaseBootstrapSecond {
-		wsrep = []string{statefulSetName + "-0." + serviceName}
-	} else if mdbc.Status.Phase == PhaseBootstrapThird {
-		wsrep = []string{statefulSetName + "-0." + serviceName, statefulSetName + "-1." + serviceName}
-	} else {
-		wsrep = []string{statefulSetName + "-0." + serviceName, statefulSetName + "-1." + serviceName, statefulSetName + "-2." + serviceName}
-	}
-	mdbConfig := &MariaDBConfig{WSREPEndpoints: wsrep}
+	var options []string
+	for _, option := range strings.Split(mdbc.GetWSREPProviderOptions(mdbc.GetServerNameForColor(color)+"-0", false), ";") {
+		if !isRuntimeProviderOption(option) {
+			options = append(options, option)
+		}
+	}
+	mdbConfig := &MariaDBConfig{
+		Name:                   mdbc.GetServerNameForColor(color),
+		WSREPEndpoints:         mdbc.GetWSREPEndpointsForColor(color),
+		WSREPProviderOptions:   strings.Join(options, ";"),
+		WSREPSSTMethod:         mdbc.Status.SSTMethod,
+		CharacterSet:           mdbc.Spec.Server.CharacterSet,
+		Collation:              mdbc.Spec.Server.Collation,
+		SQLMode:                mdbc.Spec.Server.SQLMode,
+		TimeZone:               mdbc.Status.TimeZone,
+		PluginLibraries:        mdbc.Spec.Server.GetPluginLibraries(),
+		InstrumentationOptions: mdbc.Spec.Server.GetInstrumentationOptions(),
+	}
+	mdbConfig.InnoDBBufferPoolSizeMB, mdbConfig.InnoDBBufferPoolInstances = mdbc.GetBufferPoolSize()
+	binlog := mdbc.Spec.Server.Binlog
+	mdbConfig.BinlogFormat = binlog.GetFormat()
+	mdbConfig.LogBin = binlog.Enabled
+	mdbConfig.ExpireLogsSeconds, mdbConfig.ExpireLogsDays = binlog.GetExpireLogs()
+	mdbConfig.LogSlaveUpdates = binlog.LogSlaveUpdates
 
 	operatorCnf, err := mdbConfig.Render()
 	if err != nil {
 		return err
 	}
 	cmap.Data = map[string]string{
-		"operator.cnf": operatorCnf,
-		"user.cnf":     mdbc.Spec.ServerConfig}
+		"operator.cnf":    operatorCnf,
+		"user.cnf":        `[mysqld]` + "\n" + mdbc.Spec.ServerConfig,
+		"config.cnf.tmpl": mdbc.Status.ServerConfig,
+	}
+	if cmap.Annotations == nil {
+		cmap.Annotations = make(map[string]string)
+	}
+	cmap.Annotations[MariaDBClusterConfigAnnotation] = hash
+	cmap.Annotations[MariaDBClusterContentAnnotation] = GetConfigMapContentHash(cmap.Data)
+	mdbc.inheritMetadata(&cmap.ObjectMeta)
 	return nil
 }
+
+// GetConfigMapContentHash returns a hash of the data of a ConfigMap, the server
+// ConfigMap carries the one of the content the operator rendered so that edits
+// by anyone else are told apart from its own changes
+func GetConfigMapContentHash(data map[string]string) string {
+	var keys []string
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	sum := sha256.New()
+	for _, key := range keys {
+		sum.Write([]byte(key + "\x00" + data[key] + "\x00"))
+	}
+	return hex.EncodeToString(sum.Sum(nil)[:8])
+}
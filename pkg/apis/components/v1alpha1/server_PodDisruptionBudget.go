@@ -0,0 +1,37 @@
+package v1alpha1
+
+import (
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// ServerPodDisruptionBudgetTransform renders the budget of the pods serving
+// clients, evictions of which are held back while they would take more than
+// one pod away, or a majority of pods with the Quorum budget
+func (mdbc *MariaDBCluster) ServerPodDisruptionBudgetTransform(pdb *policyv1beta1.PodDisruptionBudget) error {
+	labels := mdbc.GetServerLabels()
+	labels[MariaDBClusterNameLabel] = mdbc.Name
+
+	pdb.SetName(mdbc.GetServerName())
+	pdb.SetNamespace(mdbc.Namespace)
+	pdb.SetLabels(labels)
+	pdb.SetOwnerReferences([]metav1.OwnerReference{
+		*metav1.NewControllerRef(mdbc, schema.GroupVersionKind{
+			Group:   GroupName,
+			Version: Version,
+			Kind:    "MariaDBCluster",
+		}),
+	})
+	mdbc.inheritMetadata(&pdb.ObjectMeta)
+	pdb.Spec.Selector = &metav1.LabelSelector{MatchLabels: mdbc.GetServerLabels()}
+	if mdbc.GetDisruptionBudget() == DisruptionBudgetQuorum {
+		quorum := intstr.FromInt(int(mdbc.Spec.Replicas/2 + 1))
+		pdb.Spec.MinAvailable = &quorum
+	} else {
+		one := intstr.FromInt(1)
+		pdb.Spec.MaxUnavailable = &one
+	}
+	return nil
+}
@@ -21,10 +21,13 @@ func (mdbc *MariaDBCluster) ServerRoleTransform(r *rbac.Role) error {
 		}),
 	})
 	r.Rules = nil
+	// pods only get to read and report into their own cluster
 	r.Rules = append(r.Rules, rbac.PolicyRule{
-		APIGroups: []string{"components.dsg.dk"},
-		Resources: []string{"mariadbclusters"},
-		Verbs:     []string{"get", "watch", "list", "patch", "update"},
+		APIGroups:     []string{"components.dsg.dk"},
+		Resources:     []string{"mariadbclusters", "mariadbclusters/status"},
+		ResourceNames: []string{mdbc.Name},
+		Verbs:         []string{"get", "patch", "update"},
 	})
+	mdbc.inheritMetadata(&r.ObjectMeta)
 	return nil
 }
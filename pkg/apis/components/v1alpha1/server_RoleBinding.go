@@ -20,9 +20,10 @@ func (mdbc *MariaDBCluster) ServerRoleBindingTransform(rb *rbac.RoleBinding) err
 			Kind:    "MariaDBCluster",
 		}),
 	})
-	rb.Subjects = append(rb.Subjects, rbac.Subject{Kind: rbac.ServiceAccountKind, Name: mdbc.GetServerName(), Namespace: mdbc.Namespace})
+	rb.Subjects = []rbac.Subject{{Kind: rbac.ServiceAccountKind, Name: mdbc.GetServiceAccountName(), Namespace: mdbc.Namespace}}
 	rb.RoleRef.APIGroup = "rbac.authorization.k8s.io"
 	rb.RoleRef.Kind = "Role"
 	rb.RoleRef.Name = mdbc.GetServerName()
+	mdbc.inheritMetadata(&rb.ObjectMeta)
 	return nil
 }
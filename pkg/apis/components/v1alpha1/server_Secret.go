@@ -0,0 +1,71 @@
+package v1alpha1
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// GeneratedSecretKeys are the keys of Secrets of the operator holding
+// generated passwords, kept as found once set
+var GeneratedSecretKeys = []string{SSTPasswordKey, ReportKeyKey, OperatorPasswordKey, v1.BasicAuthPasswordKey}
+
+// ServerSecretTransform renders credentials shared by server pods, passwords
+// are generated once and kept as found afterwards
+func (mdbc *MariaDBCluster) ServerSecretTransform(secret *v1.Secret) error {
+	return mdbc.secretTransform(secret, mdbc.GetServerSecretName(), v1.SecretTypeOpaque, SSTPasswordKey, ReportKeyKey, OperatorPasswordKey)
+}
+
+// ClientSecretTransform renders the credentials of the client user, the one
+// status.connection points clients at
+func (mdbc *MariaDBCluster) ClientSecretTransform(secret *v1.Secret) error {
+	if err := mdbc.secretTransform(secret, mdbc.GetClientSecretName(), v1.SecretTypeBasicAuth, v1.BasicAuthPasswordKey); err != nil {
+		return err
+	}
+	secret.Data[v1.BasicAuthUsernameKey] = []byte(ClientUser)
+	return nil
+}
+
+// secretTransform renders a Secret of the cluster, generating the passwords
+// of the keys it does not hold yet
+func (mdbc *MariaDBCluster) secretTransform(secret *v1.Secret, name string, secretType v1.SecretType, keys ...string) error {
+	labels := mdbc.GetServerLabels()
+	labels[MariaDBClusterNameLabel] = mdbc.Name
+
+	secret.SetName(name)
+	secret.SetNamespace(mdbc.Namespace)
+	secret.SetLabels(labels)
+	secret.SetOwnerReferences([]metav1.OwnerReference{
+		*metav1.NewControllerRef(mdbc, schema.GroupVersionKind{
+			Group:   GroupName,
+			Version: Version,
+			Kind:    "MariaDBCluster",
+		}),
+	})
+	secret.Type = secretType
+	if secret.Data == nil {
+		secret.Data = make(map[string][]byte)
+	}
+	for _, key := range keys {
+		if len(secret.Data[key]) == 0 {
+			password, err := generatePassword()
+			if err != nil {
+				return err
+			}
+			secret.Data[key] = []byte(password)
+		}
+	}
+	mdbc.inheritMetadata(&secret.ObjectMeta)
+	return nil
+}
+
+func generatePassword() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
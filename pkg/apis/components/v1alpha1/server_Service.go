@@ -8,10 +8,16 @@ import (
 )
 
 func (mdbc *MariaDBCluster) ServerServiceTransform(svc *v1.Service) error {
-	labels := mdbc.GetServerLabels()
+	return mdbc.ServerServiceTransformForColor(svc, mdbc.GetActiveColor())
+}
+
+// ServerServiceTransformForColor renders the headless service galera peers of
+// given color find each other through
+func (mdbc *MariaDBCluster) ServerServiceTransformForColor(svc *v1.Service, color string) error {
+	labels := mdbc.GetServerLabelsForColor(color)
 	labels[MariaDBClusterNameLabel] = mdbc.Name
 
-	svc.SetName(mdbc.GetServerServiceName())
+	svc.SetName(mdbc.GetServerServiceNameForColor(color))
 	svc.SetNamespace(mdbc.Namespace)
 	svc.SetLabels(labels)
 	svc.SetOwnerReferences([]metav1.OwnerReference{
@@ -41,6 +47,19 @@ func (mdbc *MariaDBCluster) ServerServiceTransform(svc *v1.Service) error {
 			Port:       4567,
 			TargetPort: intstr.FromInt(4567),
 		},
+		v1.ServicePort{
+			Name:       "ist",
+			Protocol:   v1.ProtocolTCP,
+			Port:       mdbc.Spec.Galera.Ports.GetIST(),
+			TargetPort: intstr.FromInt(int(mdbc.Spec.Galera.Ports.GetIST())),
+		},
+		v1.ServicePort{
+			Name:       "sst",
+			Protocol:   v1.ProtocolTCP,
+			Port:       mdbc.Spec.Galera.Ports.GetSST(),
+			TargetPort: intstr.FromInt(int(mdbc.Spec.Galera.Ports.GetSST())),
+		},
 	}
+	mdbc.inheritMetadata(&svc.ObjectMeta)
 	return nil
 }
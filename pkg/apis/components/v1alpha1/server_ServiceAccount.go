@@ -20,5 +20,6 @@ func (mdbc *MariaDBCluster) ServerServiceAccountTransform(sa *v1.ServiceAccount)
 			Kind:    "MariaDBCluster",
 		}),
 	})
+	mdbc.inheritMetadata(&sa.ObjectMeta)
 	return nil
 }
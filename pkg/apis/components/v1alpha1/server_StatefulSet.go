@@ -1,6 +1,8 @@
 package v1alpha1
 
 import (
+	"fmt"
+
 	apps "k8s.io/api/apps/v1"
 	"k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -9,10 +11,37 @@ import (
 
 func (cluster *MariaDBCluster) StatefulSetTransform(sset *apps.StatefulSet) error {
 	pvars := GetPhaseVars(cluster)
-	ssetName := cluster.GetServerName()
-	serviceAccountName := cluster.GetServerName()
-	serviceName := cluster.GetServerServiceName()
-	labels := cluster.GetServerLabels()
+	// a running cluster only rolls to a configuration mysqld accepted, the
+	// StatefulSet is left as it is until then
+	if current := sset.Spec.Template.ObjectMeta.Annotations[MariaDBClusterConfigAnnotation]; current != "" && cluster.Status.Phase == PhaseOperational {
+		if hash := cluster.GetConfigHash(cluster.GetActiveColor()); hash != current && hash != cluster.Status.CheckedConfigHash {
+			return nil
+		}
+	}
+	return cluster.statefulSetTransform(sset, cluster.GetActiveColor(), pvars.Replicas, cluster.GetServerImage())
+}
+
+// StandbyStatefulSetTransform renders the parallel server StatefulSet of a
+// blue/green upgrade, running the target version. It starts with a single pod
+// that bootstraps a fresh cluster and gets the data loaded, it grows to full
+// size once in sync with the serving cluster.
+func (cluster *MariaDBCluster) StandbyStatefulSetTransform(sset *apps.StatefulSet) error {
+	bg := cluster.Status.BlueGreen
+	if bg == nil {
+		return fmt.Errorf("no blue/green upgrade in progress")
+	}
+	replicas := cluster.Spec.Replicas
+	if bg.Stage == BlueGreenStageProvisioning || bg.Stage == BlueGreenStageSyncing {
+		replicas = int32(1)
+	}
+	return cluster.statefulSetTransform(sset, bg.Color, replicas, cluster.GetServerImageForVersion(bg.Version))
+}
+
+func (cluster *MariaDBCluster) statefulSetTransform(sset *apps.StatefulSet, color string, replicas int32, image string) error {
+	ssetName := cluster.GetServerNameForColor(color)
+	serviceAccountName := cluster.GetServiceAccountName()
+	serviceName := cluster.GetServerServiceNameForColor(color)
+	labels := cluster.GetServerLabelsForColor(color)
 
 	sset.SetName(ssetName)
 	sset.SetNamespace(cluster.Namespace)
@@ -25,52 +54,110 @@ func (cluster *MariaDBCluster) StatefulSetTransform(sset *apps.StatefulSet) erro
 		}),
 	})
 	sset.Spec.ServiceName = serviceName
-	sset.Spec.Replicas = &pvars.Replicas
+	sset.Spec.Replicas = &replicas
 	sset.Spec.Selector = &metav1.LabelSelector{MatchLabels: labels}
-	sset.Spec.UpdateStrategy = apps.StatefulSetUpdateStrategy{Type: "RollingUpdate"}
+	sset.Spec.UpdateStrategy = apps.StatefulSetUpdateStrategy{Type: apps.RollingUpdateStatefulSetStrategyType}
+	// bootstrap and recovery rely on pods being replaced as soon as their
+	// template changes, other strategies only apply to a running cluster
+	if cluster.Status.Phase == PhaseOperational && cluster.GetUpdateStrategy() != UpdateStrategyRollingUpdate {
+		sset.Spec.UpdateStrategy = apps.StatefulSetUpdateStrategy{Type: apps.OnDeleteStatefulSetStrategyType}
+	}
 	sset.Spec.PodManagementPolicy = apps.ParallelPodManagement
 	sset.Spec.Template.ObjectMeta.Labels = labels
+	// gcache.size is read on startup only, changing it has to restart pods
+	if size := cluster.GetGCacheSizeMB(); size > 0 {
+		if sset.Spec.Template.ObjectMeta.Annotations == nil {
+			sset.Spec.Template.ObjectMeta.Annotations = make(map[string]string)
+		}
+		sset.Spec.Template.ObjectMeta.Annotations[MariaDBClusterGCacheAnnotation] = fmt.Sprintf("%dM", size)
+	}
+	if method := cluster.Status.SSTMethod; method != "" {
+		if sset.Spec.Template.ObjectMeta.Annotations == nil {
+			sset.Spec.Template.ObjectMeta.Annotations = make(map[string]string)
+		}
+		sset.Spec.Template.ObjectMeta.Annotations[MariaDBClusterSSTAnnotation] = method
+	}
+	// changed configuration rolls pods like any other template change, dynamic
+	// variables are left out as the agent sets them on the running server
+	if sset.Spec.Template.ObjectMeta.Annotations == nil {
+		sset.Spec.Template.ObjectMeta.Annotations = make(map[string]string)
+	}
+	sset.Spec.Template.ObjectMeta.Annotations[MariaDBClusterConfigAnnotation] = cluster.GetConfigHash(color)
 	sset.Spec.Template.Spec.ServiceAccountName = serviceAccountName
+	sset.Spec.Template.Spec.Affinity = cluster.GetAffinityForColor(color)
+	sset.Spec.Template.Spec.Tolerations = cluster.Spec.Tolerations
+	sset.Spec.Template.Spec.NodeSelector = cluster.Spec.NodeSelector
+	sset.Spec.Template.Spec.PriorityClassName = cluster.Spec.PriorityClassName
+	sset.Spec.Template.Spec.HostAliases = cluster.Spec.HostAliases
+	sset.Spec.Template.Spec.DNSPolicy = cluster.Spec.DNSPolicy
+	sset.Spec.Template.Spec.DNSConfig = cluster.Spec.DNSConfig
+	gracePeriod := cluster.Spec.Shutdown.GetTerminationGracePeriodSeconds()
+	sset.Spec.Template.Spec.TerminationGracePeriodSeconds = &gracePeriod
 	// InitContainers
 	if len(sset.Spec.Template.Spec.InitContainers) < 1 {
 		sset.Spec.Template.Spec.InitContainers = append(sset.Spec.Template.Spec.InitContainers, v1.Container{})
 	}
 	sset.Spec.Template.Spec.InitContainers[0].Name = "init"
-	sset.Spec.Template.Spec.InitContainers[0].Image = "goblain/mdbc:dev"
-	sset.Spec.Template.Spec.InitContainers[0].ImagePullPolicy = v1.PullAlways
+	sset.Spec.Template.Spec.InitContainers[0].Image = cluster.GetInitImage()
+	sset.Spec.Template.Spec.InitContainers[0].ImagePullPolicy = cluster.GetImagePullPolicy(v1.PullAlways)
 	sset.Spec.Template.Spec.InitContainers[0].Command = []string{"/mdbc"}
 	sset.Spec.Template.Spec.InitContainers[0].Args = []string{"init"}
 	sset.Spec.Template.Spec.InitContainers[0].Env = []v1.EnvVar{
 		v1.EnvVar{Name: "MARIADBCLUSTER_NAME", Value: cluster.Name},
 		v1.EnvVar{Name: "MARIADBCLUSTER_NAMESPACE", Value: cluster.Namespace},
+		cluster.serverSecretEnvVar("MARIADBCLUSTER_SST_PASSWORD", SSTPasswordKey),
+		cluster.serverSecretEnvVar("MARIADBCLUSTER_REPORT_KEY", ReportKeyKey),
+	}
+	if color != ColorBlue {
+		sset.Spec.Template.Spec.InitContainers[0].Env = append(sset.Spec.Template.Spec.InitContainers[0].Env,
+			v1.EnvVar{Name: "MARIADBCLUSTER_COLOR", Value: color})
 	}
+	sset.Spec.Template.Spec.InitContainers[0].Resources = cluster.GetInitResources()
 	sset.Spec.Template.Spec.InitContainers[0].VolumeMounts = []v1.VolumeMount{
 		v1.VolumeMount{Name: "config", MountPath: "/etc/mysql/conf.d"},
 		v1.VolumeMount{Name: "data", MountPath: "/var/lib/mysql"},
 	}
+	// the kernel tuning runs first when enabled, then init containers of the
+	// user in the order listed
+	var initContainers []v1.Container
+	if cluster.Spec.KernelTuning.Mode != "" {
+		initContainers = append(initContainers, cluster.kernelTuningContainer())
+	}
+	initContainers = append(initContainers, cluster.Spec.InitContainers...)
+	sset.Spec.Template.Spec.InitContainers = append(initContainers, sset.Spec.Template.Spec.InitContainers[0])
 
 	// Containers
 	if len(sset.Spec.Template.Spec.Containers) < 1 {
 		sset.Spec.Template.Spec.Containers = append(sset.Spec.Template.Spec.Containers, v1.Container{})
 	}
-	switch cluster.Status.Phase {
-	case PhaseBootstrapFirst:
+	switch {
+	case cluster.Status.Phase == PhaseBootstrapFirst && color == cluster.GetActiveColor():
 		sset.Spec.Template.Spec.Containers[0].Args = []string{"--wsrep-new-cluster"}
 	default:
 		sset.Spec.Template.Spec.Containers[0].Command = nil
 		sset.Spec.Template.Spec.Containers[0].Args = nil
 	}
 	sset.Spec.Template.Spec.Containers[0].Name = "mariadb"
-	sset.Spec.Template.Spec.Containers[0].Image = "mariadb:10.2"
+	sset.Spec.Template.Spec.Containers[0].Image = image
 	// sset.Spec.Template.Spec.Containers[0].ImagePullPolicy = v1.PullIfNotPresent
-	sset.Spec.Template.Spec.Containers[0].ImagePullPolicy = v1.PullAlways
+	sset.Spec.Template.Spec.Containers[0].ImagePullPolicy = cluster.GetImagePullPolicy(v1.PullAlways)
+	sset.Spec.Template.Spec.Containers[0].Ports = []v1.ContainerPort{
+		v1.ContainerPort{Name: "mysql", ContainerPort: DefaultMySQLPort, Protocol: v1.ProtocolTCP},
+		v1.ContainerPort{Name: "wsrep", ContainerPort: DefaultWSREPPort, Protocol: v1.ProtocolTCP},
+		v1.ContainerPort{Name: "ist", ContainerPort: cluster.Spec.Galera.Ports.GetIST(), Protocol: v1.ProtocolTCP},
+		v1.ContainerPort{Name: "sst", ContainerPort: cluster.Spec.Galera.Ports.GetSST(), Protocol: v1.ProtocolTCP},
+	}
+	sset.Spec.Template.Spec.Containers[0].Resources = cluster.GetServerResources()
 	sset.Spec.Template.Spec.Containers[0].Env = []v1.EnvVar{
 		v1.EnvVar{Name: "MYSQL_ALLOW_EMPTY_PASSWORD", Value: "yes"},
 		v1.EnvVar{Name: "MYSQL_INITDB_SKIP_TZINFO", Value: "yes"},
 	}
+	sset.Spec.Template.Spec.Containers[0].Env = append(sset.Spec.Template.Spec.Containers[0].Env, cluster.Spec.Env...)
+	sset.Spec.Template.Spec.Containers[0].EnvFrom = cluster.Spec.EnvFrom
 	sset.Spec.Template.Spec.Containers[0].VolumeMounts = []v1.VolumeMount{
 		v1.VolumeMount{Name: "config", MountPath: "/etc/mysql/conf.d/operator.cnf", SubPath: "operator.cnf"},
 		v1.VolumeMount{Name: "config", MountPath: "/etc/mysql/conf.d/user.cnf", SubPath: "user.cnf"},
+		v1.VolumeMount{Name: "config", MountPath: "/etc/mysql/conf.d/config.cnf", SubPath: "config.cnf"},
 		v1.VolumeMount{Name: "data", MountPath: "/var/lib/mysql"},
 	}
 
@@ -80,18 +167,25 @@ func (cluster *MariaDBCluster) StatefulSetTransform(sset *apps.StatefulSet) erro
 	sset.Spec.Template.Spec.Containers[0].LivenessProbe.Handler = v1.Handler{
 		Exec: &v1.ExecAction{Command: []string{"mysqladmin", "ping"}},
 	}
-	sset.Spec.Template.Spec.Containers[0].LivenessProbe.InitialDelaySeconds = 30
-	sset.Spec.Template.Spec.Containers[0].LivenessProbe.PeriodSeconds = 5
-	sset.Spec.Template.Spec.Containers[0].LivenessProbe.TimeoutSeconds = 2
+	liveness := cluster.Spec.Probes.GetLiveness()
+	sset.Spec.Template.Spec.Containers[0].LivenessProbe.InitialDelaySeconds = liveness.InitialDelaySeconds
+	sset.Spec.Template.Spec.Containers[0].LivenessProbe.PeriodSeconds = liveness.PeriodSeconds
+	sset.Spec.Template.Spec.Containers[0].LivenessProbe.TimeoutSeconds = liveness.TimeoutSeconds
+	sset.Spec.Template.Spec.Containers[0].LivenessProbe.FailureThreshold = liveness.FailureThreshold
 	if sset.Spec.Template.Spec.Containers[0].ReadinessProbe == nil {
 		sset.Spec.Template.Spec.Containers[0].ReadinessProbe = &v1.Probe{}
 	}
 	sset.Spec.Template.Spec.Containers[0].ReadinessProbe.Handler = v1.Handler{
 		Exec: &v1.ExecAction{Command: []string{"bash", "-c", "mysql --skip-column-names -e \"select variable_value from information_schema.global_status where variable_name='wsrep_local_state_comment'\" -B | grep -q Synced"}},
 	}
-	sset.Spec.Template.Spec.Containers[0].ReadinessProbe.InitialDelaySeconds = 10
-	sset.Spec.Template.Spec.Containers[0].ReadinessProbe.PeriodSeconds = 2
-	sset.Spec.Template.Spec.Containers[0].ReadinessProbe.TimeoutSeconds = 2
+	sset.Spec.Template.Spec.Containers[0].Lifecycle = &v1.Lifecycle{
+		PreStop: &v1.Handler{Exec: &v1.ExecAction{Command: []string{"bash", "-c", cluster.serverShutdownScript()}}},
+	}
+	readiness := cluster.Spec.Probes.GetReadiness()
+	sset.Spec.Template.Spec.Containers[0].ReadinessProbe.InitialDelaySeconds = readiness.InitialDelaySeconds
+	sset.Spec.Template.Spec.Containers[0].ReadinessProbe.PeriodSeconds = readiness.PeriodSeconds
+	sset.Spec.Template.Spec.Containers[0].ReadinessProbe.TimeoutSeconds = readiness.TimeoutSeconds
+	sset.Spec.Template.Spec.Containers[0].ReadinessProbe.FailureThreshold = readiness.FailureThreshold
 	sset.Spec.Template.Spec.Volumes = cluster.statefulSetVolumesTransform(sset.Spec.Template.Spec.Volumes)
 	sset.Spec.VolumeClaimTemplates = cluster.statefulSetVolumeClaimTemplatesTransform(sset.Spec.VolumeClaimTemplates)
 
@@ -101,25 +195,166 @@ func (cluster *MariaDBCluster) StatefulSetTransform(sset *apps.StatefulSet) erro
 	}
 	sset.Spec.Template.Spec.Containers[1].Command = []string{"/bin/sleep", "1d"}
 	sset.Spec.Template.Spec.Containers[1].Name = "debug"
-	sset.Spec.Template.Spec.Containers[1].Image = "mariadb:10.2"
+	sset.Spec.Template.Spec.Containers[1].Image = image
 	// sset.Spec.Template.Spec.Containers[1].ImagePullPolicy = v1.PullIfNotPresent
-	sset.Spec.Template.Spec.Containers[1].ImagePullPolicy = v1.PullAlways
+	sset.Spec.Template.Spec.Containers[1].ImagePullPolicy = cluster.GetImagePullPolicy(v1.PullAlways)
 
 	sset.Spec.Template.Spec.Containers[1].VolumeMounts = []v1.VolumeMount{
 		v1.VolumeMount{Name: "config", MountPath: "/etc/mysql/conf.d/operator.cnf", SubPath: "operator.cnf"},
 		v1.VolumeMount{Name: "config", MountPath: "/etc/mysql/conf.d/user.cnf", SubPath: "user.cnf"},
+		v1.VolumeMount{Name: "config", MountPath: "/etc/mysql/conf.d/config.cnf", SubPath: "config.cnf"},
 		v1.VolumeMount{Name: "data", MountPath: "/var/lib/mysql"},
 	}
 
+	// Agent container reporting live galera state
+	if len(sset.Spec.Template.Spec.Containers) < 3 {
+		sset.Spec.Template.Spec.Containers = append(sset.Spec.Template.Spec.Containers, v1.Container{})
+	}
+	sset.Spec.Template.Spec.Containers[2].Name = "agent"
+	sset.Spec.Template.Spec.Containers[2].Image = cluster.GetAgentImage()
+	sset.Spec.Template.Spec.Containers[2].ImagePullPolicy = cluster.GetImagePullPolicy(v1.PullAlways)
+	sset.Spec.Template.Spec.Containers[2].Command = []string{"/mdbc"}
+	sset.Spec.Template.Spec.Containers[2].Args = []string{"agent"}
+	sset.Spec.Template.Spec.Containers[2].Resources = cluster.GetAgentResources()
+	sset.Spec.Template.Spec.Containers[2].Env = []v1.EnvVar{
+		v1.EnvVar{Name: "MARIADBCLUSTER_NAME", Value: cluster.Name},
+		v1.EnvVar{Name: "MARIADBCLUSTER_NAMESPACE", Value: cluster.Namespace},
+		cluster.serverSecretEnvVar("MARIADBCLUSTER_SST_PASSWORD", SSTPasswordKey),
+		cluster.serverSecretEnvVar("MARIADBCLUSTER_REPORT_KEY", ReportKeyKey),
+		cluster.serverSecretEnvVar("MARIADBCLUSTER_OPERATOR_PASSWORD", OperatorPasswordKey),
+		cluster.clientSecretEnvVar("MARIADBCLUSTER_CLIENT_PASSWORD"),
+	}
+	if color != ColorBlue {
+		sset.Spec.Template.Spec.Containers[2].Env = append(sset.Spec.Template.Spec.Containers[2].Env,
+			v1.EnvVar{Name: "MARIADBCLUSTER_COLOR", Value: color})
+	}
+	sset.Spec.Template.Spec.Containers[2].Env = append(sset.Spec.Template.Spec.Containers[2].Env, cluster.Spec.Env...)
+	sset.Spec.Template.Spec.Containers[2].EnvFrom = cluster.Spec.EnvFrom
+	// state transfers are followed on the data directory, spec.config
+	// changes are compared with the config the server started with
+	sset.Spec.Template.Spec.Containers[2].VolumeMounts = []v1.VolumeMount{
+		v1.VolumeMount{Name: "config", MountPath: "/etc/mysql/conf.d", ReadOnly: true},
+		v1.VolumeMount{Name: "data", MountPath: "/var/lib/mysql", ReadOnly: true},
+	}
+
+	// Sidecars of the user
+	sset.Spec.Template.Spec.Containers = append(sset.Spec.Template.Spec.Containers[:3], cluster.Spec.Sidecars...)
+
 	sset.Spec.Template.Spec.Volumes = cluster.statefulSetVolumesTransform(sset.Spec.Template.Spec.Volumes)
 	sset.Spec.VolumeClaimTemplates = cluster.statefulSetVolumeClaimTemplatesTransform(sset.Spec.VolumeClaimTemplates)
 
+	cluster.mountVolumes(&sset.Spec.Template.Spec)
+
+	cluster.inheritMetadata(&sset.ObjectMeta)
+	cluster.inheritMetadata(&sset.Spec.Template.ObjectMeta)
+	sset.Spec.Template.ObjectMeta.Labels = mergeMetadata(sset.Spec.Template.ObjectMeta.Labels, cluster.Spec.PodMetadata.Labels)
+	sset.Spec.Template.ObjectMeta.Annotations = mergeMetadata(sset.Spec.Template.ObjectMeta.Annotations, cluster.Spec.PodMetadata.Annotations)
+	cluster.confinePod(&sset.Spec.Template)
 	return nil
 }
 
+// mountVolumes adds spec.volumeMounts to the containers of the operator they
+// name, after the mounts of the operator
+func (mdbc *MariaDBCluster) mountVolumes(spec *v1.PodSpec) {
+	for _, mount := range mdbc.Spec.VolumeMounts {
+		for _, name := range mount.Containers {
+			for _, containers := range [][]v1.Container{spec.InitContainers, spec.Containers} {
+				for i := range containers {
+					if containers[i].Name == name {
+						containers[i].VolumeMounts = append(containers[i].VolumeMounts, mount.VolumeMount)
+					}
+				}
+			}
+		}
+	}
+}
+
+// kernelTuningContainer sets or checks the sysctls of spec.kernelTuning on the
+// node, they are its arguments so that changing them rolls the pods. Only
+// Apply runs privileged, /proc/sys is read-only to other containers.
+func (mdbc *MariaDBCluster) kernelTuningContainer() v1.Container {
+	privileged := mdbc.Spec.KernelTuning.Mode == KernelTuningApply
+	return v1.Container{
+		Name:            "tune",
+		Image:           mdbc.GetInitImage(),
+		ImagePullPolicy: mdbc.GetImagePullPolicy(v1.PullAlways),
+		Command:         []string{"/mdbc"},
+		Args:            append([]string{"tune"}, mdbc.Spec.KernelTuning.GetSysctls()...),
+		Env: []v1.EnvVar{
+			v1.EnvVar{Name: "MARIADBCLUSTER_NAME", Value: mdbc.Name},
+			v1.EnvVar{Name: "MARIADBCLUSTER_NAMESPACE", Value: mdbc.Namespace},
+			v1.EnvVar{Name: "MARIADBCLUSTER_KERNEL_TUNING", Value: mdbc.Spec.KernelTuning.Mode},
+			v1.EnvVar{Name: "MARIADBCLUSTER_NODE_NAME", ValueFrom: &v1.EnvVarSource{
+				FieldRef: &v1.ObjectFieldSelector{FieldPath: "spec.nodeName"},
+			}},
+			mdbc.serverSecretEnvVar("MARIADBCLUSTER_REPORT_KEY", ReportKeyKey),
+		},
+		Resources:       mdbc.GetInitResources(),
+		SecurityContext: &v1.SecurityContext{Privileged: &privileged},
+	}
+}
+
+// serverShutdownScript waits for the transactions in progress, up to the
+// timeout or until the server no longer answers, then shuts the server down
+// with the innodb_fast_shutdown of spec.shutdown. mysqladmin returns once the
+// server stopped, so the container exits cleanly before it is sent SIGTERM.
+func (mdbc *MariaDBCluster) serverShutdownScript() string {
+	return fmt.Sprintf("for i in $(seq %d); do "+
+		"trx=$(mysql --skip-column-names -B -e 'select count(*) from information_schema.innodb_trx') || break; "+
+		"[ \"$trx\" = 0 ] && break; sleep 1; done; "+
+		"mysql -e 'set global innodb_fast_shutdown=%d'; mysqladmin shutdown",
+		mdbc.Spec.Shutdown.GetTransactionTimeoutSeconds(), mdbc.Spec.Shutdown.GetInnoDBFastShutdown())
+}
+
+// serverSecretEnvVar exposes a key of the server Secret to the initializer,
+// the agent and Jobs: the passwords of the SST and operator users, rendered
+// into wsrep_sst_auth and maintained by the agent, and the key reports are
+// signed with
+func (mdbc *MariaDBCluster) serverSecretEnvVar(name, key string) v1.EnvVar {
+	return v1.EnvVar{
+		Name: name,
+		ValueFrom: &v1.EnvVarSource{
+			SecretKeyRef: &v1.SecretKeySelector{
+				LocalObjectReference: v1.LocalObjectReference{Name: mdbc.GetServerSecretName()},
+				Key:                  key,
+			},
+		},
+	}
+}
+
+// clientSecretEnvVar exposes the client user password to the agent creating
+// the user
+func (mdbc *MariaDBCluster) clientSecretEnvVar(name string) v1.EnvVar {
+	return v1.EnvVar{
+		Name: name,
+		ValueFrom: &v1.EnvVarSource{
+			SecretKeyRef: &v1.SecretKeySelector{
+				LocalObjectReference: v1.LocalObjectReference{Name: mdbc.GetClientSecretName()},
+				Key:                  v1.BasicAuthPasswordKey,
+			},
+		},
+	}
+}
+
+// statefulSetVolumeClaimTemplatesTransform renders the data claim template,
+// owned by the cluster when its data is deleted along so that claims created
+// from it are too. Retained claims are never owned, as the garbage collector
+// would delete them whenever the finalizer is bypassed. Templates of existing
+// StatefulSets can not change, their claims get their owner and the inherited
+// metadata through reconcileDataVolumeOwners instead.
 func (mdbc *MariaDBCluster) statefulSetVolumeClaimTemplatesTransform(current []v1.PersistentVolumeClaim) []v1.PersistentVolumeClaim {
 	if len(current) != 1 {
 		current = make([]v1.PersistentVolumeClaim, 1)
+		if mdbc.GetDataRetentionPolicy() == RetentionPolicyDelete {
+			current[0].SetOwnerReferences([]metav1.OwnerReference{
+				*metav1.NewControllerRef(mdbc, schema.GroupVersionKind{
+					Group:   GroupName,
+					Version: Version,
+					Kind:    "MariaDBCluster",
+				}),
+			})
+		}
+		mdbc.inheritMetadata(&current[0].ObjectMeta)
 	}
 	expectedSpec := mdbc.Spec.Storages.Data.GetPersistentVolumeClaimSpecWithMode(v1.ReadWriteOnce)
 	current[0].Name = "data"
@@ -135,5 +370,5 @@ func (mdbc *MariaDBCluster) statefulSetVolumesTransform(current []v1.Volume) []v
 	}
 	current[0].VolumeSource = v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{}}
 	current[0].Name = "config"
-	return current
+	return append(current[:1], mdbc.Spec.Volumes...)
 }
@@ -0,0 +1,634 @@
+package v1alpha1
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// keys rendered into operator.cnf, spec.config may not set them
+var operatorManagedKeys = []string{
+	"wsrep_on",
+	"wsrep_provider",
+	"wsrep_provider_options",
+	"wsrep_cluster_name",
+	"wsrep_cluster_address",
+	"wsrep_sst_donor",
+	"wsrep_sst_method",
+	"wsrep_sst_auth",
+	"binlog_format",
+	"default_storage_engine",
+	"innodb_autoinc_lock_mode",
+}
+
+// sql_mode values MariaDB knows, combined ones included
+var sqlModes = map[string]bool{
+	"ALLOW_INVALID_DATES":        true,
+	"ANSI":                       true,
+	"ANSI_QUOTES":                true,
+	"DB2":                        true,
+	"EMPTY_STRING_IS_NULL":       true,
+	"ERROR_FOR_DIVISION_BY_ZERO": true,
+	"HIGH_NOT_PRECEDENCE":        true,
+	"IGNORE_BAD_TABLE_OPTIONS":   true,
+	"IGNORE_SPACE":               true,
+	"MAXDB":                      true,
+	"MSSQL":                      true,
+	"MYSQL323":                   true,
+	"MYSQL40":                    true,
+	"NO_AUTO_CREATE_USER":        true,
+	"NO_AUTO_VALUE_ON_ZERO":      true,
+	"NO_BACKSLASH_ESCAPES":       true,
+	"NO_DIR_IN_CREATE":           true,
+	"NO_ENGINE_SUBSTITUTION":     true,
+	"NO_FIELD_OPTIONS":           true,
+	"NO_KEY_OPTIONS":             true,
+	"NO_TABLE_OPTIONS":           true,
+	"NO_UNSIGNED_SUBTRACTION":    true,
+	"NO_ZERO_DATE":               true,
+	"NO_ZERO_IN_DATE":            true,
+	"ONLY_FULL_GROUP_BY":         true,
+	"ORACLE":                     true,
+	"PAD_CHAR_TO_FULL_LENGTH":    true,
+	"PIPES_AS_CONCAT":            true,
+	"POSTGRESQL":                 true,
+	"REAL_AS_FLOAT":              true,
+	"SIMULTANEOUS_ASSIGNMENT":    true,
+	"STRICT_ALL_TABLES":          true,
+	"STRICT_TRANS_TABLES":        true,
+	"TIME_ROUND_FRACTIONAL":      true,
+	"TRADITIONAL":                true,
+}
+
+// performance_schema options rendered for each instrumentation profile
+var instrumentationProfiles = map[string][]string{
+	InstrumentationOff: {
+		"performance_schema=OFF",
+	},
+	InstrumentationMinimal: {
+		"performance_schema=ON",
+		"performance_schema_instrument=%=OFF",
+		"performance_schema_instrument=statement/%=ON",
+		"performance_schema_consumer_events_statements_current=OFF",
+		"performance_schema_consumer_events_statements_history=OFF",
+		"performance_schema_consumer_events_statements_history_long=OFF",
+		"performance_schema_consumer_statements_digest=ON",
+		"performance_schema_digests_size=1000",
+	},
+	InstrumentationFull: {
+		"performance_schema=ON",
+		"performance_schema_instrument=%=ON",
+		"performance_schema_consumer_events_stages_current=ON",
+		"performance_schema_consumer_events_stages_history=ON",
+		"performance_schema_consumer_events_stages_history_long=ON",
+		"performance_schema_consumer_events_statements_current=ON",
+		"performance_schema_consumer_events_statements_history=ON",
+		"performance_schema_consumer_events_statements_history_long=ON",
+		"performance_schema_consumer_events_waits_current=ON",
+		"performance_schema_consumer_events_waits_history=ON",
+		"performance_schema_consumer_events_waits_history_long=ON",
+		"performance_schema_consumer_statements_digest=ON",
+	},
+}
+
+// GetInstrumentationOptions returns the performance_schema options of the
+// Instrumentation profile, none when it is not set
+func (s *ServerSettings) GetInstrumentationOptions() []string {
+	return instrumentationProfiles[s.Instrumentation]
+}
+
+// binlog_format values, galera only replicates ROW reliably
+var binlogFormats = map[string]bool{"ROW": true, "MIXED": true, "STATEMENT": true}
+
+const (
+	// server_id is 32 bits
+	maxServerID int64 = 1<<32 - 1
+	// pods of the green color get ids this far above those of blue, so that
+	// a standby cluster does not skip binary log events of the serving one
+	// while replicating from it
+	serverIDColorOffset int64 = 1000
+)
+
+// GetFormat returns binlog_format, ROW unless set
+func (b *BinlogSettings) GetFormat() string {
+	if b.Format == "" {
+		return "ROW"
+	}
+	return strings.ToUpper(b.Format)
+}
+
+// GetExpireLogs returns the binary log retention in seconds and in whole days
+// rounded up, zero when it is left to MariaDB
+func (b *BinlogSettings) GetExpireLogs() (seconds, days int64) {
+	if b.ExpireLogs == nil || b.ExpireLogs.Duration <= 0 {
+		return 0, 0
+	}
+	seconds = int64(b.ExpireLogs.Duration.Round(time.Second) / time.Second)
+	return seconds, (seconds + 86399) / 86400
+}
+
+// GetServerID returns server_id of a pod of color, zero when it is left to
+// MariaDB
+func (b *BinlogSettings) GetServerID(pod, color string) int64 {
+	base := int64(1)
+	if b.ServerIDBase != nil {
+		base = *b.ServerIDBase
+	} else if !b.Enabled {
+		return 0
+	}
+	var ordinal int64
+	if i := strings.LastIndex(pod, "-"); i >= 0 {
+		ordinal, _ = strconv.ParseInt(pod[i+1:], 10, 64)
+	}
+	if color == ColorGreen {
+		base += serverIDColorOffset
+	}
+	return base + ordinal
+}
+
+func (b *BinlogSettings) Validate(replicas int32) error {
+	if !binlogFormats[b.GetFormat()] {
+		return fmt.Errorf("server binlog format %q is not one of ROW, MIXED or STATEMENT", b.Format)
+	}
+	if b.ExpireLogs != nil && b.ExpireLogs.Duration < time.Second {
+		return fmt.Errorf("server binlog expireLogs %s is below a second", b.ExpireLogs.Duration)
+	}
+	if base := b.ServerIDBase; base != nil && (*base < 1 || *base+serverIDColorOffset+int64(replicas) > maxServerID) {
+		return fmt.Errorf("server binlog serverIDBase %d leaves no valid server_id for %d pods", *base, replicas)
+	}
+	return nil
+}
+
+// plugins known by a short name, with the name the server lists them under
+// and their library
+var knownPlugins = map[string]ServerPlugin{
+	"audit":    ServerPlugin{Name: "SERVER_AUDIT", Library: "server_audit"},
+	"cracklib": ServerPlugin{Name: "cracklib_password_check", Library: "cracklib_password_check"},
+	"spider":   ServerPlugin{Name: "SPIDER", Library: "ha_spider"},
+	"connect":  ServerPlugin{Name: "CONNECT", Library: "ha_connect"},
+}
+
+var (
+	pluginNameRegexp  = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+	charsetNameRegexp = regexp.MustCompile(`^[a-z0-9_]+$`)
+	timeZoneRegexp    = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_+-]*(/[A-Za-z0-9_+-]+)*$`)
+	timeOffsetRegexp  = regexp.MustCompile(`^[+-][0-9]{1,2}:[0-9]{2}$`)
+)
+
+// Validate checks the names of the character set and collation, and that
+// sql_mode only lists modes MariaDB knows
+func (s *ServerSettings) Validate() error {
+	if s.CharacterSet != "" && !charsetNameRegexp.MatchString(s.CharacterSet) {
+		return fmt.Errorf("server characterSet %q is not a character set name", s.CharacterSet)
+	}
+	if s.Collation != "" && !charsetNameRegexp.MatchString(s.Collation) {
+		return fmt.Errorf("server collation %q is not a collation name", s.Collation)
+	}
+	if s.CharacterSet != "" && s.Collation != "" && !strings.HasPrefix(s.Collation, s.CharacterSet+"_") {
+		return fmt.Errorf("server collation %s is not one of character set %s", s.Collation, s.CharacterSet)
+	}
+	if s.TimeZone != "" && !timeZoneRegexp.MatchString(s.TimeZone) && !timeOffsetRegexp.MatchString(s.TimeZone) {
+		return fmt.Errorf("server timeZone %q is neither an offset nor a time zone name", s.TimeZone)
+	}
+	if _, ok := instrumentationProfiles[s.Instrumentation]; s.Instrumentation != "" && !ok {
+		return fmt.Errorf("server instrumentation %q is not one of %s, %s or %s", s.Instrumentation,
+			InstrumentationOff, InstrumentationMinimal, InstrumentationFull)
+	}
+	seen := make(map[string]bool)
+	for _, plugin := range s.GetPlugins() {
+		if plugin.Library == "" {
+			return fmt.Errorf("server plugin %q is not known, its library has to be set", plugin.Name)
+		}
+		if !pluginNameRegexp.MatchString(plugin.Name) || !pluginNameRegexp.MatchString(strings.TrimSuffix(plugin.Library, ".so")) {
+			return fmt.Errorf("server plugin %q of library %q is not a plugin name", plugin.Name, plugin.Library)
+		}
+		if seen[strings.ToLower(plugin.Name)] {
+			return fmt.Errorf("server plugin %s listed twice", plugin.Name)
+		}
+		seen[strings.ToLower(plugin.Name)] = true
+	}
+	if s.SQLMode != nil && *s.SQLMode != "" {
+		for _, mode := range strings.Split(*s.SQLMode, ",") {
+			if !sqlModes[strings.ToUpper(strings.TrimSpace(mode))] {
+				return fmt.Errorf("server sqlMode %q is not a known sql mode", mode)
+			}
+		}
+	}
+	return nil
+}
+
+// GetPlugins returns Plugins with known ones resolved to the name the server
+// lists them under and their library
+func (s *ServerSettings) GetPlugins() []ServerPlugin {
+	var plugins []ServerPlugin
+	for _, plugin := range s.Plugins {
+		if known, ok := knownPlugins[strings.ToLower(plugin.Name)]; ok {
+			if plugin.Library != "" {
+				known.Library = plugin.Library
+			}
+			plugin = known
+		}
+		plugins = append(plugins, plugin)
+	}
+	return plugins
+}
+
+// GetPluginLibraries returns the libraries rendered as plugin_load_add, a
+// library holding several of the plugins listed only once
+func (s *ServerSettings) GetPluginLibraries() []string {
+	var libraries []string
+	for _, plugin := range s.GetPlugins() {
+		library := strings.TrimSuffix(plugin.Library, ".so")
+		if library != "" && !containsString(libraries, library) {
+			libraries = append(libraries, library)
+		}
+	}
+	return libraries
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// NeedsTimeZoneTables tells whether the time zone tables are to be loaded,
+// named time zones can not be used without them
+func (s *ServerSettings) NeedsTimeZoneTables() bool {
+	return s.LoadTimeZones || (s.TimeZone != "" && s.TimeZone != "SYSTEM" && !timeOffsetRegexp.MatchString(s.TimeZone))
+}
+
+// getManagedKeys returns the keys spec.config may not set, those rendered
+// from spec.server included once set
+func (mdbc *MariaDBCluster) getManagedKeys() []string {
+	keys := append([]string{}, operatorManagedKeys...)
+	if mdbc.Spec.Server.CharacterSet != "" {
+		keys = append(keys, "character_set_server")
+	}
+	if mdbc.Spec.Server.Collation != "" {
+		keys = append(keys, "collation_server")
+	}
+	if mdbc.Spec.Server.SQLMode != nil {
+		keys = append(keys, "sql_mode")
+	}
+	if mdbc.Spec.Server.TimeZone != "" {
+		keys = append(keys, "default_time_zone")
+	}
+	for _, option := range mdbc.Spec.Server.GetInstrumentationOptions() {
+		if key := configLineKey(option); !containsString(keys, key) {
+			keys = append(keys, key)
+		}
+	}
+	if mdbc.Spec.Galera.Ports.GetSST() != DefaultSSTPort {
+		keys = append(keys, "wsrep_sst_receive_address")
+	}
+	binlog := mdbc.Spec.Server.Binlog
+	if binlog.Enabled {
+		keys = append(keys, "log_bin")
+	}
+	if binlog.GetServerID("", ColorBlue) != 0 {
+		keys = append(keys, "server_id")
+	}
+	if seconds, _ := binlog.GetExpireLogs(); seconds != 0 {
+		keys = append(keys, "expire_logs_days", "binlog_expire_logs_seconds")
+	}
+	if binlog.LogSlaveUpdates {
+		keys = append(keys, "log_slave_updates")
+	}
+	return keys
+}
+
+// server variables that can be changed at runtime, spec.config changes to any
+// other key only apply once pods restart
+var dynamicServerVariables = map[string]bool{
+	"binlog_cache_size":              true,
+	"bulk_insert_buffer_size":        true,
+	"character_set_server":           true,
+	"collation_server":               true,
+	"event_scheduler":                true,
+	"expire_logs_days":               true,
+	"general_log":                    true,
+	"general_log_file":               true,
+	"group_concat_max_len":           true,
+	"innodb_adaptive_hash_index":     true,
+	"innodb_buffer_pool_size":        true,
+	"innodb_file_per_table":          true,
+	"innodb_flush_log_at_trx_commit": true,
+	"innodb_flush_neighbors":         true,
+	"innodb_io_capacity":             true,
+	"innodb_io_capacity_max":         true,
+	"innodb_lock_wait_timeout":       true,
+	"innodb_max_dirty_pages_pct":     true,
+	"innodb_print_all_deadlocks":     true,
+	"innodb_stats_on_metadata":       true,
+	"innodb_thread_concurrency":      true,
+	"interactive_timeout":            true,
+	"join_buffer_size":               true,
+	"key_buffer_size":                true,
+	"lock_wait_timeout":              true,
+	"log_queries_not_using_indexes":  true,
+	"log_warnings":                   true,
+	"long_query_time":                true,
+	"max_allowed_packet":             true,
+	"max_binlog_size":                true,
+	"max_connect_errors":             true,
+	"max_connections":                true,
+	"max_heap_table_size":            true,
+	"max_statement_time":             true,
+	"net_read_timeout":               true,
+	"net_write_timeout":              true,
+	"query_cache_limit":              true,
+	"query_cache_size":               true,
+	"query_cache_type":               true,
+	"read_buffer_size":               true,
+	"read_rnd_buffer_size":           true,
+	"slow_query_log":                 true,
+	"slow_query_log_file":            true,
+	"sort_buffer_size":               true,
+	"sql_mode":                       true,
+	"sync_binlog":                    true,
+	"table_definition_cache":         true,
+	"table_open_cache":               true,
+	"thread_cache_size":              true,
+	"time_zone":                      true,
+	"tmp_table_size":                 true,
+	"wait_timeout":                   true,
+	"wsrep_certify_nonpk":            true,
+	"wsrep_debug":                    true,
+	"wsrep_log_conflicts":            true,
+	"wsrep_max_ws_rows":              true,
+	"wsrep_max_ws_size":              true,
+	"wsrep_osu_method":               true,
+	"wsrep_retry_autocommit":         true,
+	"wsrep_slave_threads":            true,
+	"wsrep_sync_wait":                true,
+}
+
+// IsDynamicServerVariable tells whether an option parsed by ParseServerConfig
+// can be set on a running server with SET GLOBAL
+func IsDynamicServerVariable(key string) bool {
+	return dynamicServerVariables[key]
+}
+
+// functions spec.config templates can use besides the text/template builtins
+var serverConfigFuncs = template.FuncMap{
+	"mul": func(a, b int64) int64 { return a * b },
+	"div": func(a, b int64) int64 {
+		if b == 0 {
+			return 0
+		}
+		return a / b
+	},
+}
+
+// ServerConfigValues is what spec.config is rendered with for each pod
+type ServerConfigValues struct {
+	ClusterName string
+	Namespace   string
+	PodName     string
+	Ordinal     int
+	// Limits of the server container, zero when not set
+	CPULimitMillis   int64
+	MemoryLimitBytes int64
+	MemoryLimitMB    int64
+}
+
+func (mdbc *MariaDBCluster) GetServerConfigValues(pod string) ServerConfigValues {
+	values := ServerConfigValues{
+		ClusterName: mdbc.Name,
+		Namespace:   mdbc.Namespace,
+		PodName:     pod,
+	}
+	if i := strings.LastIndex(pod, "-"); i >= 0 {
+		values.Ordinal, _ = strconv.Atoi(pod[i+1:])
+	}
+	if cpu, ok := mdbc.Spec.Resources.Limits["cpu"]; ok {
+		values.CPULimitMillis = cpu.MilliValue()
+	}
+	if memory, ok := mdbc.Spec.Resources.Limits["memory"]; ok {
+		values.MemoryLimitBytes = memory.Value()
+		values.MemoryLimitMB = values.MemoryLimitBytes >> 20
+	}
+	return values
+}
+
+const (
+	// innodb_buffer_pool_chunk_size default, the server rounds the pool to a
+	// multiple of it times the instances
+	bufferPoolChunkMB = 128
+	// the server only splits pools of a GB or more into instances
+	bufferPoolInstanceMB   = 1024
+	maxBufferPoolInstances = 8
+)
+
+// GetBufferPoolSize returns innodb_buffer_pool_size in MB and the instances it
+// is split into, taking Spec.BufferPool.MemoryPercent of the memory limit.
+// Zero when disabled, without a memory limit or when spec.serverConfig or
+// spec.config set the size themselves.
+func (mdbc *MariaDBCluster) GetBufferPoolSize() (int64, int64) {
+	if mdbc.Spec.BufferPool.Disabled {
+		return 0, 0
+	}
+	for _, line := range strings.Split(mdbc.Spec.ServerConfig+"\n"+mdbc.Status.ServerConfig, "\n") {
+		if configLineKey(line) == "innodb_buffer_pool_size" {
+			return 0, 0
+		}
+	}
+	limit := mdbc.GetServerConfigValues("").MemoryLimitMB
+	size := limit * int64(mdbc.Spec.BufferPool.GetMemoryPercent()) / 100
+	instances := size / bufferPoolInstanceMB
+	if instances < 1 {
+		instances = 1
+	} else if instances > maxBufferPoolInstances {
+		instances = maxBufferPoolInstances
+	}
+	size -= size % (bufferPoolChunkMB * instances)
+	if size < bufferPoolChunkMB {
+		return 0, 0
+	}
+	return size, instances
+}
+
+// RenderServerConfig renders a spec.config fragment for pod, failing when it
+// sets a key the operator manages itself
+func (mdbc *MariaDBCluster) RenderServerConfig(fragment, pod string) (string, error) {
+	tmpl, err := template.New("ServerConfig").Funcs(serverConfigFuncs).Option("missingkey=error").Parse(fragment)
+	if err != nil {
+		return "", err
+	}
+	buffer := bytes.NewBufferString("")
+	if err = tmpl.Execute(buffer, mdbc.GetServerConfigValues(pod)); err != nil {
+		return "", err
+	}
+	if err = mdbc.checkServerConfigKeys(buffer.String()); err != nil {
+		return "", err
+	}
+	return buffer.String(), nil
+}
+
+// ValidateServerConfig renders a spec.config fragment for every server pod
+func (mdbc *MariaDBCluster) ValidateServerConfig(fragment string) error {
+	for ordinal := int32(0); ordinal < mdbc.Spec.Replicas; ordinal++ {
+		pod := fmt.Sprintf("%s-%d", mdbc.GetServerStatefulSetName(), ordinal)
+		if _, err := mdbc.RenderServerConfig(fragment, pod); err != nil {
+			return fmt.Errorf("config for %s : %s", pod, err.Error())
+		}
+	}
+	return nil
+}
+
+// ParseServerConfig returns the options a rendered fragment sets in groups the
+// server reads, keyed by their normalized name: lower case underscores without
+// the loose_ prefix
+func ParseServerConfig(cnf string) (map[string]string, error) {
+	options := make(map[string]string)
+	var group string
+	scanner := bufio.NewScanner(strings.NewReader(cnf))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		switch {
+		case text == "" || strings.HasPrefix(text, "#") || strings.HasPrefix(text, ";"):
+			continue
+		case strings.HasPrefix(text, "!"):
+			return nil, fmt.Errorf("line %d : include directives are not supported", line)
+		case strings.HasPrefix(text, "[") && strings.HasSuffix(text, "]"):
+			group = strings.ToLower(strings.TrimSpace(text[1 : len(text)-1]))
+			continue
+		case group == "":
+			return nil, fmt.Errorf("line %d : option outside of any group", line)
+		}
+		if !isServerGroup(group) {
+			continue
+		}
+		key, value := text, ""
+		if i := strings.Index(text, "="); i >= 0 {
+			key, value = strings.TrimSpace(text[:i]), strings.TrimSpace(text[i+1:])
+		}
+		options[normalizeOptionKey(key)] = value
+	}
+	return options, scanner.Err()
+}
+
+func (mdbc *MariaDBCluster) checkServerConfigKeys(cnf string) error {
+	options, err := ParseServerConfig(cnf)
+	if err != nil {
+		return err
+	}
+	var conflicts []string
+	for _, key := range mdbc.getManagedKeys() {
+		if _, ok := options[key]; ok {
+			conflicts = append(conflicts, key)
+		}
+	}
+	if len(conflicts) > 0 {
+		return fmt.Errorf("%s managed by the operator", strings.Join(conflicts, ", "))
+	}
+	return nil
+}
+
+// isServerGroup tells whether mysqld reads options of a my.cnf group
+func isServerGroup(group string) bool {
+	switch group {
+	case "mysqld", "server", "galera", "mariadb", "mariadbd":
+		return true
+	}
+	return strings.HasPrefix(group, "mysqld-") || strings.HasPrefix(group, "mariadb-") || strings.HasPrefix(group, "mariadbd-")
+}
+
+// provider options left out of the config hash: maintained by agents, only
+// rendered for a bootstrap, or following the placement of the pod
+var runtimeProviderOptions = []string{"pc.bootstrap=", "pc.weight=", "gcs.fc_limit=", "gmcast.segment=", "ist.recv_addr="}
+
+// GetConfigHash returns a hash of the configuration pods of a color only read
+// on startup: spec.serverConfig, spec.server with the time zone resolved in
+// status, the buffer pool size, the lines of spec.config not setting dynamic
+// variables and the provider options, leaving out those maintained at runtime.
+// It is taken from the inputs rather than what each pod renders so that
+// scaling does not change it. Pods restart when it changes, and report the
+// one they started with.
+func (mdbc *MariaDBCluster) GetConfigHash(color string) string {
+	lines := []string{"serverConfig " + mdbc.Spec.ServerConfig}
+	if server := mdbc.Spec.Server; server.CharacterSet != "" || server.Collation != "" || server.SQLMode != nil {
+		line := "server " + server.CharacterSet + " " + server.Collation
+		if server.SQLMode != nil {
+			line += " sqlMode=" + *server.SQLMode
+		}
+		lines = append(lines, line)
+	}
+	if profile := mdbc.Spec.Server.Instrumentation; profile != "" {
+		lines = append(lines, "instrumentation "+profile)
+	}
+	if libraries := mdbc.Spec.Server.GetPluginLibraries(); len(libraries) > 0 {
+		lines = append(lines, "plugins "+strings.Join(libraries, ","))
+	}
+	if zone := mdbc.Status.TimeZone; zone != "" {
+		lines = append(lines, "timeZone "+zone)
+	}
+	if binlog := mdbc.Spec.Server.Binlog; binlog.Enabled || binlog.Format != "" || binlog.ExpireLogs != nil || binlog.ServerIDBase != nil || binlog.LogSlaveUpdates {
+		seconds, _ := binlog.GetExpireLogs()
+		lines = append(lines, fmt.Sprintf("binlog %t %s %ds serverIDBase=%d logSlaveUpdates=%t",
+			binlog.Enabled, binlog.GetFormat(), seconds, binlog.GetServerID("", ColorBlue), binlog.LogSlaveUpdates))
+	}
+	if size, instances := mdbc.GetBufferPoolSize(); size > 0 {
+		lines = append(lines, fmt.Sprintf("bufferPool %dM %d", size, instances))
+	}
+	pod := mdbc.GetServerNameForColor(color) + "-0"
+	for _, option := range strings.Split(mdbc.GetWSREPProviderOptions(pod, false), ";") {
+		if !isRuntimeProviderOption(option) {
+			lines = append(lines, "provider "+option)
+		}
+	}
+	if ports := mdbc.Spec.Galera.Ports; ports.GetSST() != DefaultSSTPort || ports.GetIST() != DefaultISTPort {
+		lines = append(lines, fmt.Sprintf("ports sst=%d ist=%d", ports.GetSST(), ports.GetIST()))
+	}
+	for pod, addr := range mdbc.Status.ISTRecvAddr {
+		lines = append(lines, "provider "+pod+" ist.recv_addr="+addr)
+	}
+	for _, line := range strings.Split(mdbc.Status.ServerConfig, "\n") {
+		if key := configLineKey(line); key == "" || !IsDynamicServerVariable(key) {
+			lines = append(lines, "config "+line)
+		}
+	}
+	sort.Strings(lines)
+	sum := sha256.Sum256([]byte(strings.Join(lines, "\n")))
+	return hex.EncodeToString(sum[:8])
+}
+
+func isRuntimeProviderOption(option string) bool {
+	for _, prefix := range runtimeProviderOptions {
+		if strings.HasPrefix(option, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// configLineKey returns the option a my.cnf line sets, empty for anything else
+func configLineKey(line string) string {
+	text := strings.TrimSpace(line)
+	if text == "" || strings.ContainsAny(text[:1], "#;[!{") {
+		return ""
+	}
+	if i := strings.Index(text, "="); i >= 0 {
+		text = text[:i]
+	}
+	return normalizeOptionKey(text)
+}
+
+// normalizeOptionKey returns an option name in lower case with underscores,
+// without the loose_ prefix
+func normalizeOptionKey(key string) string {
+	key = strings.Replace(strings.ToLower(strings.TrimSpace(key)), "-", "_", -1)
+	return strings.TrimPrefix(key, "loose_")
+}
@@ -3,7 +3,7 @@ package v1alpha1
 import (
 	"bytes"
 	"fmt"
-	"html/template"
+	"text/template"
 )
 
 const (
@@ -14,19 +14,63 @@ const (
 
 wsrep_on=ON
 wsrep_provider=/usr/lib/galera/libgalera_smm.so
-binlog_format=row
+binlog_format={{or .BinlogFormat "row"}}
 default_storage_engine=InnoDB
 innodb_autoinc_lock_mode=2
 wsrep_cluster_name="{{.Name}}"
 wsrep_cluster_address = gcomm://{{range $key, $value := .WSREPEndpoints}}{{if $key}},{{end}}{{$value}}{{end}}
 wsrep_provider_options="{{.WSREPProviderOptions}}"
-`
+{{if .WSREPSSTDonor}}wsrep_sst_donor="{{.WSREPSSTDonor}}"
+{{end}}{{if .WSREPSSTMethod}}wsrep_sst_method={{.WSREPSSTMethod}}
+{{end}}{{if .WSREPSSTReceiveAddress}}wsrep_sst_receive_address={{.WSREPSSTReceiveAddress}}
+{{end}}{{if .WSREPSSTAuth}}wsrep_sst_auth="{{.WSREPSSTAuth}}"
+{{end}}{{if .CharacterSet}}character_set_server={{.CharacterSet}}
+{{end}}{{if .Collation}}collation_server={{.Collation}}
+{{end}}{{with .SQLMode}}sql_mode="{{.}}"
+{{end}}{{if .TimeZone}}default_time_zone="{{.TimeZone}}"
+{{end}}{{range .PluginLibraries}}plugin_load_add={{.}}
+{{end}}{{range .InstrumentationOptions}}{{.}}
+{{end}}{{if .LogBin}}log_bin=mysql-bin
+{{end}}{{if .ServerID}}server_id={{.ServerID}}
+{{end}}{{if .ExpireLogsSeconds}}expire_logs_days={{.ExpireLogsDays}}
+loose_binlog_expire_logs_seconds={{.ExpireLogsSeconds}}
+{{end}}{{if .LogSlaveUpdates}}log_slave_updates=ON
+{{end}}{{if .InnoDBBufferPoolSizeMB}}innodb_buffer_pool_size={{.InnoDBBufferPoolSizeMB}}M
+loose_innodb_buffer_pool_instances={{.InnoDBBufferPoolInstances}}
+{{end}}`
 )
 
 type MariaDBConfig struct {
 	Name                 string
 	WSREPEndpoints       []string
 	WSREPProviderOptions string
+	WSREPSSTDonor        string
+	WSREPSSTMethod       string
+	WSREPSSTAuth         string
+	// Empty has the server pick its address and the default port
+	WSREPSSTReceiveAddress string
+	CharacterSet           string
+	Collation              string
+	// nil leaves sql_mode to MariaDB
+	SQLMode  *string
+	TimeZone string
+	// libraries loaded with plugin_load_add
+	PluginLibraries []string
+	// performance_schema options of the instrumentation profile
+	InstrumentationOptions []string
+	// Empty renders ROW
+	BinlogFormat string
+	LogBin       bool
+	// Zero leaves server_id to MariaDB
+	ServerID int64
+	// Zero leaves binary log expiry to MariaDB, servers knowing
+	// binlog_expire_logs_seconds take it over expire_logs_days
+	ExpireLogsSeconds int64
+	ExpireLogsDays    int64
+	LogSlaveUpdates   bool
+	// Zero leaves the buffer pool to MariaDB
+	InnoDBBufferPoolSizeMB    int64
+	InnoDBBufferPoolInstances int64
 }
 
 func (conf *MariaDBConfig) Render() (string, error) {
@@ -2,6 +2,9 @@ package v1alpha1
 
 import (
 	"testing"
+
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 )
 
 var (
@@ -37,3 +40,80 @@ func TestConfigTemplates(t *testing.T) {
 	t.Logf("Output WSREP : \n%s", output)
 
 }
+
+func TestServerConfig(t *testing.T) {
+	mdbc := &MariaDBCluster{}
+	mdbc.Name = "db"
+	mdbc.Spec.Replicas = 3
+	output, err := mdbc.RenderServerConfig("[mysqld]\nserver_id={{add1 .Ordinal}}\n", "db-server-1")
+	if err == nil {
+		t.Errorf("unknown function rendered: %s", output)
+	}
+	output, err = mdbc.RenderServerConfig("[mysqld]\nwsrep_node_name={{.PodName}}\n[client]\nwsrep-on=OFF\n", "db-server-1")
+	if err != nil || output != "[mysqld]\nwsrep_node_name=db-server-1\n[client]\nwsrep-on=OFF\n" {
+		t.Errorf("unexpected output %q : %v", output, err)
+	}
+	for _, fragment := range []string{
+		"[mysqld]\nloose-wsrep-provider-options=gcache.size=1G\n",
+		"[galera]\nbinlog_format = statement\n",
+		"max_connections=10\n",
+		"[mysqld]\n!includedir /etc/mysql/extra\n",
+	} {
+		if _, err = mdbc.RenderServerConfig(fragment, "db-server-0"); err == nil {
+			t.Errorf("fragment accepted: %q", fragment)
+		}
+	}
+}
+
+func TestBufferPoolSize(t *testing.T) {
+	mdbc := &MariaDBCluster{}
+	for _, test := range []struct {
+		memory    string
+		size      int64
+		instances int64
+	}{
+		{"", 0, 0},
+		{"128Mi", 0, 0},
+		{"512Mi", 256, 1},
+		{"4Gi", 2304, 2},
+		{"64Gi", 38912, 8},
+	} {
+		mdbc.Spec.Resources.Limits = nil
+		if test.memory != "" {
+			mdbc.Spec.Resources.Limits = v1.ResourceList{"memory": resource.MustParse(test.memory)}
+		}
+		size, instances := mdbc.GetBufferPoolSize()
+		if size != test.size || instances != test.instances {
+			t.Errorf("%s : got %dM in %d instances, expected %dM in %d", test.memory, size, instances, test.size, test.instances)
+		}
+	}
+	mdbc.Spec.ServerConfig = "innodb-buffer-pool-size=1G"
+	if size, _ := mdbc.GetBufferPoolSize(); size != 0 {
+		t.Errorf("size %dM rendered over spec.serverConfig", size)
+	}
+}
+
+func TestBinlogServerID(t *testing.T) {
+	binlog := &BinlogSettings{}
+	if id := binlog.GetServerID("db-server-2", ColorBlue); id != 0 {
+		t.Errorf("server_id %d rendered without binary logging", id)
+	}
+	binlog.Enabled = true
+	base := int64(100)
+	for _, test := range []struct {
+		base  *int64
+		pod   string
+		color string
+		id    int64
+	}{
+		{nil, "db-server-0", ColorBlue, 1},
+		{nil, "db-server-2", ColorBlue, 3},
+		{&base, "db-server-1", ColorBlue, 101},
+		{&base, "db-server-green-1", ColorGreen, 1101},
+	} {
+		binlog.ServerIDBase = test.base
+		if id := binlog.GetServerID(test.pod, test.color); id != test.id {
+			t.Errorf("%s %s : got server_id %d, expected %d", test.pod, test.color, id, test.id)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package v1alpha1
+
+import (
+	batch "k8s.io/api/batch/v1"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// ProviderCheckJobTransform renders a Job that reports the galera provider
+// package shipped in the server image of given version through its
+// termination message, formatted as <package>:<version>
+func (mdbc *MariaDBCluster) ProviderCheckJobTransform(job *batch.Job, version string) error {
+	labels := mdbc.GetServerLabels()
+	labels[MariaDBClusterRoleLabel] = MariaDBClusterProviderCheckRole
+	backoffLimit := int32(1)
+
+	job.SetName(mdbc.GetProviderCheckJobName(version))
+	job.SetNamespace(mdbc.Namespace)
+	job.SetLabels(labels)
+	job.SetAnnotations(map[string]string{MariaDBClusterVersionAnnotation: version})
+	job.SetOwnerReferences([]metav1.OwnerReference{
+		*metav1.NewControllerRef(mdbc, schema.GroupVersionKind{
+			Group:   GroupName,
+			Version: Version,
+			Kind:    "MariaDBCluster",
+		}),
+	})
+	job.Spec.BackoffLimit = &backoffLimit
+	job.Spec.Template.ObjectMeta.Labels = labels
+	job.Spec.Template.Spec.RestartPolicy = v1.RestartPolicyNever
+	if len(job.Spec.Template.Spec.Containers) < 1 {
+		job.Spec.Template.Spec.Containers = append(job.Spec.Template.Spec.Containers, v1.Container{})
+	}
+	job.Spec.Template.Spec.Containers[0].Name = MariaDBClusterProviderCheckRole
+	job.Spec.Template.Spec.Containers[0].Image = mdbc.GetImage(version)
+	job.Spec.Template.Spec.Containers[0].ImagePullPolicy = mdbc.GetImagePullPolicy(v1.PullIfNotPresent)
+	job.Spec.Template.Spec.Containers[0].TerminationMessagePolicy = v1.TerminationMessageReadFile
+	job.Spec.Template.Spec.Containers[0].Command = []string{"bash", "-c",
+		"dpkg-query -W -f='${Status} ${Package}:${Version}\\n' 'galera*' | " +
+			"awk '/^install ok installed/ && $4 !~ /arbitrator/ {print $4; exit}' | tee /dev/termination-log | grep -q ."}
+	mdbc.inheritMetadata(&job.ObjectMeta)
+	mdbc.inheritMetadata(&job.Spec.Template.ObjectMeta)
+	mdbc.confinePod(&job.Spec.Template)
+	return nil
+}
+
+// SSTCheckJobTransform renders a Job that reports the SST methods the server
+// image of given version ships the tools for, space separated through its
+// termination message
+func (mdbc *MariaDBCluster) SSTCheckJobTransform(job *batch.Job, version string) error {
+	labels := mdbc.GetServerLabels()
+	labels[MariaDBClusterRoleLabel] = MariaDBClusterSSTCheckRole
+	backoffLimit := int32(1)
+
+	job.SetName(mdbc.GetSSTCheckJobName(version))
+	job.SetNamespace(mdbc.Namespace)
+	job.SetLabels(labels)
+	job.SetAnnotations(map[string]string{MariaDBClusterVersionAnnotation: version})
+	job.SetOwnerReferences([]metav1.OwnerReference{
+		*metav1.NewControllerRef(mdbc, schema.GroupVersionKind{
+			Group:   GroupName,
+			Version: Version,
+			Kind:    "MariaDBCluster",
+		}),
+	})
+	job.Spec.BackoffLimit = &backoffLimit
+	job.Spec.Template.ObjectMeta.Labels = labels
+	job.Spec.Template.Spec.RestartPolicy = v1.RestartPolicyNever
+	if len(job.Spec.Template.Spec.Containers) < 1 {
+		job.Spec.Template.Spec.Containers = append(job.Spec.Template.Spec.Containers, v1.Container{})
+	}
+	job.Spec.Template.Spec.Containers[0].Name = MariaDBClusterSSTCheckRole
+	job.Spec.Template.Spec.Containers[0].Image = mdbc.GetServerImageForVersion(version)
+	job.Spec.Template.Spec.Containers[0].ImagePullPolicy = mdbc.GetImagePullPolicy(v1.PullIfNotPresent)
+	job.Spec.Template.Spec.Containers[0].TerminationMessagePolicy = v1.TerminationMessageReadFile
+	job.Spec.Template.Spec.Containers[0].Command = []string{"bash", "-c",
+		"m=''; " +
+			"command -v rsync >/dev/null && m=\"$m " + SSTMethodRsync + "\"; " +
+			"command -v mariabackup >/dev/null && command -v socat >/dev/null && m=\"$m " + SSTMethodMariaBackup + "\"; " +
+			"echo $m | tee /dev/termination-log"}
+	mdbc.inheritMetadata(&job.ObjectMeta)
+	mdbc.inheritMetadata(&job.Spec.Template.ObjectMeta)
+	mdbc.confinePod(&job.Spec.Template)
+	return nil
+}
+
+// ImageResolveJobTransform renders a Job pulling the server image tag of given
+// version, the resulting pod reports the digest it got in its imageID
+func (mdbc *MariaDBCluster) ImageResolveJobTransform(job *batch.Job, version string) error {
+	labels := mdbc.GetServerLabels()
+	labels[MariaDBClusterRoleLabel] = MariaDBClusterImageResolveRole
+	backoffLimit := int32(1)
+
+	job.SetName(mdbc.GetImageResolveJobName(version))
+	job.SetNamespace(mdbc.Namespace)
+	job.SetLabels(labels)
+	job.SetAnnotations(map[string]string{MariaDBClusterVersionAnnotation: version})
+	job.SetOwnerReferences([]metav1.OwnerReference{
+		*metav1.NewControllerRef(mdbc, schema.GroupVersionKind{
+			Group:   GroupName,
+			Version: Version,
+			Kind:    "MariaDBCluster",
+		}),
+	})
+	job.Spec.BackoffLimit = &backoffLimit
+	job.Spec.Template.ObjectMeta.Labels = labels
+	job.Spec.Template.Spec.RestartPolicy = v1.RestartPolicyNever
+	if len(job.Spec.Template.Spec.Containers) < 1 {
+		job.Spec.Template.Spec.Containers = append(job.Spec.Template.Spec.Containers, v1.Container{})
+	}
+	job.Spec.Template.Spec.Containers[0].Name = MariaDBClusterImageResolveRole
+	job.Spec.Template.Spec.Containers[0].Image = mdbc.GetImage(version)
+	job.Spec.Template.Spec.Containers[0].ImagePullPolicy = v1.PullAlways
+	job.Spec.Template.Spec.Containers[0].Command = []string{"true"}
+	mdbc.inheritMetadata(&job.ObjectMeta)
+	mdbc.inheritMetadata(&job.Spec.Template.ObjectMeta)
+	mdbc.confinePod(&job.Spec.Template)
+	return nil
+}
+
+// WriteRateJobTransform renders a Job sampling the replication traffic of the
+// cluster for a minute and reporting it in bytes per second through its
+// termination message
+func (mdbc *MariaDBCluster) WriteRateJobTransform(job *batch.Job, version string) error {
+	labels := mdbc.GetServerLabels()
+	labels[MariaDBClusterRoleLabel] = MariaDBClusterWriteRateRole
+	backoffLimit := int32(1)
+	host := mdbc.GetServerStatefulSetName() + "-0." + mdbc.GetServerServiceName()
+	query := "SELECT SUM(variable_value) FROM information_schema.global_status WHERE variable_name IN ('wsrep_replicated_bytes', 'wsrep_received_bytes')"
+
+	job.SetName(mdbc.GetWriteRateJobName(version))
+	job.SetNamespace(mdbc.Namespace)
+	job.SetLabels(labels)
+	job.SetAnnotations(map[string]string{MariaDBClusterVersionAnnotation: version})
+	job.SetOwnerReferences([]metav1.OwnerReference{
+		*metav1.NewControllerRef(mdbc, schema.GroupVersionKind{
+			Group:   GroupName,
+			Version: Version,
+			Kind:    "MariaDBCluster",
+		}),
+	})
+	job.Spec.BackoffLimit = &backoffLimit
+	job.Spec.Template.ObjectMeta.Labels = labels
+	job.Spec.Template.Spec.RestartPolicy = v1.RestartPolicyNever
+	if len(job.Spec.Template.Spec.Containers) < 1 {
+		job.Spec.Template.Spec.Containers = append(job.Spec.Template.Spec.Containers, v1.Container{})
+	}
+	job.Spec.Template.Spec.Containers[0].Name = MariaDBClusterWriteRateRole
+	job.Spec.Template.Spec.Containers[0].Image = mdbc.GetServerImage()
+	job.Spec.Template.Spec.Containers[0].ImagePullPolicy = mdbc.GetImagePullPolicy(v1.PullIfNotPresent)
+	job.Spec.Template.Spec.Containers[0].TerminationMessagePolicy = v1.TerminationMessageReadFile
+	job.Spec.Template.Spec.Containers[0].Command = []string{"bash", "-c",
+		"set -e; " +
+			"a=$(mysql -h " + host + " -N -B -e \"" + query + "\"); sleep 60; " +
+			"b=$(mysql -h " + host + " -N -B -e \"" + query + "\"); " +
+			"echo $(( (b - a) / 60 )) | tee /dev/termination-log"}
+	mdbc.inheritMetadata(&job.ObjectMeta)
+	mdbc.inheritMetadata(&job.Spec.Template.ObjectMeta)
+	mdbc.confinePod(&job.Spec.Template)
+	return nil
+}
@@ -20,63 +20,1291 @@ limitations under the License.
 package v1alpha1
 
 import (
+	core_v1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BackupStatus) DeepCopyInto(out *BackupStatus) {
+	*out = *in
+	in.CompletionTime.DeepCopyInto(&out.CompletionTime)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupStatus.
+func (in *BackupStatus) DeepCopy() *BackupStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(BackupStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BinlogSettings) DeepCopyInto(out *BinlogSettings) {
+	*out = *in
+	if in.ExpireLogs != nil {
+		in, out := &in.ExpireLogs, &out.ExpireLogs
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(v1.Duration)
+			**out = **in
+		}
+	}
+	if in.ServerIDBase != nil {
+		in, out := &in.ServerIDBase, &out.ServerIDBase
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(int64)
+			**out = **in
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BinlogSettings.
+func (in *BinlogSettings) DeepCopy() *BinlogSettings {
+	if in == nil {
+		return nil
+	}
+	out := new(BinlogSettings)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BlueGreenStatus) DeepCopyInto(out *BlueGreenStatus) {
+	*out = *in
+	in.StartTime.DeepCopyInto(&out.StartTime)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BlueGreenStatus.
+func (in *BlueGreenStatus) DeepCopy() *BlueGreenStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(BlueGreenStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BootstrapRecord) DeepCopyInto(out *BootstrapRecord) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BootstrapRecord.
+func (in *BootstrapRecord) DeepCopy() *BootstrapRecord {
+	if in == nil {
+		return nil
+	}
+	out := new(BootstrapRecord)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BufferPoolPolicy) DeepCopyInto(out *BufferPoolPolicy) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BufferPoolPolicy.
+func (in *BufferPoolPolicy) DeepCopy() *BufferPoolPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(BufferPoolPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConnectionStatus) DeepCopyInto(out *ConnectionStatus) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConnectionStatus.
+func (in *ConnectionStatus) DeepCopy() *ConnectionStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ConnectionStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ContainerResources) DeepCopyInto(out *ContainerResources) {
+	*out = *in
+	if in.Init != nil {
+		in, out := &in.Init, &out.Init
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(core_v1.ResourceRequirements)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	if in.Agent != nil {
+		in, out := &in.Agent, &out.Agent
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(core_v1.ResourceRequirements)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ContainerResources.
+func (in *ContainerResources) DeepCopy() *ContainerResources {
+	if in == nil {
+		return nil
+	}
+	out := new(ContainerResources)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ContainerVolumeMount) DeepCopyInto(out *ContainerVolumeMount) {
+	*out = *in
+	if in.Containers != nil {
+		in, out := &in.Containers, &out.Containers
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	in.VolumeMount.DeepCopyInto(&out.VolumeMount)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ContainerVolumeMount.
+func (in *ContainerVolumeMount) DeepCopy() *ContainerVolumeMount {
+	if in == nil {
+		return nil
+	}
+	out := new(ContainerVolumeMount)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DivergenceReport) DeepCopyInto(out *DivergenceReport) {
+	*out = *in
+	out.GRAState = in.GRAState
+	in.Reported.DeepCopyInto(&out.Reported)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DivergenceReport.
+func (in *DivergenceReport) DeepCopy() *DivergenceReport {
+	if in == nil {
+		return nil
+	}
+	out := new(DivergenceReport)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DonorPolicy) DeepCopyInto(out *DonorPolicy) {
+	*out = *in
+	if in.Preferred != nil {
+		in, out := &in.Preferred, &out.Preferred
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DonorPolicy.
+func (in *DonorPolicy) DeepCopy() *DonorPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(DonorPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FlowControlPolicy) DeepCopyInto(out *FlowControlPolicy) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FlowControlPolicy.
+func (in *FlowControlPolicy) DeepCopy() *FlowControlPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(FlowControlPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FlowControlStatus) DeepCopyInto(out *FlowControlStatus) {
+	*out = *in
+	if in.Limits != nil {
+		in, out := &in.Limits, &out.Limits
+		*out = make(map[string]int, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	in.LastChange.DeepCopyInto(&out.LastChange)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FlowControlStatus.
+func (in *FlowControlStatus) DeepCopy() *FlowControlStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(FlowControlStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GCachePolicy) DeepCopyInto(out *GCachePolicy) {
+	*out = *in
+	if in.Window != nil {
+		in, out := &in.Window, &out.Window
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(v1.Duration)
+			**out = **in
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GCachePolicy.
+func (in *GCachePolicy) DeepCopy() *GCachePolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(GCachePolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GCacheStatus) DeepCopyInto(out *GCacheStatus) {
+	*out = *in
+	in.MeasuredTime.DeepCopyInto(&out.MeasuredTime)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GCacheStatus.
+func (in *GCacheStatus) DeepCopy() *GCacheStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(GCacheStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GRAState) DeepCopyInto(out *GRAState) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GRAState.
+func (in *GRAState) DeepCopy() *GRAState {
+	if in == nil {
+		return nil
+	}
+	out := new(GRAState)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GaleraConfig) DeepCopyInto(out *GaleraConfig) {
+	*out = *in
+	if in.SSTTimeout != nil {
+		in, out := &in.SSTTimeout, &out.SSTTimeout
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(v1.Duration)
+			**out = **in
+		}
+	}
+	in.Donor.DeepCopyInto(&out.Donor)
+	in.GCache.DeepCopyInto(&out.GCache)
+	out.FlowControl = in.FlowControl
+	in.Weights.DeepCopyInto(&out.Weights)
+	in.Resilience.DeepCopyInto(&out.Resilience)
+	in.IST.DeepCopyInto(&out.IST)
+	out.Ports = in.Ports
+	if in.ProviderOptions != nil {
+		in, out := &in.ProviderOptions, &out.ProviderOptions
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GaleraConfig.
+func (in *GaleraConfig) DeepCopy() *GaleraConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(GaleraConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GaleraPorts) DeepCopyInto(out *GaleraPorts) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GaleraPorts.
+func (in *GaleraPorts) DeepCopy() *GaleraPorts {
+	if in == nil {
+		return nil
+	}
+	out := new(GaleraPorts)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ISTConfig) DeepCopyInto(out *ISTConfig) {
+	*out = *in
+	if in.RecvAddr != nil {
+		in, out := &in.RecvAddr, &out.RecvAddr
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ISTConfig.
+func (in *ISTConfig) DeepCopy() *ISTConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(ISTConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InitSQLScript) DeepCopyInto(out *InitSQLScript) {
+	*out = *in
+	if in.ConfigMapKeyRef != nil {
+		in, out := &in.ConfigMapKeyRef, &out.ConfigMapKeyRef
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(core_v1.ConfigMapKeySelector)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	if in.SecretKeyRef != nil {
+		in, out := &in.SecretKeyRef, &out.SecretKeyRef
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(core_v1.SecretKeySelector)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InitSQLScript.
+func (in *InitSQLScript) DeepCopy() *InitSQLScript {
+	if in == nil {
+		return nil
+	}
+	out := new(InitSQLScript)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InitSQLStatus) DeepCopyInto(out *InitSQLStatus) {
+	*out = *in
+	in.CompletionTime.DeepCopyInto(&out.CompletionTime)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InitSQLStatus.
+func (in *InitSQLStatus) DeepCopy() *InitSQLStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(InitSQLStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KernelReport) DeepCopyInto(out *KernelReport) {
+	*out = *in
+	if in.Differing != nil {
+		in, out := &in.Differing, &out.Differing
+		*out = make([]KernelSetting, len(*in))
+		copy(*out, *in)
+	}
+	in.Reported.DeepCopyInto(&out.Reported)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KernelReport.
+func (in *KernelReport) DeepCopy() *KernelReport {
+	if in == nil {
+		return nil
+	}
+	out := new(KernelReport)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KernelSetting) DeepCopyInto(out *KernelSetting) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KernelSetting.
+func (in *KernelSetting) DeepCopy() *KernelSetting {
+	if in == nil {
+		return nil
+	}
+	out := new(KernelSetting)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KernelTuning) DeepCopyInto(out *KernelTuning) {
+	*out = *in
+	if in.Sysctls != nil {
+		in, out := &in.Sysctls, &out.Sysctls
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KernelTuning.
+func (in *KernelTuning) DeepCopy() *KernelTuning {
+	if in == nil {
+		return nil
+	}
+	out := new(KernelTuning)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LineageStatus) DeepCopyInto(out *LineageStatus) {
+	*out = *in
+	if in.Bootstraps != nil {
+		in, out := &in.Bootstraps, &out.Bootstraps
+		*out = make([]BootstrapRecord, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LineageStatus.
+func (in *LineageStatus) DeepCopy() *LineageStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(LineageStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MariaDBCluster) DeepCopyInto(out *MariaDBCluster) {
 	*out = *in
-	out.TypeMeta = in.TypeMeta
-	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	in.Spec.DeepCopyInto(&out.Spec)
-	in.Status.DeepCopyInto(&out.Status)
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MariaDBCluster.
+func (in *MariaDBCluster) DeepCopy() *MariaDBCluster {
+	if in == nil {
+		return nil
+	}
+	out := new(MariaDBCluster)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *MariaDBCluster) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MariaDBClusterCondition) DeepCopyInto(out *MariaDBClusterCondition) {
+	*out = *in
+	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
+	in.LastUpdateTime.DeepCopyInto(&out.LastUpdateTime)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MariaDBClusterCondition.
+func (in *MariaDBClusterCondition) DeepCopy() *MariaDBClusterCondition {
+	if in == nil {
+		return nil
+	}
+	out := new(MariaDBClusterCondition)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MariaDBClusterList) DeepCopyInto(out *MariaDBClusterList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	out.ListMeta = in.ListMeta
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]MariaDBCluster, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MariaDBClusterList.
+func (in *MariaDBClusterList) DeepCopy() *MariaDBClusterList {
+	if in == nil {
+		return nil
+	}
+	out := new(MariaDBClusterList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *MariaDBClusterList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MariaDBClusterSpec) DeepCopyInto(out *MariaDBClusterSpec) {
+	*out = *in
+	in.Resources.DeepCopyInto(&out.Resources)
+	out.Storages = in.Storages
+	in.Config.DeepCopyInto(&out.Config)
+	out.BufferPool = in.BufferPool
+	in.Server.DeepCopyInto(&out.Server)
+	if in.InitSQL != nil {
+		in, out := &in.InitSQL, &out.InitSQL
+		*out = make([]InitSQLScript, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	in.Upgrade.DeepCopyInto(&out.Upgrade)
+	in.Recovery.DeepCopyInto(&out.Recovery)
+	in.Galera.DeepCopyInto(&out.Galera)
+	out.Probes = in.Probes
+	in.Shutdown.DeepCopyInto(&out.Shutdown)
+	in.KernelTuning.DeepCopyInto(&out.KernelTuning)
+	out.Teardown = in.Teardown
+	if in.PhaseTimeouts != nil {
+		in, out := &in.PhaseTimeouts, &out.PhaseTimeouts
+		*out = make(map[string]v1.Duration, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	out.Suspend = in.Suspend
+	in.InheritMetadata.DeepCopyInto(&out.InheritMetadata)
+	in.PodMetadata.DeepCopyInto(&out.PodMetadata)
+	out.SecurityProfiles = in.SecurityProfiles
+	if in.Affinity != nil {
+		in, out := &in.Affinity, &out.Affinity
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(core_v1.Affinity)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	if in.TopologySpreadConstraints != nil {
+		in, out := &in.TopologySpreadConstraints, &out.TopologySpreadConstraints
+		*out = make([]TopologySpreadConstraint, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Tolerations != nil {
+		in, out := &in.Tolerations, &out.Tolerations
+		*out = make([]core_v1.Toleration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Sidecars != nil {
+		in, out := &in.Sidecars, &out.Sidecars
+		*out = make([]core_v1.Container, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.InitContainers != nil {
+		in, out := &in.InitContainers, &out.InitContainers
+		*out = make([]core_v1.Container, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Volumes != nil {
+		in, out := &in.Volumes, &out.Volumes
+		*out = make([]core_v1.Volume, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.VolumeMounts != nil {
+		in, out := &in.VolumeMounts, &out.VolumeMounts
+		*out = make([]ContainerVolumeMount, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	in.ContainerResources.DeepCopyInto(&out.ContainerResources)
+	if in.Env != nil {
+		in, out := &in.Env, &out.Env
+		*out = make([]core_v1.EnvVar, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.EnvFrom != nil {
+		in, out := &in.EnvFrom, &out.EnvFrom
+		*out = make([]core_v1.EnvFromSource, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.HostAliases != nil {
+		in, out := &in.HostAliases, &out.HostAliases
+		*out = make([]core_v1.HostAlias, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.DNSConfig != nil {
+		in, out := &in.DNSConfig, &out.DNSConfig
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(core_v1.PodDNSConfig)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MariaDBClusterSpec.
+func (in *MariaDBClusterSpec) DeepCopy() *MariaDBClusterSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(MariaDBClusterSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MariaDBClusterStatus) DeepCopyInto(out *MariaDBClusterStatus) {
+	*out = *in
+	in.PhaseStartTime.DeepCopyInto(&out.PhaseStartTime)
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]MariaDBClusterCondition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.RecoveryReports != nil {
+		in, out := &in.RecoveryReports, &out.RecoveryReports
+		*out = make(map[string]RecoveryReport, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
+	if in.LastBackup != nil {
+		in, out := &in.LastBackup, &out.LastBackup
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(BackupStatus)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	if in.ProviderVersions != nil {
+		in, out := &in.ProviderVersions, &out.ProviderVersions
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.PinnedImage != nil {
+		in, out := &in.PinnedImage, &out.PinnedImage
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(PinnedImageStatus)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	if in.BlueGreen != nil {
+		in, out := &in.BlueGreen, &out.BlueGreen
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(BlueGreenStatus)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	if in.GCache != nil {
+		in, out := &in.GCache, &out.GCache
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(GCacheStatus)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	if in.WSREP != nil {
+		in, out := &in.WSREP, &out.WSREP
+		*out = make(map[string]WSREPStatus, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
+	if in.SSTExcludedDonors != nil {
+		in, out := &in.SSTExcludedDonors, &out.SSTExcludedDonors
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.SSTMethods != nil {
+		in, out := &in.SSTMethods, &out.SSTMethods
+		*out = make(map[string][]string, len(*in))
+		for key, val := range *in {
+			var outVal []string
+			if val == nil {
+				(*out)[key] = nil
+			} else {
+				in, out := &val, &outVal
+				*out = make([]string, len(*in))
+				copy(*out, *in)
+			}
+			(*out)[key] = outVal
+		}
+	}
+	if in.Placement != nil {
+		in, out := &in.Placement, &out.Placement
+		*out = make(map[string]PodPlacement, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Segments != nil {
+		in, out := &in.Segments, &out.Segments
+		*out = make(map[string]int, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.ISTRecvAddr != nil {
+		in, out := &in.ISTRecvAddr, &out.ISTRecvAddr
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.FlowControl != nil {
+		in, out := &in.FlowControl, &out.FlowControl
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(FlowControlStatus)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	if in.ReportRetry != nil {
+		in, out := &in.ReportRetry, &out.ReportRetry
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(ReportRetryStatus)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	if in.PCBootstrap != nil {
+		in, out := &in.PCBootstrap, &out.PCBootstrap
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(PCBootstrapStatus)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	if in.Stalled != nil {
+		in, out := &in.Stalled, &out.Stalled
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(StalledStatus)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	if in.Diverged != nil {
+		in, out := &in.Diverged, &out.Diverged
+		*out = make(map[string]DivergenceReport, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
+	if in.Kernel != nil {
+		in, out := &in.Kernel, &out.Kernel
+		*out = make(map[string]KernelReport, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
+	if in.Lineage != nil {
+		in, out := &in.Lineage, &out.Lineage
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(LineageStatus)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	if in.RecoveryTimeline != nil {
+		in, out := &in.RecoveryTimeline, &out.RecoveryTimeline
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(RecoveryTimeline)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	if in.Connection != nil {
+		in, out := &in.Connection, &out.Connection
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(ConnectionStatus)
+			**out = **in
+		}
+	}
+	if in.Adopted != nil {
+		in, out := &in.Adopted, &out.Adopted
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Transitions != nil {
+		in, out := &in.Transitions, &out.Transitions
+		*out = make([]PhaseTransition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	in.BootstrapFromTime.DeepCopyInto(&out.BootstrapFromTime)
+	if in.BootstrapExcluded != nil {
+		in, out := &in.BootstrapExcluded, &out.BootstrapExcluded
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.QueriedPositions != nil {
+		in, out := &in.QueriedPositions, &out.QueriedPositions
+		*out = make(map[string]GRAState, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.InitSQL != nil {
+		in, out := &in.InitSQL, &out.InitSQL
+		*out = make(map[string]InitSQLStatus, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MariaDBClusterStatus.
+func (in *MariaDBClusterStatus) DeepCopy() *MariaDBClusterStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(MariaDBClusterStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MariaDBConfig) DeepCopyInto(out *MariaDBConfig) {
+	*out = *in
+	if in.WSREPEndpoints != nil {
+		in, out := &in.WSREPEndpoints, &out.WSREPEndpoints
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MariaDBConfig.
+func (in *MariaDBConfig) DeepCopy() *MariaDBConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(MariaDBConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MetadataTemplate) DeepCopyInto(out *MetadataTemplate) {
+	*out = *in
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MetadataTemplate.
+func (in *MetadataTemplate) DeepCopy() *MetadataTemplate {
+	if in == nil {
+		return nil
+	}
+	out := new(MetadataTemplate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PCBootstrapStatus) DeepCopyInto(out *PCBootstrapStatus) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PCBootstrapStatus.
+func (in *PCBootstrapStatus) DeepCopy() *PCBootstrapStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PCBootstrapStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PCWeights) DeepCopyInto(out *PCWeights) {
+	*out = *in
+	if in.Pods != nil {
+		in, out := &in.Pods, &out.Pods
+		*out = make(map[string]int, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Zones != nil {
+		in, out := &in.Zones, &out.Zones
+		*out = make(map[string]int, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PCWeights.
+func (in *PCWeights) DeepCopy() *PCWeights {
+	if in == nil {
+		return nil
+	}
+	out := new(PCWeights)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PhaseTransition) DeepCopyInto(out *PhaseTransition) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PhaseTransition.
+func (in *PhaseTransition) DeepCopy() *PhaseTransition {
+	if in == nil {
+		return nil
+	}
+	out := new(PhaseTransition)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PhaseVars) DeepCopyInto(out *PhaseVars) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PhaseVars.
+func (in *PhaseVars) DeepCopy() *PhaseVars {
+	if in == nil {
+		return nil
+	}
+	out := new(PhaseVars)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PinnedImageStatus) DeepCopyInto(out *PinnedImageStatus) {
+	*out = *in
+	in.ResolvedTime.DeepCopyInto(&out.ResolvedTime)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PinnedImageStatus.
+func (in *PinnedImageStatus) DeepCopy() *PinnedImageStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PinnedImageStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PodPlacement) DeepCopyInto(out *PodPlacement) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PodPlacement.
+func (in *PodPlacement) DeepCopy() *PodPlacement {
+	if in == nil {
+		return nil
+	}
+	out := new(PodPlacement)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProbeSettings) DeepCopyInto(out *ProbeSettings) {
+	*out = *in
+	out.Liveness = in.Liveness
+	out.Readiness = in.Readiness
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProbeSettings.
+func (in *ProbeSettings) DeepCopy() *ProbeSettings {
+	if in == nil {
+		return nil
+	}
+	out := new(ProbeSettings)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProbeTimings) DeepCopyInto(out *ProbeTimings) {
+	*out = *in
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MariaDBCluster.
-func (in *MariaDBCluster) DeepCopy() *MariaDBCluster {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProbeTimings.
+func (in *ProbeTimings) DeepCopy() *ProbeTimings {
 	if in == nil {
 		return nil
 	}
-	out := new(MariaDBCluster)
+	out := new(ProbeTimings)
 	in.DeepCopyInto(out)
 	return out
 }
 
-// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *MariaDBCluster) DeepCopyObject() runtime.Object {
-	if c := in.DeepCopy(); c != nil {
-		return c
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RecoveryEvent) DeepCopyInto(out *RecoveryEvent) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RecoveryEvent.
+func (in *RecoveryEvent) DeepCopy() *RecoveryEvent {
+	if in == nil {
+		return nil
 	}
-	return nil
+	out := new(RecoveryEvent)
+	in.DeepCopyInto(out)
+	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *MariaDBClusterCondition) DeepCopyInto(out *MariaDBClusterCondition) {
+func (in *RecoveryPolicy) DeepCopyInto(out *RecoveryPolicy) {
 	*out = *in
-	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
-	in.LastUpdateTime.DeepCopyInto(&out.LastUpdateTime)
+	if in.ReportRetryTimeout != nil {
+		in, out := &in.ReportRetryTimeout, &out.ReportRetryTimeout
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(v1.Duration)
+			**out = **in
+		}
+	}
+	if in.UnsyncedTimeout != nil {
+		in, out := &in.UnsyncedTimeout, &out.UnsyncedTimeout
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(v1.Duration)
+			**out = **in
+		}
+	}
+	if in.BootstrapReadyTimeout != nil {
+		in, out := &in.BootstrapReadyTimeout, &out.BootstrapReadyTimeout
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(v1.Duration)
+			**out = **in
+		}
+	}
+	if in.OverwriteDiverged != nil {
+		in, out := &in.OverwriteDiverged, &out.OverwriteDiverged
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MariaDBClusterCondition.
-func (in *MariaDBClusterCondition) DeepCopy() *MariaDBClusterCondition {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RecoveryPolicy.
+func (in *RecoveryPolicy) DeepCopy() *RecoveryPolicy {
 	if in == nil {
 		return nil
 	}
-	out := new(MariaDBClusterCondition)
+	out := new(RecoveryPolicy)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *MariaDBClusterList) DeepCopyInto(out *MariaDBClusterList) {
+func (in *RecoveryReport) DeepCopyInto(out *RecoveryReport) {
 	*out = *in
-	out.TypeMeta = in.TypeMeta
-	out.ListMeta = in.ListMeta
-	if in.Items != nil {
-		in, out := &in.Items, &out.Items
-		*out = make([]MariaDBCluster, len(*in))
+	out.GRAState = in.GRAState
+	in.Reported.DeepCopyInto(&out.Reported)
+	if in.Recovered != nil {
+		in, out := &in.Recovered, &out.Recovered
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(GRAState)
+			**out = **in
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RecoveryReport.
+func (in *RecoveryReport) DeepCopy() *RecoveryReport {
+	if in == nil {
+		return nil
+	}
+	out := new(RecoveryReport)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RecoveryTimeline) DeepCopyInto(out *RecoveryTimeline) {
+	*out = *in
+	in.StartTime.DeepCopyInto(&out.StartTime)
+	in.EndTime.DeepCopyInto(&out.EndTime)
+	if in.Positions != nil {
+		in, out := &in.Positions, &out.Positions
+		*out = make(map[string]GRAState, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Events != nil {
+		in, out := &in.Events, &out.Events
+		*out = make([]RecoveryEvent, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Transfers != nil {
+		in, out := &in.Transfers, &out.Transfers
+		*out = make([]RecoveryTransfer, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
@@ -84,139 +1312,267 @@ func (in *MariaDBClusterList) DeepCopyInto(out *MariaDBClusterList) {
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MariaDBClusterList.
-func (in *MariaDBClusterList) DeepCopy() *MariaDBClusterList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RecoveryTimeline.
+func (in *RecoveryTimeline) DeepCopy() *RecoveryTimeline {
 	if in == nil {
 		return nil
 	}
-	out := new(MariaDBClusterList)
+	out := new(RecoveryTimeline)
 	in.DeepCopyInto(out)
 	return out
 }
 
-// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *MariaDBClusterList) DeepCopyObject() runtime.Object {
-	if c := in.DeepCopy(); c != nil {
-		return c
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RecoveryTransfer) DeepCopyInto(out *RecoveryTransfer) {
+	*out = *in
+	in.StartTime.DeepCopyInto(&out.StartTime)
+	out.Duration = in.Duration
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RecoveryTransfer.
+func (in *RecoveryTransfer) DeepCopy() *RecoveryTransfer {
+	if in == nil {
+		return nil
 	}
-	return nil
+	out := new(RecoveryTransfer)
+	in.DeepCopyInto(out)
+	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *MariaDBClusterSpec) DeepCopyInto(out *MariaDBClusterSpec) {
+func (in *ReportRetryStatus) DeepCopyInto(out *ReportRetryStatus) {
 	*out = *in
-	in.Resources.DeepCopyInto(&out.Resources)
-	out.Storages = in.Storages
+	in.StartTime.DeepCopyInto(&out.StartTime)
+	in.LastTime.DeepCopyInto(&out.LastTime)
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MariaDBClusterSpec.
-func (in *MariaDBClusterSpec) DeepCopy() *MariaDBClusterSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReportRetryStatus.
+func (in *ReportRetryStatus) DeepCopy() *ReportRetryStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(MariaDBClusterSpec)
+	out := new(ReportRetryStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *MariaDBClusterStatus) DeepCopyInto(out *MariaDBClusterStatus) {
+func (in *ResiliencePolicy) DeepCopyInto(out *ResiliencePolicy) {
 	*out = *in
-	if in.Conditions != nil {
-		in, out := &in.Conditions, &out.Conditions
-		*out = make([]MariaDBClusterCondition, len(*in))
-		for i := range *in {
-			(*in)[i].DeepCopyInto(&(*out)[i])
+	if in.PCRecovery != nil {
+		in, out := &in.PCRecovery, &out.PCRecovery
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(bool)
+			**out = **in
 		}
 	}
-	if in.StatefulSetPodConditions != nil {
-		in, out := &in.StatefulSetPodConditions, &out.StatefulSetPodConditions
-		*out = make([]PodCondition, len(*in))
-		for i := range *in {
-			(*in)[i].DeepCopyInto(&(*out)[i])
+	if in.SuspectTimeout != nil {
+		in, out := &in.SuspectTimeout, &out.SuspectTimeout
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(v1.Duration)
+			**out = **in
+		}
+	}
+	if in.InactiveTimeout != nil {
+		in, out := &in.InactiveTimeout, &out.InactiveTimeout
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(v1.Duration)
+			**out = **in
+		}
+	}
+	if in.InstallTimeout != nil {
+		in, out := &in.InstallTimeout, &out.InstallTimeout
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(v1.Duration)
+			**out = **in
 		}
 	}
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MariaDBClusterStatus.
-func (in *MariaDBClusterStatus) DeepCopy() *MariaDBClusterStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResiliencePolicy.
+func (in *ResiliencePolicy) DeepCopy() *ResiliencePolicy {
 	if in == nil {
 		return nil
 	}
-	out := new(MariaDBClusterStatus)
+	out := new(ResiliencePolicy)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *MariaDBConfig) DeepCopyInto(out *MariaDBConfig) {
+func (in *SSTProgress) DeepCopyInto(out *SSTProgress) {
 	*out = *in
-	if in.WSREPEndpoints != nil {
-		in, out := &in.WSREPEndpoints, &out.WSREPEndpoints
-		*out = make([]string, len(*in))
-		copy(*out, *in)
+	in.StartTime.DeepCopyInto(&out.StartTime)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SSTProgress.
+func (in *SSTProgress) DeepCopy() *SSTProgress {
+	if in == nil {
+		return nil
 	}
+	out := new(SSTProgress)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecurityProfiles) DeepCopyInto(out *SecurityProfiles) {
+	*out = *in
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MariaDBConfig.
-func (in *MariaDBConfig) DeepCopy() *MariaDBConfig {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecurityProfiles.
+func (in *SecurityProfiles) DeepCopy() *SecurityProfiles {
 	if in == nil {
 		return nil
 	}
-	out := new(MariaDBConfig)
+	out := new(SecurityProfiles)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *PhaseVars) DeepCopyInto(out *PhaseVars) {
+func (in *ServerConfigSource) DeepCopyInto(out *ServerConfigSource) {
 	*out = *in
+	if in.ConfigMapKeyRef != nil {
+		in, out := &in.ConfigMapKeyRef, &out.ConfigMapKeyRef
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(core_v1.ConfigMapKeySelector)
+			(*in).DeepCopyInto(*out)
+		}
+	}
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PhaseVars.
-func (in *PhaseVars) DeepCopy() *PhaseVars {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServerConfigSource.
+func (in *ServerConfigSource) DeepCopy() *ServerConfigSource {
 	if in == nil {
 		return nil
 	}
-	out := new(PhaseVars)
+	out := new(ServerConfigSource)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *PodCondition) DeepCopyInto(out *PodCondition) {
+func (in *ServerConfigValues) DeepCopyInto(out *ServerConfigValues) {
 	*out = *in
-	in.Reported.DeepCopyInto(&out.Reported)
-	out.GRAState = in.GRAState
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PodCondition.
-func (in *PodCondition) DeepCopy() *PodCondition {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServerConfigValues.
+func (in *ServerConfigValues) DeepCopy() *ServerConfigValues {
+	if in == nil {
+		return nil
+	}
+	out := new(ServerConfigValues)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ServerPlugin) DeepCopyInto(out *ServerPlugin) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServerPlugin.
+func (in *ServerPlugin) DeepCopy() *ServerPlugin {
+	if in == nil {
+		return nil
+	}
+	out := new(ServerPlugin)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ServerSettings) DeepCopyInto(out *ServerSettings) {
+	*out = *in
+	if in.SQLMode != nil {
+		in, out := &in.SQLMode, &out.SQLMode
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(string)
+			**out = **in
+		}
+	}
+	if in.Plugins != nil {
+		in, out := &in.Plugins, &out.Plugins
+		*out = make([]ServerPlugin, len(*in))
+		copy(*out, *in)
+	}
+	in.Binlog.DeepCopyInto(&out.Binlog)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServerSettings.
+func (in *ServerSettings) DeepCopy() *ServerSettings {
+	if in == nil {
+		return nil
+	}
+	out := new(ServerSettings)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ShutdownPolicy) DeepCopyInto(out *ShutdownPolicy) {
+	*out = *in
+	if in.InnoDBFastShutdown != nil {
+		in, out := &in.InnoDBFastShutdown, &out.InnoDBFastShutdown
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(int32)
+			**out = **in
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ShutdownPolicy.
+func (in *ShutdownPolicy) DeepCopy() *ShutdownPolicy {
 	if in == nil {
 		return nil
 	}
-	out := new(PodCondition)
+	out := new(ShutdownPolicy)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *PodConditionGRAState) DeepCopyInto(out *PodConditionGRAState) {
+func (in *StalledStatus) DeepCopyInto(out *StalledStatus) {
 	*out = *in
+	if in.Diagnostics != nil {
+		in, out := &in.Diagnostics, &out.Diagnostics
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PodConditionGRAState.
-func (in *PodConditionGRAState) DeepCopy() *PodConditionGRAState {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StalledStatus.
+func (in *StalledStatus) DeepCopy() *StalledStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(PodConditionGRAState)
+	out := new(StalledStatus)
 	in.DeepCopyInto(out)
 	return out
 }
@@ -254,3 +1610,132 @@ func (in *Storages) DeepCopy() *Storages {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SuspendPolicy) DeepCopyInto(out *SuspendPolicy) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SuspendPolicy.
+func (in *SuspendPolicy) DeepCopy() *SuspendPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(SuspendPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TeardownPolicy) DeepCopyInto(out *TeardownPolicy) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TeardownPolicy.
+func (in *TeardownPolicy) DeepCopy() *TeardownPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(TeardownPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TopologySpreadConstraint) DeepCopyInto(out *TopologySpreadConstraint) {
+	*out = *in
+	if in.LabelSelector != nil {
+		in, out := &in.LabelSelector, &out.LabelSelector
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(v1.LabelSelector)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TopologySpreadConstraint.
+func (in *TopologySpreadConstraint) DeepCopy() *TopologySpreadConstraint {
+	if in == nil {
+		return nil
+	}
+	out := new(TopologySpreadConstraint)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UpgradePolicy) DeepCopyInto(out *UpgradePolicy) {
+	*out = *in
+	if in.BackupMaxAge != nil {
+		in, out := &in.BackupMaxAge, &out.BackupMaxAge
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(v1.Duration)
+			**out = **in
+		}
+	}
+	if in.ISTWindow != nil {
+		in, out := &in.ISTWindow, &out.ISTWindow
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(v1.Duration)
+			**out = **in
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UpgradePolicy.
+func (in *UpgradePolicy) DeepCopy() *UpgradePolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(UpgradePolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WSREPStatus) DeepCopyInto(out *WSREPStatus) {
+	*out = *in
+	in.ClusterStatusSince.DeepCopyInto(&out.ClusterStatusSince)
+	in.LocalStateSince.DeepCopyInto(&out.LocalStateSince)
+	if in.SST != nil {
+		in, out := &in.SST, &out.SST
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(SSTProgress)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	if in.PendingRestart != nil {
+		in, out := &in.PendingRestart, &out.PendingRestart
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.InactivePlugins != nil {
+		in, out := &in.InactivePlugins, &out.InactivePlugins
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	in.Reported.DeepCopyInto(&out.Reported)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WSREPStatus.
+func (in *WSREPStatus) DeepCopy() *WSREPStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(WSREPStatus)
+	in.DeepCopyInto(out)
+	return out
+}
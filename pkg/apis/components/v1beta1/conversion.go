@@ -0,0 +1,168 @@
+package v1beta1
+
+import (
+	"github.com/dansksupermarked/mariadb-galera-operator/pkg/apis/components/v1alpha1"
+)
+
+// ConvertFrom returns the v1beta1 form of a v1alpha1 MariaDBCluster, every
+// field has a place in both versions so converting back gives it unchanged
+func ConvertFrom(in *v1alpha1.MariaDBCluster) *MariaDBCluster {
+	in = in.DeepCopy()
+	out := &MariaDBCluster{
+		TypeMeta:   in.TypeMeta,
+		ObjectMeta: in.ObjectMeta,
+		Status:     in.Status,
+	}
+	out.APIVersion = SchemeGroupVersion.String()
+	out.Spec = MariaDBClusterSpec{
+		Version: in.Spec.Version,
+		Image: ImageSpec{
+			Repository: in.Spec.Image,
+			Digest:     in.Spec.ImageDigest,
+			PinDigest:  in.Spec.PinImageDigest,
+			Init:       in.Spec.InitImage,
+			Agent:      in.Spec.AgentImage,
+			PullPolicy: in.Spec.ImagePullPolicy,
+		},
+		Paused:         in.Spec.Paused,
+		Replicas:       in.Spec.Replicas,
+		UpdateStrategy: in.Spec.UpdateStrategy,
+		Storages:       in.Spec.Storages,
+		Server: ServerSpec{
+			Resources:      in.Spec.Resources,
+			ConfigMapName:  in.Spec.ConfigMapName,
+			Config:         in.Spec.Config,
+			ExtraConfig:    in.Spec.ServerConfig,
+			BufferPool:     in.Spec.BufferPool,
+			Probes:         in.Spec.Probes,
+			InitSQL:        in.Spec.InitSQL,
+			Sidecars:       in.Spec.Sidecars,
+			InitContainers: in.Spec.InitContainers,
+			Volumes:        in.Spec.Volumes,
+			VolumeMounts:   in.Spec.VolumeMounts,
+			Env:            in.Spec.Env,
+			EnvFrom:        in.Spec.EnvFrom,
+			ServerSettings: in.Spec.Server,
+
+			ContainerResources: in.Spec.ContainerResources,
+			Shutdown:           in.Spec.Shutdown,
+			KernelTuning:       in.Spec.KernelTuning,
+		},
+		Galera: GaleraSpec{GaleraConfig: in.Spec.Galera, Recovery: in.Spec.Recovery},
+		Backup: BackupSpec{
+			RequiredBeforeUpgrade: in.Spec.Upgrade.RequireBackup,
+			TriggerBeforeUpgrade:  in.Spec.Upgrade.TriggerBackup,
+			MaxAge:                in.Spec.Upgrade.BackupMaxAge,
+		},
+		Upgrade: UpgradeSpec{
+			Strategy:          in.Spec.Upgrade.Strategy,
+			SkipProviderCheck: in.Spec.Upgrade.SkipProviderCheck,
+			PresizeGCache:     in.Spec.Upgrade.PresizeGCache,
+			ISTWindow:         in.Spec.Upgrade.ISTWindow,
+		},
+		Proxy:         ProxySpec{Enabled: in.Spec.Proxy, PriorityClassName: in.Spec.ProxyPriorityClassName},
+		Teardown:      in.Spec.Teardown,
+		ReclaimPolicy: in.Spec.ReclaimPolicy,
+		PhaseTimeouts: in.Spec.PhaseTimeouts,
+		Suspend:       in.Spec.Suspend,
+
+		InheritMetadata:    in.Spec.InheritMetadata,
+		PodMetadata:        in.Spec.PodMetadata,
+		SecurityProfiles:   in.Spec.SecurityProfiles,
+		ServiceAccountName: in.Spec.ServiceAccountName,
+		SkipRBAC:           in.Spec.SkipRBAC,
+		Maintenance:        in.Spec.Maintenance,
+		Scheduling: SchedulingSpec{
+			Affinity:                  in.Spec.Affinity,
+			TopologySpreadConstraints: in.Spec.TopologySpreadConstraints,
+			Tolerations:               in.Spec.Tolerations,
+			NodeSelector:              in.Spec.NodeSelector,
+			PriorityClassName:         in.Spec.PriorityClassName,
+		},
+		DisruptionBudget: in.Spec.DisruptionBudget,
+		Networking: NetworkingSpec{
+			HostAliases: in.Spec.HostAliases,
+			DNSPolicy:   in.Spec.DNSPolicy,
+			DNSConfig:   in.Spec.DNSConfig,
+		},
+	}
+	return out
+}
+
+// ConvertTo returns the v1alpha1 form of a MariaDBCluster, the one the
+// operator works with and that is stored
+func (in *MariaDBCluster) ConvertTo() *v1alpha1.MariaDBCluster {
+	in = in.DeepCopy()
+	out := &v1alpha1.MariaDBCluster{
+		TypeMeta:   in.TypeMeta,
+		ObjectMeta: in.ObjectMeta,
+		Status:     in.Status,
+	}
+	out.APIVersion = v1alpha1.SchemeGroupVersion.String()
+	out.Spec = v1alpha1.MariaDBClusterSpec{
+		Version:        in.Spec.Version,
+		ImageDigest:    in.Spec.Image.Digest,
+		PinImageDigest: in.Spec.Image.PinDigest,
+		Paused:         in.Spec.Paused,
+		Replicas:       in.Spec.Replicas,
+		ConfigMapName:  in.Spec.Server.ConfigMapName,
+		Resources:      in.Spec.Server.Resources,
+		Storages:       in.Spec.Storages,
+		ServerConfig:   in.Spec.Server.ExtraConfig,
+		Config:         in.Spec.Server.Config,
+		BufferPool:     in.Spec.Server.BufferPool,
+		Server:         in.Spec.Server.ServerSettings,
+		InitSQL:        in.Spec.Server.InitSQL,
+		Proxy:          in.Spec.Proxy.Enabled,
+		Upgrade: v1alpha1.UpgradePolicy{
+			RequireBackup:     in.Spec.Backup.RequiredBeforeUpgrade,
+			BackupMaxAge:      in.Spec.Backup.MaxAge,
+			TriggerBackup:     in.Spec.Backup.TriggerBeforeUpgrade,
+			SkipProviderCheck: in.Spec.Upgrade.SkipProviderCheck,
+			Strategy:          in.Spec.Upgrade.Strategy,
+			PresizeGCache:     in.Spec.Upgrade.PresizeGCache,
+			ISTWindow:         in.Spec.Upgrade.ISTWindow,
+		},
+		UpdateStrategy: in.Spec.UpdateStrategy,
+		Recovery:       in.Spec.Galera.Recovery,
+		Galera:         in.Spec.Galera.GaleraConfig,
+		Probes:         in.Spec.Server.Probes,
+		Shutdown:       in.Spec.Server.Shutdown,
+		KernelTuning:   in.Spec.Server.KernelTuning,
+		Teardown:       in.Spec.Teardown,
+		ReclaimPolicy:  in.Spec.ReclaimPolicy,
+		PhaseTimeouts:  in.Spec.PhaseTimeouts,
+		Suspend:        in.Spec.Suspend,
+
+		Image:           in.Spec.Image.Repository,
+		InitImage:       in.Spec.Image.Init,
+		AgentImage:      in.Spec.Image.Agent,
+		ImagePullPolicy: in.Spec.Image.PullPolicy,
+		InheritMetadata: in.Spec.InheritMetadata,
+		PodMetadata:     in.Spec.PodMetadata,
+
+		ServiceAccountName:        in.Spec.ServiceAccountName,
+		SkipRBAC:                  in.Spec.SkipRBAC,
+		Maintenance:               in.Spec.Maintenance,
+		SecurityProfiles:          in.Spec.SecurityProfiles,
+		Affinity:                  in.Spec.Scheduling.Affinity,
+		TopologySpreadConstraints: in.Spec.Scheduling.TopologySpreadConstraints,
+		Tolerations:               in.Spec.Scheduling.Tolerations,
+		NodeSelector:              in.Spec.Scheduling.NodeSelector,
+		DisruptionBudget:          in.Spec.DisruptionBudget,
+
+		PriorityClassName:      in.Spec.Scheduling.PriorityClassName,
+		ProxyPriorityClassName: in.Spec.Proxy.PriorityClassName,
+		Sidecars:               in.Spec.Server.Sidecars,
+		HostAliases:            in.Spec.Networking.HostAliases,
+		DNSPolicy:              in.Spec.Networking.DNSPolicy,
+		DNSConfig:              in.Spec.Networking.DNSConfig,
+		InitContainers:         in.Spec.Server.InitContainers,
+		Volumes:                in.Spec.Server.Volumes,
+		VolumeMounts:           in.Spec.Server.VolumeMounts,
+		ContainerResources:     in.Spec.Server.ContainerResources,
+		Env:                    in.Spec.Server.Env,
+		EnvFrom:                in.Spec.Server.EnvFrom,
+	}
+	return out
+}
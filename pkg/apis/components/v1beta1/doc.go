@@ -0,0 +1,33 @@
+/*
+Copyright 2017 The etcd-operator Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// +k8s:deepcopy-gen=package,register
+// +groupName=components.dsg.dk
+package v1beta1
+
+import "k8s.io/apimachinery/pkg/runtime/schema"
+
+const (
+	GroupName      string = "components.dsg.dk"
+	Version        string = "v1beta1"
+	ResourceKind          = "MariaDBCluster"
+	ResourcePlural        = "mariadbclusters"
+)
+
+var (
+	CRDName            = ResourcePlural + "." + GroupName
+	SchemeGroupVersion = schema.GroupVersion{Group: GroupName, Version: Version}
+)
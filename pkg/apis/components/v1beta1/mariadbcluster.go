@@ -0,0 +1,182 @@
+package v1beta1
+
+import (
+	"github.com/dansksupermarked/mariadb-galera-operator/pkg/apis/components/v1alpha1"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+type MariaDBClusterList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []MariaDBCluster `json:"items"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// MariaDBCluster is the v1alpha1 resource with its spec grouped by concern,
+// the status is the same in both versions
+type MariaDBCluster struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              MariaDBClusterSpec            `json:"spec"`
+	Status            v1alpha1.MariaDBClusterStatus `json:"status,omitempty"`
+}
+
+type MariaDBClusterSpec struct {
+	// MariaDB container/engine version, no less then 10.2.8
+	Version string `json:"version"`
+	// Pinning of the server image behind the version tag
+	Image ImageSpec `json:"image,omitempty"`
+	// Pause any control from operator on this resource
+	Paused   bool  `json:"paused,omitempty"`
+	Replicas int32 `json:"replicas"`
+	// How server pods pick up changes once the cluster is Operational, one of
+	// RollingUpdate (default), Operator or OnDelete
+	UpdateStrategy string            `json:"updateStrategy,omitempty"`
+	Storages       v1alpha1.Storages `json:"storages"`
+	// Server container, configuration and settings rendered by the operator
+	Server ServerSpec `json:"server,omitempty"`
+	// Galera replication and recovery of the cluster
+	Galera GaleraSpec `json:"galera,omitempty"`
+	// Backups taken ahead of upgrades
+	Backup BackupSpec `json:"backup,omitempty"`
+	// Policies applied when Version changes on a running cluster
+	Upgrade UpgradeSpec `json:"upgrade,omitempty"`
+	Proxy   ProxySpec   `json:"proxy,omitempty"`
+	// Steps run on deletion of the cluster, before its objects go away
+	Teardown v1alpha1.TeardownPolicy `json:"teardown,omitempty"`
+	// Retain or Delete the volumes, backups and Secret once the cluster is
+	// deleted, the retentionPolicy of a storage takes precedence
+	ReclaimPolicy string `json:"reclaimPolicy,omitempty"`
+	// Time the cluster may spend in a phase before it is reported as stalled,
+	// keyed by phase
+	PhaseTimeouts map[string]metav1.Duration `json:"phaseTimeouts,omitempty"`
+	// Automation of the operator held back while the cluster is otherwise
+	// reconciled, unlike Paused
+	Suspend v1alpha1.SuspendPolicy `json:"suspend,omitempty"`
+	// Labels and annotations stamped onto the objects and pods of the cluster
+	InheritMetadata v1alpha1.MetadataTemplate `json:"inheritMetadata,omitempty"`
+	// Labels and annotations of the server pods only
+	PodMetadata v1alpha1.MetadataTemplate `json:"podMetadata,omitempty"`
+	// Seccomp and AppArmor profiles of every pod of the cluster
+	SecurityProfiles v1alpha1.SecurityProfiles `json:"securityProfiles,omitempty"`
+	// Existing ServiceAccount of the cluster, and whether to skip its Role and
+	// RoleBinding
+	ServiceAccountName string `json:"serviceAccountName,omitempty"`
+	SkipRBAC           bool   `json:"skipRBAC,omitempty"`
+	// Health is reported but no corrective action taken
+	Maintenance bool `json:"maintenance,omitempty"`
+	// Placement of the server pods onto nodes
+	Scheduling SchedulingSpec `json:"scheduling,omitempty"`
+	// PodDisruptionBudget of the server pods, one of MaxUnavailable
+	// (default), Quorum or None
+	DisruptionBudget string `json:"disruptionBudget,omitempty"`
+	// Host aliases and DNS settings of the server pods
+	Networking NetworkingSpec `json:"networking,omitempty"`
+}
+
+type ImageSpec struct {
+	// Repository of the server image, tagged with the version
+	Repository string `json:"repository,omitempty"`
+	// Server image digest (sha256:...) pods are pinned to, regardless of the version tag
+	Digest string `json:"digest,omitempty"`
+	// Resolve the digest behind the version tag periodically and pin pods to it
+	PinDigest bool `json:"pinDigest,omitempty"`
+	// Images of the init and agent containers
+	Init  string `json:"init,omitempty"`
+	Agent string `json:"agent,omitempty"`
+	// Pull policy of every container run from the images above
+	PullPolicy v1.PullPolicy `json:"pullPolicy,omitempty"`
+}
+
+type ServerSpec struct {
+	Resources v1.ResourceRequirements `json:"resources,omitempty"`
+	// Resources of the initializer and agent containers
+	ContainerResources v1alpha1.ContainerResources `json:"containerResources,omitempty"`
+	// ConfigMap of the server configuration, named after the cluster unless set
+	ConfigMapName string `json:"configMapName,omitempty"`
+	// my.cnf fragment added to the server configuration, rendered as a Go
+	// template for each pod. It may not set keys the operator manages.
+	Config v1alpha1.ServerConfigSource `json:"config,omitempty"`
+	// mysqld settings rendered as they are into user.cnf, spec.serverConfig
+	// of v1alpha1
+	ExtraConfig string `json:"extraConfig,omitempty"`
+	// Sizing of the InnoDB buffer pool from the memory limit
+	BufferPool v1alpha1.BufferPoolPolicy `json:"bufferPool,omitempty"`
+	// Timings of the probes of the server container
+	Probes v1alpha1.ProbeSettings `json:"probes,omitempty"`
+	// Clean stop of the server container
+	Shutdown v1alpha1.ShutdownPolicy `json:"shutdown,omitempty"`
+	// Kernel parameters set or checked on the nodes of server pods
+	KernelTuning v1alpha1.KernelTuning `json:"kernelTuning,omitempty"`
+	// SQL scripts run once each, in order, once the cluster is Operational
+	InitSQL []v1alpha1.InitSQLScript `json:"initSQL,omitempty"`
+	// Containers of the user run alongside the server
+	Sidecars []v1.Container `json:"sidecars,omitempty"`
+	// Init containers of the user run in order before the initializer
+	InitContainers []v1.Container `json:"initContainers,omitempty"`
+	// Volumes of the user and their mounts into the containers of the operator
+	Volumes      []v1.Volume                     `json:"volumes,omitempty"`
+	VolumeMounts []v1alpha1.ContainerVolumeMount `json:"volumeMounts,omitempty"`
+	// Environment of the user set on the server and agent containers
+	Env     []v1.EnvVar        `json:"env,omitempty"`
+	EnvFrom []v1.EnvFromSource `json:"envFrom,omitempty"`
+	// Server defaults rendered by the operator, spec.server.config may not set them
+	v1alpha1.ServerSettings `json:",inline"`
+}
+
+type GaleraSpec struct {
+	v1alpha1.GaleraConfig `json:",inline"`
+	// Policies applied when the cluster lost all of its ready pods
+	Recovery v1alpha1.RecoveryPolicy `json:"recovery,omitempty"`
+}
+
+type BackupSpec struct {
+	// Hold upgrades until a successful backup of the current version exists
+	RequiredBeforeUpgrade bool `json:"requiredBeforeUpgrade,omitempty"`
+	// Start a backup Job ahead of an upgrade instead of waiting for one to appear
+	TriggerBeforeUpgrade bool `json:"triggerBeforeUpgrade,omitempty"`
+	// Age after which a backup is no longer considered fresh, defaults to 1h
+	MaxAge *metav1.Duration `json:"maxAge,omitempty"`
+}
+
+type UpgradeSpec struct {
+	// Rolling (default) restarts pods in place, BlueGreen builds a parallel
+	// cluster on the new version, replicates into it and switches services over
+	Strategy string `json:"strategy,omitempty"`
+	// Roll out without comparing galera providers of the current and new image
+	SkipProviderCheck bool `json:"skipProviderCheck,omitempty"`
+	// Grow gcache ahead of a rolling upgrade so that pods rejoin through IST
+	PresizeGCache bool `json:"presizeGCache,omitempty"`
+	// Time a pod is expected to be away during the upgrade, defaults to 10m
+	ISTWindow *metav1.Duration `json:"istWindow,omitempty"`
+}
+
+type SchedulingSpec struct {
+	// Affinity of the server pods, spreading them over nodes and zones unless
+	// set
+	Affinity *v1.Affinity `json:"affinity,omitempty"`
+	// Topology spread constraints of the server pods, spreading them over
+	// zones unless set
+	TopologySpreadConstraints []v1alpha1.TopologySpreadConstraint `json:"topologySpreadConstraints"`
+	// Tolerations and node selector of the server pods
+	Tolerations  []v1.Toleration   `json:"tolerations,omitempty"`
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+	// PriorityClass of the server pods
+	PriorityClassName string `json:"priorityClassName,omitempty"`
+}
+
+type NetworkingSpec struct {
+	HostAliases []v1.HostAlias   `json:"hostAliases,omitempty"`
+	DNSPolicy   v1.DNSPolicy     `json:"dnsPolicy,omitempty"`
+	DNSConfig   *v1.PodDNSConfig `json:"dnsConfig,omitempty"`
+}
+
+type ProxySpec struct {
+	Enabled bool `json:"enabled,omitempty"`
+	// PriorityClass of the proxy pods
+	PriorityClassName string `json:"priorityClassName,omitempty"`
+}
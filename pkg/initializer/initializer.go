@@ -1,27 +1,27 @@
 package initializer
 
 import (
-	"fmt"
 	"io/ioutil"
 	"os"
-	"os/exec"
 	"os/signal"
-	"reflect"
-	"regexp"
-	"strconv"
 	"syscall"
 	"time"
 
 	"github.com/Sirupsen/logrus"
+	"github.com/dansksupermarked/mariadb-galera-operator/pkg/agent"
 	components "github.com/dansksupermarked/mariadb-galera-operator/pkg/apis/components/v1alpha1"
 	componentsclientset "github.com/dansksupermarked/mariadb-galera-operator/pkg/generated/clientset/versioned"
-	"github.com/dansksupermarked/mariadb-galera-operator/pkg/util"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/rest"
 )
 
 const (
 	defaultKubeAPIRequestTimeout = 30 * time.Second
+	// time the operator gets to record the zone of a new pod
+	placementTimeout = 30 * time.Second
+	// agents refresh their report every 30s, without any from a primary
+	// component within this long the lineage of this pod is not checked
+	lineageTimeout = time.Minute
 )
 
 type Initializer struct {
@@ -33,6 +33,7 @@ type Initializer struct {
 	logger    *logrus.Entry
 	name      string
 	namespace string
+	color     string
 }
 
 func (i *Initializer) Run() {
@@ -49,6 +50,10 @@ func (i *Initializer) Run() {
 
 	i.name = os.Getenv("MARIADBCLUSTER_NAME")
 	i.namespace = os.Getenv("MARIADBCLUSTER_NAMESPACE")
+	i.color = os.Getenv("MARIADBCLUSTER_COLOR")
+	if i.color == "" {
+		i.color = components.ColorBlue
+	}
 	if i.Hostname, err = os.Hostname(); err != nil {
 		panic(err.Error())
 	}
@@ -65,56 +70,167 @@ func (i *Initializer) Run() {
 	i.componentsClient = componentsclientset.NewForConfigOrDie(i.clientConfig)
 
 	mdbc := i.getMariaDBCluster()
+	// a config check Job only has the configuration written for mysqld to parse
+	if os.Getenv("MARIADBCLUSTER_CONFIG_CHECK") != "" {
+		writeConfig(mdbc, i.color)
+		return
+	}
+	if mdbc.NeedsPlacement() && i.color == mdbc.GetActiveColor() {
+		mdbc = i.waitForPlacement(mdbc)
+	}
 
-	writeConfig(mdbc)
+	writeConfig(mdbc, i.color)
 
 	hostname, _ := os.Hostname()
 
-	if mdbc.Status.Phase == components.PhaseRecovery {
+	if mdbc.Status.Phase == components.PhaseOperational && i.color == mdbc.GetActiveColor() {
+		mdbc = i.waitForLineage(mdbc)
+	}
+
+	// Recovery is driven from the serving cluster only, a standby cluster of a
+	// blue/green upgrade is rebuilt from scratch instead
+	if mdbc.Status.Phase == components.PhaseRecovery && i.color == mdbc.GetActiveColor() {
 		// Hold on waiting for recovery stuff to happen
+		reporter, err := agent.NewReporter()
+		if err != nil {
+			panic(err.Error())
+		}
+		state := agent.ReadGRAState(i.logger)
 		for true {
-			i.logger.Debug("Recovery phase detected, reporting my condition to MariaDBCluster object")
-			i.reportToMariaDBCluster(mdbc)
+			i.logger.Debug("Recovery phase detected, reporting my position to MariaDBCluster object")
+			reporter.ReportRecovery(state)
 			time.Sleep(time.Second * 5)
 			mdbc = i.getMariaDBCluster()
-			if mdbc.Status.Stage == "PrimaryRecovered" {
+			if mdbc.Status.Phase != components.PhaseRecovery {
+				break
+			} else if mdbc.Status.GetStage() == components.StagePrimaryRecovered {
 				// Primary recovered, release from the stasis for cluster rejoin
-				mdbc.Status.StatefulSetPodConditions = nil
 				break
 			} else if hostname == mdbc.Status.BootstrapFrom {
 				// Marked for primary recovery, release and bootstrap new cluster
-				setSafeToBootstrap()
+				agent.SetSafeToBootstrap()
 				break
 			}
 		}
 	}
 
-	writeConfig(mdbc)
+	writeConfig(mdbc, i.color)
 }
 
-func setSafeToBootstrap() {
-	state := []byte(getStateString())
-	re := regexp.MustCompile(`(safe_to_bootstrap:.*)(0)`)
-	newState := re.ReplaceAll(state, []byte(`$1 1`))
-	ioutil.WriteFile("/var/lib/mysql/grastate.dat", newState, 0440)
+// waitForPlacement gives the operator some time to record the zone of this
+// pod, donors and segment are set without regard to zones when it does not show up
+func (i *Initializer) waitForPlacement(mdbc *components.MariaDBCluster) *components.MariaDBCluster {
+	deadline := time.Now().Add(placementTimeout)
+	for time.Now().Before(deadline) {
+		if _, ok := mdbc.Status.Placement[i.Hostname]; ok {
+			return mdbc
+		}
+		i.logger.Debug("waiting for the zone of this pod to be recorded")
+		time.Sleep(time.Second * 5)
+		mdbc = i.getMariaDBCluster()
+	}
+	i.logger.Warn("zone of this pod not recorded, it is not taken into account")
+	return mdbc
 }
 
-func writeConfig(mdbc *components.MariaDBCluster) {
+// waitForLineage holds this pod back while its data diverged from the running
+// primary component, as galera would replace it through SST on joining. That
+// is a different cluster UUID, or a higher seqno than the primary component
+// reached, in which case this pod holds transactions the others never got.
+// The pod joins once listed in Spec.Recovery.OverwriteDiverged.
+func (i *Initializer) waitForLineage(mdbc *components.MariaDBCluster) *components.MariaDBCluster {
+	reporter, err := agent.NewReporter()
+	if err != nil {
+		panic(err.Error())
+	}
+	start := metav1.Now()
+	var state *components.GRAState
+	for {
+		uuid, seqno, ok := reporter.PrimaryPosition(mdbc, i.color, start)
+		if !ok && time.Since(start.Time) > lineageTimeout {
+			i.logger.Info("no primary component reported, not checking lineage")
+			return mdbc
+		}
+		if ok {
+			if state == nil {
+				current := agent.ReadGRAState(i.logger)
+				state = &current
+			}
+			if !isDiverged(*state, uuid, seqno) {
+				reporter.ClearDivergence(mdbc)
+				return mdbc
+			}
+			if containsString(mdbc.Spec.Recovery.OverwriteDiverged, i.Hostname) {
+				i.logger.Warnf("joining %s:%d, data at %s:%d is replaced", uuid, seqno, state.UUID, state.SeqNo)
+				reporter.ClearDivergence(mdbc)
+				return mdbc
+			}
+			i.logger.Warnf("data at %s:%d diverged from primary component at %s:%d, not joining", state.UUID, state.SeqNo, uuid, seqno)
+			reporter.ReportDivergence(mdbc, *state, uuid, seqno)
+		}
+		time.Sleep(time.Second * 5)
+		mdbc = i.getMariaDBCluster()
+		if mdbc.Status.Phase != components.PhaseOperational {
+			return mdbc
+		}
+	}
+}
+
+// isDiverged tells whether a pod at given position can not join a primary
+// component at uuid and seqno without losing data. A pod without any state or
+// whose seqno is unknown joins as usual.
+func isDiverged(state components.GRAState, uuid string, seqno int64) bool {
+	if state.UUID == "" || state.UUID == "00000000-0000-0000-0000-000000000000" {
+		return false
+	}
+	return state.UUID != uuid || state.SeqNo > seqno
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func writeConfig(mdbc *components.MariaDBCluster, color string) {
 	var mdbConfig *components.MariaDBConfig
 	hostname, _ := os.Hostname()
-	if hostname == mdbc.Status.BootstrapFrom {
+	if hostname == mdbc.Status.BootstrapFrom && color == mdbc.GetActiveColor() {
 		mdbConfig = &components.MariaDBConfig{
-			Name:                 mdbc.GetServerName(),
+			Name:                 mdbc.GetServerNameForColor(color),
 			WSREPEndpoints:       nil,
-			WSREPProviderOptions: "pc.bootstrap=true",
+			WSREPProviderOptions: mdbc.GetWSREPProviderOptions(hostname, true),
 		}
 	} else {
 		mdbConfig = &components.MariaDBConfig{
-			Name:                 mdbc.GetServerName(),
-			WSREPEndpoints:       mdbc.GetWSREPEndpoints(),
-			WSREPProviderOptions: "",
+			Name:                 mdbc.GetServerNameForColor(color),
+			WSREPEndpoints:       mdbc.GetWSREPEndpointsForColor(color),
+			WSREPProviderOptions: mdbc.GetWSREPProviderOptions(hostname, false),
+			WSREPSSTDonor:        mdbc.GetSSTDonor(hostname, color),
 		}
 	}
+	mdbConfig.WSREPSSTReceiveAddress = mdbc.GetSSTReceiveAddress(hostname)
+	mdbConfig.CharacterSet = mdbc.Spec.Server.CharacterSet
+	mdbConfig.Collation = mdbc.Spec.Server.Collation
+	mdbConfig.SQLMode = mdbc.Spec.Server.SQLMode
+	mdbConfig.TimeZone = mdbc.Status.TimeZone
+	mdbConfig.PluginLibraries = mdbc.Spec.Server.GetPluginLibraries()
+	mdbConfig.InstrumentationOptions = mdbc.Spec.Server.GetInstrumentationOptions()
+	mdbConfig.InnoDBBufferPoolSizeMB, mdbConfig.InnoDBBufferPoolInstances = mdbc.GetBufferPoolSize()
+	binlog := mdbc.Spec.Server.Binlog
+	mdbConfig.BinlogFormat = binlog.GetFormat()
+	mdbConfig.LogBin = binlog.Enabled
+	mdbConfig.ServerID = binlog.GetServerID(hostname, color)
+	mdbConfig.ExpireLogsSeconds, mdbConfig.ExpireLogsDays = binlog.GetExpireLogs()
+	mdbConfig.LogSlaveUpdates = binlog.LogSlaveUpdates
+	// every pod may end up donor, so all of them carry the credentials
+	mdbConfig.WSREPSSTMethod = mdbc.Status.SSTMethod
+	if mdbConfig.WSREPSSTMethod == components.SSTMethodMariaBackup {
+		mdbConfig.WSREPSSTAuth = components.SSTUser + ":" + os.Getenv("MARIADBCLUSTER_SST_PASSWORD")
+	}
 
 	operatorCnf, err := mdbConfig.Render()
 	if err != nil {
@@ -131,6 +247,23 @@ func writeConfig(mdbc *components.MariaDBCluster) {
 	if err != nil {
 		panic(err.Error())
 	}
+	// the operator only copies a fragment rendering for every pod into
+	// status, failing here leaves the pod with the configuration it manages
+	config, err = mdbc.RenderServerConfig(mdbc.Status.ServerConfig, hostname)
+	if err != nil {
+		logrus.Errorf("Can't render spec.config : %s", err.Error())
+		config = ""
+	}
+	logrus.Debug(config)
+	err = ioutil.WriteFile("/etc/mysql/conf.d/config.cnf", []byte(config), 0444)
+	if err != nil {
+		panic(err.Error())
+	}
+	// not a .cnf, the server does not read it, the agent reports it
+	err = ioutil.WriteFile("/etc/mysql/conf.d/config-hash", []byte(mdbc.GetConfigHash(color)), 0444)
+	if err != nil {
+		panic(err.Error())
+	}
 }
 
 func (i *Initializer) getMariaDBCluster() *components.MariaDBCluster {
@@ -142,118 +275,6 @@ func (i *Initializer) getMariaDBCluster() *components.MariaDBCluster {
 	return mdbc
 }
 
-func (i *Initializer) reportToMariaDBCluster(mdbc *components.MariaDBCluster) {
-	current := i.getMariaDBCluster()
-	expected := current.DeepCopy()
-
-	version, uuid, seqno, safeToBootstrap := parseGRAState()
-	if seqno <= 0 {
-		// got some stupid SeqNo, proceed into recovery
-		uuidRec, seqnoRec, err := recoverGRAStateUuidSeqNo()
-		if err == nil && uuid == uuidRec {
-			seqno = seqnoRec
-		} else {
-			panic("UUID missmatch")
-		}
-	}
-
-	podCondition := components.PodCondition{
-		Hostname: i.Hostname,
-		Reported: metav1.Now(),
-		GRAState: components.PodConditionGRAState{Version: version, UUID: uuid, SeqNo: seqno, SafeToBootstrap: safeToBootstrap},
-	}
-	match := false
-	for k, v := range expected.Status.StatefulSetPodConditions {
-		if v.Hostname == podCondition.Hostname {
-			match = true
-			if !reflect.DeepEqual(v.GRAState, podCondition.GRAState) {
-				logrus.Debug("Entry found and status needs update")
-				expected.Status.StatefulSetPodConditions[k].Hostname = podCondition.Hostname
-				expected.Status.StatefulSetPodConditions[k].Reported = podCondition.Reported
-				expected.Status.StatefulSetPodConditions[k].GRAState = podCondition.GRAState
-			} else {
-				logrus.Debug("Entry found and no status update required")
-			}
-			break
-		}
-	}
-	if !match {
-		expected.Status.StatefulSetPodConditions = append(expected.Status.StatefulSetPodConditions, podCondition)
-	}
-	util.CheckAndPatchMariaDBCluster(current, expected, i.componentsClient.Components(), i.logger)
-}
-
-func recoverGRAStateUuidSeqNo() (string, int64, error) {
-	logrus.Debug("Recovering wsrep state")
-	cmd := exec.Command("su", "mysql", "-c", "/usr/sbin/mysqld --wsrep-recover")
-	out, _ := cmd.CombinedOutput()
-	re := regexp.MustCompile(`WSREP: Recovered position:\s*([0-9a-z-]*):(\d+)`)
-	result := re.FindStringSubmatch(string(out))
-	if len(result) > 1 {
-		seqno, _ := strconv.ParseInt(result[2], 10, 64)
-		return result[1], seqno, nil
-	}
-	return "", int64(0), fmt.Errorf("failed to recover")
-}
-
-func getStateString() string {
-	stateString, err := ioutil.ReadFile("/var/lib/mysql/grastate.dat")
-	if err != nil {
-		panic("missing grastate.dat : " + err.Error())
-	}
-	return string(stateString)
-}
-
-func parseGRAState() (string, string, int64, int) {
-	stateString := getStateString()
-	var safeToBootstrap int
-	var seqno int64
-	var uuid, version string
-	var re *regexp.Regexp
-	var result []string
-
-	logrus.Debug("stateString : " + stateString)
-
-	re = regexp.MustCompile(`version:\s*([0-9\.]*)`)
-	result = re.FindStringSubmatch(stateString)
-	if len(result) > 1 {
-		version = result[1]
-	} else {
-		panic("Version missing")
-	}
-
-	logrus.Debug("version " + version)
-
-	re = regexp.MustCompile(`uuid:\s*([A-Za-z0-9-]*)`)
-	result = re.FindStringSubmatch(stateString)
-	if len(result) > 1 {
-		uuid = result[1]
-	} else {
-		panic("UUID missing")
-	}
-	logrus.Debug("uuid " + uuid)
-
-	re = regexp.MustCompile(`seqno:\s*([-]?\d+)`)
-	result = re.FindStringSubmatch(stateString)
-	if len(result) > 1 {
-		seqno, _ = strconv.ParseInt(result[1], 10, 64)
-	} else {
-		panic("SeqNo missing")
-	}
-	logrus.Debug("version " + string(seqno))
-
-	re = regexp.MustCompile(`safe_to_bootstrap:\s*(\d)`)
-	result = re.FindStringSubmatch(string(stateString))
-	if len(result) > 1 {
-		safeToBootstrap, _ = strconv.Atoi(result[1])
-	} else {
-		logrus.Warn("safe_to_bootstrap missing")
-	}
-	logrus.Debug("safetoBootstrap " + string(safeToBootstrap))
-
-	return version, uuid, seqno, safeToBootstrap
-}
-
 func InClusterConfig() (*rest.Config, error) {
 	cfg, err := rest.InClusterConfig()
 	if err != nil {
@@ -0,0 +1,86 @@
+package initializer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/dansksupermarked/mariadb-galera-operator/pkg/agent"
+	components "github.com/dansksupermarked/mariadb-galera-operator/pkg/apis/components/v1alpha1"
+)
+
+// KernelTuner runs in the tune init container of server pods, ahead of the
+// initializer. It sets the sysctls it is given on the node in Apply mode, then
+// reports those the node does not have to the MariaDBCluster. It never fails
+// the pod, a node left as it is only gets reported.
+type KernelTuner struct {
+	logger *logrus.Entry
+}
+
+// Run sets or checks sysctls given as name=value
+func (t *KernelTuner) Run(sysctls []string) {
+	mode := os.Getenv("MARIADBCLUSTER_KERNEL_TUNING")
+	node := os.Getenv("MARIADBCLUSTER_NODE_NAME")
+	t.logger = logrus.WithField("namespace", os.Getenv("MARIADBCLUSTER_NAMESPACE")).WithField("name", os.Getenv("MARIADBCLUSTER_NAME")).WithField("node", node)
+
+	var differing []components.KernelSetting
+	for _, sysctl := range sysctls {
+		parts := strings.SplitN(sysctl, "=", 2)
+		if len(parts) != 2 {
+			t.logger.Warnf("ignoring %q, not a name=value sysctl", sysctl)
+			continue
+		}
+		name, expected := parts[0], normalizeSysctl(parts[1])
+		if mode == components.KernelTuningApply {
+			if err := writeSysctl(name, expected); err != nil {
+				t.logger.Errorf("Can't set %s : %s", name, err.Error())
+			}
+		}
+		// a sysctl this kernel does not have can not be told to differ
+		value, err := readSysctl(name)
+		if err != nil {
+			t.logger.Errorf("Can't read %s : %s", name, err.Error())
+			continue
+		}
+		if value != expected {
+			t.logger.Warnf("%s is %q, expected %q", name, value, expected)
+			differing = append(differing, components.KernelSetting{Name: name, Value: value, Expected: expected})
+		}
+	}
+
+	reporter, err := agent.NewReporter()
+	if err != nil {
+		t.logger.Errorf("Can't report kernel parameters : %s", err.Error())
+		return
+	}
+	current, err := reporter.Get()
+	if err != nil {
+		t.logger.Errorf("Error fetching object : %s", err.Error())
+		return
+	}
+	reporter.ReportKernel(current, node, differing)
+}
+
+func sysctlPath(name string) string {
+	return filepath.Join("/proc/sys", strings.Replace(name, ".", "/", -1))
+}
+
+// readSysctl returns the value of a sysctl with its fields separated by a
+// single space, as net.ipv4.tcp_rmem holding several of them
+func readSysctl(name string) (string, error) {
+	value, err := ioutil.ReadFile(sysctlPath(name))
+	if err != nil {
+		return "", err
+	}
+	return normalizeSysctl(string(value)), nil
+}
+
+func writeSysctl(name, value string) error {
+	return ioutil.WriteFile(sysctlPath(name), []byte(value+"\n"), 0644)
+}
+
+func normalizeSysctl(value string) string {
+	return strings.Join(strings.Fields(value), " ")
+}
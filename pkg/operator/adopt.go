@@ -0,0 +1,138 @@
+package operator
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	componentsv1alpha1 "github.com/dansksupermarked/mariadb-galera-operator/pkg/apis/components/v1alpha1"
+	"github.com/dansksupermarked/mariadb-galera-operator/pkg/util"
+	apps "k8s.io/api/apps/v1"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// noConflictingResources returns nil while a StatefulSet or ConfigMap the
+// cluster would create already exists without belonging to it, as reconciling
+// would overwrite it. With the adopt annotation set, such objects are taken over
+// instead when found before bootstrap starts and they fit the cluster. Otherwise
+// it returns the cluster as patched, with the kinds it adopted in status.
+func (c *Controller) noConflictingResources(cluster *componentsv1alpha1.MariaDBCluster) (*componentsv1alpha1.MariaDBCluster, error) {
+	logger := util.GetClusterLogger(cluster).WithField("kind", "MariaDBCluster").WithField("action", "adopt")
+	original := cluster
+	cluster = cluster.DeepCopy()
+	var conflicts []string
+	var adopt []metav1.Object
+	if sset, err := c.statefulsetLister.StatefulSets(cluster.Namespace).Get(cluster.GetServerStatefulSetName()); err == nil {
+		if reason := c.checkAdoption(cluster, sset, statefulSetMismatch(cluster, sset)); reason != "" {
+			conflicts = append(conflicts, "StatefulSet "+sset.Name+" "+reason)
+		} else if !metav1.IsControlledBy(sset, cluster) {
+			adopt = append(adopt, sset)
+		}
+	} else if !errors.IsNotFound(err) {
+		return nil, err
+	}
+	if cmap, err := c.configmapLister.ConfigMaps(cluster.Namespace).Get(cluster.GetServerConfigMapName()); err == nil {
+		if reason := c.checkAdoption(cluster, cmap, ""); reason != "" {
+			conflicts = append(conflicts, "ConfigMap "+cmap.Name+" "+reason)
+		} else if !metav1.IsControlledBy(cmap, cluster) {
+			adopt = append(adopt, cmap)
+		}
+	} else if !errors.IsNotFound(err) {
+		return nil, err
+	}
+
+	if len(conflicts) > 0 {
+		message := strings.Join(conflicts, ", ")
+		logger.WithField("event", "conflict").Warn(message)
+		if cond := cluster.Status.GetCondition(componentsv1alpha1.ConditionConflict); cond == nil || cond.Message != message {
+			c.recorder.Event(cluster, v1.EventTypeWarning, "ResourceConflict", message)
+		}
+		cluster.Status.SetCondition(componentsv1alpha1.ConditionConflict, true, "Conflict", message)
+		_, err := checkAndPatchMariaDBCluster(original, cluster, c.operator.ComponentsClient.Components(), logger)
+		return nil, err
+	}
+	for _, obj := range adopt {
+		if err := c.adopt(cluster, obj); err != nil {
+			// objects adopted before are recorded all the same
+			if _, patchErr := checkAndPatchMariaDBCluster(original, cluster, c.operator.ComponentsClient.Components(), logger); patchErr != nil {
+				return nil, patchErr
+			}
+			return nil, err
+		}
+	}
+	cluster.Status.RemoveCondition(componentsv1alpha1.ConditionConflict)
+	// an adopted StatefulSet not recorded in status would be bootstrapped
+	if _, err := checkAndPatchMariaDBCluster(original, cluster, c.operator.ComponentsClient.Components(), logger); err != nil {
+		return nil, err
+	}
+	return cluster, nil
+}
+
+// checkAdoption returns why an existing object can not be reconciled by the
+// cluster, empty when it belongs to it or can be adopted
+func (c *Controller) checkAdoption(cluster *componentsv1alpha1.MariaDBCluster, obj metav1.Object, mismatch string) string {
+	if metav1.IsControlledBy(obj, cluster) {
+		return ""
+	}
+	if ref := metav1.GetControllerOf(obj); ref != nil {
+		return "is controlled by " + ref.Kind + " " + ref.Name
+	}
+	if !cluster.IsAdoptionEnabled() {
+		return "already exists, set the " + componentsv1alpha1.MariaDBClusterAdoptAnnotation + " annotation to adopt it"
+	}
+	if phase := cluster.Status.Phase; phase != "" && phase != componentsv1alpha1.PhasePreFlight {
+		return "can only be adopted before bootstrap, the cluster is " + phase
+	}
+	return mismatch
+}
+
+// statefulSetMismatch returns how an existing StatefulSet differs from the
+// server StatefulSet in fields that can not be changed once created
+func statefulSetMismatch(cluster *componentsv1alpha1.MariaDBCluster, s *apps.StatefulSet) string {
+	labels := cluster.GetServerLabels()
+	if s.Spec.Selector == nil || len(s.Spec.Selector.MatchExpressions) > 0 || !reflect.DeepEqual(s.Spec.Selector.MatchLabels, labels) {
+		return fmt.Sprintf("does not select pods by %v", labels)
+	}
+	if s.Spec.ServiceName != cluster.GetServerServiceName() {
+		return "does not use Service " + cluster.GetServerServiceName()
+	}
+	if len(s.Spec.VolumeClaimTemplates) != 1 || s.Spec.VolumeClaimTemplates[0].Name != "data" {
+		return "does not have a single volume claim template named data"
+	}
+	return ""
+}
+
+// adopt sets the cluster as controller of an object, an adopted StatefulSet
+// also has the cluster skip bootstrap as its pods hold the data already
+func (c *Controller) adopt(cluster *componentsv1alpha1.MariaDBCluster, obj metav1.Object) error {
+	logger := util.GetClusterLogger(cluster).WithField("action", "adopt").WithField("name", obj.GetName())
+	owner := metav1.NewControllerRef(cluster, componentsv1alpha1.SchemeGroupVersion.WithKind(componentsv1alpha1.ResourceKind))
+	patch, err := json.Marshal(map[string]interface{}{"metadata": map[string]interface{}{
+		"ownerReferences": append(obj.GetOwnerReferences(), *owner),
+	}})
+	if err != nil {
+		return err
+	}
+	var kind string
+	switch obj.(type) {
+	case *apps.StatefulSet:
+		kind = "StatefulSet"
+		_, err = c.operator.Client.AppsV1().StatefulSets(cluster.Namespace).Patch(obj.GetName(), types.MergePatchType, patch)
+	case *v1.ConfigMap:
+		kind = "ConfigMap"
+		_, err = c.operator.Client.CoreV1().ConfigMaps(cluster.Namespace).Patch(obj.GetName(), types.MergePatchType, patch)
+	}
+	logger = logger.WithField("kind", kind)
+	if err != nil {
+		logger.Errorf("Patch failed with : %s", err.Error())
+		return err
+	}
+	logger.WithField("event", "adopted").Info()
+	c.recorder.Event(cluster, v1.EventTypeNormal, "Adopted", kind+" "+obj.GetName()+" adopted")
+	cluster.Status.Adopted = append(cluster.Status.Adopted, kind)
+	return nil
+}
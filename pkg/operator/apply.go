@@ -0,0 +1,99 @@
+package operator
+
+import (
+	"encoding/json"
+
+	"github.com/Sirupsen/logrus"
+	componentsv1alpha1 "github.com/dansksupermarked/mariadb-galera-operator/pkg/apis/components/v1alpha1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+)
+
+const (
+	// patch type of server-side apply, which the vendored apimachinery
+	// predates
+	applyPatchType = types.PatchType("application/apply-patch+yaml")
+	// manager of the fields the operator applies
+	fieldManager = name
+)
+
+// apply sends the fields the operator sets on an object through server-side
+// apply, creating the object when missing. Fields set by others are left as
+// they are, fields the operator sets are taken back from whoever changed them.
+func (o *Operator) apply(client rest.Interface, resource string, gvk schema.GroupVersionKind, obj metav1.Object, logger *logrus.Entry) error {
+	config, err := applyConfig(gvk, obj)
+	if err != nil {
+		return err
+	}
+	return o.patchApplied(client, resource, obj, config, logger)
+}
+
+// applyDrifted applies an object like apply does, returning the fields of it
+// someone else changed since it was last applied
+func (o *Operator) applyDrifted(client rest.Interface, resource string, gvk schema.GroupVersionKind, obj metav1.Object, logger *logrus.Entry) ([]string, error) {
+	config, err := applyConfig(gvk, obj)
+	if err != nil {
+		return nil, err
+	}
+	hash := renderedHash(config)
+	body, err := client.Get().Namespace(obj.GetNamespace()).Resource(resource).Name(obj.GetName()).Context(o.ctx).Do().Raw()
+	if err != nil && !apierrors.IsNotFound(err) {
+		logger.Errorf("Error fetching object : %s", err.Error())
+		return nil, err
+	}
+	var drifted []string
+	if err == nil {
+		current := map[string]interface{}{}
+		if err = json.Unmarshal(body, &current); err != nil {
+			return nil, err
+		}
+		metadata, _ := current["metadata"].(map[string]interface{})
+		annotations, _ := metadata["annotations"].(map[string]interface{})
+		if annotations[componentsv1alpha1.MariaDBClusterRenderedAnnotation] == hash {
+			drifted = driftedFields(config, current, "")
+		}
+	}
+	metadata := config["metadata"].(map[string]interface{})
+	annotations, _ := metadata["annotations"].(map[string]interface{})
+	if annotations == nil {
+		annotations = map[string]interface{}{}
+		metadata["annotations"] = annotations
+	}
+	annotations[componentsv1alpha1.MariaDBClusterRenderedAnnotation] = hash
+	return drifted, o.patchApplied(client, resource, obj, config, logger)
+}
+
+// patchApplied sends apply configuration of an object as fieldManager
+func (o *Operator) patchApplied(client rest.Interface, resource string, obj metav1.Object, config map[string]interface{}, logger *logrus.Entry) error {
+	body, err := json.Marshal(config)
+	if err != nil {
+		return err
+	}
+	err = client.Patch(applyPatchType).Namespace(obj.GetNamespace()).Resource(resource).Name(obj.GetName()).
+		Param("fieldManager", fieldManager).Param("force", "true").Body(body).Context(o.ctx).Do().Error()
+	if err != nil {
+		logger.Errorf("Apply failed with : %s", err.Error())
+		return err
+	}
+	logger.WithField("event", "applied").Debug()
+	return nil
+}
+
+// applyConfig returns an object rendered by a transform as apply
+// configuration, with its kind and without the status and creation timestamp
+// every object of the vendored types carries
+func applyConfig(gvk schema.GroupVersionKind, obj metav1.Object) (map[string]interface{}, error) {
+	config, err := toFields(obj)
+	if err != nil {
+		return nil, err
+	}
+	config["apiVersion"], config["kind"] = gvk.ToAPIVersionAndKind()
+	delete(config, "status")
+	if metadata, ok := config["metadata"].(map[string]interface{}); ok {
+		delete(metadata, "creationTimestamp")
+	}
+	return config, nil
+}
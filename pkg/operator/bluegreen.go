@@ -0,0 +1,163 @@
+package operator
+
+import (
+	"fmt"
+
+	componentsv1alpha1 "github.com/dansksupermarked/mariadb-galera-operator/pkg/apis/components/v1alpha1"
+	"github.com/dansksupermarked/mariadb-galera-operator/pkg/phase"
+	"github.com/dansksupermarked/mariadb-galera-operator/pkg/util"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// startBlueGreen records the start of a blue/green upgrade to the target
+// version, the standby objects are then created by reconcileCluster
+func (c *Controller) startBlueGreen(mdbc *componentsv1alpha1.MariaDBCluster) {
+	color := mdbc.GetStandbyColor()
+	util.GetClusterLogger(mdbc).WithField("action", "upgrade").WithField("event", "started").
+		Infof("building %s cluster on version %s", color, mdbc.Status.TargetVersion)
+	mdbc.Status.BlueGreen = &componentsv1alpha1.BlueGreenStatus{
+		Color:     color,
+		Version:   mdbc.Status.TargetVersion,
+		Stage:     componentsv1alpha1.BlueGreenStageProvisioning,
+		StartTime: metav1.Now(),
+	}
+	mdbc.Status.SetCondition(componentsv1alpha1.ConditionUpgrading, true, componentsv1alpha1.BlueGreenStageProvisioning,
+		fmt.Sprintf("building %s cluster on version %s", color, mdbc.Status.TargetVersion))
+}
+
+// runBlueGreen advances a blue/green upgrade by at most one stage per call.
+// Until clients are switched over the upgrade is aborted by reverting
+// Spec.Version, afterwards it runs to completion.
+func (c *Controller) runBlueGreen(mdbc *componentsv1alpha1.MariaDBCluster) error {
+	logger := util.GetClusterLogger(mdbc).WithField("action", "upgrade")
+	bg := mdbc.Status.BlueGreen
+	standbyName := mdbc.GetServerNameForColor(bg.Color)
+
+	// once writes may be fenced for the switch the upgrade runs to completion
+	if bg.Color != mdbc.GetActiveColor() && bg.Stage != componentsv1alpha1.BlueGreenStageSwitching && mdbc.GetVersion() != bg.Version {
+		logger.WithField("event", "aborted").Infof("version changed to %s, removing %s cluster", mdbc.GetVersion(), bg.Color)
+		if err := c.deleteServerColor(mdbc, bg.Color); err != nil {
+			return err
+		}
+		c.deleteJob(mdbc, mdbc.GetBlueGreenSyncJobName())
+		mdbc.Status.BlueGreen = nil
+		mdbc.Status.SetCondition(componentsv1alpha1.ConditionUpgrading, false, "Aborted",
+			"upgrade to "+bg.Version+" aborted, running version "+mdbc.Status.CurrentVersion)
+		return nil
+	}
+
+	switch bg.Stage {
+	case componentsv1alpha1.BlueGreenStageProvisioning:
+		sset, err := c.statefulsetLister.StatefulSets(mdbc.Namespace).Get(standbyName)
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				return nil
+			}
+			return err
+		}
+		if phase.StatefulSetReady(sset) {
+			c.setBlueGreenStage(mdbc, componentsv1alpha1.BlueGreenStageSyncing, "loading data into "+standbyName)
+		}
+
+	case componentsv1alpha1.BlueGreenStageSyncing:
+		name := mdbc.GetBlueGreenSyncJobName()
+		pod, failed, err := c.runCheckJob(mdbc, name, mdbc.BlueGreenSyncJobTransform)
+		if err != nil {
+			return err
+		}
+		if failed {
+			mdbc.Status.SetCondition(componentsv1alpha1.ConditionUpgrading, false, "SyncFailed",
+				"sync job "+name+" failed, delete it to retry or revert spec.version to abort")
+		} else if pod != nil {
+			c.setBlueGreenStage(mdbc, componentsv1alpha1.BlueGreenStageScaling, "scaling "+standbyName)
+		}
+
+	case componentsv1alpha1.BlueGreenStageScaling:
+		sset, err := c.statefulsetLister.StatefulSets(mdbc.Namespace).Get(standbyName)
+		if err != nil {
+			return err
+		}
+		if *sset.Spec.Replicas == mdbc.Spec.Replicas && phase.StatefulSetReady(sset) {
+			c.setBlueGreenStage(mdbc, componentsv1alpha1.BlueGreenStageSwitching, "fencing writes and switching clients over to "+standbyName)
+		}
+
+	case componentsv1alpha1.BlueGreenStageSwitching:
+		name := mdbc.GetBlueGreenSwitchJobName()
+		pod, failed, err := c.runCheckJob(mdbc, name, mdbc.BlueGreenSwitchJobTransform)
+		if err != nil {
+			return err
+		}
+		if failed {
+			mdbc.Status.SetCondition(componentsv1alpha1.ConditionUpgrading, true, "SwitchFailed",
+				"switch job "+name+" failed, writes to the serving cluster were let through again, delete it to retry: "+
+					c.jobFailureMessage(mdbc, name))
+			return nil
+		}
+		if pod == nil {
+			return nil
+		}
+		logger.WithField("event", "switched").Infof("%s cluster on version %s is now serving", bg.Color, bg.Version)
+		mdbc.Status.ActiveColor = bg.Color
+		mdbc.Status.CurrentVersion = bg.Version
+		mdbc.Status.TargetVersion = ""
+		c.setBlueGreenStage(mdbc, componentsv1alpha1.BlueGreenStageRetiring, "retiring "+mdbc.GetServerNameForColor(mdbc.GetStandbyColor()))
+
+	case componentsv1alpha1.BlueGreenStageRetiring:
+		name := mdbc.GetBlueGreenFinalizeJobName()
+		pod, failed, err := c.runCheckJob(mdbc, name, mdbc.BlueGreenFinalizeJobTransform)
+		if err != nil {
+			return err
+		}
+		if failed {
+			mdbc.Status.SetCondition(componentsv1alpha1.ConditionUpgrading, true, "FinalizeFailed",
+				"finalize job "+name+" failed, delete it to retry")
+			return nil
+		}
+		if pod == nil {
+			return nil
+		}
+		if err = c.deleteServerColor(mdbc, mdbc.GetStandbyColor()); err != nil {
+			return err
+		}
+		logger.WithField("event", "completed").Infof("running version %s", mdbc.Status.CurrentVersion)
+		mdbc.Status.BlueGreen = nil
+		mdbc.Status.SetCondition(componentsv1alpha1.ConditionUpgrading, false, "Completed", "running version "+mdbc.Status.CurrentVersion)
+	}
+	return nil
+}
+
+func (c *Controller) setBlueGreenStage(mdbc *componentsv1alpha1.MariaDBCluster, stage, message string) {
+	util.GetClusterLogger(mdbc).WithField("action", "upgrade").WithField("event", "stageTransition").Info(message)
+	mdbc.Status.BlueGreen.Stage = stage
+	mdbc.Status.SetCondition(componentsv1alpha1.ConditionUpgrading, true, stage, message)
+}
+
+// deleteServerColor removes the StatefulSet, headless Service and data volumes
+// of given color, so a later upgrade into that color starts from empty volumes
+func (c *Controller) deleteServerColor(mdbc *componentsv1alpha1.MariaDBCluster, color string) error {
+	logger := util.GetClusterLogger(mdbc).WithField("action", "delete")
+	propagation := metav1.DeletePropagationForeground
+	options := &metav1.DeleteOptions{PropagationPolicy: &propagation}
+	name := mdbc.GetServerNameForColor(color)
+	err := c.operator.Client.AppsV1().StatefulSets(mdbc.Namespace).Delete(name, options)
+	if err != nil && !apierrors.IsNotFound(err) {
+		logger.WithField("kind", "StatefulSet").Errorf("Deletion failed with : %s", err.Error())
+		return err
+	}
+	err = c.operator.Client.CoreV1().Services(mdbc.Namespace).Delete(mdbc.GetServerServiceNameForColor(color), &metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		logger.WithField("kind", "Service").Errorf("Deletion failed with : %s", err.Error())
+		return err
+	}
+	// claims created from volumeClaimTemplates carry the StatefulSet selector labels
+	selector := labels.SelectorFromSet(mdbc.GetServerLabelsForColor(color)).String()
+	err = c.operator.Client.CoreV1().PersistentVolumeClaims(mdbc.Namespace).DeleteCollection(&metav1.DeleteOptions{}, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		logger.WithField("kind", "PersistentVolumeClaim").Errorf("Deletion failed with : %s", err.Error())
+		return err
+	}
+	logger.WithField("event", "deleted").Infof("removed %s cluster", color)
+	return nil
+}
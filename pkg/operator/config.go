@@ -0,0 +1,268 @@
+package operator
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	componentsv1alpha1 "github.com/dansksupermarked/mariadb-galera-operator/pkg/apis/components/v1alpha1"
+	"github.com/dansksupermarked/mariadb-galera-operator/pkg/util"
+	apps "k8s.io/api/apps/v1"
+	batch "k8s.io/api/batch/v1"
+	"k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// checkServerConfig copies the fragment of Spec.Config into status once it
+// renders for every server pod without setting keys the operator manages.
+// Pods render status only, so a broken fragment or ConfigMap keeps the last
+// valid one in place, the ServerConfig condition telling what is wrong.
+func (c *Controller) checkServerConfig(mdbc *componentsv1alpha1.MariaDBCluster) error {
+	fragment := mdbc.Spec.Config.Inline
+	var invalid error
+	if ref := mdbc.Spec.Config.ConfigMapKeyRef; ref != nil {
+		optional := ref.Optional != nil && *ref.Optional
+		cmap, err := c.operator.Client.CoreV1().ConfigMaps(mdbc.Namespace).Get(ref.Name, metav1.GetOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			util.GetClusterLogger(mdbc).WithField("kind", "ConfigMap").WithField("action", "reconcile").Errorf("Error fetching object : %s", err.Error())
+			return err
+		}
+		var ok bool
+		if err != nil {
+			fragment = ""
+			if !optional {
+				invalid = fmt.Errorf("ConfigMap %s not found", ref.Name)
+			}
+		} else if fragment, ok = cmap.Data[ref.Key]; !ok && !optional {
+			invalid = fmt.Errorf("ConfigMap %s has no key %s", ref.Name, ref.Key)
+		}
+	}
+	if invalid == nil && fragment != "" {
+		invalid = mdbc.ValidateServerConfig(fragment)
+	}
+	if invalid != nil {
+		message := invalid.Error()
+		if cond := mdbc.Status.GetCondition(componentsv1alpha1.ConditionServerConfig); cond == nil || cond.Message != message {
+			util.GetClusterLogger(mdbc).WithField("action", "serverConfig").WithField("event", "invalid").Warn(message)
+			c.recorder.Event(mdbc, v1.EventTypeWarning, "InvalidServerConfig", message)
+		}
+		mdbc.Status.SetCondition(componentsv1alpha1.ConditionServerConfig, false, "Invalid", message)
+		return nil
+	}
+	mdbc.Status.ServerConfig = fragment
+	mdbc.Status.RemoveCondition(componentsv1alpha1.ConditionServerConfig)
+	return nil
+}
+
+// checkPendingRestart raises the ConfigPendingRestart condition while agents
+// report static options of spec.config their server did not start with. The
+// static config annotation has the pods restart for them, unless the update
+// strategy leaves that to someone else.
+func checkPendingRestart(mdbc *componentsv1alpha1.MariaDBCluster) {
+	var pending []string
+	for name, status := range mdbc.Status.WSREP {
+		color := status.Color
+		if color == "" {
+			color = componentsv1alpha1.ColorBlue
+		}
+		if color != mdbc.GetActiveColor() || len(status.PendingRestart) == 0 {
+			continue
+		}
+		pending = append(pending, name+" "+strings.Join(status.PendingRestart, ", "))
+	}
+	if len(pending) == 0 {
+		mdbc.Status.RemoveCondition(componentsv1alpha1.ConditionConfigPending)
+		return
+	}
+	sort.Strings(pending)
+	mdbc.Status.SetCondition(componentsv1alpha1.ConditionConfigPending, true, "StaticOptions",
+		"waiting for a restart to apply: "+strings.Join(pending, "; "))
+}
+
+// checkConfigDrift restarts a pod whose agent reports a configuration hash
+// other than the one its up to date StatefulSet rolls out, as when its
+// initializer read status right before a change. This is only done while every
+// pod is Synced in the primary component, one pod at a time, the ConfigDrift
+// condition listing the pods. With OnDelete pods are only reported.
+func (c *Controller) checkConfigDrift(mdbc *componentsv1alpha1.MariaDBCluster, sset *apps.StatefulSet) error {
+	expected := sset.Spec.Template.Annotations[componentsv1alpha1.MariaDBClusterConfigAnnotation]
+	pending := mdbc.GetConfigHash(mdbc.GetActiveColor())
+	var synced int32
+	var drifted []string
+	for name, status := range mdbc.Status.WSREP {
+		color := status.Color
+		if color == "" {
+			color = componentsv1alpha1.ColorBlue
+		}
+		if color != mdbc.GetActiveColor() || !mdbc.IsServerPod(name) {
+			continue
+		}
+		if status.ClusterStatus == componentsv1alpha1.WSREPClusterStatusPrimary && status.LocalState == syncedState {
+			synced++
+		}
+		// a pod restarted on a change still being checked can not be helped
+		if expected != "" && status.ConfigHash != "" && status.ConfigHash != expected && status.ConfigHash != pending {
+			drifted = append(drifted, name)
+		}
+	}
+	if len(drifted) == 0 {
+		mdbc.Status.RemoveCondition(componentsv1alpha1.ConditionConfigDrift)
+		return nil
+	}
+	sort.Strings(drifted)
+	mdbc.Status.SetCondition(componentsv1alpha1.ConditionConfigDrift, true, "HashMismatch",
+		fmt.Sprintf("not running configuration %s: %s", expected, strings.Join(drifted, ", ")))
+	if mdbc.GetUpdateStrategy() == componentsv1alpha1.UpdateStrategyOnDelete || synced < mdbc.Spec.Replicas {
+		return nil
+	}
+	name := drifted[0]
+	restarted, err := c.restartReportedPod(mdbc, name)
+	if err != nil || !restarted {
+		return err
+	}
+	message := fmt.Sprintf("%s started with a configuration other than %s, restarted it", name, expected)
+	util.GetClusterLogger(mdbc).WithField("action", "serverConfig").WithField("event", "restarted").Warn(message)
+	c.recorder.Event(mdbc, v1.EventTypeWarning, "ConfigDrift", message)
+	return nil
+}
+
+// checkConfigRollout runs a check Job on a configuration hash the StatefulSet
+// is about to roll to, StatefulSetTransform holding the change back until it
+// passed. A refused configuration raises the ConfigCheck condition with the
+// output of mysqld, its Job is kept until the configuration changes again.
+func (c *Controller) checkConfigRollout(mdbc *componentsv1alpha1.MariaDBCluster) error {
+	logger := util.GetClusterLogger(mdbc).WithField("action", "configCheck")
+	sset, err := c.statefulsetLister.StatefulSets(mdbc.Namespace).Get(mdbc.GetServerStatefulSetName())
+	if err != nil {
+		return nil
+	}
+	current := sset.Spec.Template.Annotations[componentsv1alpha1.MariaDBClusterConfigAnnotation]
+	hash := mdbc.GetConfigHash(mdbc.GetActiveColor())
+	name := mdbc.GetConfigCheckJobName(hash)
+	if err = c.deleteConfigCheckJobs(mdbc, name); err != nil {
+		return err
+	}
+	if current == "" || current == hash || hash == mdbc.Status.CheckedConfigHash {
+		mdbc.Status.RemoveCondition(componentsv1alpha1.ConditionConfigCheck)
+		return nil
+	}
+	pod, failed, err := c.runCheckJob(mdbc, name, func(job *batch.Job) error {
+		return mdbc.ConfigCheckJobTransform(job, hash)
+	})
+	if err != nil {
+		return err
+	}
+	if failed {
+		message := fmt.Sprintf("configuration %s refused, not rolling it out: %s", hash, c.jobFailureMessage(mdbc, name))
+		if cond := mdbc.Status.GetCondition(componentsv1alpha1.ConditionConfigCheck); cond == nil || cond.Reason != "Invalid" {
+			logger.WithField("event", "invalid").Warn(message)
+			c.recorder.Event(mdbc, v1.EventTypeWarning, "InvalidConfig", message)
+		}
+		mdbc.Status.SetCondition(componentsv1alpha1.ConditionConfigCheck, false, "Invalid", message)
+		return nil
+	}
+	if pod == nil {
+		mdbc.Status.SetCondition(componentsv1alpha1.ConditionConfigCheck, false, "Checking", "waiting for config check job "+name)
+		return nil
+	}
+	logger.WithField("event", "checked").Infof("configuration %s accepted, rolling it out", hash)
+	mdbc.Status.CheckedConfigHash = hash
+	mdbc.Status.RemoveCondition(componentsv1alpha1.ConditionConfigCheck)
+	return nil
+}
+
+// deleteConfigCheckJobs removes the check Jobs of other configurations than
+// the one of given Job name, left over from refused ones
+func (c *Controller) deleteConfigCheckJobs(mdbc *componentsv1alpha1.MariaDBCluster, keep string) error {
+	selector := mdbc.GetServerLabels()
+	selector[componentsv1alpha1.MariaDBClusterRoleLabel] = componentsv1alpha1.MariaDBClusterConfigCheckRole
+	jobs, err := c.operator.Client.BatchV1().Jobs(mdbc.Namespace).List(metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(selector).String(),
+	})
+	if err != nil {
+		util.GetClusterLogger(mdbc).WithField("kind", "Job").WithField("action", "reconcile").Errorf("Error fetching object : %s", err.Error())
+		return err
+	}
+	for _, job := range jobs.Items {
+		if job.Name != keep {
+			c.deleteJob(mdbc, job.Name)
+		}
+	}
+	return nil
+}
+
+// resolveTimeZone copies Spec.Server.TimeZone into status for pods to render,
+// a named one only once the time zone tables are loaded as mysqld would not
+// start otherwise
+func resolveTimeZone(mdbc *componentsv1alpha1.MariaDBCluster) {
+	server := mdbc.Spec.Server
+	if server.TimeZone == "" || !server.NeedsTimeZoneTables() || mdbc.Status.TimeZonesLoaded {
+		mdbc.Status.TimeZone = server.TimeZone
+	}
+}
+
+// loadTimeZones runs the Job loading the time zone tables into every pod once
+// the cluster is ready and Spec.Server asks for them. Pods joining later get
+// them through state transfers. A failed Job is reported by the TimeZone
+// condition and kept until deleted, which has it run again.
+func (c *Controller) loadTimeZones(mdbc *componentsv1alpha1.MariaDBCluster) error {
+	if !mdbc.Spec.Server.NeedsTimeZoneTables() || mdbc.Status.TimeZonesLoaded {
+		return nil
+	}
+	logger := util.GetClusterLogger(mdbc).WithField("action", "timeZones")
+	name := mdbc.GetTimeZoneJobName()
+	pod, failed, err := c.runCheckJob(mdbc, name, mdbc.TimeZoneJobTransform)
+	if err != nil {
+		return err
+	}
+	if failed {
+		message := "loading time zone tables failed, delete job " + name + " to retry: " + c.jobFailureMessage(mdbc, name)
+		if cond := mdbc.Status.GetCondition(componentsv1alpha1.ConditionTimeZone); cond == nil || cond.Reason != "LoadFailed" {
+			logger.WithField("event", "failed").Warn(message)
+			c.recorder.Event(mdbc, v1.EventTypeWarning, "TimeZoneLoadFailed", message)
+		}
+		mdbc.Status.SetCondition(componentsv1alpha1.ConditionTimeZone, false, "LoadFailed", message)
+		return nil
+	}
+	if pod == nil {
+		mdbc.Status.SetCondition(componentsv1alpha1.ConditionTimeZone, false, "Loading", "waiting for time zone job "+name)
+		return nil
+	}
+	logger.WithField("event", "loaded").Info("time zone tables loaded")
+	c.recorder.Event(mdbc, v1.EventTypeNormal, "TimeZonesLoaded", "time zone tables loaded on every pod")
+	mdbc.Status.TimeZonesLoaded = true
+	mdbc.Status.RemoveCondition(componentsv1alpha1.ConditionTimeZone)
+	resolveTimeZone(mdbc)
+	return nil
+}
+
+// checkPlugins raises the Plugins condition while agents of pods running the
+// configuration of the StatefulSet report plugins of spec.server that are not
+// active, with a Warning Event whenever the list changes
+func (c *Controller) checkPlugins(mdbc *componentsv1alpha1.MariaDBCluster, sset *apps.StatefulSet) {
+	expected := sset.Spec.Template.Annotations[componentsv1alpha1.MariaDBClusterConfigAnnotation]
+	var inactive []string
+	for name, status := range mdbc.Status.WSREP {
+		color := status.Color
+		if color == "" {
+			color = componentsv1alpha1.ColorBlue
+		}
+		if color != mdbc.GetActiveColor() || status.ConfigHash != expected || len(status.InactivePlugins) == 0 {
+			continue
+		}
+		inactive = append(inactive, name+" "+strings.Join(status.InactivePlugins, ", "))
+	}
+	if len(inactive) == 0 {
+		mdbc.Status.RemoveCondition(componentsv1alpha1.ConditionPlugins)
+		return
+	}
+	sort.Strings(inactive)
+	message := "plugins not active: " + strings.Join(inactive, "; ")
+	if cond := mdbc.Status.GetCondition(componentsv1alpha1.ConditionPlugins); cond == nil || cond.Message != message {
+		util.GetClusterLogger(mdbc).WithField("action", "plugins").WithField("event", "inactive").Warn(message)
+		c.recorder.Event(mdbc, v1.EventTypeWarning, "PluginsInactive", message)
+	}
+	mdbc.Status.SetCondition(componentsv1alpha1.ConditionPlugins, false, "Inactive", message)
+}
@@ -0,0 +1,118 @@
+package operator
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+
+	componentsclientset "github.com/dansksupermarked/mariadb-galera-operator/pkg/generated/clientset/versioned"
+	apiextensionsclientset "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/flowcontrol"
+)
+
+// reconcileTimeout bounds the requests of syncing a cluster, along with
+// recording its outcome, on top of the timeout of each of them
+const reconcileTimeout = 2 * time.Minute
+
+// contextConfig returns the configuration of clients sending requests through
+// the transport of config, and all of them through the same rate limiter, so
+// that clients bound to a context per sync reuse its connections and share
+// the rate of requests of the operator
+func contextConfig(config *rest.Config) (*rest.Config, error) {
+	transport, err := rest.TransportFor(config)
+	if err != nil {
+		return nil, err
+	}
+	qps, burst := config.QPS, config.Burst
+	if qps == 0 {
+		qps = rest.DefaultQPS
+	}
+	if burst == 0 {
+		burst = rest.DefaultBurst
+	}
+	return &rest.Config{
+		Host:          config.Host,
+		APIPath:       config.APIPath,
+		ContentConfig: config.ContentConfig,
+		UserAgent:     config.UserAgent,
+		Transport:     transport,
+		RateLimiter:   flowcontrol.NewTokenBucketRateLimiter(qps, burst),
+		Timeout:       config.Timeout,
+	}, nil
+}
+
+// withContext returns a copy of the operator whose clients bind every request
+// to ctx, as the calls of the vendored clients take no context. The operator
+// itself is returned when it was not started with clients of its own.
+func (op *Operator) withContext(ctx context.Context) *Operator {
+	if op.contextConfig == nil {
+		return op
+	}
+	bound := op.bindContext(ctx)
+	if op.dryRun == op {
+		bound.dryRun = bound
+	} else {
+		bound.dryRun = op.dryRun.bindContext(ctx)
+		bound.dryRun.dryRun = bound
+	}
+	return bound
+}
+
+func (op *Operator) bindContext(ctx context.Context) *Operator {
+	bound := *op
+	config := rest.CopyConfig(op.contextConfig)
+	config.Transport = &contextTransport{ctx: ctx, rt: op.contextConfig.Transport}
+	bound.Client = kubernetes.NewForConfigOrDie(config)
+	bound.ComponentsClient = componentsclientset.NewForConfigOrDie(config)
+	bound.ApiExtensionsClient = apiextensionsclientset.NewForConfigOrDie(config)
+	bound.ctx = ctx
+	return &bound
+}
+
+// withContext returns a copy of the controller whose requests are bound to ctx
+func (c *Controller) withContext(ctx context.Context) *Controller {
+	bound := *c
+	bound.operator = c.operator.withContext(ctx)
+	return &bound
+}
+
+// contextTransport cancels the requests it sends once its context is done, on
+// top of the context each of them carries, as the one of its timeout
+type contextTransport struct {
+	ctx context.Context
+	rt  http.RoundTripper
+}
+
+func (t *contextTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, cancel := context.WithCancel(req.Context())
+	go func() {
+		select {
+		case <-t.ctx.Done():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	resp, err := t.rt.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	// the body is read after RoundTrip returned, the request is done once
+	// it is closed
+	resp.Body = &cancelingBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+type cancelingBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
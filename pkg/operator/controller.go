@@ -1,21 +1,29 @@
 package operator
 
 import (
+	"context"
 	"fmt"
+	"sync"
 
 	"github.com/Sirupsen/logrus"
 	componentsv1alpha1 "github.com/dansksupermarked/mariadb-galera-operator/pkg/apis/components/v1alpha1"
+	componentsscheme "github.com/dansksupermarked/mariadb-galera-operator/pkg/generated/clientset/versioned/scheme"
 	componentinformers "github.com/dansksupermarked/mariadb-galera-operator/pkg/generated/informers/externalversions"
 	listers "github.com/dansksupermarked/mariadb-galera-operator/pkg/generated/listers/components/v1alpha1"
+	"github.com/dansksupermarked/mariadb-galera-operator/pkg/phase"
 	"github.com/dansksupermarked/mariadb-galera-operator/pkg/util"
 	apps "k8s.io/api/apps/v1"
+	"k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/scheme"
 	appslisters "k8s.io/client-go/listers/apps/v1"
 	corelisters "k8s.io/client-go/listers/core/v1"
+	policylisters "k8s.io/client-go/listers/policy/v1beta1"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/workqueue"
 )
 
@@ -34,6 +42,15 @@ type Controller struct {
 	statefulsetSynced     cache.InformerSynced
 	mariadbclustersLister listers.MariaDBClusterLister
 	mariadbclustersSynced cache.InformerSynced
+	// pods of clusters only, see registerTrimmedInformers
+	podLister corelisters.PodLister
+	podSynced cache.InformerSynced
+	// budgets of server pods, told apart from missing ones when deleting them
+	pdbLister policylisters.PodDisruptionBudgetLister
+	// secrets without their data but the report key, see trimObject
+	secretLister corelisters.SecretLister
+	// other kinds of children, only watched to queue their cluster
+	childrenSynced []cache.InformerSynced
 
 	// workqueue is a rate limited work queue. This is used to queue work to be
 	// processed instead of performing it as soon as a change happens. This
@@ -41,13 +58,19 @@ type Controller struct {
 	// time, and makes it easy to ensure we are never processing the same item
 	// simultaneously in two different workers.
 	workqueue workqueue.RateLimitingInterface
-	stopChan  chan struct{}
+	stopChan  <-chan struct{}
+	// recorder publishes Events on MariaDBCluster objects
+	recorder record.EventRecorder
 }
 
 func NewController(op *Operator, kubeInformerFactory informers.SharedInformerFactory, componentsInformerFactory componentinformers.SharedInformerFactory) *Controller {
+	op.registerTrimmedInformers(kubeInformerFactory)
 	statefulsetInformer := kubeInformerFactory.Apps().V1().StatefulSets()
 	configmapInformer := kubeInformerFactory.Core().V1().ConfigMaps()
 	mariaInformer := componentsInformerFactory.Components().V1alpha1().MariaDBClusters()
+	podInformer := kubeInformerFactory.Core().V1().Pods()
+	pdbInformer := kubeInformerFactory.Policy().V1beta1().PodDisruptionBudgets()
+	secretInformer := kubeInformerFactory.Core().V1().Secrets()
 	c := &Controller{
 		operator:              op,
 		configmapLister:       configmapInformer.Lister(),
@@ -56,8 +79,15 @@ func NewController(op *Operator, kubeInformerFactory informers.SharedInformerFac
 		statefulsetSynced:     statefulsetInformer.Informer().HasSynced,
 		mariadbclustersLister: mariaInformer.Lister(),
 		mariadbclustersSynced: mariaInformer.Informer().HasSynced,
-		workqueue:             workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "MariaDBClusters"),
+		podLister:             podInformer.Lister(),
+		podSynced:             podInformer.Informer().HasSynced,
+		pdbLister:             pdbInformer.Lister(),
+		secretLister:          secretInformer.Lister(),
+		workqueue:             workqueue.NewNamedRateLimitingQueue(op.rateLimiter(), "MariaDBClusters"),
 	}
+	// Events reference MariaDBCluster objects, so the recorder needs their kind
+	componentsscheme.AddToScheme(scheme.Scheme)
+	c.recorder = createRecorder(op.Client, op.Name, metav1.NamespaceAll, op.EventDedupWindow)
 
 	logrus.Info("Adding event handlers for MariaDBClusters informer")
 	mariaInformer.Informer().AddEventHandler(
@@ -67,6 +97,14 @@ func NewController(op *Operator, kubeInformerFactory informers.SharedInformerFac
 			DeleteFunc: c.MariaDBClusterDeleteEventHandler,
 		})
 
+	logrus.Info("Adding event handlers for ConfigMap informer")
+	configmapInformer.Informer().AddEventHandler(
+		cache.ResourceEventHandlerFuncs{
+			AddFunc:    c.ConfigMapAddEventHandler,
+			UpdateFunc: c.ConfigMapUpdateEventHandler,
+			DeleteFunc: c.ConfigMapDeleteEventHandler,
+		})
+
 	logrus.Info("Adding event handlers for StatefulSet informer")
 	statefulsetInformer.Informer().AddEventHandler(
 		cache.ResourceEventHandlerFuncs{
@@ -75,30 +113,68 @@ func NewController(op *Operator, kubeInformerFactory informers.SharedInformerFac
 			DeleteFunc: c.StatefulSetDeleteEventHandler,
 		})
 
+	logrus.Info("Adding event handlers for Service, Secret, ServiceAccount, Role, RoleBinding, PersistentVolumeClaim and PodDisruptionBudget informers")
+	for _, informer := range []cache.SharedIndexInformer{
+		kubeInformerFactory.Core().V1().Services().Informer(),
+		secretInformer.Informer(),
+		kubeInformerFactory.Core().V1().ServiceAccounts().Informer(),
+		kubeInformerFactory.Rbac().V1().Roles().Informer(),
+		kubeInformerFactory.Rbac().V1().RoleBindings().Informer(),
+		kubeInformerFactory.Core().V1().PersistentVolumeClaims().Informer(),
+		pdbInformer.Informer(),
+	} {
+		informer.AddEventHandler(
+			cache.ResourceEventHandlerFuncs{
+				AddFunc:    c.ChildAddEventHandler,
+				UpdateFunc: c.ChildUpdateEventHandler,
+				DeleteFunc: c.ChildDeleteEventHandler,
+			})
+		c.childrenSynced = append(c.childrenSynced, informer.HasSynced)
+	}
+
+	logrus.Info("Adding event handlers for Pod informer")
+	podInformer.Informer().AddEventHandler(
+		cache.ResourceEventHandlerFuncs{
+			AddFunc:    c.PodAddEventHandler,
+			UpdateFunc: c.PodUpdateEventHandler,
+			DeleteFunc: c.PodDeleteEventHandler,
+		})
+
 	return c
 }
 
-func (c *Controller) WaitForCacheSync() {
-	if ok := cache.WaitForCacheSync(c.stopChan, c.statefulsetSynced, c.configmapSynced, c.mariadbclustersSynced); !ok {
-		panic("Failed to sync cache")
-	}
+// WaitForCacheSync returns false when stopped before the caches synced
+func (c *Controller) WaitForCacheSync() bool {
+	synced := append([]cache.InformerSynced{c.statefulsetSynced, c.configmapSynced, c.mariadbclustersSynced, c.podSynced}, c.childrenSynced...)
+	return cache.WaitForCacheSync(c.stopChan, synced...)
 }
 
 func (c *Controller) MariaDBClusterEnqueue(obj interface{}) error {
 	mdb := obj.(*componentsv1alpha1.MariaDBCluster)
 	logrus.WithFields(logrus.Fields{"cluster": mdb.Namespace + "/" + mdb.Name}).Debugf("Adding MariaDBCluster to workqueue")
 	key, err := cache.MetaNamespaceKeyFunc(obj)
-	c.workqueue.AddRateLimited(key)
-	return err
+	if err != nil {
+		return err
+	}
+	// the rate limiter only backs off keys that failed, see retry
+	c.enqueue(key)
+	return nil
 }
 
-func (c *Controller) processNextFromQueue() error {
+// processNextFromQueue syncs the next key of the queue, returning false once
+// the queue is shut down and drained
+func (c *Controller) processNextFromQueue() bool {
 	obj, shutdown := c.workqueue.Get()
 	if shutdown {
-		return nil
+		return false
 	}
 	err := func(obj interface{}) error {
 		defer c.workqueue.Done(obj)
+		// the requests of the sync are let finish on termination, as the
+		// workers drain the queue
+		ctx, cancel := context.WithTimeout(context.Background(), reconcileTimeout)
+		defer cancel()
+		c := c.withContext(ctx)
 		var key string
 		var ok bool
 		if key, ok = obj.(string); !ok {
@@ -111,18 +187,20 @@ func (c *Controller) processNextFromQueue() error {
 		}
 		// Run the syncHandler, passing it the namespace/name string of the
 		// Foo resource to be synced.
-		if err := c.syncHandler(key); err != nil {
+		if err := c.syncRecovered(key); err != nil {
+			c.retry(key, err)
 			return fmt.Errorf("error syncing '%s': %s", key, err.Error())
 		}
 		// Finally, if no error occurs we Forget this item so it does not
 		// get queued again until another change happens.
 		c.workqueue.Forget(obj)
+		c.clearReconcileFailed(key)
 		return nil
 	}(obj)
 	if err != nil {
-		return fmt.Errorf("Ooops somthing failed processing a work item")
+		runtime.HandleError(err)
 	}
-	return nil
+	return true
 }
 
 func (c *Controller) syncHandler(key string) error {
@@ -144,212 +222,292 @@ func (c *Controller) syncHandler(key string) error {
 		return err
 	}
 
-	c.reconcileCluster(cluster)
-	return nil
+	// the lister hands out the object of the shared informer cache, which
+	// reconciliation edits in place
+	if c.operator.DryRun || cluster.IsDryRun() {
+		return c.dryRunCluster(cluster.DeepCopy())
+	}
+	return c.reconcileCluster(cluster.DeepCopy())
 }
 
-func (c *Controller) noConflictingResources(cluster *componentsv1alpha1.MariaDBCluster) bool {
-	var resources string
-	var err error
-	_, err = c.statefulsetLister.StatefulSets(cluster.Namespace).Get(cluster.Name)
-	if !errors.IsNotFound(err) {
-		resources = resources + " StatefulSet"
+// reconcileCluster returns the first error met reconciling the objects of a
+// cluster, the others are still reconciled
+func (c *Controller) reconcileCluster(cluster *componentsv1alpha1.MariaDBCluster) error {
+	if cluster.DeletionTimestamp != nil {
+		err := c.teardown(cluster.DeepCopy())
+		if err != nil {
+			util.GetClusterLogger(cluster).WithField("action", "teardown").Errorf("Teardown failed with : %s", err.Error())
+		}
+		return err
 	}
-	_, err = c.configmapLister.ConfigMaps(cluster.Namespace).Get(cluster.Name)
-	if !errors.IsNotFound(err) {
-		resources = resources + " ConfigMap"
+	if cluster.Spec.Paused {
+		c.pause(cluster.DeepCopy())
+		return nil
 	}
-
-	if resources == "" {
-		return true
-	} else {
-		logrus.Debugf("Found conflicting resources : %s", resources)
-		return false
+	// reconciled from here on with the adoption status just patched
+	cluster, err := c.noConflictingResources(cluster)
+	if cluster == nil || err != nil {
+		return err
 	}
-}
-
-func (c *Controller) reconcileCluster(cluster *componentsv1alpha1.MariaDBCluster) {
-	c.reconcileMariaDBCluster(cluster)
+	errs := []error{c.reconcileMariaDBCluster(cluster)}
 	pvc := cluster.GetSnapshotPVC()
-	reconcile(c.operator.Client.CoreV1(), cluster, pvc)
-	c.operator.reconcileServerServiceAccount(cluster)
-	c.operator.reconcileServerRole(cluster)
-	c.operator.reconcileServerRoleBinding(cluster)
-	// c.operator.reconcileServerConfigMap(cluster)
-	c.operator.reconcileStatefulSet(cluster)
-	c.operator.reconcileServerService(cluster)
-	c.operator.reconcileProxyService(cluster)
+	errs = append(errs, reconcile(c.operator.Client.CoreV1(), cluster, pvc))
+	if cluster.Spec.ServiceAccountName == "" {
+		errs = append(errs, c.operator.reconcileServerServiceAccount(cluster))
+	}
+	if !cluster.Spec.SkipRBAC {
+		errs = append(errs, c.operator.reconcileServerRole(cluster))
+		errs = append(errs, c.operator.reconcileServerRoleBinding(cluster))
+	}
+	errs = append(errs, c.operator.reconcileServerSecret(cluster))
+	errs = append(errs, c.operator.reconcileClientSecret(cluster))
+	edited, err := c.operator.reconcileServerConfigMap(cluster)
+	c.reportDrift(cluster, "ConfigMap", cluster.GetServerConfigMapName(), edited)
+	errs = append(errs, err)
+	drifted, err := c.operator.reconcileServerStatefulSet(cluster)
+	c.reportDrift(cluster, "StatefulSet", cluster.GetServerStatefulSetName(), drifted)
+	errs = append(errs, err)
+	errs = append(errs, c.operator.reconcileDataVolumeOwners(cluster))
+	errs = append(errs, c.reconcileServerPodDisruptionBudget(cluster))
+	drifted, err = c.operator.reconcileServerService(cluster)
+	c.reportDrift(cluster, "Service", cluster.GetServerServiceName(), drifted)
+	errs = append(errs, err)
+	if bg := cluster.Status.BlueGreen; bg != nil && bg.Color != cluster.GetActiveColor() {
+		drifted, err = c.operator.reconcileStandbyStatefulSet(cluster)
+		c.reportDrift(cluster, "StatefulSet", cluster.GetServerNameForColor(bg.Color), drifted)
+		errs = append(errs, err)
+		drifted, err = c.operator.reconcileStandbyServerService(cluster)
+		c.reportDrift(cluster, "Service", cluster.GetServerServiceNameForColor(bg.Color), drifted)
+		errs = append(errs, err)
+	}
+	drifted, err = c.operator.reconcileProxyService(cluster)
+	c.reportDrift(cluster, "Service", cluster.GetProxyServiceName(), drifted)
+	errs = append(errs, err)
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return c.observeGeneration(cluster)
 }
 
-type Patch []PatchSpec
-
-type PatchSpec struct {
-	Op    string `json:"op"`
-	Path  string `json:"path"`
-	Value string `json:"value"`
+// observeGeneration records the generation of a cluster all of whose objects
+// were reconciled, so that clients waiting on a spec change can tell it was
+// taken into account
+func (c *Controller) observeGeneration(mdbc *componentsv1alpha1.MariaDBCluster) error {
+	if mdbc.Status.ObservedGeneration == mdbc.Generation {
+		return nil
+	}
+	logger := util.GetClusterLogger(mdbc).WithField("kind", "MariaDBCluster").WithField("action", "reconcile")
+	expected := mdbc.DeepCopy()
+	expected.Status.ObservedGeneration = mdbc.Generation
+	logger.WithField("event", "observed").Debugf("generation %d", mdbc.Generation)
+	_, err := checkAndPatchMariaDBCluster(mdbc, expected, c.operator.ComponentsClient.Components(), logger)
+	return err
 }
 
 func (c *Controller) syncWorker() {
-	for {
-		c.processNextFromQueue()
+	for c.processNextFromQueue() {
 	}
 }
 
-func (c *Controller) Run() {
-	c.WaitForCacheSync()
-	go c.syncWorker()
+// Run starts workers processing the queue once the caches synced, the queue
+// hands a key to a single worker at a time. Once stop is closed the queue is
+// shut down, Run returns when the workers finished the keys left in it.
+func (c *Controller) Run(workers int, stop <-chan struct{}) {
+	c.stopChan = stop
+	if !c.WaitForCacheSync() {
+		logrus.Info("Stopped before caches synced")
+		c.workqueue.ShutDown()
+		return
+	}
+	if workers < 1 {
+		workers = 1
+	}
+	logrus.Infof("Starting %d workers", workers)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.syncWorker()
+		}()
+	}
+	<-stop
+	logrus.Info("Shutting down workers")
+	c.workqueue.ShutDown()
+	wg.Wait()
 }
 
 // check if any criteria for state transition are met
 func (c *Controller) MariaDBClusterTransform(mdbc *componentsv1alpha1.MariaDBCluster) error {
 	logger := logrus.WithField("kind", "MariaDBCluster")
 	logger.Debug("Detected " + mdbc.Status.Phase + " Phase, checking transitions")
-	// Start cluster bootstrap if phase is empty
-	switch mdbc.Status.Phase {
-
-	case "":
-		mdbc.Status.Phase = componentsv1alpha1.PhasePreFlight
-
-	case componentsv1alpha1.PhasePreFlight:
-		// TODO : implement preflight checks verifying the definition of cluster, naming collisions etc.
-		mdbc.Status.Phase = componentsv1alpha1.PhaseBootstrapFirst
-
-	// First phase of bootstrap, starting the cluster with --wsrep-cluster-new
-	case componentsv1alpha1.PhaseBootstrapFirst:
-		sset, err := c.statefulsetLister.StatefulSets(mdbc.Namespace).Get(mdbc.GetServerName())
-		if err == nil {
-			if mdbc.Spec.Replicas > 1 &&
-				isStatefulSetReady(sset) {
-				logger.WithField("event", "phaseTransition").Info("Transitioning to BootstrapFirstRestart phase")
-				mdbc.Status.Phase = componentsv1alpha1.PhaseBootstrapFirstRestart
-				mdbc.Status.StatefulSetObservedGeneration = sset.Status.ObservedGeneration
-			}
-		}
-
-	// Restart loosing --wsrep-cluster-new so we do not wipe cluster IP
-	// TODO : move this phase into initialiser internal logic
-	case componentsv1alpha1.PhaseBootstrapFirstRestart:
-		sset, _ := c.statefulsetLister.StatefulSets(mdbc.Namespace).Get(mdbc.GetServerName())
-		if mdbc.Spec.Replicas > 1 &&
-			isStatefulSetUpdated(mdbc, sset) &&
-			isStatefulSetReady(sset) {
-			logger.WithField("event", "phaseTransition").Info("Transitioning to BootstrapSecond phase")
-			mdbc.Status.Phase = componentsv1alpha1.PhaseBootstrapSecond
-			mdbc.Status.StatefulSetObservedGeneration = sset.Status.ObservedGeneration
-		}
-
-	// Bootstrap second node of galera cluster
-	case componentsv1alpha1.PhaseBootstrapSecond:
-		sset, _ := c.statefulsetLister.StatefulSets(mdbc.Namespace).Get(mdbc.GetServerName())
-		if mdbc.Spec.Replicas > 2 &&
-			isStatefulSetUpdated(mdbc, sset) &&
-			isStatefulSetReady(sset) {
-			logger.WithField("event", "phaseTransition").Info("Transitioning to BootstrapSecond phase")
-			mdbc.Status.Phase = componentsv1alpha1.PhaseBootstrapThird
-			mdbc.Status.StatefulSetObservedGeneration = sset.Status.ObservedGeneration
-		}
-
-	// Bootstrap third node of galera cluster
-	case componentsv1alpha1.PhaseBootstrapThird:
-		sset, _ := c.statefulsetLister.StatefulSets(mdbc.Namespace).Get(mdbc.GetServerName())
-		if mdbc.Spec.Replicas > 2 &&
-			isStatefulSetUpdated(mdbc, sset) &&
-			isStatefulSetReady(sset) {
-			logger.WithField("event", "phaseTransition").Info("Transitioning to BootstrapSecond phase")
-			mdbc.Status.Phase = componentsv1alpha1.PhaseOperational
-			mdbc.Status.StatefulSetObservedGeneration = sset.Status.ObservedGeneration
-		}
-		// Detect unhealthy state
-	case componentsv1alpha1.PhaseOperational:
-		sset, _ := c.statefulsetLister.StatefulSets(mdbc.Namespace).Get(mdbc.GetServerName())
-		if sset.Status.ReadyReplicas == 0 {
-			mdbc.Status.Phase = componentsv1alpha1.PhaseRecovery
-		} else if isStatefulSetReady(sset) {
-			mdbc.Status.Stage = componentsv1alpha1.StageSynced
-		}
-
-	case componentsv1alpha1.PhaseRecovery:
-		// A bootstrap pod has been indicated, parse status of the pod to verify
-		// if it bootstrapped successfully (indicated by readiness probe success)
-		// when successfull, transition to PrimaryRecovered stage of Recovery Phase
-		if mdbc.Status.BootstrapFrom != "" {
-			pod, err := c.operator.Client.Core().Pods(mdbc.Namespace).Get(mdbc.Status.BootstrapFrom, metav1.GetOptions{})
-			if err != nil {
-				return err
-			}
-			var ready bool
-			ready = true
-			for _, status := range pod.Status.ContainerStatuses {
-				if !status.Ready {
-					ready = false
-				}
-			}
-			if ready {
-				// Bootstrap pod is alive and ready, remove bootstrap indicator and start joining others
-				mdbc.Status.Stage = componentsv1alpha1.StagePrimaryRecovered
-				mdbc.Status.BootstrapFrom = ""
-			}
-			return nil
+	if err := c.verifyReports(mdbc); err != nil {
+		return err
+	}
+	if err := c.recordPlacement(mdbc); err != nil {
+		return err
+	}
+	c.checkISTAddresses(mdbc)
+	c.checkKernelTuning(mdbc)
+	if err := c.checkServerConfig(mdbc); err != nil {
+		return err
+	}
+	checkPendingRestart(mdbc)
+	resolveTimeZone(mdbc)
+	if mdbc.Status.Phase == componentsv1alpha1.PhaseOperational {
+		if err := c.checkConfigRollout(mdbc); err != nil {
+			return err
 		}
-
-		// Transition to operational if Primary Component is recovered
-		// so that other galera cluster nodes can join new primary
-		if mdbc.Status.Stage == componentsv1alpha1.StagePrimaryRecovered {
-			sset, err := c.statefulsetLister.StatefulSets(mdbc.Namespace).Get(mdbc.GetServerName())
-			if err != nil {
-				logger.Error(err.Error())
+	}
+	sset, err := c.statefulsetLister.StatefulSets(mdbc.Namespace).Get(mdbc.GetServerStatefulSetName())
+	if err != nil {
+		sset = nil
+	}
+	in := phase.Input{
+		Phase:                         mdbc.Status.Phase,
+		Stage:                         mdbc.Status.Stage,
+		StatefulSetObservedGeneration: mdbc.Status.StatefulSetObservedGeneration,
+		Replicas:                      mdbc.Spec.Replicas,
+		ParallelJoin:                  mdbc.IsFeatureEnabled(componentsv1alpha1.FeatureParallelJoin),
+		AdoptedStatefulSet:            mdbc.Status.IsAdopted("StatefulSet"),
+		RecoverySuspended:             recoverySuspended(mdbc),
+		StatefulSet:                   sset,
+		ServerPods:                    c.serverPods(mdbc),
+	}
+	out := phase.Next(in)
+	for _, action := range out.Actions {
+		switch action {
+		case phase.ActionRecordVersion:
+			// TODO : implement preflight checks verifying the definition of cluster, naming collisions etc.
+			mdbc.Status.CurrentVersion = mdbc.GetVersion()
+		case phase.ActionSkipBootstrap:
+			c.recorder.Event(mdbc, v1.EventTypeNormal, "BootstrapSkipped", "adopted StatefulSet "+mdbc.GetServerStatefulSetName()+" already runs the cluster")
+		case phase.ActionStartBootstrap:
+			recordBootstrap(mdbc, mdbc.GetServerStatefulSetName()+"-0", componentsv1alpha1.PhaseBootstrapFirst, "", -1)
+		case phase.ActionObserveStatefulSet:
+			mdbc.Status.StatefulSetObservedGeneration = out.StatefulSetObservedGeneration
+		case phase.ActionReportRecoveryHeld:
+			message := "no ready pods left, not recovering as recovery is suspended"
+			if !mdbc.Spec.Suspend.Recovery {
+				message = "no ready pods left, not recovering during maintenance"
 			}
-		        if sset.Status.ReadyReplicas == 0 {
-			        mdbc.Status.Phase = componentsv1alpha1.PhaseRecovery
-				mdbc.Status.Stage = ""
-
-		        } else if isStatefulSetReady(sset) {
-				mdbc.Status.Phase = componentsv1alpha1.PhaseOperational
-				mdbc.Status.Stage = componentsv1alpha1.StageDegraded
-				mdbc.Status.StatefulSetPodConditions = nil
-				mdbc.Status.BootstrapFrom = ""
+			util.GetClusterLogger(mdbc).WithField("action", "recovery").WithField("event", "suspended").Warn(message)
+			c.recorder.Event(mdbc, v1.EventTypeWarning, "RecoverySuspended", message)
+		case phase.ActionHoldRecovery:
+			mdbc.Status.Stage = componentsv1alpha1.StageDegraded
+		case phase.ActionStartRecovery:
+			if c.checkPCBootstrap(mdbc) {
+				return nil
 			}
+			logger.WithField("event", "phaseTransition").Infof("%s, transitioning to %s phase", out.Reason, out.Phase)
+			startRecovery(mdbc, out.Reason)
 			return nil
-		}
-
-		// Check if all pods reported their conditions and select the most advanced one
-		reported := int32(len(mdbc.Status.StatefulSetPodConditions))
-		if mdbc.Spec.Replicas > 1 {
-			if reported == mdbc.Spec.Replicas {
-				var maxSeqNoHostname string
-				var maxSeqNoValue, minSeqNoValue int64
-				maxSeqNoValue = -1
-				minSeqNoValue = -1
-				for _, v := range mdbc.Status.StatefulSetPodConditions {
-					if v.GRAState.SeqNo > maxSeqNoValue {
-						maxSeqNoValue = v.GRAState.SeqNo
-						maxSeqNoHostname = v.Hostname
-					} else {
-						minSeqNoValue = v.GRAState.SeqNo
-					}
-				}
-				// Select bootstrap node only if all nodes reported positive values
-				// to avoid risk of missing out on the most advanced node
-				if minSeqNoValue > 0 && maxSeqNoValue > 0 {
-					mdbc.Status.BootstrapFrom = maxSeqNoHostname
-				} else {
-					mdbc.Status.Stage = componentsv1alpha1.StageInvalidReport
-				}
+		case phase.ActionCheckHealth:
+			if done, err := c.checkHealth(mdbc); err != nil || done {
+				return err
 			}
+		case phase.ActionCheckSynced:
+			return c.checkSynced(mdbc, sset)
+		case phase.ActionCheckRollout:
+			return c.checkRollout(mdbc, sset)
+		case phase.ActionRecover:
+			return c.recoverCluster(mdbc)
 		}
 	}
+	if out.Phase != in.Phase {
+		logger.WithField("event", "phaseTransition").Infof("%s, transitioning to %s phase", out.Reason, out.Phase)
+		mdbc.Status.Phase = out.Phase
+	}
 	return nil
 }
 
-func isStatefulSetUpdated(mdbc *componentsv1alpha1.MariaDBCluster, sset *apps.StatefulSet) bool {
-	return sset.Status.ObservedGeneration > mdbc.Status.StatefulSetObservedGeneration
+// checkHealth runs the checks of an Operational cluster with ready pods,
+// returning true when one of them took the cluster over, as on a split brain
+func (c *Controller) checkHealth(mdbc *componentsv1alpha1.MariaDBCluster) (bool, error) {
+	if split, err := c.checkSplitBrain(mdbc); err != nil || split {
+		return true, err
+	}
+	trackLineage(mdbc)
+	if err := c.checkNonPrimary(mdbc); err != nil {
+		return true, err
+	}
+	if err := c.checkSST(mdbc); err != nil {
+		return true, err
+	}
+	if err := c.checkUnsynced(mdbc); err != nil {
+		return true, err
+	}
+	c.checkDivergence(mdbc)
+	c.checkFlowControl(mdbc)
+	return false, nil
+}
+
+// checkSynced runs the checks of an Operational cluster whose pods are all
+// ready and updated
+func (c *Controller) checkSynced(mdbc *componentsv1alpha1.MariaDBCluster, sset *apps.StatefulSet) error {
+	mdbc.Status.Stage = componentsv1alpha1.StageSynced
+	mdbc.Status.SSTExcludedDonors = nil
+	if mdbc.IsInMaintenance() {
+		return nil
+	}
+	if err := c.checkSSTMethod(mdbc); err != nil {
+		return err
+	}
+	c.checkGCacheSize(mdbc)
+	if err := c.checkConfigDrift(mdbc, sset); err != nil {
+		return err
+	}
+	if err := c.loadTimeZones(mdbc); err != nil {
+		return err
+	}
+	c.checkPlugins(mdbc, sset)
+	if err := c.runInitSQL(mdbc); err != nil {
+		return err
+	}
+	mdbc.Status.RemoveCondition(componentsv1alpha1.ConditionUpdatePending)
+	if err := c.checkImageDigest(mdbc); err != nil {
+		return err
+	}
+	return c.checkUpgrade(mdbc, sset)
 }
 
-func isStatefulSetReady(sset *apps.StatefulSet) bool {
-	return *sset.Spec.Replicas == sset.Status.CurrentReplicas &&
-		*sset.Spec.Replicas == sset.Status.Replicas &&
-		*sset.Spec.Replicas == sset.Status.ReadyReplicas &&
-		sset.Status.CurrentRevision == sset.Status.UpdateRevision
+// summarizeStatus sets the ready pods and the primary shown by kubectl get
+func (c *Controller) summarizeStatus(mdbc *componentsv1alpha1.MariaDBCluster) {
+	mdbc.Status.ReadyReplicas = 0
+	if sset, err := c.statefulsetLister.StatefulSets(mdbc.Namespace).Get(mdbc.GetServerStatefulSetName()); err == nil {
+		mdbc.Status.ReadyReplicas = sset.Status.ReadyReplicas
+	}
+	var primary string
+	var seqno int64
+	for name, status := range mdbc.Status.WSREP {
+		color := status.Color
+		if color == "" {
+			color = componentsv1alpha1.ColorBlue
+		}
+		if color != mdbc.GetActiveColor() || !mdbc.IsServerPod(name) || status.ClusterStatus != componentsv1alpha1.WSREPClusterStatusPrimary {
+			continue
+		}
+		if primary == "" || status.LastCommitted > seqno || (status.LastCommitted == seqno && podOrdinal(name) < podOrdinal(primary)) {
+			primary, seqno = name, status.LastCommitted
+		}
+	}
+	mdbc.Status.Primary = primary
+}
+
+// reportConnection publishes where clients connect once the cluster serves,
+// it is kept through later phases as the Services stay in place
+func reportConnection(mdbc *componentsv1alpha1.MariaDBCluster) {
+	if mdbc.Status.Phase != componentsv1alpha1.PhaseOperational {
+		return
+	}
+	mdbc.Status.Connection = &componentsv1alpha1.ConnectionStatus{
+		PrimaryHost: mdbc.GetPrimaryHost(),
+		ReaderHost:  mdbc.GetReaderHost(),
+		Port:        componentsv1alpha1.DefaultMySQLPort,
+		SecretName:  mdbc.GetClientSecretName(),
+	}
 }
 
 func (c *Controller) reconcileMariaDBCluster(mdbc *componentsv1alpha1.MariaDBCluster) error {
@@ -357,7 +515,26 @@ func (c *Controller) reconcileMariaDBCluster(mdbc *componentsv1alpha1.MariaDBClu
 	logger.WithField("event", "started").Debug()
 	defer logger.WithField("event", "finished").Debug()
 	original := mdbc.DeepCopy()
-	c.MariaDBClusterTransform(mdbc)
-	checkAndPatchMariaDBCluster(original, mdbc, c.operator.ComponentsClient.Components(), logger)
-	return nil
+	resumeStalledStage(mdbc)
+	err := c.MariaDBClusterTransform(mdbc)
+	// a failed timeout check is retried like a failed reconcile, the status
+	// gathered so far is patched all the same
+	if timeoutErr := c.checkPhaseTimeout(mdbc, original.Status.Phase); timeoutErr != nil {
+		logger.WithField("action", "phaseTimeout").Errorf("Checking phase timeout failed with : %s", timeoutErr.Error())
+		if err == nil {
+			err = timeoutErr
+		}
+	}
+	c.summarizeStatus(mdbc)
+	recordTransition(original, mdbc)
+	reportSuspended(mdbc)
+	c.reportMaintenance(mdbc)
+	reportFeatures(mdbc)
+	reportConnection(mdbc)
+	ensureFinalizer(mdbc)
+	ensureStorageVersion(mdbc)
+	if _, patchErr := checkAndPatchMariaDBCluster(original, mdbc, c.operator.ComponentsClient.Components(), logger); patchErr != nil && err == nil {
+		err = patchErr
+	}
+	return err
 }
@@ -1,28 +1,160 @@
 package operator
 
 import (
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"strings"
 
 	"github.com/Sirupsen/logrus"
 	mariadbv1alpha1 "github.com/dansksupermarked/mariadb-galera-operator/pkg/apis/components/v1alpha1"
+	"github.com/dansksupermarked/mariadb-galera-operator/pkg/webhook"
 	apiextensionsv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/yaml"
 )
 
+// EnsureSupportedCRDs creates the CustomResourceDefinition of crdManifest when
+// there is none. An existing one is only updated to it with UpgradeCRDs. An
+// operator watching a single namespace leaves it alone.
 func (op *Operator) EnsureSupportedCRDs() error {
-	crds := mariadbv1alpha1.GetCRDs()
-	for _, crd := range crds {
-		_, err := op.ApiExtensionsClient.ApiextensionsV1beta1().CustomResourceDefinitions().Create(crd)
-		if apierrors.IsAlreadyExists(err) {
+	if op.Namespace != "" {
+		if op.UpgradeCRDs {
+			logrus.Warnf("watching namespace %s only, not upgrading the CRD", op.Namespace)
+		}
+		logrus.Infof("watching namespace %s only, the CRD is to be installed beforehand", op.Namespace)
+		return nil
+	}
+	expected, err := op.expectedCRD()
+	if err != nil {
+		panic(err)
+	}
+	err = op.createCRD(expected)
+	if apierrors.IsAlreadyExists(err) {
+		if !op.UpgradeCRDs {
 			logrus.Info("CRD already exists, not creating but ok to pass")
-		} else if err != nil {
-			// if err != nil {
+		} else if err = op.upgradeCRD(expected); err != nil {
 			panic(err)
 		}
-		op.WaitCRDReady(mariadbv1alpha1.CRDName)
-		return nil
+	} else if err != nil {
+		panic(err)
+	}
+	op.WaitCRDReady(mariadbv1alpha1.CRDName)
+	return nil
+}
+
+// expectedCRD decodes crdManifest and sets its conversion. Without a
+// ConversionService only the storage version is served, objects of the others
+// could not be converted.
+func (op *Operator) expectedCRD() (map[string]interface{}, error) {
+	body, err := yaml.ToJSON([]byte(crdManifest))
+	if err != nil {
+		return nil, err
+	}
+	crd := map[string]interface{}{}
+	if err = json.Unmarshal(body, &crd); err != nil {
+		return nil, err
+	}
+	spec := crd["spec"].(map[string]interface{})
+	if op.ConversionService == "" {
+		for _, version := range spec["versions"].([]interface{}) {
+			version := version.(map[string]interface{})
+			version["served"] = version["storage"]
+		}
+		spec["conversion"] = map[string]interface{}{"strategy": "None"}
+		return crd, nil
+	}
+	parts := strings.SplitN(op.ConversionService, "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, fmt.Errorf("conversion service %q is not namespace/name", op.ConversionService)
+	}
+	var caBundle []byte
+	if op.ConversionCAFile != "" {
+		if caBundle, err = ioutil.ReadFile(op.ConversionCAFile); err != nil {
+			return nil, err
+		}
+	}
+	spec["conversion"] = map[string]interface{}{
+		"strategy": "Webhook",
+		"webhookClientConfig": map[string]interface{}{
+			"service":  map[string]interface{}{"namespace": parts[0], "name": parts[1], "path": webhook.ConvertPath},
+			"caBundle": caBundle,
+		},
+	}
+	return crd, nil
+}
+
+// createCRD creates a CustomResourceDefinition, posted as plain JSON as the
+// typed client would drop what its types do not know of
+func (op *Operator) createCRD(crd map[string]interface{}) error {
+	body, err := json.Marshal(crd)
+	if err != nil {
+		return err
+	}
+	return op.ApiExtensionsClient.ApiextensionsV1beta1().RESTClient().Post().Resource("customresourcedefinitions").Body(body).Context(op.ctx).Do().Error()
+}
+
+// upgradeCRD replaces an existing CustomResourceDefinition with the expected
+// one. Versions objects are still stored in stay served, as the API server
+// refuses to drop them, and a conversion webhook registered by hand is kept
+// when the operator was not given one.
+func (op *Operator) upgradeCRD(expected map[string]interface{}) error {
+	logger := logrus.WithField("kind", "CustomResourceDefinition").WithField("action", "upgrade")
+	client := op.ApiExtensionsClient.ApiextensionsV1beta1().RESTClient()
+	body, err := client.Get().Resource("customresourcedefinitions").Name(mariadbv1alpha1.CRDName).Context(op.ctx).Do().Raw()
+	if err != nil {
+		logger.Errorf("Error fetching object : %s", err.Error())
+		return err
+	}
+	current := struct {
+		Metadata metav1.ObjectMeta `json:"metadata"`
+		Spec     struct {
+			Versions   []map[string]interface{} `json:"versions"`
+			Conversion map[string]interface{}   `json:"conversion"`
+		} `json:"spec"`
+		Status struct {
+			StoredVersions []string `json:"storedVersions"`
+		} `json:"status"`
+	}{}
+	if err = json.Unmarshal(body, &current); err != nil {
+		return err
+	}
+
+	spec := expected["spec"].(map[string]interface{})
+	versions := spec["versions"].([]interface{})
+	if op.ConversionService == "" && current.Spec.Conversion["strategy"] == "Webhook" {
+		logger.WithField("event", "kept").Info("keeping the conversion webhook of the existing definition")
+		spec["conversion"] = current.Spec.Conversion
+		for _, version := range versions {
+			version.(map[string]interface{})["served"] = true
+		}
+	}
+	for _, stored := range current.Status.StoredVersions {
+		found := false
+		for _, version := range versions {
+			if version.(map[string]interface{})["name"] == stored {
+				found = true
+				version.(map[string]interface{})["served"] = true
+			}
+		}
+		if !found {
+			logger.WithField("event", "kept").Warnf("version %s is still stored, keeping it served", stored)
+			versions = append(versions, map[string]interface{}{"name": stored, "served": true, "storage": false})
+		}
+	}
+	spec["versions"] = versions
+	metadata := expected["metadata"].(map[string]interface{})
+	metadata["resourceVersion"] = current.Metadata.ResourceVersion
+
+	if body, err = json.Marshal(expected); err != nil {
+		return err
+	}
+	if err = client.Put().Resource("customresourcedefinitions").Name(mariadbv1alpha1.CRDName).Body(body).Context(op.ctx).Do().Error(); err != nil {
+		logger.Errorf("Update failed with : %s", err.Error())
+		return err
 	}
+	logger.WithField("event", "updated").Info(mariadbv1alpha1.CRDName)
 	return nil
 }
 
@@ -0,0 +1,55 @@
+package operator
+
+// crdManifest is the CustomResourceDefinition of MariaDBClusters matching this
+// operator, kept as YAML as the vendored apiextensions types predate versions,
+// conversion and printer columns. Its conversion is set on start, see
+// expectedCRD.
+const crdManifest = `
+apiVersion: apiextensions.k8s.io/v1beta1
+kind: CustomResourceDefinition
+metadata:
+  name: mariadbclusters.components.dsg.dk
+spec:
+  group: components.dsg.dk
+  scope: Namespaced
+  names:
+    plural: mariadbclusters
+    kind: MariaDBCluster
+  versions:
+  - name: v1alpha1
+    served: true
+    storage: true
+  - name: v1beta1
+    served: true
+    storage: false
+  subresources:
+    status: {}
+  additionalPrinterColumns:
+  - name: Phase
+    type: string
+    JSONPath: .status.phase
+    description: Phase of the cluster lifecycle
+  - name: Stage
+    type: string
+    JSONPath: .status.stage
+    description: Stage within the phase
+  - name: Maintenance
+    type: boolean
+    JSONPath: .status.maintenance
+    description: No corrective action is taken
+  - name: Ready
+    type: integer
+    JSONPath: .status.readyReplicas
+    description: Ready server pods
+  - name: Replicas
+    type: integer
+    JSONPath: .spec.replicas
+    description: Requested server pods
+  - name: Primary
+    type: string
+    JSONPath: .status.primary
+    description: Pod of the primary component furthest ahead
+  - name: Age
+    type: date
+    JSONPath: .metadata.creationTimestamp
+`
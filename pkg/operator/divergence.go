@@ -0,0 +1,52 @@
+package operator
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	componentsv1alpha1 "github.com/dansksupermarked/mariadb-galera-operator/pkg/apis/components/v1alpha1"
+	"github.com/dansksupermarked/mariadb-galera-operator/pkg/util"
+	"k8s.io/api/core/v1"
+)
+
+// checkDivergence raises the Diverged condition while initializers hold pods
+// back from joining as their data diverged from the primary component. Reports
+// of pods that joined since, or are gone, are dropped.
+func (c *Controller) checkDivergence(mdbc *componentsv1alpha1.MariaDBCluster) {
+	logger := util.GetClusterLogger(mdbc).WithField("action", "divergence")
+	var names []string
+	for name, report := range mdbc.Status.Diverged {
+		status, joined := mdbc.Status.WSREP[name]
+		if !mdbc.IsServerPod(name) || (joined && report.Reported.Before(&status.Reported)) {
+			delete(mdbc.Status.Diverged, name)
+			continue
+		}
+		names = append(names, name)
+	}
+	if len(mdbc.Status.Diverged) == 0 {
+		mdbc.Status.Diverged = nil
+	}
+	cond := mdbc.Status.GetCondition(componentsv1alpha1.ConditionDiverged)
+	if len(names) == 0 {
+		if cond != nil {
+			logger.WithField("event", "resolved").Info("no pod held back for divergent data")
+			mdbc.Status.RemoveCondition(componentsv1alpha1.ConditionDiverged)
+		}
+		return
+	}
+	sort.Strings(names)
+	var parts []string
+	for _, name := range names {
+		report := mdbc.Status.Diverged[name]
+		parts = append(parts, fmt.Sprintf("%s at %s:%d", name, report.GRAState.UUID, report.GRAState.SeqNo))
+	}
+	report := mdbc.Status.Diverged[names[0]]
+	message := fmt.Sprintf("%s diverged from the primary component at %s:%d", strings.Join(parts, ", "), report.ClusterStateUUID, report.LastCommitted)
+	if cond == nil || cond.Message != message {
+		logger.WithField("event", "detected").Warn(message)
+		c.recorder.Event(mdbc, v1.EventTypeWarning, componentsv1alpha1.ConditionDiverged,
+			message+", back up their data and list them in spec.recovery.overwriteDiverged to have them join through SST")
+	}
+	mdbc.Status.SetCondition(componentsv1alpha1.ConditionDiverged, true, "HeldBack", message)
+}
@@ -0,0 +1,101 @@
+package operator
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	componentsv1alpha1 "github.com/dansksupermarked/mariadb-galera-operator/pkg/apis/components/v1alpha1"
+	"github.com/dansksupermarked/mariadb-galera-operator/pkg/util"
+	"k8s.io/api/core/v1"
+)
+
+// toFields returns the fields of an object as decoded from its JSON form
+func toFields(obj interface{}) (map[string]interface{}, error) {
+	body, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	fields := map[string]interface{}{}
+	return fields, json.Unmarshal(body, &fields)
+}
+
+// renderedHash returns the hash of the fields the operator rendered for an
+// object, carried in MariaDBClusterRenderedAnnotation. An object whose hash is
+// unchanged but that differs from them was edited by someone else.
+func renderedHash(fields map[string]interface{}) string {
+	body, _ := json.Marshal(fields)
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:8])
+}
+
+// driftedFields lists the paths of the fields of expected that current holds
+// other values for, sorted. Fields only current has, as set by the API server,
+// are left out, as are zero values current leaves unset. A list is reported as
+// a whole when any item of expected has no match in current.
+func driftedFields(expected, current interface{}, path string) []string {
+	switch expected := expected.(type) {
+	case map[string]interface{}:
+		current, _ := current.(map[string]interface{})
+		var keys []string
+		for key := range expected {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		var drifted []string
+		for _, key := range keys {
+			drifted = append(drifted, driftedFields(expected[key], current[key], fieldPath(path, key))...)
+		}
+		return drifted
+	case []interface{}:
+		current, _ := current.([]interface{})
+		for _, item := range expected {
+			found := false
+			for _, other := range current {
+				if len(driftedFields(item, other, path)) == 0 {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return []string{path}
+			}
+		}
+		return nil
+	case nil:
+		return nil
+	}
+	if current == nil && (expected == "" || expected == float64(0) || expected == false) {
+		return nil
+	}
+	if !reflect.DeepEqual(expected, current) {
+		return []string{path}
+	}
+	return nil
+}
+
+// fieldPath appends a key to a path, in brackets for keys such as labels
+func fieldPath(path, key string) string {
+	if strings.ContainsAny(key, "./") {
+		return path + "[" + key + "]"
+	}
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// reportDrift warns with an Event about fields of an object edited outside of
+// the operator and set back
+func (c *Controller) reportDrift(mdbc *componentsv1alpha1.MariaDBCluster, kind, name string, fields []string) {
+	if len(fields) == 0 {
+		return
+	}
+	message := fmt.Sprintf("%s of %s %s edited outside of the operator, restored", strings.Join(fields, ", "), kind, name)
+	util.GetClusterLogger(mdbc).WithField("kind", kind).WithField("action", "reconcile").WithField("event", "drift").Warn(message)
+	c.recorder.Event(mdbc, v1.EventTypeWarning, kind+"Drift", message)
+}
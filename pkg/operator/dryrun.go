@@ -0,0 +1,156 @@
+package operator
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/Sirupsen/logrus"
+	componentsv1alpha1 "github.com/dansksupermarked/mariadb-galera-operator/pkg/apis/components/v1alpha1"
+	"github.com/dansksupermarked/mariadb-galera-operator/pkg/util"
+	"k8s.io/api/core/v1"
+)
+
+// verbs changes are told with, by method of the request
+var dryRunVerbs = map[string]string{
+	http.MethodPost:   "create",
+	http.MethodPut:    "update",
+	http.MethodPatch:  "patch",
+	http.MethodDelete: "delete",
+}
+
+// dryRunLog collects the changes the operator would have made to the objects
+// of a cluster while it reconciles, between begin and end. Clusters in dry run
+// are reconciled one at a time so that changes are told apart.
+type dryRunLog struct {
+	reconcile sync.Mutex
+	mu        sync.Mutex
+	active    bool
+	changes   []string
+}
+
+func (l *dryRunLog) begin() {
+	l.reconcile.Lock()
+	l.mu.Lock()
+	l.active = true
+	l.mu.Unlock()
+}
+
+// end returns the changes collected since begin
+func (l *dryRunLog) end() []string {
+	l.mu.Lock()
+	changes := l.changes
+	l.active = false
+	l.changes = nil
+	l.mu.Unlock()
+	l.reconcile.Unlock()
+	return changes
+}
+
+// record collects a change, changes made outside of a reconcile, as while
+// starting, are only logged
+func (l *dryRunLog) record(change string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.active {
+		logrus.WithField("action", "dryRun").WithField("event", "skipped").Info("would " + change)
+		return
+	}
+	l.changes = append(l.changes, change)
+}
+
+// wrap returns a WrapTransport turning the changes requested through
+// transports of inner into dry runs
+func (l *dryRunLog) wrap(inner func(rt http.RoundTripper) http.RoundTripper) func(rt http.RoundTripper) http.RoundTripper {
+	return func(rt http.RoundTripper) http.RoundTripper {
+		if inner != nil {
+			rt = inner(rt)
+		}
+		return &dryRunTransport{rt: rt, log: l}
+	}
+}
+
+// dryRunTransport sends the changes requested to the API server as
+// server-side dry runs, which validate and answer them as usual without
+// persisting anything. Events are still created, they tell what the operator
+// would have done.
+type dryRunTransport struct {
+	rt  http.RoundTripper
+	log *dryRunLog
+}
+
+func (t *dryRunTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	verb, ok := dryRunVerbs[req.Method]
+	if !ok {
+		return t.rt.RoundTrip(req)
+	}
+	resource, namespace := requestedObject(req)
+	if strings.HasPrefix(resource, "events") {
+		return t.rt.RoundTrip(req)
+	}
+	change := verb + " " + resource
+	if namespace != "" {
+		change += " in " + namespace
+	}
+	t.log.record(change)
+
+	dryRun := req.WithContext(req.Context())
+	url := *req.URL
+	query := url.Query()
+	query.Set("dryRun", "All")
+	url.RawQuery = query.Encode()
+	dryRun.URL = &url
+	return t.rt.RoundTrip(dryRun)
+}
+
+// CancelRequest passes the cancellation of the request timeout on to the
+// wrapped transport
+func (t *dryRunTransport) CancelRequest(req *http.Request) {
+	cancelWrapped(t.rt, req)
+}
+
+// requestedObject returns the resource, name and subresource a request is
+// made on, as statefulsets/rocket-server, and its namespace
+func requestedObject(req *http.Request) (string, string) {
+	segments := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+	// /api/v1/... or /apis/<group>/<version>/...
+	switch {
+	case segments[0] == "api" && len(segments) > 2:
+		segments = segments[2:]
+	case segments[0] == "apis" && len(segments) > 3:
+		segments = segments[3:]
+	}
+	var namespace string
+	if len(segments) > 2 && segments[0] == "namespaces" {
+		namespace, segments = segments[1], segments[2:]
+	}
+	return strings.Join(segments, "/"), namespace
+}
+
+// dryRunCluster reconciles a cluster in dry run through the clients of the
+// dry run operator, telling through Events and the log what would have been
+// changed. The cluster stays where it is, so that every reconcile tells the
+// changes of its next step.
+func (c *Controller) dryRunCluster(mdbc *componentsv1alpha1.MariaDBCluster) error {
+	log := c.operator.dryRunLog
+	log.begin()
+	err := c.forCluster(mdbc).reconcileCluster(mdbc)
+	changes := log.end()
+	logger := util.GetClusterLogger(mdbc).WithField("action", "dryRun").WithField("event", "skipped")
+	for _, change := range changes {
+		logger.Info("would " + change)
+		c.recorder.Event(mdbc, v1.EventTypeNormal, "DryRun", "would "+change)
+	}
+	return err
+}
+
+// forCluster returns the controller to reconcile a cluster with, one whose
+// changes are dry runs for a cluster in dry run
+func (c *Controller) forCluster(mdbc *componentsv1alpha1.MariaDBCluster) *Controller {
+	if c.operator.DryRun || !mdbc.IsDryRun() {
+		return c
+	}
+	dryRun := *c
+	dryRun.operator = c.operator.dryRun
+	return &dryRun
+}
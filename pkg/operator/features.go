@@ -0,0 +1,32 @@
+package operator
+
+import (
+	"strings"
+
+	componentsv1alpha1 "github.com/dansksupermarked/mariadb-galera-operator/pkg/apis/components/v1alpha1"
+)
+
+// reportFeatures raises the ExperimentalFeatures condition listing the
+// features switched on through annotations. A flag that does not parse, on a
+// cluster admitted without the webhook, is named as well as it is ignored.
+func reportFeatures(mdbc *componentsv1alpha1.MariaDBCluster) {
+	var enabled []string
+	for _, name := range componentsv1alpha1.Features {
+		if mdbc.IsFeatureEnabled(name) {
+			enabled = append(enabled, name)
+		}
+	}
+	_, err := mdbc.GetFeatures()
+	if len(enabled) == 0 && err == nil {
+		mdbc.Status.RemoveCondition(componentsv1alpha1.ConditionFeatures)
+		return
+	}
+	reason, message := "Enabled", "no feature enabled"
+	if len(enabled) > 0 {
+		message = strings.Join(enabled, ", ") + " enabled through annotations"
+	}
+	if err != nil {
+		reason, message = "InvalidFeature", message+", ignoring "+err.Error()
+	}
+	mdbc.Status.SetCondition(componentsv1alpha1.ConditionFeatures, len(enabled) > 0, reason, message)
+}
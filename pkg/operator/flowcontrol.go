@@ -0,0 +1,129 @@
+package operator
+
+import (
+	"fmt"
+	"time"
+
+	componentsv1alpha1 "github.com/dansksupermarked/mariadb-galera-operator/pkg/apis/components/v1alpha1"
+	"github.com/dansksupermarked/mariadb-galera-operator/pkg/util"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// time a mitigation is given to show effect before the next one is applied
+	flowControlMitigationInterval = 5 * time.Minute
+	// a desynced pod is resynced after this long even when it did not catch up
+	flowControlDesyncTimeout = 30 * time.Minute
+)
+
+// checkFlowControl raises the FlowControl condition while agents report
+// replication paused for more than Spec.Galera.FlowControl.PausedThresholdPercent
+// of the time. The pod sending most pause requests is the one lagging behind,
+// depending on the policy its gcs.fc_limit is doubled or it is desynced until
+// its receive queue drained. Agents apply both from status.flowControl.
+func (c *Controller) checkFlowControl(mdbc *componentsv1alpha1.MariaDBCluster) {
+	logger := util.GetClusterLogger(mdbc).WithField("action", "flowControl")
+	policy := mdbc.Spec.Galera.FlowControl
+	var paused, synced int
+	var sent int64
+	var lagging string
+	for name, status := range mdbc.Status.WSREP {
+		color := status.Color
+		if color == "" {
+			color = componentsv1alpha1.ColorBlue
+		}
+		if color != mdbc.GetActiveColor() || status.ClusterStatus != componentsv1alpha1.WSREPClusterStatusPrimary {
+			continue
+		}
+		if status.LocalState == syncedState {
+			synced++
+		}
+		if status.FlowControlPausedPercent > paused {
+			paused = status.FlowControlPausedPercent
+		}
+		if status.FlowControlSent > sent || (status.FlowControlSent == sent && sent > 0 && name < lagging) {
+			sent, lagging = status.FlowControlSent, name
+		}
+	}
+
+	fc := mdbc.Status.FlowControl
+	if fc != nil && fc.Desynced != "" {
+		status, ok := mdbc.Status.WSREP[fc.Desynced]
+		caughtUp := ok && status.Desynced && status.RecvQueue == 0 && fc.LastChange.Before(&status.Reported)
+		if !ok || caughtUp || time.Since(fc.LastChange.Time) > flowControlDesyncTimeout {
+			message := fmt.Sprintf("resyncing %s", fc.Desynced)
+			if !caughtUp {
+				message += ", it did not catch up within " + flowControlDesyncTimeout.String()
+			}
+			logger.WithField("event", "resync").Info(message)
+			c.recorder.Event(mdbc, v1.EventTypeNormal, "FlowControlResync", message)
+			fc.Desynced = ""
+			fc.LastChange = metav1.Now()
+		}
+	}
+	if fc != nil && fc.Desynced == "" && len(fc.Limits) == 0 {
+		mdbc.Status.FlowControl = nil
+	}
+
+	if paused < policy.GetPausedThresholdPercent() {
+		if cond := mdbc.Status.GetCondition(componentsv1alpha1.ConditionFlowControl); cond != nil {
+			logger.WithField("event", "resumed").Info("replication no longer throttled")
+			mdbc.Status.RemoveCondition(componentsv1alpha1.ConditionFlowControl)
+		}
+		return
+	}
+	message := fmt.Sprintf("replication paused %d%% of the time", paused)
+	if lagging != "" {
+		message += fmt.Sprintf(", %s sent %d pause requests", lagging, sent)
+	}
+	if cond := mdbc.Status.GetCondition(componentsv1alpha1.ConditionFlowControl); cond == nil {
+		logger.WithField("event", "throttled").Warn(message)
+		c.recorder.Event(mdbc, v1.EventTypeWarning, "FlowControl", message)
+	}
+	mdbc.Status.SetCondition(componentsv1alpha1.ConditionFlowControl, true, "Throttled", message)
+
+	if lagging == "" || (fc != nil && time.Since(fc.LastChange.Time) < flowControlMitigationInterval) {
+		return
+	}
+	if fc == nil {
+		fc = &componentsv1alpha1.FlowControlStatus{}
+	}
+	// a desynced pod fails its readiness probe, so two others have to serve
+	others := synced
+	if mdbc.Status.WSREP[lagging].LocalState == syncedState {
+		others--
+	}
+	if policy.DesyncLagging && fc.Desynced == "" && others >= 2 {
+		message = fmt.Sprintf("desyncing %s until it caught up, %s", lagging, message)
+		logger.WithField("event", "desync").Warn(message)
+		c.recorder.Event(mdbc, v1.EventTypeWarning, "FlowControlDesync", message)
+		fc.Desynced = lagging
+		fc.LastChange = metav1.Now()
+		mdbc.Status.FlowControl = fc
+		return
+	}
+	if !policy.RaiseLimit {
+		return
+	}
+	limit := fc.Limits[lagging]
+	if limit == 0 {
+		limit = componentsv1alpha1.DefaultFlowControlLimit
+	}
+	if limit >= policy.GetMaxLimit() {
+		return
+	}
+	raised := limit * 2
+	if raised > policy.GetMaxLimit() {
+		raised = policy.GetMaxLimit()
+	}
+	message = fmt.Sprintf("raising gcs.fc_limit of %s from %d to %d, %s", lagging, limit, raised, message)
+	logger.WithField("event", "limitRaised").Info(message)
+	c.recorder.Event(mdbc, v1.EventTypeNormal, "FlowControlLimitRaised", message)
+	if fc.Limits == nil {
+		fc.Limits = make(map[string]int)
+	}
+	fc.Limits[lagging] = raised
+	fc.LastChange = metav1.Now()
+	mdbc.Status.FlowControl = fc
+}
@@ -0,0 +1,159 @@
+package operator
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	componentsv1alpha1 "github.com/dansksupermarked/mariadb-galera-operator/pkg/apis/components/v1alpha1"
+	"github.com/dansksupermarked/mariadb-galera-operator/pkg/phase"
+	"github.com/dansksupermarked/mariadb-galera-operator/pkg/util"
+	apps "k8s.io/api/apps/v1"
+	batch "k8s.io/api/batch/v1"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// peak write rate automatic sizing holds on to before taking a lower one
+const gcacheRateMaxAge = 24 * time.Hour
+
+// checkGCacheGate grows gcache ahead of a rolling upgrade so that it holds the
+// writes a restarting pod misses, letting it rejoin through IST. The write rate
+// is measured once per upgrade target, a larger gcache is then rolled out on
+// the current version before the upgrade itself may start. gcache is never
+// shrunk here, as that would cost another restart of every pod.
+func (c *Controller) checkGCacheGate(mdbc *componentsv1alpha1.MariaDBCluster, sset *apps.StatefulSet) (bool, error) {
+	// automatic sizing already keeps gcache large enough
+	if !mdbc.Spec.Upgrade.PresizeGCache || mdbc.Spec.Galera.GCache.Auto {
+		return true, nil
+	}
+	gcache := mdbc.Status.GCache
+	if gcache == nil || gcache.Version != mdbc.Status.TargetVersion {
+		rate, err := c.probeWriteRate(mdbc, mdbc.Status.TargetVersion)
+		if err != nil || rate < 0 {
+			return false, err
+		}
+		size := estimateGCacheSizeMB(rate, mdbc.Spec.Upgrade.GetISTWindow())
+		if size <= componentsv1alpha1.DefaultGCacheSizeMB || size < mdbc.GetGCacheSizeMB() {
+			size = mdbc.GetGCacheSizeMB()
+		}
+		util.GetClusterLogger(mdbc).WithField("action", "upgrade").WithField("event", "estimated").
+			Infof("writing %d bytes/s, gcache size %dM", rate, size)
+		mdbc.Status.GCache = &componentsv1alpha1.GCacheStatus{
+			Version:   mdbc.Status.TargetVersion,
+			WriteRate: rate,
+			SizeMB:    size,
+		}
+		return false, nil
+	}
+	if gcache.SizeMB == 0 {
+		return true, nil
+	}
+	expected := fmt.Sprintf("%dM", gcache.SizeMB)
+	if sset.Spec.Template.Annotations[componentsv1alpha1.MariaDBClusterGCacheAnnotation] != expected ||
+		sset.Status.ObservedGeneration < sset.Generation ||
+		!phase.StatefulSetReady(sset) {
+		mdbc.Status.SetCondition(componentsv1alpha1.ConditionUpgrading, false, "ResizingGCache",
+			"restarting pods with gcache.size="+expected+" ahead of the upgrade")
+		return false, nil
+	}
+	return true, nil
+}
+
+// checkGCacheSize sizes gcache from the write rates agents report, keeping the
+// peak of the last day, within Spec.Galera.GCache bounds and half of the space
+// the fullest data volume has for it. As every change restarts all pods, the
+// size is only changed when off by more than a quarter or half of it.
+func (c *Controller) checkGCacheSize(mdbc *componentsv1alpha1.MariaDBCluster) {
+	policy := mdbc.Spec.Galera.GCache
+	if !policy.Auto {
+		return
+	}
+	current := mdbc.GetGCacheSizeMB()
+	if current == 0 {
+		current = componentsv1alpha1.DefaultGCacheSizeMB
+	}
+	var rate, diskMB int64 = 0, -1
+	for _, status := range mdbc.Status.WSREP {
+		if status.ClusterStatus != componentsv1alpha1.WSREPClusterStatusPrimary {
+			continue
+		}
+		if status.WriteRate > rate {
+			rate = status.WriteRate
+		}
+		// gcache is preallocated, its current size is available to it as well
+		if available := status.DataAvailableBytes>>20 + current; status.DataAvailableBytes > 0 && (diskMB < 0 || available < diskMB) {
+			diskMB = available
+		}
+	}
+	gcache := mdbc.Status.GCache
+	if gcache == nil || gcache.Version != "" {
+		gcache = &componentsv1alpha1.GCacheStatus{SizeMB: mdbc.GetGCacheSizeMB()}
+	}
+	if rate > gcache.WriteRate || time.Since(gcache.MeasuredTime.Time) > gcacheRateMaxAge {
+		gcache.WriteRate = rate
+		gcache.MeasuredTime = metav1.Now()
+	}
+	mdbc.Status.GCache = gcache
+
+	size := estimateGCacheSizeMB(gcache.WriteRate, policy.GetWindow())
+	minSize := policy.MinSizeMB
+	if minSize == 0 {
+		minSize = componentsv1alpha1.DefaultGCacheSizeMB
+	}
+	if size < minSize {
+		size = minSize
+	}
+	if policy.MaxSizeMB > 0 && size > policy.MaxSizeMB {
+		size = policy.MaxSizeMB
+	}
+	if diskMB > 0 && size > diskMB/2 {
+		size = diskMB / 2
+	}
+	if size <= current*5/4 && size >= current/2 {
+		return
+	}
+	message := fmt.Sprintf("resizing gcache from %dM to %dM for %d bytes/s written", current, size, gcache.WriteRate)
+	util.GetClusterLogger(mdbc).WithField("action", "gcache").WithField("event", "resized").Info(message)
+	c.recorder.Event(mdbc, v1.EventTypeNormal, "GCacheResized", message)
+	gcache.SizeMB = size
+}
+
+// probeWriteRate returns replicated bytes per second measured by a Job, -1
+// while the measurement is in progress or failed
+func (c *Controller) probeWriteRate(mdbc *componentsv1alpha1.MariaDBCluster, version string) (int64, error) {
+	name := mdbc.GetWriteRateJobName(version)
+	pod, failed, err := c.runCheckJob(mdbc, name, func(job *batch.Job) error {
+		return mdbc.WriteRateJobTransform(job, version)
+	})
+	if err != nil {
+		return -1, err
+	}
+	if failed {
+		mdbc.Status.SetCondition(componentsv1alpha1.ConditionUpgrading, false, "WriteRateCheckFailed",
+			"could not measure write rate, delete job "+name+" to retry")
+		return -1, nil
+	}
+	if pod == nil {
+		mdbc.Status.SetCondition(componentsv1alpha1.ConditionUpgrading, false, "MeasuringWriteRate", "waiting for write rate job "+name)
+		return -1, nil
+	}
+	var message string
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.State.Terminated != nil {
+			message = strings.TrimSpace(status.State.Terminated.Message)
+		}
+	}
+	rate, err := strconv.ParseInt(message, 10, 64)
+	if err != nil {
+		return -1, fmt.Errorf("write rate job %s reported %q", name, message)
+	}
+	return rate, nil
+}
+
+// estimateGCacheSizeMB sizes gcache to twice the writes expected within window
+func estimateGCacheSizeMB(rate int64, window time.Duration) int64 {
+	bytes := 2 * rate * int64(window/time.Second)
+	return (bytes + 1<<20 - 1) >> 20
+}
@@ -1,11 +1,19 @@
 package operator
 
 import (
+	"fmt"
 	"reflect"
+	"strings"
 
 	"github.com/Sirupsen/logrus"
 	componentsv1alpha1 "github.com/dansksupermarked/mariadb-galera-operator/pkg/apis/components/v1alpha1"
+	"github.com/dansksupermarked/mariadb-galera-operator/pkg/phase"
 	apps "k8s.io/api/apps/v1"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
 )
 
 /*
@@ -24,7 +32,12 @@ func (c *Controller) MariaDBClusterUpdateEventHandler(oldobj, newobj interface{}
 	logger := logrus.WithFields(logrus.Fields{"cluster": oldmdb.Namespace + "/" + oldmdb.Name})
 	logger.Debug("MariaDBCluster Update Event recieved")
 
-	if !reflect.DeepEqual(newmdb.Spec, oldmdb.Spec) || !reflect.DeepEqual(newmdb.Status, oldmdb.Status) {
+	if newmdb.ResourceVersion == oldmdb.ResourceVersion {
+		// periodic resync, reconciled again in case changes of its objects
+		// were missed
+		logger.Debug("MariaDBCluster resync, queue for reconcile")
+		c.MariaDBClusterEnqueue(newobj)
+	} else if !reflect.DeepEqual(newmdb.Spec, oldmdb.Spec) || !reflect.DeepEqual(newmdb.Status, oldmdb.Status) || newmdb.DeletionTimestamp != nil {
 		logger.Debug("MariaDBCluster change detected, queue for reconcile")
 		c.MariaDBClusterEnqueue(newobj)
 	} else {
@@ -46,24 +59,185 @@ func (c *Controller) MariaDBClusterDeleteEventHandler(obj interface{}) {
 func (c *Controller) StatefulSetAddEventHandler(obj interface{}) {
 	sset := obj.(*apps.StatefulSet)
 	logrus.Infof("StatefulSet Add Event logged for %s/%s", sset.Namespace, sset.Name)
-	if len(sset.Labels[componentsv1alpha1.MariaDBClusterNameLabel]) > 0 {
-		c.workqueue.AddRateLimited(sset.Namespace + "/" + sset.Labels[componentsv1alpha1.MariaDBClusterNameLabel])
-	}
+	c.enqueueOwner(sset)
 }
 
 func (c *Controller) StatefulSetUpdateEventHandler(oldobj, newobj interface{}) {
 	oldsset := oldobj.(*apps.StatefulSet)
 	newsset := newobj.(*apps.StatefulSet)
 	logrus.Infof("StatefulSet Update Event logged for %s/%s", oldsset.Namespace, oldsset.Name)
-	if len(newsset.Labels[componentsv1alpha1.MariaDBClusterNameLabel]) > 0 && !reflect.DeepEqual(oldsset, newsset) {
-		c.workqueue.AddRateLimited(newsset.Namespace + "/" + newsset.Labels[componentsv1alpha1.MariaDBClusterNameLabel])
+	if !reflect.DeepEqual(oldsset, newsset) {
+		c.enqueueOwner(newsset)
 	}
 }
 
 func (c *Controller) StatefulSetDeleteEventHandler(obj interface{}) {
-	sset := obj.(*apps.StatefulSet)
+	sset, ok := deletedObject(obj).(*apps.StatefulSet)
+	if !ok {
+		return
+	}
 	logrus.Infof("StatefulSet Delete Event logged for %s/%s", sset.Namespace, sset.Name)
-	if len(sset.Labels[componentsv1alpha1.MariaDBClusterNameLabel]) > 0 {
-		c.workqueue.AddRateLimited(sset.Namespace + "/" + sset.Labels[componentsv1alpha1.MariaDBClusterNameLabel])
+	c.enqueueOwner(sset)
+}
+
+/*
+ *  ConfigMap Handlers
+ */
+
+func (c *Controller) ConfigMapAddEventHandler(obj interface{}) {
+	c.enqueueOwner(obj.(*v1.ConfigMap))
+}
+
+func (c *Controller) ConfigMapUpdateEventHandler(oldobj, newobj interface{}) {
+	oldcmap := oldobj.(*v1.ConfigMap)
+	newcmap := newobj.(*v1.ConfigMap)
+	if !reflect.DeepEqual(oldcmap.Data, newcmap.Data) || !reflect.DeepEqual(oldcmap.Annotations, newcmap.Annotations) {
+		logrus.Debugf("ConfigMap Update Event logged for %s/%s", newcmap.Namespace, newcmap.Name)
+		c.enqueueOwner(newcmap)
+	}
+}
+
+func (c *Controller) ConfigMapDeleteEventHandler(obj interface{}) {
+	if cmap, ok := deletedObject(obj).(*v1.ConfigMap); ok {
+		logrus.Infof("ConfigMap Delete Event logged for %s/%s", cmap.Namespace, cmap.Name)
+		c.enqueueOwner(cmap)
+	}
+}
+
+/*
+ *  Handlers of the other children: Services, Secrets, ServiceAccounts, Roles,
+ *  RoleBindings, PersistentVolumeClaims and PodDisruptionBudgets
+ */
+
+func (c *Controller) ChildAddEventHandler(obj interface{}) {
+	if child, err := meta.Accessor(obj); err == nil {
+		c.enqueueOwner(child)
+	}
+}
+
+// ChildUpdateEventHandler queues the cluster of a child that changed, leaving
+// resyncs to the cluster itself
+func (c *Controller) ChildUpdateEventHandler(oldobj, newobj interface{}) {
+	oldchild, err := meta.Accessor(oldobj)
+	if err != nil {
+		return
+	}
+	newchild, err := meta.Accessor(newobj)
+	if err != nil || oldchild.GetResourceVersion() == newchild.GetResourceVersion() {
+		return
+	}
+	logrus.Debugf("%T Update Event logged for %s/%s", newobj, newchild.GetNamespace(), newchild.GetName())
+	c.enqueueOwner(newchild)
+}
+
+func (c *Controller) ChildDeleteEventHandler(obj interface{}) {
+	if child, err := meta.Accessor(deletedObject(obj)); err == nil {
+		logrus.Infof("%T Delete Event logged for %s/%s", deletedObject(obj), child.GetNamespace(), child.GetName())
+		c.enqueueOwner(child)
+	}
+}
+
+// enqueueOwner queues the MariaDBCluster controlling an object, going by the
+// cluster name label for objects created before they had owner references
+func (c *Controller) enqueueOwner(obj metav1.Object) {
+	if ref := metav1.GetControllerOf(obj); ref != nil {
+		if ref.Kind == componentsv1alpha1.ResourceKind && strings.HasPrefix(ref.APIVersion, componentsv1alpha1.GroupName+"/") {
+			c.enqueueKnown(obj.GetNamespace(), ref.Name)
+		}
+		return
+	}
+	if name := obj.GetLabels()[componentsv1alpha1.MariaDBClusterNameLabel]; name != "" {
+		c.enqueueKnown(obj.GetNamespace(), name)
+	}
+}
+
+// enqueueKnown queues a cluster the lister knows of, leaving out the ones gone
+// or not selected by the instance selector
+func (c *Controller) enqueueKnown(namespace, name string) {
+	if _, err := c.mariadbclustersLister.MariaDBClusters(namespace).Get(name); err != nil {
+		return
+	}
+	c.enqueue(namespace + "/" + name)
+}
+
+// enqueue queues a cluster key once the debounce window passed. The queue
+// keeps the earliest time a key was added for, so that a burst of events, as
+// of StatefulSet status during a rollout, is reconciled once.
+func (c *Controller) enqueue(key string) {
+	if c.operator.Debounce > 0 {
+		c.workqueue.AddAfter(key, c.operator.Debounce)
+		return
+	}
+	c.workqueue.Add(key)
+}
+
+// deletedObject unwraps the last known state of an object whose deletion the
+// informer missed
+func deletedObject(obj interface{}) interface{} {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		return tombstone.Obj
+	}
+	return obj
+}
+
+/*
+ *  Pod Handlers
+ */
+
+func (c *Controller) PodAddEventHandler(obj interface{}) {
+	pod := obj.(*v1.Pod)
+	c.enqueueKnown(pod.Namespace, pod.Labels[componentsv1alpha1.MariaDBClusterNameLabel])
+}
+
+// PodUpdateEventHandler queues the cluster of a pod that changed phase,
+// readiness, node or state of a container, as on a crash loop, without
+// waiting for the StatefulSet status to follow
+func (c *Controller) PodUpdateEventHandler(oldobj, newobj interface{}) {
+	oldpod := oldobj.(*v1.Pod)
+	newpod := newobj.(*v1.Pod)
+	if oldpod.Status.Phase != newpod.Status.Phase ||
+		isPodReady(oldpod) != isPodReady(newpod) ||
+		oldpod.Spec.NodeName != newpod.Spec.NodeName ||
+		(oldpod.DeletionTimestamp == nil) != (newpod.DeletionTimestamp == nil) ||
+		!reflect.DeepEqual(containerStates(oldpod), containerStates(newpod)) {
+		logrus.Debugf("Pod Update Event logged for %s/%s", newpod.Namespace, newpod.Name)
+		c.enqueueKnown(newpod.Namespace, newpod.Labels[componentsv1alpha1.MariaDBClusterNameLabel])
+	}
+}
+
+func (c *Controller) PodDeleteEventHandler(obj interface{}) {
+	if pod, ok := deletedObject(obj).(*v1.Pod); ok {
+		logrus.Debugf("Pod Delete Event logged for %s/%s", pod.Namespace, pod.Name)
+		c.enqueueKnown(pod.Namespace, pod.Labels[componentsv1alpha1.MariaDBClusterNameLabel])
+	}
+}
+
+// containerStates sums up restarts, readiness and waiting reason of each
+// container of a pod
+func containerStates(pod *v1.Pod) []string {
+	statuses := append([]v1.ContainerStatus{}, pod.Status.InitContainerStatuses...)
+	statuses = append(statuses, pod.Status.ContainerStatuses...)
+	var states []string
+	for _, status := range statuses {
+		reason := ""
+		if status.State.Waiting != nil {
+			reason = status.State.Waiting.Reason
+		}
+		states = append(states, fmt.Sprintf("%s %d %t %s", status.Name, status.RestartCount, status.Ready, reason))
+	}
+	return states
+}
+
+// serverPods returns the server pods of the active color from the pod cache,
+// which tells of pods no longer ready ahead of the StatefulSet status
+func (c *Controller) serverPods(mdbc *componentsv1alpha1.MariaDBCluster) []phase.Pod {
+	pods, err := c.podLister.Pods(mdbc.Namespace).List(labels.SelectorFromSet(mdbc.GetServerLabels()))
+	if err != nil {
+		return nil
+	}
+	var states []phase.Pod
+	for _, pod := range pods {
+		states = append(states, phase.Pod{Name: pod.Name, Ready: isPodReady(pod)})
 	}
+	return states
 }
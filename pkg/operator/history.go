@@ -0,0 +1,61 @@
+package operator
+
+import (
+	"strings"
+
+	componentsv1alpha1 "github.com/dansksupermarked/mariadb-galera-operator/pkg/apis/components/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// transitions kept in status.transitions, older ones are dropped
+	maxPhaseTransitions = 20
+	// longest reason recorded with a transition
+	maxTransitionReason = 256
+)
+
+// recordTransition adds the move to status.transitions when the phase or
+// stage changed since original, along with why as far as the status tells
+func recordTransition(original, mdbc *componentsv1alpha1.MariaDBCluster) {
+	from, to := original.Status, mdbc.Status
+	if from.Phase == to.Phase && from.Stage == to.Stage {
+		return
+	}
+	reason := transitionReason(original, mdbc)
+	if len(reason) > maxTransitionReason {
+		reason = reason[:maxTransitionReason-3] + "..."
+	}
+	mdbc.Status.Transitions = append(mdbc.Status.Transitions, componentsv1alpha1.PhaseTransition{
+		Time:      metav1.Now(),
+		Phase:     to.Phase,
+		Stage:     to.Stage,
+		FromPhase: from.Phase,
+		FromStage: from.Stage,
+		Reason:    reason,
+	})
+	if len(mdbc.Status.Transitions) > maxPhaseTransitions {
+		mdbc.Status.Transitions = mdbc.Status.Transitions[len(mdbc.Status.Transitions)-maxPhaseTransitions:]
+	}
+}
+
+// transitionReason returns the diagnostics of a stalled phase, else the last
+// recovery timeline event of this pass, else the conditions updated in it
+func transitionReason(original, mdbc *componentsv1alpha1.MariaDBCluster) string {
+	if mdbc.Status.Stage == componentsv1alpha1.StageStalled && mdbc.Status.Stalled != nil {
+		return "stalled: " + strings.Join(mdbc.Status.Stalled.Diagnostics, ", ")
+	}
+	if timeline := mdbc.Status.RecoveryTimeline; timeline != nil && len(timeline.Events) > 0 {
+		last := timeline.Events[len(timeline.Events)-1]
+		if previous := original.Status.RecoveryTimeline; previous == nil || len(previous.Events) == 0 ||
+			!previous.Events[len(previous.Events)-1].Time.Equal(&last.Time) {
+			return last.Message
+		}
+	}
+	var reasons []string
+	for _, cond := range mdbc.Status.Conditions {
+		if previous := original.Status.GetCondition(cond.Type); previous == nil || !previous.LastUpdateTime.Equal(&cond.LastUpdateTime) {
+			reasons = append(reasons, cond.Type+" "+cond.Reason+": "+cond.Message)
+		}
+	}
+	return strings.Join(reasons, "; ")
+}
@@ -0,0 +1,109 @@
+package operator
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	componentsv1alpha1 "github.com/dansksupermarked/mariadb-galera-operator/pkg/apis/components/v1alpha1"
+	"github.com/dansksupermarked/mariadb-galera-operator/pkg/util"
+	batch "k8s.io/api/batch/v1"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// wait before a failed resolve Job is run again, doubled on each failure up
+// to the refresh interval
+const imageResolveRetryBackoff = time.Minute
+
+// checkImageDigest re-resolves the tag of the running version periodically and
+// pins the server image to the result. As the pinned reference only changes
+// when the tag moved, pods are rolled only when there is a different image to run.
+func (c *Controller) checkImageDigest(mdbc *componentsv1alpha1.MariaDBCluster) error {
+	if !mdbc.Spec.PinImageDigest || mdbc.Spec.ImageDigest != "" {
+		mdbc.Status.PinnedImage = nil
+		mdbc.Status.ImageResolveAttempts = 0
+		mdbc.Status.RemoveCondition(componentsv1alpha1.ConditionImageResolve)
+		return nil
+	}
+	version := mdbc.Status.CurrentVersion
+	image := mdbc.GetImage(version)
+	pinned := mdbc.Status.PinnedImage
+	if pinned != nil && pinned.GetImage() == image && time.Since(pinned.ResolvedTime.Time) < componentsv1alpha1.DefaultImageDigestRefresh {
+		return nil
+	}
+	digest, err := c.resolveImageDigest(mdbc, version)
+	if err != nil || digest == "" {
+		return err
+	}
+	logger := util.GetClusterLogger(mdbc).WithField("action", "pinImage")
+	if pinned == nil || pinned.GetImage() != image || pinned.Digest != digest {
+		logger.WithField("event", "changed").Infof("pinning %s to %s", image, digest)
+	} else {
+		logger.WithField("event", "unchanged").Debugf("%s still resolves to %s", image, digest)
+	}
+	mdbc.Status.PinnedImage = &componentsv1alpha1.PinnedImageStatus{
+		Version:      version,
+		Digest:       digest,
+		ResolvedTime: metav1.Now(),
+		Image:        image,
+	}
+	return nil
+}
+
+// resolveImageDigest returns the digest the image tag of given version resolves
+// to, as reported by the kubelet that pulled it for a resolve Job. An empty
+// result means the Job has not finished yet or failed, which retryImageResolve
+// reports.
+func (c *Controller) resolveImageDigest(mdbc *componentsv1alpha1.MariaDBCluster, version string) (string, error) {
+	name := mdbc.GetImageResolveJobName(version)
+	pod, failed, err := c.runCheckJob(mdbc, name, func(job *batch.Job) error {
+		return mdbc.ImageResolveJobTransform(job, version)
+	})
+	if err != nil {
+		return "", err
+	}
+	if failed {
+		c.retryImageResolve(mdbc, name, version)
+		return "", nil
+	}
+	if pod == nil {
+		return "", nil
+	}
+	// a Job started before spec.image changed pulled the former repository,
+	// it is already removed so the next pass resolves the current one
+	if len(pod.Spec.Containers) > 0 && pod.Spec.Containers[0].Image != mdbc.GetImage(version) {
+		return "", nil
+	}
+	for _, status := range pod.Status.ContainerStatuses {
+		// imageID is reported like docker-pullable://mariadb@sha256:...
+		if i := strings.LastIndex(status.ImageID, "@"); i >= 0 {
+			mdbc.Status.ImageResolveAttempts = 0
+			mdbc.Status.RemoveCondition(componentsv1alpha1.ConditionImageResolve)
+			return status.ImageID[i+1:], nil
+		}
+	}
+	return "", fmt.Errorf("resolve job %s did not report an image digest", name)
+}
+
+// retryImageResolve reports a failed resolve Job by the ImageResolve condition
+// and removes it once the backoff since the failure passed, so that the next
+// pass runs it again. The rest of the reconcile goes on in the meantime.
+func (c *Controller) retryImageResolve(mdbc *componentsv1alpha1.MariaDBCluster, name, version string) {
+	logger := util.GetClusterLogger(mdbc).WithField("action", "pinImage")
+	attempts := mdbc.Status.ImageResolveAttempts
+	delay := doubledDelay(imageResolveRetryBackoff, componentsv1alpha1.DefaultImageDigestRefresh, attempts)
+	message := fmt.Sprintf("resolving %s failed %d times in a row, retrying in %s: %s",
+		mdbc.GetImage(version), attempts+1, delay, c.jobFailureMessage(mdbc, name))
+	if cond := mdbc.Status.GetCondition(componentsv1alpha1.ConditionImageResolve); cond == nil || cond.Message != message {
+		logger.WithField("event", "failed").Warn(message)
+		c.recorder.Event(mdbc, v1.EventTypeWarning, "ImageResolveFailed", message)
+	}
+	mdbc.Status.SetCondition(componentsv1alpha1.ConditionImageResolve, false, "ResolveFailed", message)
+	if time.Since(mdbc.Status.GetCondition(componentsv1alpha1.ConditionImageResolve).LastUpdateTime.Time) < delay {
+		return
+	}
+	logger.WithField("event", "retry").Infof("running resolve job %s again", name)
+	c.deleteJob(mdbc, name)
+	mdbc.Status.ImageResolveAttempts++
+}
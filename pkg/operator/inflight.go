@@ -0,0 +1,53 @@
+package operator
+
+import (
+	"net/http"
+)
+
+// inflightLimiter holds back requests to the API server while max of them are
+// in flight, so that many clusters reconciling at once queue up in the
+// operator rather than at the API server. Watches are let through, they stay
+// open for as long as the informers run.
+type inflightLimiter struct {
+	rt    http.RoundTripper
+	slots chan struct{}
+}
+
+// limitInflight returns a WrapTransport bounding the requests in flight to max
+func limitInflight(max int) func(rt http.RoundTripper) http.RoundTripper {
+	slots := make(chan struct{}, max)
+	return func(rt http.RoundTripper) http.RoundTripper {
+		return &inflightLimiter{rt: rt, slots: slots}
+	}
+}
+
+// RoundTrip sends a request once a slot is free, giving the slot back when
+// the response headers came in
+func (l *inflightLimiter) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Query().Get("watch") == "true" {
+		return l.rt.RoundTrip(req)
+	}
+	select {
+	case l.slots <- struct{}{}:
+	case <-req.Context().Done():
+		return nil, req.Context().Err()
+	}
+	defer func() { <-l.slots }()
+	return l.rt.RoundTrip(req)
+}
+
+// CancelRequest passes the cancellation of the request timeout on to the
+// wrapped transport
+func (l *inflightLimiter) CancelRequest(req *http.Request) {
+	cancelWrapped(l.rt, req)
+}
+
+// cancelWrapped cancels a request through the transport a wrapper wraps,
+// when it can be
+func cancelWrapped(rt http.RoundTripper, req *http.Request) {
+	if canceler, ok := rt.(interface {
+		CancelRequest(*http.Request)
+	}); ok {
+		canceler.CancelRequest(req)
+	}
+}
@@ -0,0 +1,69 @@
+package operator
+
+import (
+	"fmt"
+
+	componentsv1alpha1 "github.com/dansksupermarked/mariadb-galera-operator/pkg/apis/components/v1alpha1"
+	"github.com/dansksupermarked/mariadb-galera-operator/pkg/util"
+	batch "k8s.io/api/batch/v1"
+	"k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// runInitSQL runs the scripts of Spec.InitSQL one after the other once the
+// cluster is ready, each through a Job. A script is recorded in status once
+// its Job completed and never runs again, its Job is only removed on the next
+// pass so that a lost status update does not have it run twice. A failed
+// script holds back the ones after it, the InitSQL condition tells why until
+// its Job is deleted, which runs it again.
+func (c *Controller) runInitSQL(mdbc *componentsv1alpha1.MariaDBCluster) error {
+	logger := util.GetClusterLogger(mdbc).WithField("action", "initSQL")
+	jobs := c.operator.Client.BatchV1().Jobs(mdbc.Namespace)
+	for _, script := range mdbc.Spec.InitSQL {
+		name := mdbc.GetInitSQLJobName(script.Name)
+		if status, done := mdbc.Status.InitSQL[script.Name]; done {
+			if status.Job != "" {
+				c.deleteJob(mdbc, status.Job)
+				status.Job = ""
+				mdbc.Status.InitSQL[script.Name] = status
+			}
+			continue
+		}
+		job, err := jobs.Get(name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			expected := &batch.Job{}
+			mdbc.InitSQLJobTransform(expected, script)
+			if _, err = jobs.Create(expected); err != nil {
+				logger.WithField("kind", "Job").Errorf("Creation failed with : %s", err.Error())
+				return err
+			}
+			logger.WithField("event", "started").Infof("running init script %s", script.Name)
+			mdbc.Status.SetCondition(componentsv1alpha1.ConditionInitSQL, false, "Running", "running init script "+script.Name)
+			return nil
+		} else if err != nil {
+			logger.WithField("kind", "Job").Errorf("Error fetching object : %s", err.Error())
+			return err
+		}
+		if isJobConditionTrue(job, batch.JobFailed) {
+			message := fmt.Sprintf("init script %s failed, delete job %s to run it again: %s", script.Name, name, c.jobFailureMessage(mdbc, name))
+			if cond := mdbc.Status.GetCondition(componentsv1alpha1.ConditionInitSQL); cond == nil || cond.Reason != "Failed" {
+				logger.WithField("event", "failed").Warn(message)
+				c.recorder.Event(mdbc, v1.EventTypeWarning, "InitSQLFailed", message)
+			}
+			mdbc.Status.SetCondition(componentsv1alpha1.ConditionInitSQL, false, "Failed", message)
+			return nil
+		}
+		if !isJobConditionTrue(job, batch.JobComplete) {
+			return nil
+		}
+		if mdbc.Status.InitSQL == nil {
+			mdbc.Status.InitSQL = make(map[string]componentsv1alpha1.InitSQLStatus)
+		}
+		mdbc.Status.InitSQL[script.Name] = componentsv1alpha1.InitSQLStatus{CompletionTime: metav1.Now(), Job: name}
+		logger.WithField("event", "completed").Infof("init script %s completed", script.Name)
+		c.recorder.Event(mdbc, v1.EventTypeNormal, "InitSQLCompleted", "init script "+script.Name+" completed")
+	}
+	mdbc.Status.RemoveCondition(componentsv1alpha1.ConditionInitSQL)
+	return nil
+}
@@ -0,0 +1,66 @@
+package operator
+
+import (
+	"context"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	componentsv1alpha1 "github.com/dansksupermarked/mariadb-galera-operator/pkg/apis/components/v1alpha1"
+	"github.com/dansksupermarked/mariadb-galera-operator/pkg/util"
+)
+
+// time given to resolve each IST address
+const istResolveTimeout = 5 * time.Second
+
+// checkISTAddresses copies Spec.Galera.IST.RecvAddr entries into status once
+// their host resolves from the operator, which runs inside the cluster like
+// the pods that connect to them. Pods render status only, so a typo does not
+// leave one unreachable for IST, the ISTAddress condition lists what did not
+// resolve. Entries already in status are not looked up again.
+func (c *Controller) checkISTAddresses(mdbc *componentsv1alpha1.MariaDBCluster) {
+	logger := util.GetClusterLogger(mdbc).WithField("action", "ist")
+	addrs := mdbc.Spec.Galera.IST.RecvAddr
+	for pod := range mdbc.Status.ISTRecvAddr {
+		if _, ok := addrs[pod]; !ok {
+			delete(mdbc.Status.ISTRecvAddr, pod)
+		}
+	}
+	var pods, unresolved []string
+	for pod := range addrs {
+		pods = append(pods, pod)
+	}
+	sort.Strings(pods)
+	for _, pod := range pods {
+		addr := addrs[pod]
+		if mdbc.Status.ISTRecvAddr[pod] == addr {
+			continue
+		}
+		host, err := componentsv1alpha1.ParseISTAddr(addr)
+		if err == nil && net.ParseIP(host) == nil {
+			ctx, cancel := context.WithTimeout(context.Background(), istResolveTimeout)
+			_, err = net.DefaultResolver.LookupHost(ctx, host)
+			cancel()
+		}
+		if err != nil {
+			logger.WithField("event", "unresolved").Warnf("ist recvAddr %s of %s : %s", addr, pod, err.Error())
+			unresolved = append(unresolved, pod+" "+addr)
+			continue
+		}
+		logger.WithField("event", "resolved").Infof("ist recvAddr of %s set to %s", pod, addr)
+		if mdbc.Status.ISTRecvAddr == nil {
+			mdbc.Status.ISTRecvAddr = make(map[string]string)
+		}
+		mdbc.Status.ISTRecvAddr[pod] = addr
+	}
+	if len(mdbc.Status.ISTRecvAddr) == 0 {
+		mdbc.Status.ISTRecvAddr = nil
+	}
+	if len(unresolved) == 0 {
+		mdbc.Status.RemoveCondition(componentsv1alpha1.ConditionISTAddress)
+		return
+	}
+	mdbc.Status.SetCondition(componentsv1alpha1.ConditionISTAddress, false, "Unresolved",
+		"not resolving, not rendered: "+strings.Join(unresolved, ", "))
+}
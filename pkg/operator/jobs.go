@@ -0,0 +1,107 @@
+package operator
+
+import (
+	"strings"
+
+	componentsv1alpha1 "github.com/dansksupermarked/mariadb-galera-operator/pkg/apis/components/v1alpha1"
+	"github.com/dansksupermarked/mariadb-galera-operator/pkg/util"
+	batch "k8s.io/api/batch/v1"
+	"k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// runCheckJob ensures a one-shot Job exists and returns its succeeded pod once
+// complete. The Job is removed once its result has been read so that the next
+// check starts fresh, a failed Job is kept for inspection and reported as such.
+// A complete Job whose pods were garbage collected before the result was read
+// is removed and run again.
+func (c *Controller) runCheckJob(mdbc *componentsv1alpha1.MariaDBCluster, name string, transformer func(*batch.Job) error) (*v1.Pod, bool, error) {
+	logger := util.GetClusterLogger(mdbc).WithField("kind", "Job").WithField("action", "reconcile").WithField("name", name)
+	jobs := c.operator.Client.BatchV1().Jobs(mdbc.Namespace)
+	job, err := jobs.Get(name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		expected := &batch.Job{}
+		transformer(expected)
+		if _, err = jobs.Create(expected); err != nil {
+			logger.Errorf("Creation failed with : %s", err.Error())
+			return nil, false, err
+		}
+		logger.WithField("event", "created").Info()
+		return nil, false, nil
+	} else if err != nil {
+		logger.Errorf("Error fetching object : %s", err.Error())
+		return nil, false, err
+	}
+
+	if isJobConditionTrue(job, batch.JobFailed) {
+		return nil, true, nil
+	}
+	if !isJobConditionTrue(job, batch.JobComplete) {
+		return nil, false, nil
+	}
+	pods, err := c.operator.Client.CoreV1().Pods(mdbc.Namespace).List(metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(map[string]string{"job-name": name}).String(),
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	if len(pods.Items) == 0 {
+		logger.WithField("event", "podsGone").Info("no pod left to read the result from, running the job again")
+		c.deleteJob(mdbc, name)
+		return nil, false, nil
+	}
+	var succeeded *v1.Pod
+	for i := range pods.Items {
+		if pods.Items[i].Status.Phase == v1.PodSucceeded {
+			succeeded = &pods.Items[i]
+		}
+	}
+	c.deleteJob(mdbc, name)
+	return succeeded, succeeded == nil, nil
+}
+
+// deleteJob removes a Job along with its pods, errors are only logged as a
+// leftover Job is retried on the next pass
+func (c *Controller) deleteJob(mdbc *componentsv1alpha1.MariaDBCluster, name string) {
+	logger := util.GetClusterLogger(mdbc).WithField("kind", "Job").WithField("action", "delete").WithField("name", name)
+	propagation := metav1.DeletePropagationBackground
+	err := c.operator.Client.BatchV1().Jobs(mdbc.Namespace).Delete(name, &metav1.DeleteOptions{PropagationPolicy: &propagation})
+	if err != nil && !apierrors.IsNotFound(err) {
+		logger.Errorf("Deletion failed with : %s", err.Error())
+	} else {
+		logger.WithField("event", "deleted").Debug()
+	}
+}
+
+func isJobConditionTrue(job *batch.Job, conditionType batch.JobConditionType) bool {
+	for _, cond := range job.Status.Conditions {
+		if cond.Type == conditionType && cond.Status == v1.ConditionTrue {
+			return true
+		}
+	}
+	return false
+}
+
+// jobFailureMessage returns the termination message of a failed Job pod, or
+// the reason its container terminated when it left none
+func (c *Controller) jobFailureMessage(mdbc *componentsv1alpha1.MariaDBCluster, name string) string {
+	pods, err := c.operator.Client.CoreV1().Pods(mdbc.Namespace).List(metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(map[string]string{"job-name": name}).String(),
+	})
+	if err != nil {
+		return "job " + name + " failed"
+	}
+	for _, pod := range pods.Items {
+		for _, status := range pod.Status.ContainerStatuses {
+			if terminated := status.State.Terminated; terminated != nil && terminated.ExitCode != 0 {
+				if message := strings.TrimSpace(terminated.Message); message != "" {
+					return message
+				}
+				return "job " + name + " failed: " + terminated.Reason
+			}
+		}
+	}
+	return "job " + name + " failed"
+}
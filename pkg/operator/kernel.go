@@ -0,0 +1,57 @@
+package operator
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	componentsv1alpha1 "github.com/dansksupermarked/mariadb-galera-operator/pkg/apis/components/v1alpha1"
+	"github.com/dansksupermarked/mariadb-galera-operator/pkg/util"
+	"k8s.io/api/core/v1"
+)
+
+// checkKernelTuning raises the KernelTuning condition while tune init
+// containers report nodes whose sysctls differ from spec.kernelTuning. Reports
+// of pods that are gone, or left over once the tuning is turned off, are
+// dropped.
+func (c *Controller) checkKernelTuning(mdbc *componentsv1alpha1.MariaDBCluster) {
+	logger := util.GetClusterLogger(mdbc).WithField("action", "kernelTuning")
+	nodes := make(map[string]string)
+	for name, report := range mdbc.Status.Kernel {
+		if !mdbc.IsServerPod(name) || mdbc.Spec.KernelTuning.Mode == "" {
+			delete(mdbc.Status.Kernel, name)
+			continue
+		}
+		var parts []string
+		for _, setting := range report.Differing {
+			parts = append(parts, fmt.Sprintf("%s %s, expected %s", setting.Name, setting.Value, setting.Expected))
+		}
+		nodes[report.Node] = strings.Join(parts, ", ")
+	}
+	if len(mdbc.Status.Kernel) == 0 {
+		mdbc.Status.Kernel = nil
+	}
+	cond := mdbc.Status.GetCondition(componentsv1alpha1.ConditionKernelTuning)
+	if len(nodes) == 0 {
+		if cond != nil {
+			logger.WithField("event", "resolved").Info("kernel parameters of every node conform")
+			mdbc.Status.RemoveCondition(componentsv1alpha1.ConditionKernelTuning)
+		}
+		return
+	}
+	var names []string
+	for node := range nodes {
+		names = append(names, node)
+	}
+	sort.Strings(names)
+	var parts []string
+	for _, node := range names {
+		parts = append(parts, fmt.Sprintf("%s (%s)", node, nodes[node]))
+	}
+	message := "kernel parameters differ on " + strings.Join(parts, "; ")
+	if cond == nil || cond.Message != message {
+		logger.WithField("event", "detected").Warn(message)
+		c.recorder.Event(mdbc, v1.EventTypeWarning, componentsv1alpha1.ConditionKernelTuning, message)
+	}
+	mdbc.Status.SetCondition(componentsv1alpha1.ConditionKernelTuning, true, "NonConformant", message)
+}
@@ -0,0 +1,161 @@
+package operator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+const (
+	// lock on a coordination.k8s.io Lease, next to the Endpoints and
+	// ConfigMaps ones of resourcelock
+	LeasesResourceLock = "leases"
+	leaseAPIVersion    = "coordination.k8s.io/v1"
+	// namespace of the service account mounted into the operator pod
+	serviceAccountNamespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+)
+
+// lease is the part of a coordination.k8s.io/v1 Lease leader election uses,
+// the vendored API types predate it
+type lease struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+	Spec              struct {
+		HolderIdentity       string           `json:"holderIdentity"`
+		LeaseDurationSeconds int              `json:"leaseDurationSeconds"`
+		AcquireTime          metav1.MicroTime `json:"acquireTime"`
+		RenewTime            metav1.MicroTime `json:"renewTime"`
+		LeaseTransitions     int              `json:"leaseTransitions"`
+	} `json:"spec"`
+}
+
+// leaseLock holds the leader election record in a Lease, read and written as
+// plain JSON
+type leaseLock struct {
+	namespace string
+	name      string
+	client    rest.Interface
+	config    resourcelock.ResourceLockConfig
+	lease     *lease
+}
+
+func (l *leaseLock) path(name ...string) []string {
+	return append([]string{"/apis", leaseAPIVersion, "namespaces", l.namespace, "leases"}, name...)
+}
+
+// Get returns the election record of the Lease
+func (l *leaseLock) Get() (*resourcelock.LeaderElectionRecord, error) {
+	body, err := l.client.Get().AbsPath(l.path(l.name)...).Do().Raw()
+	if err != nil {
+		return nil, err
+	}
+	current := &lease{}
+	if err = json.Unmarshal(body, current); err != nil {
+		return nil, err
+	}
+	l.lease = current
+	return &resourcelock.LeaderElectionRecord{
+		HolderIdentity:       current.Spec.HolderIdentity,
+		LeaseDurationSeconds: current.Spec.LeaseDurationSeconds,
+		AcquireTime:          metav1.NewTime(current.Spec.AcquireTime.Time),
+		RenewTime:            metav1.NewTime(current.Spec.RenewTime.Time),
+		LeaderTransitions:    current.Spec.LeaseTransitions,
+	}, nil
+}
+
+// Create creates the Lease holding the election record
+func (l *leaseLock) Create(ler resourcelock.LeaderElectionRecord) error {
+	expected := &lease{
+		TypeMeta:   metav1.TypeMeta{APIVersion: leaseAPIVersion, Kind: "Lease"},
+		ObjectMeta: metav1.ObjectMeta{Namespace: l.namespace, Name: l.name},
+	}
+	return l.write(expected, ler, l.client.Post().AbsPath(l.path()...))
+}
+
+// Update writes the election record into the Lease last read or written
+func (l *leaseLock) Update(ler resourcelock.LeaderElectionRecord) error {
+	if l.lease == nil {
+		return errors.New("lease not initialized, call get or create first")
+	}
+	return l.write(l.lease, ler, l.client.Put().AbsPath(l.path(l.name)...))
+}
+
+func (l *leaseLock) write(expected *lease, ler resourcelock.LeaderElectionRecord, request *rest.Request) error {
+	expected.Spec.HolderIdentity = ler.HolderIdentity
+	expected.Spec.LeaseDurationSeconds = ler.LeaseDurationSeconds
+	expected.Spec.AcquireTime = metav1.NewMicroTime(ler.AcquireTime.Time)
+	expected.Spec.RenewTime = metav1.NewMicroTime(ler.RenewTime.Time)
+	expected.Spec.LeaseTransitions = ler.LeaderTransitions
+	body, err := json.Marshal(expected)
+	if err != nil {
+		return err
+	}
+	if body, err = request.Body(body).Do().Raw(); err != nil {
+		return err
+	}
+	written := &lease{}
+	if err = json.Unmarshal(body, written); err != nil {
+		return err
+	}
+	l.lease = written
+	return nil
+}
+
+// RecordEvent records an Event on the Lease, referenced through an
+// unstructured object as its kind is unknown to the scheme
+func (l *leaseLock) RecordEvent(s string) {
+	if l.lease == nil {
+		return
+	}
+	ref := &unstructured.Unstructured{}
+	ref.SetAPIVersion(leaseAPIVersion)
+	ref.SetKind("Lease")
+	ref.SetNamespace(l.lease.Namespace)
+	ref.SetName(l.lease.Name)
+	ref.SetUID(l.lease.UID)
+	ref.SetResourceVersion(l.lease.ResourceVersion)
+	l.config.EventRecorder.Eventf(ref, "Normal", "LeaderElection", "%v %v", l.config.Identity, s)
+}
+
+// Describe returns the namespace and name of the Lease
+func (l *leaseLock) Describe() string {
+	return fmt.Sprintf("%v/%v", l.namespace, l.name)
+}
+
+// Identity returns the identity the lock is held under
+func (l *leaseLock) Identity() string {
+	return l.config.Identity
+}
+
+// leaderElectionLock returns the lock of LeaderElectionLock kind in
+// LeaderElectionNamespace, or the namespace of the operator pod, held under
+// the name of the pod so that every replica competes under its own identity
+func (op *Operator) leaderElectionLock() (resourcelock.Interface, error) {
+	identity, err := os.Hostname()
+	if err != nil {
+		return nil, err
+	}
+	ns := op.LeaderElectionNamespace
+	if ns == "" {
+		ns = namespace
+		if body, err := ioutil.ReadFile(serviceAccountNamespaceFile); err == nil && strings.TrimSpace(string(body)) != "" {
+			ns = strings.TrimSpace(string(body))
+		}
+	}
+	config := resourcelock.ResourceLockConfig{
+		Identity:      identity,
+		EventRecorder: createRecorder(op.electionClient, name, ns, op.EventDedupWindow),
+	}
+	if op.LeaderElectionLock == LeasesResourceLock {
+		return &leaseLock{namespace: ns, name: op.LeaderElectionID, client: op.electionClient.CoreV1().RESTClient(), config: config}, nil
+	}
+	return resourcelock.New(op.LeaderElectionLock, ns, op.LeaderElectionID, op.electionClient.CoreV1(), config)
+}
@@ -0,0 +1,80 @@
+package operator
+
+import (
+	"fmt"
+
+	componentsv1alpha1 "github.com/dansksupermarked/mariadb-galera-operator/pkg/apis/components/v1alpha1"
+	"github.com/dansksupermarked/mariadb-galera-operator/pkg/util"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// bootstraps kept in status.lineage, older ones are dropped
+const maxBootstrapHistory = 10
+
+// trackLineage records the cluster UUID and highest seqno serving pods report
+// as part of the primary component. A new UUID, as after bootstrapping from
+// an empty volume, starts over.
+func trackLineage(mdbc *componentsv1alpha1.MariaDBCluster) {
+	var uuid string
+	var seqno int64 = -1
+	for _, status := range mdbc.Status.WSREP {
+		color := status.Color
+		if color == "" {
+			color = componentsv1alpha1.ColorBlue
+		}
+		if color != mdbc.GetActiveColor() || status.ClusterStatus != componentsv1alpha1.WSREPClusterStatusPrimary {
+			continue
+		}
+		if status.LastCommitted > seqno {
+			uuid, seqno = status.ClusterStateUUID, status.LastCommitted
+		}
+	}
+	if uuid == "" {
+		return
+	}
+	lineage := mdbc.Status.Lineage
+	if lineage == nil {
+		lineage = &componentsv1alpha1.LineageStatus{}
+		mdbc.Status.Lineage = lineage
+	}
+	if lineage.ClusterStateUUID != uuid {
+		if lineage.ClusterStateUUID != "" {
+			util.GetClusterLogger(mdbc).WithField("action", "lineage").WithField("event", "changed").
+				Warnf("cluster now runs as %s, was %s at seqno %d", uuid, lineage.ClusterStateUUID, lineage.LastCommitted)
+		}
+		lineage.ClusterStateUUID = uuid
+		lineage.LastCommitted = seqno
+	} else if seqno > lineage.LastCommitted {
+		lineage.LastCommitted = seqno
+	}
+}
+
+// recordBootstrap adds a primary component bootstrapped from pod to the history
+// in status.lineage
+func recordBootstrap(mdbc *componentsv1alpha1.MariaDBCluster, pod, reason, uuid string, seqno int64) {
+	lineage := mdbc.Status.Lineage
+	if lineage == nil {
+		lineage = &componentsv1alpha1.LineageStatus{}
+		mdbc.Status.Lineage = lineage
+	}
+	lineage.Bootstraps = append(lineage.Bootstraps, componentsv1alpha1.BootstrapRecord{
+		Time:             metav1.Now(),
+		Pod:              pod,
+		Reason:           reason,
+		ClusterStateUUID: uuid,
+		SeqNo:            seqno,
+	})
+	if len(lineage.Bootstraps) > maxBootstrapHistory {
+		lineage.Bootstraps = lineage.Bootstraps[len(lineage.Bootstraps)-maxBootstrapHistory:]
+	}
+}
+
+// checkLineage refuses a bootstrap pod holding another cluster than the one
+// last seen running, it would not be joined by pods that kept the right one
+func checkLineage(mdbc *componentsv1alpha1.MariaDBCluster, pod string, state componentsv1alpha1.GRAState) error {
+	lineage := mdbc.Status.Lineage
+	if lineage == nil || lineage.ClusterStateUUID == "" || state.UUID == lineage.ClusterStateUUID {
+		return nil
+	}
+	return fmt.Errorf("%s reported cluster %s while the cluster last ran as %s", pod, state.UUID, lineage.ClusterStateUUID)
+}
@@ -0,0 +1,163 @@
+package operator
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+)
+
+// entries kept by eventFilter and samplingFormatter before expired ones are
+// swept out
+const noiseSweepSize = 1024
+
+// eventKey is what an Event is told apart from others of the same object by
+type eventKey struct {
+	uid       types.UID
+	eventtype string
+	reason    string
+}
+
+type recordedEvent struct {
+	message string
+	time    time.Time
+}
+
+// eventFilter drops an Event repeating the last one of its object and reason
+// within window, as those of the same state on every reconcile. An Event with
+// another message is always recorded, so that every change of state is told,
+// and so is a repeated one once window passed. The broadcaster correlates the
+// Events recorded, aggregating similar ones into counts.
+type eventFilter struct {
+	record.EventRecorder
+	window time.Duration
+
+	mu      sync.Mutex
+	last    map[eventKey]recordedEvent
+	sweepAt int
+}
+
+func newEventFilter(recorder record.EventRecorder, window time.Duration) record.EventRecorder {
+	if window <= 0 {
+		return recorder
+	}
+	return &eventFilter{EventRecorder: recorder, window: window, last: map[eventKey]recordedEvent{}, sweepAt: noiseSweepSize}
+}
+
+func (f *eventFilter) Event(object runtime.Object, eventtype, reason, message string) {
+	if f.novel(object, eventtype, reason, message) {
+		f.EventRecorder.Event(object, eventtype, reason, message)
+	}
+}
+
+func (f *eventFilter) Eventf(object runtime.Object, eventtype, reason, messageFmt string, args ...interface{}) {
+	f.Event(object, eventtype, reason, fmt.Sprintf(messageFmt, args...))
+}
+
+// novel tells whether an Event is to be recorded, remembering it when it is
+func (f *eventFilter) novel(object runtime.Object, eventtype, reason, message string) bool {
+	accessor, err := meta.Accessor(object)
+	if err != nil || accessor.GetUID() == "" {
+		return true
+	}
+	key := eventKey{uid: accessor.GetUID(), eventtype: eventtype, reason: reason}
+	now := time.Now()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if last, ok := f.last[key]; ok && last.message == message && now.Sub(last.time) < f.window {
+		return false
+	}
+	f.last[key] = recordedEvent{message: message, time: now}
+	if len(f.last) > f.sweepAt {
+		for key, last := range f.last {
+			if now.Sub(last.time) >= f.window {
+				delete(f.last, key)
+			}
+		}
+		f.sweepAt = 2 * len(f.last)
+		if f.sweepAt < noiseSweepSize {
+			f.sweepAt = noiseSweepSize
+		}
+	}
+	return true
+}
+
+type sampledLine struct {
+	since   time.Time
+	dropped int
+}
+
+// samplingFormatter leaves out info and debug lines repeating, with the same
+// fields, one logged within window, as the ones of reconciles finding nothing
+// to change. The next one logged after window tells how many were left out
+// in repeated. Warnings and errors are always logged.
+type samplingFormatter struct {
+	logrus.Formatter
+	window time.Duration
+
+	mu      sync.Mutex
+	seen    map[string]*sampledLine
+	sweepAt int
+}
+
+func newSamplingFormatter(formatter logrus.Formatter, window time.Duration) *samplingFormatter {
+	return &samplingFormatter{Formatter: formatter, window: window, seen: map[string]*sampledLine{}, sweepAt: noiseSweepSize}
+}
+
+func (f *samplingFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	if entry.Level <= logrus.WarnLevel {
+		return f.Formatter.Format(entry)
+	}
+	key := lineKey(entry)
+	f.mu.Lock()
+	line := f.seen[key]
+	if line != nil && entry.Time.Sub(line.since) < f.window {
+		line.dropped++
+		f.mu.Unlock()
+		return nil, nil
+	}
+	var dropped int
+	if line != nil {
+		dropped = line.dropped
+	}
+	f.seen[key] = &sampledLine{since: entry.Time}
+	if len(f.seen) > f.sweepAt {
+		for key, line := range f.seen {
+			if entry.Time.Sub(line.since) >= f.window {
+				delete(f.seen, key)
+			}
+		}
+		f.sweepAt = 2 * len(f.seen)
+		if f.sweepAt < noiseSweepSize {
+			f.sweepAt = noiseSweepSize
+		}
+	}
+	f.mu.Unlock()
+
+	if dropped > 0 {
+		repeated := *entry
+		repeated.Data = logrus.Fields{"repeated": dropped}
+		for k, v := range entry.Data {
+			repeated.Data[k] = v
+		}
+		entry = &repeated
+	}
+	return f.Formatter.Format(entry)
+}
+
+// lineKey returns the level, message and fields of a line, sorted
+func lineKey(entry *logrus.Entry) string {
+	fields := make([]string, 0, len(entry.Data))
+	for k, v := range entry.Data {
+		fields = append(fields, fmt.Sprintf("%s=%v", k, v))
+	}
+	sort.Strings(fields)
+	return entry.Level.String() + " " + entry.Message + " " + strings.Join(fields, " ")
+}
@@ -0,0 +1,136 @@
+package operator
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	componentsv1alpha1 "github.com/dansksupermarked/mariadb-galera-operator/pkg/apis/components/v1alpha1"
+	"github.com/dansksupermarked/mariadb-galera-operator/pkg/util"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// galera has a pod rejoin by itself once a partition heals, it is only
+	// restarted when still outside of the primary component after this long
+	nonPrimaryGracePeriod = 2 * time.Minute
+	// time a pod told to bootstrap gets before the cluster goes through Recovery
+	pcBootstrapTimeout = 2 * time.Minute
+)
+
+// checkNonPrimary restarts serving pods left outside of the primary component
+// for longer than nonPrimaryGracePeriod while others are part of it, so that
+// they rejoin. A pending pc.bootstrap is done once pods are ready again.
+func (c *Controller) checkNonPrimary(mdbc *componentsv1alpha1.MariaDBCluster) error {
+	logger := util.GetClusterLogger(mdbc).WithField("action", "nonPrimary")
+	if request := mdbc.Status.PCBootstrap; request != nil {
+		message := fmt.Sprintf("primary component restored by %s", request.Pod)
+		logger.WithField("event", "restored").Info(message)
+		c.recorder.Event(mdbc, v1.EventTypeNormal, "PrimaryRestored", message)
+		mdbc.Status.PCBootstrap = nil
+	}
+
+	var primary int
+	var outside []string
+	for name, status := range mdbc.Status.WSREP {
+		color := status.Color
+		if color == "" {
+			color = componentsv1alpha1.ColorBlue
+		}
+		if color != mdbc.GetActiveColor() {
+			continue
+		}
+		if status.ClusterStatus == componentsv1alpha1.WSREPClusterStatusPrimary {
+			primary++
+		} else if !status.ClusterStatusSince.IsZero() && time.Since(status.ClusterStatusSince.Time) > nonPrimaryGracePeriod {
+			outside = append(outside, name)
+		}
+	}
+	if primary == 0 {
+		return nil
+	}
+	sort.Strings(outside)
+	for _, name := range outside {
+		status := mdbc.Status.WSREP[name]
+		restarted, err := c.restartReportedPod(mdbc, name)
+		if err != nil {
+			return err
+		}
+		if !restarted {
+			continue
+		}
+		message := fmt.Sprintf("%s was %s for %s while %d pods are part of the primary component, restarted it to rejoin",
+			name, status.ClusterStatus, time.Since(status.ClusterStatusSince.Time).Truncate(time.Second), primary)
+		logger.WithField("event", "restarted").Warn(message)
+		c.recorder.Event(mdbc, v1.EventTypeWarning, "NonPrimaryRestarted", message)
+	}
+	return nil
+}
+
+// checkPCBootstrap handles a cluster whose pods all lost quorum while still
+// running, as after a partition between every one of them. With the Bootstrap
+// non-Primary policy the most advanced pod is told through status to form a
+// new primary component, which the others rejoin without any restart. This is
+// only done when every pod reports and none of them is part of a primary
+// component, otherwise one might still be serving unseen. Returns false when
+// the cluster is to go through Recovery instead.
+func (c *Controller) checkPCBootstrap(mdbc *componentsv1alpha1.MariaDBCluster) bool {
+	if mdbc.Spec.Recovery.GetNonPrimaryPolicy() != componentsv1alpha1.NonPrimaryPolicyBootstrap {
+		return false
+	}
+	logger := util.GetClusterLogger(mdbc).WithField("action", "nonPrimary")
+	if request := mdbc.Status.PCBootstrap; request != nil {
+		if time.Since(request.Time.Time) < pcBootstrapTimeout {
+			return true
+		}
+		message := fmt.Sprintf("%s did not restore a primary component within %s, recovering", request.Pod, pcBootstrapTimeout)
+		logger.WithField("event", "timeout").Warn(message)
+		c.recorder.Event(mdbc, v1.EventTypeWarning, "PCBootstrapTimeout", message)
+		mdbc.Status.PCBootstrap = nil
+		return false
+	}
+
+	pruneWSREPStatus(mdbc)
+	var names []string
+	for name, status := range mdbc.Status.WSREP {
+		color := status.Color
+		if color == "" {
+			color = componentsv1alpha1.ColorBlue
+		}
+		if color == mdbc.GetActiveColor() && mdbc.IsServerPod(name) {
+			names = append(names, name)
+		}
+	}
+	if int32(len(names)) < mdbc.Spec.Replicas {
+		logger.WithField("event", "skipped").Infof("%d of %d pods report their state, not bootstrapping", len(names), mdbc.Spec.Replicas)
+		return false
+	}
+	sort.Strings(names)
+	var selected, uuid string
+	var seqno int64 = -1
+	for _, name := range names {
+		status := mdbc.Status.WSREP[name]
+		if status.ClusterStatus == componentsv1alpha1.WSREPClusterStatusPrimary {
+			return false
+		}
+		if uuid != "" && status.ClusterStateUUID != uuid {
+			logger.WithField("event", "skipped").Warnf("%s reports cluster %s while others report %s, not bootstrapping", name, status.ClusterStateUUID, uuid)
+			return false
+		}
+		uuid = status.ClusterStateUUID
+		if status.LastCommitted > seqno {
+			selected, seqno = name, status.LastCommitted
+		}
+	}
+	message := fmt.Sprintf("all pods lost quorum, bootstrapping a new primary component from %s at seqno %d", selected, seqno)
+	logger.WithField("event", "bootstrap").Warn(message)
+	c.recorder.Event(mdbc, v1.EventTypeWarning, "PCBootstrap", message)
+	recordBootstrap(mdbc, selected, "PCBootstrap", uuid, seqno)
+	mdbc.Status.PCBootstrap = &componentsv1alpha1.PCBootstrapStatus{
+		Pod:           selected,
+		LastCommitted: seqno,
+		Time:          metav1.Now(),
+	}
+	return true
+}
@@ -1,18 +1,24 @@
 package operator
 
 import (
+	"context"
 	"flag"
+	"net/http"
 	"os"
 	"os/signal"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	componentsclientset "github.com/dansksupermarked/mariadb-galera-operator/pkg/generated/clientset/versioned"
 	componentsinformers "github.com/dansksupermarked/mariadb-galera-operator/pkg/generated/informers/externalversions"
+	"github.com/dansksupermarked/mariadb-galera-operator/pkg/webhook"
 
 	"github.com/Sirupsen/logrus"
 	corev1 "k8s.io/api/core/v1"
 	apiextensionsclientset "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/scheme"
@@ -20,16 +26,33 @@ import (
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/client-go/tools/leaderelection"
-	"k8s.io/client-go/tools/leaderelection/resourcelock"
 	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
 )
 
 const (
 	defaultKubeAPIRequestTimeout = 30 * time.Second
+	// clusters reconciled at the same time
+	DefaultWorkers = 1
+	// every cluster is reconciled at least this often
+	DefaultResyncPeriod = 5 * time.Minute
+	// backoff of a cluster failing to reconcile, and the rate of retries of
+	// all clusters, the ones of workqueue.DefaultControllerRateLimiter
+	DefaultRetryBaseDelay = 5 * time.Millisecond
+	DefaultRetryMaxDelay  = 1000 * time.Second
+	DefaultRetryQPS       = 10
+	DefaultRetryBurst     = 100
+	// events of a cluster within this window are reconciled once
+	DefaultDebounce = time.Second
+	// an Event or log line repeating one within these is left out
+	DefaultEventDedupWindow = 30 * time.Minute
+	DefaultLogSampleWindow  = 5 * time.Minute
+	// time given to workers to finish on termination, below the default
+	// grace period of pods
+	shutdownTimeout = 25 * time.Second
 	//	TODO: remove these temporary assocs with coded
 	name      = "mariadb-operator"
 	namespace = "kube-system"
-	id        = "123213123"
 )
 
 type Operator struct {
@@ -38,11 +61,98 @@ type Operator struct {
 	Client              *kubernetes.Clientset
 	ComponentsClient    *componentsclientset.Clientset
 	ApiExtensionsClient *apiextensionsclientset.Clientset
+	// client of leader election, apart from the rate limits of the others
+	// so that renewing the lease never waits on reconciliation
+	electionClient *kubernetes.Clientset
+	// operator whose clients turn changes into dry runs, collected in
+	// dryRunLog, the operator itself with DryRun
+	dryRun    *Operator
+	dryRunLog *dryRunLog
+	// Admission webhook served by every operator pod, leader or not, when
+	// given a certificate
+	Webhook webhook.Server
+	// Update an existing CustomResourceDefinition to the one of this version
+	// on start, instead of only creating it when missing
+	UpgradeCRDs bool
+	// Service in front of the webhook server as namespace/name, and the CA of
+	// its certificate, registered as conversion webhook of the definition
+	ConversionService string
+	ConversionCAFile  string
+	// Rewrite every MariaDBCluster in the storage version on start and drop
+	// the other versions from the stored versions of the definition
+	MigrateStorage bool
+	// Number of clusters reconciled concurrently, a cluster is never
+	// reconciled by two workers at once
+	Workers int
+	// Kind of object the leader election lock is held in, one of leases,
+	// endpoints and configmaps, and its namespace, the one of the operator
+	// pod by default
+	LeaderElectionLock      string
+	LeaderElectionNamespace string
+	// Name of the lock, operators sharing clusters out through
+	// InstanceSelector each need their own
+	LeaderElectionID string
+	// Namespace clusters are watched in, all of them when empty. The
+	// CustomResourceDefinition is then left to be installed beforehand, so
+	// that the operator gets by with namespaced permissions.
+	Namespace string
+	// Label selector of the clusters this operator manages, so that several
+	// operators can share clusters out among them. Empty selects them all.
+	InstanceSelector string
+	// Period of informer resyncs, on which every cluster is reconciled again
+	// to correct drift of its objects whose changes were missed. Zero
+	// disables them.
+	ResyncPeriod time.Duration
+	// Backoff of a cluster failing to reconcile, doubling from RetryBaseDelay
+	// up to RetryMaxDelay, and the overall rate of retries of all clusters
+	RetryBaseDelay time.Duration
+	RetryMaxDelay  time.Duration
+	RetryQPS       float64
+	RetryBurst     int
+	// Rate of requests to the API server, the client-go defaults when zero
+	KubeAPIQPS   float32
+	KubeAPIBurst int
+	// Requests to the API server in flight at once, watches aside, unbounded
+	// when zero
+	MaxInflightRequests int
+	// Only tell what would be changed, for every cluster rather than those
+	// annotated with MariaDBClusterDryRunAnnotation
+	DryRun bool
+	// Delay of reconciling a cluster after an event, further events within
+	// it are coalesced into the same reconcile. Zero reconciles right away.
+	Debounce time.Duration
+	// Window an Event repeating the last one of its object and reason, or an
+	// info or debug line repeating one, is left out within. Zero keeps all.
+	EventDedupWindow time.Duration
+	LogSampleWindow  time.Duration
+
+	// cancelled on termination, stopping informers, workers and requests
+	// of the operator made while starting. Copies of the operator syncing a
+	// cluster carry the context of the sync instead, see withContext.
+	ctx context.Context
+	// configuration of the clients of withContext, nil until started
+	contextConfig *rest.Config
+	// set once leading, stopped is closed when the workers returned
+	leading int32
+	stopped chan struct{}
 }
 
 func NewOperator() *Operator {
 	op := &Operator{
-		Name: "mariadb-operator",
+		Name:               "mariadb-operator",
+		Workers:            DefaultWorkers,
+		LeaderElectionLock: LeasesResourceLock,
+		LeaderElectionID:   name,
+		ResyncPeriod:       DefaultResyncPeriod,
+		RetryBaseDelay:     DefaultRetryBaseDelay,
+		RetryMaxDelay:      DefaultRetryMaxDelay,
+		RetryQPS:           DefaultRetryQPS,
+		RetryBurst:         DefaultRetryBurst,
+		Debounce:           DefaultDebounce,
+		EventDedupWindow:   DefaultEventDedupWindow,
+		LogSampleWindow:    DefaultLogSampleWindow,
+		ctx:                context.Background(),
+		stopped:            make(chan struct{}),
 	}
 	return op
 }
@@ -61,30 +171,97 @@ func (op *Operator) Start() {
 		}
 	}
 	op.ClientConfig.Timeout = defaultKubeAPIRequestTimeout
+	op.ClientConfig.QPS = op.KubeAPIQPS
+	op.ClientConfig.Burst = op.KubeAPIBurst
+	if _, err = labels.Parse(op.InstanceSelector); err != nil {
+		logrus.Fatalf("invalid instance selector %q : %s", op.InstanceSelector, err.Error())
+	}
+	if op.RetryBaseDelay <= 0 || op.RetryMaxDelay < op.RetryBaseDelay || op.RetryQPS <= 0 || op.RetryBurst <= 0 {
+		logrus.Fatalf("invalid retry rate limits: base delay %s, max delay %s, qps %v, burst %d", op.RetryBaseDelay, op.RetryMaxDelay, op.RetryQPS, op.RetryBurst)
+	}
+	if op.Debounce < 0 {
+		logrus.Fatalf("invalid debounce window %s", op.Debounce)
+	}
+	if op.EventDedupWindow < 0 || op.LogSampleWindow < 0 {
+		logrus.Fatalf("invalid deduplication windows: events %s, logs %s", op.EventDedupWindow, op.LogSampleWindow)
+	}
+	if op.LogSampleWindow > 0 {
+		logrus.SetFormatter(newSamplingFormatter(&logrus.TextFormatter{}, op.LogSampleWindow))
+	}
+	if op.KubeAPIQPS < 0 || op.KubeAPIBurst < 0 || op.MaxInflightRequests < 0 {
+		logrus.Fatalf("invalid API server rate limits: qps %v, burst %d, max in flight %d", op.KubeAPIQPS, op.KubeAPIBurst, op.MaxInflightRequests)
+	}
 
+	electionConfig := rest.CopyConfig(op.ClientConfig)
+	electionConfig.QPS = 0
+	electionConfig.Burst = 0
+	op.electionClient = kubernetes.NewForConfigOrDie(electionConfig)
+	var inflight func(rt http.RoundTripper) http.RoundTripper
+	if op.MaxInflightRequests > 0 {
+		inflight = limitInflight(op.MaxInflightRequests)
+	}
+	op.dryRunLog = &dryRunLog{}
+	dryRunConfig := rest.CopyConfig(op.ClientConfig)
+	dryRunConfig.WrapTransport = op.dryRunLog.wrap(inflight)
+	if op.DryRun {
+		logrus.Warn("dry run, changes are only validated by the API server and logged")
+		op.ClientConfig = dryRunConfig
+	} else {
+		op.ClientConfig.WrapTransport = inflight
+	}
 	op.Client = kubernetes.NewForConfigOrDie(op.ClientConfig)
 	op.ComponentsClient = componentsclientset.NewForConfigOrDie(op.ClientConfig)
 	op.ApiExtensionsClient = apiextensionsclientset.NewForConfigOrDie(op.ClientConfig)
+	if op.contextConfig, err = contextConfig(op.ClientConfig); err != nil {
+		panic(err)
+	}
+	op.dryRun = op
+	if !op.DryRun {
+		dryRun := *op
+		dryRun.Client = kubernetes.NewForConfigOrDie(dryRunConfig)
+		dryRun.ComponentsClient = componentsclientset.NewForConfigOrDie(dryRunConfig)
+		dryRun.ApiExtensionsClient = apiextensionsclientset.NewForConfigOrDie(dryRunConfig)
+		if dryRun.contextConfig, err = contextConfig(dryRunConfig); err != nil {
+			panic(err)
+		}
+		op.dryRun = &dryRun
+	}
 
-	// Take care of termination by signal
-	c := make(chan os.Signal, 1)
+	// Take care of termination by signal, letting workers finish the
+	// clusters at hand unless signalled again
+	ctx, cancel := context.WithCancel(context.Background())
+	op.ctx = ctx
+	c := make(chan os.Signal, 2)
 	signal.Notify(c, syscall.SIGTERM, syscall.SIGSTOP, syscall.SIGHUP, syscall.SIGQUIT, syscall.SIGINT)
 	go func() {
-		logrus.Infof("received signal: %v, exiting", <-c)
+		logrus.Infof("received signal: %v, shutting down", <-c)
+		cancel()
+		if atomic.LoadInt32(&op.leading) == 0 {
+			os.Exit(0)
+		}
+		select {
+		case <-op.stopped:
+			logrus.Info("workers stopped, exiting")
+			os.Exit(0)
+		case sig := <-c:
+			logrus.Infof("received signal: %v, exiting", sig)
+		case <-time.After(shutdownTimeout):
+			logrus.Warnf("workers still busy after %s, exiting", shutdownTimeout)
+		}
 		os.Exit(1)
 	}()
 
-	lock, err := resourcelock.New(resourcelock.EndpointsResourceLock,
-		namespace,
-		op.Name,
-		op.Client.CoreV1(),
-		resourcelock.ResourceLockConfig{
-			Identity:      id,
-			EventRecorder: createRecorder(op.Client, name, namespace),
-		})
+	if op.Webhook.CertFile != "" {
+		go func() {
+			logrus.Fatalf("admission webhook stopped : %s", op.Webhook.Run())
+		}()
+	}
+
+	lock, err := op.leaderElectionLock()
 	if err != nil {
-		panic("no configuration for kubernetes client")
+		panic(err)
 	}
+	logrus.WithField("action", "leaderElection").Infof("waiting to lead through %s %s as %s", op.LeaderElectionLock, lock.Describe(), lock.Identity())
 
 	leaderelection.RunOrDie(leaderelection.LeaderElectionConfig{
 		Lock:          lock,
@@ -102,22 +279,53 @@ func (op *Operator) Start() {
 	panic("wtf")
 }
 
-// Register all supported CRDs and launch all supported controller versions
-func (op *Operator) run(stop <-chan struct{}) {
+// Register all supported CRDs and launch all supported controller versions,
+// returning once they stopped on termination or loss of leadership
+func (op *Operator) run(leading <-chan struct{}) {
+	atomic.StoreInt32(&op.leading, 1)
+	defer close(op.stopped)
+	stop := make(chan struct{})
+	go func() {
+		select {
+		case <-leading:
+		case <-op.ctx.Done():
+		}
+		close(stop)
+	}()
 	// v1alpha1api :=
 	// Register all supported CRDs
 	op.EnsureSupportedCRDs()
+	if op.MigrateStorage {
+		if err := op.migrateStorage(); err != nil {
+			logrus.WithField("action", "migrateStorage").Errorf("Storage migration failed with : %s", err.Error())
+		}
+	}
 	// Get informerFactories
-	kubeInformerFactory := informers.NewSharedInformerFactory(op.Client, time.Second*30)
-	componentInformerFactory := componentsinformers.NewSharedInformerFactory(op.ComponentsClient, time.Second*30)
+	kubeInformerFactory := informers.NewFilteredSharedInformerFactory(op.Client, op.ResyncPeriod, op.Namespace, nil)
+	// StatefulSets and ConfigMaps are watched whatever their labels, for
+	// conflicts with objects of the names of a cluster to be noticed
+	componentInformerFactory := componentsinformers.NewFilteredSharedInformerFactory(op.ComponentsClient, op.ResyncPeriod, op.Namespace, op.selectClusters)
 	// Launch all supported controller versions
 	// v1alpha1ctrl := NewController(op, kubeInformerFactory)
 	v1alpha1ctrl := NewController(op, kubeInformerFactory, componentInformerFactory)
-	go v1alpha1ctrl.Run()
 
 	go kubeInformerFactory.Start(stop)
 	go componentInformerFactory.Start(stop)
+	v1alpha1ctrl.Run(op.Workers, stop)
+}
+
+// rateLimiter returns the rate limiter of the workqueue, built as
+// workqueue.DefaultControllerRateLimiter from the Retry settings
+func (op *Operator) rateLimiter() workqueue.RateLimiter {
+	return workqueue.NewMaxOfRateLimiter(
+		workqueue.NewItemExponentialFailureRateLimiter(op.RetryBaseDelay, op.RetryMaxDelay),
+		newBucketRateLimiter(op.RetryQPS, op.RetryBurst),
+	)
+}
 
+// selectClusters restricts listing MariaDBClusters to the InstanceSelector
+func (op *Operator) selectClusters(options *metav1.ListOptions) {
+	options.LabelSelector = op.InstanceSelector
 }
 
 func InClusterConfig() (*rest.Config, error) {
@@ -136,11 +344,11 @@ func OutOfClusterConfig() (*rest.Config, error) {
 	return cfg, nil
 }
 
-func createRecorder(kcs *kubernetes.Clientset, name, namespace string) record.EventRecorder {
+func createRecorder(kcs *kubernetes.Clientset, name, namespace string, window time.Duration) record.EventRecorder {
 	eventBroadcaster := record.NewBroadcaster()
 	eventBroadcaster.StartLogging(logrus.Infof)
 	eventBroadcaster.StartRecordingToSink(&v1.EventSinkImpl{Interface: kcs.CoreV1().Events(namespace)})
-	return eventBroadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: name})
+	return newEventFilter(eventBroadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: name}), window)
 }
 
 // func getMyPodServiceAccount(kubecli kubernetes.Interface) (string, error) {
@@ -0,0 +1,68 @@
+package operator
+
+import (
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strings"
+
+	"github.com/Sirupsen/logrus"
+	componentsv1alpha1 "github.com/dansksupermarked/mariadb-galera-operator/pkg/apis/components/v1alpha1"
+	"github.com/dansksupermarked/mariadb-galera-operator/pkg/util"
+	"k8s.io/api/core/v1"
+)
+
+// frames of the panicking goroutine kept in the ReconcileError condition
+const panicStackFrames = 5
+
+// syncRecovered syncs a key, turning a panic of the reconcile into an error
+// retried as any other, so that the worker goes on with the other clusters.
+// The cluster gets the ReconcileError condition telling where it panicked.
+func (c *Controller) syncRecovered(key string) (err error) {
+	defer func() {
+		r := recover()
+		if r == nil {
+			return
+		}
+		summary := stackSummary()
+		err = fmt.Errorf("panic: %v at %s", r, summary)
+		logrus.WithField("action", "reconcile").WithField("event", "panic").Errorf("reconciling %s panicked : %v\n%s", key, r, debug.Stack())
+		c.reportPanic(key, err)
+	}()
+	return c.syncHandler(key)
+}
+
+// reportPanic raises the ReconcileError condition of the cluster of a key
+func (c *Controller) reportPanic(key string, err error) {
+	mdbc := c.getQueuedCluster(key)
+	if mdbc == nil {
+		return
+	}
+	logger := util.GetClusterLogger(mdbc).WithField("kind", "MariaDBCluster").WithField("action", "reconcile")
+	c.recorder.Event(mdbc, v1.EventTypeWarning, componentsv1alpha1.ConditionReconcileError, err.Error())
+	expected := mdbc.DeepCopy()
+	expected.Status.SetCondition(componentsv1alpha1.ConditionReconcileError, true, "Panic", err.Error())
+	checkAndPatchMariaDBCluster(mdbc, expected, c.forCluster(mdbc).operator.ComponentsClient.Components(), logger)
+}
+
+// stackSummary returns the innermost frames of the panicking goroutine, as
+// operator.(*Controller).MariaDBClusterTransform (controller.go:412), when
+// called from a deferred function
+func stackSummary() string {
+	pcs := make([]uintptr, 64)
+	// past runtime.Callers, stackSummary and the deferred function
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(3, pcs)])
+	var summary []string
+	for len(summary) < panicStackFrames {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, "runtime.") {
+			function := frame.Function[strings.LastIndex(frame.Function, "/")+1:]
+			summary = append(summary, fmt.Sprintf("%s (%s:%d)", function, filepath.Base(frame.File), frame.Line))
+		}
+		if !more {
+			break
+		}
+	}
+	return strings.Join(summary, " < ")
+}
@@ -0,0 +1,97 @@
+package operator
+
+import (
+	"sort"
+
+	componentsv1alpha1 "github.com/dansksupermarked/mariadb-galera-operator/pkg/apis/components/v1alpha1"
+	"github.com/dansksupermarked/mariadb-galera-operator/pkg/util"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// recordPlacement keeps node and zone of server pods in status, pod
+// initializers read them to order donors and pick their segment. A node is
+// only looked up for pods not recorded on it yet.
+func (c *Controller) recordPlacement(mdbc *componentsv1alpha1.MariaDBCluster) error {
+	if !mdbc.Spec.Galera.ZoneSegments {
+		mdbc.Status.Segments = nil
+	}
+	if !mdbc.NeedsPlacement() {
+		mdbc.Status.Placement = nil
+		return nil
+	}
+	logger := util.GetClusterLogger(mdbc).WithField("kind", "Node").WithField("action", "placement")
+	pods, err := c.operator.Client.CoreV1().Pods(mdbc.Namespace).List(metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(mdbc.GetServerLabels()).String(),
+	})
+	if err != nil {
+		return err
+	}
+	placement := make(map[string]componentsv1alpha1.PodPlacement)
+	for _, pod := range pods.Items {
+		if pod.Spec.NodeName == "" {
+			continue
+		}
+		if known, ok := mdbc.Status.Placement[pod.Name]; ok && known.NodeName == pod.Spec.NodeName {
+			placement[pod.Name] = known
+			continue
+		}
+		node, err := c.operator.Client.CoreV1().Nodes().Get(pod.Spec.NodeName, metav1.GetOptions{})
+		if apierrors.IsForbidden(err) {
+			// an operator with namespaced permissions can not read nodes
+			logger.WithField("event", "forbidden").Warnf("%s runs on %s of unknown zone : %s", pod.Name, pod.Spec.NodeName, err.Error())
+			placement[pod.Name] = componentsv1alpha1.PodPlacement{NodeName: pod.Spec.NodeName}
+			continue
+		} else if err != nil {
+			logger.Errorf("Error fetching object : %s", err.Error())
+			return err
+		}
+		zone := node.Labels[componentsv1alpha1.ZoneLabel]
+		if zone == "" {
+			zone = node.Labels[componentsv1alpha1.LegacyZoneLabel]
+		}
+		logger.WithField("event", "placed").Debugf("%s runs on %s in zone %q", pod.Name, node.Name, zone)
+		placement[pod.Name] = componentsv1alpha1.PodPlacement{NodeName: node.Name, Zone: zone}
+	}
+	if len(placement) == 0 {
+		placement = nil
+	}
+	mdbc.Status.Placement = placement
+	if mdbc.Spec.Galera.ZoneSegments {
+		assignSegments(mdbc)
+	}
+	return nil
+}
+
+// assignSegments numbers zones not seen before after the ones already known,
+// so pods already running keep a segment consistent with new ones
+func assignSegments(mdbc *componentsv1alpha1.MariaDBCluster) {
+	var zones []string
+	for _, placement := range mdbc.Status.Placement {
+		if _, ok := mdbc.Status.Segments[placement.Zone]; !ok && placement.Zone != "" {
+			zones = append(zones, placement.Zone)
+		}
+	}
+	sort.Strings(zones)
+	next := 0
+	for _, segment := range mdbc.Status.Segments {
+		if segment >= next {
+			next = segment + 1
+		}
+	}
+	for _, zone := range zones {
+		if _, ok := mdbc.Status.Segments[zone]; ok {
+			continue
+		}
+		// gmcast.segment is a single byte
+		if next > 255 {
+			return
+		}
+		if mdbc.Status.Segments == nil {
+			mdbc.Status.Segments = make(map[string]int)
+		}
+		mdbc.Status.Segments[zone] = next
+		next++
+	}
+}
@@ -0,0 +1,72 @@
+package operator
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	componentsv1alpha1 "github.com/dansksupermarked/mariadb-galera-operator/pkg/apis/components/v1alpha1"
+)
+
+// snapshotQueriedPositions keeps the position agents of the active color last
+// read over SQL, for pods whose server still answered when Recovery started.
+// Their reports are about to go stale once the pods are restarted.
+func snapshotQueriedPositions(mdbc *componentsv1alpha1.MariaDBCluster) {
+	mdbc.Status.QueriedPositions = nil
+	for name, status := range mdbc.Status.WSREP {
+		color := status.Color
+		if color == "" {
+			color = componentsv1alpha1.ColorBlue
+		}
+		if color != mdbc.GetActiveColor() || !mdbc.IsServerPod(name) || time.Since(status.Reported.Time) > wsrepStatusStaleAfter {
+			continue
+		}
+		if status.ClusterStateUUID == "" || status.ClusterStateUUID == nilGaleraUUID || status.LastCommitted < 0 {
+			continue
+		}
+		if mdbc.Status.QueriedPositions == nil {
+			mdbc.Status.QueriedPositions = make(map[string]componentsv1alpha1.GRAState)
+		}
+		mdbc.Status.QueriedPositions[name] = componentsv1alpha1.GRAState{
+			UUID:  status.ClusterStateUUID,
+			SeqNo: status.LastCommitted,
+		}
+	}
+}
+
+// reconcileQueriedPositions checks the position each report gives against the
+// wsrep_last_committed its server answered with when Recovery started. The pod
+// committed at least that much, so a grastate.dat behind it is stale and the
+// queried position is taken instead. One holding another cluster UUID is not
+// trusted at all, while an empty one is left as is, the volume may have been
+// replaced since. Returns the reports to select from along with what was
+// corrected, Status is left as is.
+func reconcileQueriedPositions(mdbc *componentsv1alpha1.MariaDBCluster) (map[string]componentsv1alpha1.RecoveryReport, []string, error) {
+	if len(mdbc.Status.QueriedPositions) == 0 {
+		return mdbc.Status.RecoveryReports, nil, nil
+	}
+	var stale []string
+	reports := make(map[string]componentsv1alpha1.RecoveryReport, len(mdbc.Status.RecoveryReports))
+	for name, report := range mdbc.Status.RecoveryReports {
+		reports[name] = report
+		queried, ok := mdbc.Status.QueriedPositions[name]
+		if !ok {
+			continue
+		}
+		state := report.GetGRAState()
+		if state.UUID != "" && state.UUID != nilGaleraUUID && state.UUID != queried.UUID {
+			return nil, nil, fmt.Errorf("grastate.dat of %s holds cluster %s while its server reported %s over SQL", name, state.UUID, queried.UUID)
+		}
+		if state.UUID != queried.UUID || state.SeqNo >= queried.SeqNo {
+			continue
+		}
+		stale = append(stale, fmt.Sprintf("grastate.dat of %s gives %s:%d while its server reported %s:%d over SQL, taking the latter",
+			name, state.UUID, state.SeqNo, queried.UUID, queried.SeqNo))
+		report.GRAState.UUID = queried.UUID
+		report.GRAState.SeqNo = queried.SeqNo
+		report.Recovered = nil
+		reports[name] = report
+	}
+	sort.Strings(stale)
+	return reports, stale, nil
+}
@@ -1,7 +1,9 @@
 package operator
 
 import (
+	"encoding/json"
 	"reflect"
+	"sort"
 
 	"github.com/Sirupsen/logrus"
 	componentsv1alpha1 "github.com/dansksupermarked/mariadb-galera-operator/pkg/apis/components/v1alpha1"
@@ -11,83 +13,272 @@ import (
 	"k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/types"
 	clientappsv1 "k8s.io/client-go/kubernetes/typed/apps/v1"
 	clientcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
 )
 
-func (o *Operator) reconcileStatefulSet(mdbc *componentsv1alpha1.MariaDBCluster) error {
-	logger := util.GetClusterLogger(mdbc).WithField("kind", "StatefulSet").WithField("action", "reconcile")
+func (o *Operator) reconcileServerStatefulSet(mdbc *componentsv1alpha1.MariaDBCluster) ([]string, error) {
+	return o.reconcileStatefulSet(mdbc, mdbc.GetServerStatefulSetName(), mdbc.GetActiveColor(), mdbc.StatefulSetTransform)
+}
+
+func (o *Operator) reconcileStandbyStatefulSet(mdbc *componentsv1alpha1.MariaDBCluster) ([]string, error) {
+	return o.reconcileStatefulSet(mdbc, mdbc.GetServerNameForColor(mdbc.Status.BlueGreen.Color), mdbc.Status.BlueGreen.Color, mdbc.StandbyStatefulSetTransform)
+}
+
+// reconcileDataVolumeOwners sets the cluster as controller of the data claims
+// of its pods that have none when their retention policy is Delete, as those
+// created from templates of StatefulSets from before claim templates were
+// owned. Under Retain the cluster is removed from their owners instead, so
+// that only the teardown decides on them. It also adds spec.inheritMetadata,
+// which claim templates can not pick up once their StatefulSet exists.
+func (o *Operator) reconcileDataVolumeOwners(mdbc *componentsv1alpha1.MariaDBCluster) error {
+	logger := util.GetClusterLogger(mdbc).WithField("kind", "PersistentVolumeClaim").WithField("action", "reconcile")
+	owner := metav1.NewControllerRef(mdbc, componentsv1alpha1.SchemeGroupVersion.WithKind(componentsv1alpha1.ResourceKind))
+	owned := mdbc.GetDataRetentionPolicy() == componentsv1alpha1.RetentionPolicyDelete
+	claims := o.Client.CoreV1().PersistentVolumeClaims(mdbc.Namespace)
+	for _, color := range []string{componentsv1alpha1.ColorBlue, componentsv1alpha1.ColorGreen} {
+		// claims created from volumeClaimTemplates carry the StatefulSet selector labels
+		list, err := claims.List(metav1.ListOptions{LabelSelector: labels.SelectorFromSet(mdbc.GetServerLabelsForColor(color)).String()})
+		if err != nil {
+			logger.Errorf("Error fetching object : %s", err.Error())
+			return err
+		}
+		for _, claim := range list.Items {
+			if claim.DeletionTimestamp != nil {
+				continue
+			}
+			metadata := map[string]interface{}{}
+			if owned && metav1.GetControllerOf(&claim) == nil {
+				metadata["ownerReferences"] = append(claim.OwnerReferences, *owner)
+			} else if refs, released := withoutOwner(claim.OwnerReferences, mdbc.UID); !owned && released {
+				metadata["ownerReferences"] = refs
+			}
+			if missing := missingMetadata(claim.Labels, mdbc.Spec.InheritMetadata.Labels); len(missing) > 0 {
+				metadata["labels"] = missing
+			}
+			if missing := missingMetadata(claim.Annotations, mdbc.Spec.InheritMetadata.Annotations); len(missing) > 0 {
+				metadata["annotations"] = missing
+			}
+			if len(metadata) == 0 {
+				continue
+			}
+			patch, err := json.Marshal(map[string]interface{}{"metadata": metadata})
+			if err != nil {
+				return err
+			}
+			if _, err = claims.Patch(claim.Name, types.MergePatchType, patch); err != nil {
+				logger.WithField("name", claim.Name).Errorf("Patch failed with : %s", err.Error())
+				return err
+			}
+			logger.WithField("name", claim.Name).WithField("event", "patched").Info()
+		}
+	}
+	return nil
+}
+
+// withoutOwner returns references without those to given owner, and whether
+// there were any
+func withoutOwner(refs []metav1.OwnerReference, uid types.UID) ([]metav1.OwnerReference, bool) {
+	var kept []metav1.OwnerReference
+	for _, ref := range refs {
+		if ref.UID != uid {
+			kept = append(kept, ref)
+		}
+	}
+	return kept, len(kept) != len(refs)
+}
+
+// missingMetadata returns the entries of expected that current lacks or holds
+// another value for
+func missingMetadata(current, expected map[string]string) map[string]string {
+	missing := make(map[string]string)
+	for key, value := range expected {
+		if current[key] != value {
+			missing[key] = value
+		}
+	}
+	return missing
+}
+
+// reconcileStatefulSet creates or patches a StatefulSet to what the transform
+// renders onto it. Returns the fields someone else edited that were set back,
+// told apart from changes of the rendering by the hash of the StatefulSet the
+// transform renders from scratch. The topology spread constraints, missing
+// from the vendored types, are set back whenever they differ.
+func (o *Operator) reconcileStatefulSet(mdbc *componentsv1alpha1.MariaDBCluster, name, color string, transformer func(*appsv1.StatefulSet) error) ([]string, error) {
+	logger := util.GetClusterLogger(mdbc).WithField("kind", "StatefulSet").WithField("action", "reconcile").WithField("name", name)
 	logger.WithField("event", "started").Debug()
 	defer logger.WithField("event", "finished").Debug()
-	current, err := o.Client.AppsV1().StatefulSets(mdbc.Namespace).Get(mdbc.GetServerName(), metav1.GetOptions{})
+	rendered := &appsv1.StatefulSet{}
+	if err := transformer(rendered); err != nil {
+		logger.Error(err.Error())
+		return nil, err
+	}
+	fields, err := toFields(rendered)
+	if err != nil {
+		return nil, err
+	}
+	hash := renderedHash(fields)
+	// the vendored pod spec has no topologySpreadConstraints, they are written
+	// and compared as JSON
+	body, err := json.Marshal(mdbc.GetTopologySpreadConstraintsForColor(color))
+	var constraints []interface{}
+	if err == nil {
+		err = json.Unmarshal(body, &constraints)
+	}
+	if err != nil {
+		return nil, err
+	}
+	client := o.Client.AppsV1().RESTClient()
+	current := &appsv1.StatefulSet{}
+	stored := map[string]interface{}{}
+	body, err = client.Get().Namespace(mdbc.Namespace).Resource("statefulsets").Name(name).Context(o.ctx).Do().Raw()
+	if err == nil {
+		if err = json.Unmarshal(body, current); err != nil {
+			return nil, err
+		}
+		if err = json.Unmarshal(body, &stored); err != nil {
+			return nil, err
+		}
+	}
 	if err != nil {
 		if apierrors.IsNotFound(err) {
 			logger.WithField("event", "NotFound").Debug("not found in cluster")
-			expected := &appsv1.StatefulSet{}
-			mdbc.StatefulSetTransform(expected)
-			_, err = o.Client.AppsV1().StatefulSets(mdbc.Namespace).Create(expected)
+			setRenderedHash(&rendered.ObjectMeta, hash)
+			config, err := applyConfig(appsv1.SchemeGroupVersion.WithKind("StatefulSet"), rendered)
+			if err != nil {
+				return nil, err
+			}
+			podTemplateSpec(config)["topologySpreadConstraints"] = constraints
+			if body, err = json.Marshal(config); err != nil {
+				return nil, err
+			}
+			err = client.Post().Namespace(mdbc.Namespace).Resource("statefulsets").Body(body).Context(o.ctx).Do().Error()
 			if err != nil {
 				logger.Errorf("Creation failed with : %s", err.Error())
-				return err
+				return nil, err
 			} else {
 				logger.WithField("event", "created").Info()
-				return nil
+				return nil, nil
 			}
 		} else {
 			logger.Errorf("Error fetching object : %s", err.Error())
-			return err
+			return nil, err
 		}
 	} else {
 		expected := current.DeepCopy()
-		mdbc.StatefulSetTransform(expected)
-		checkAndPatchStatefulSet(current, expected, o.Client.Apps(), logger)
-		if err != nil {
-			logger.Error(err.Error())
-			return err
+		transformer(expected)
+		if !reflect.DeepEqual(podTemplateSpec(stored)["topologySpreadConstraints"], constraints) {
+			logger.WithField("event", "change").Info("topology spread constraints changed")
+			patch, _ := json.Marshal(map[string]interface{}{"spec": map[string]interface{}{"template": map[string]interface{}{
+				"spec": map[string]interface{}{"topologySpreadConstraints": constraints},
+			}}})
+			if _, err = o.Client.AppsV1().StatefulSets(mdbc.Namespace).Patch(name, types.MergePatchType, patch); err != nil {
+				logger.Errorf("Patch failed with : %s", err.Error())
+				return nil, err
+			}
 		}
-		return nil
+		if reflect.DeepEqual(expected, current) {
+			logger.WithField("event", "nochange").Info("no changes")
+			return nil, nil
+		}
+		var drifted []string
+		if current.Annotations[componentsv1alpha1.MariaDBClusterRenderedAnnotation] == hash {
+			expectedFields, err := toFields(expected)
+			if err != nil {
+				return nil, err
+			}
+			currentFields, err := toFields(current)
+			if err != nil {
+				return nil, err
+			}
+			drifted = driftedFields(expectedFields, currentFields, "")
+		}
+		setRenderedHash(&expected.ObjectMeta, hash)
+		if _, err = checkAndPatchStatefulSet(current, expected, o.Client.Apps(), logger); err != nil {
+			return nil, err
+		}
+		return drifted, nil
 	}
 }
 
-func (o *Operator) reconcileServerConfigMap(mdbc *componentsv1alpha1.MariaDBCluster) error {
+// podTemplateSpec returns the pod spec among the fields of a StatefulSet
+func podTemplateSpec(fields map[string]interface{}) map[string]interface{} {
+	spec, _ := fields["spec"].(map[string]interface{})
+	template, _ := spec["template"].(map[string]interface{})
+	podSpec, _ := template["spec"].(map[string]interface{})
+	return podSpec
+}
+
+// setRenderedHash records the hash of the rendering of an object on it
+func setRenderedHash(meta *metav1.ObjectMeta, hash string) {
+	if meta.Annotations == nil {
+		meta.Annotations = make(map[string]string)
+	}
+	meta.Annotations[componentsv1alpha1.MariaDBClusterRenderedAnnotation] = hash
+}
+
+// reconcileServerConfigMap keeps the server ConfigMap to the content the
+// operator renders. Returns the keys someone else edited and that were
+// restored, told apart from changes of the rendered content by the hash of the
+// content last written.
+func (o *Operator) reconcileServerConfigMap(mdbc *componentsv1alpha1.MariaDBCluster) ([]string, error) {
 	logger := util.GetClusterLogger(mdbc).WithField("kind", "ConfigMap").WithField("action", "reconcile")
 	logger.WithField("event", "started").Debug()
 	defer logger.WithField("event", "finished").Debug()
+	expected := &v1.ConfigMap{}
 	current, err := o.Client.CoreV1().ConfigMaps(mdbc.Namespace).Get(mdbc.GetServerConfigMapName(), metav1.GetOptions{})
-	if err != nil {
-		if apierrors.IsNotFound(err) {
-			logger.WithField("event", "NotFound").Debug("not found in cluster")
-			expected := &v1.ConfigMap{}
-			mdbc.ServerConfigMapTransform(expected)
-			_, err = o.Client.CoreV1().ConfigMaps(mdbc.Namespace).Create(expected)
-			if err != nil {
-				logger.Errorf("Creation failed with : %s", err.Error())
-				return err
-			} else {
-				logger.WithField("event", "created").Info()
-				return nil
-			}
-		} else {
-			logger.Errorf("Error fetching object : %s", err.Error())
-			return err
+	if err == nil {
+		// the transform holds back a configuration not checked yet
+		if hash, ok := current.Annotations[componentsv1alpha1.MariaDBClusterConfigAnnotation]; ok {
+			expected.Annotations = map[string]string{componentsv1alpha1.MariaDBClusterConfigAnnotation: hash}
 		}
+	} else if apierrors.IsNotFound(err) {
+		current = nil
 	} else {
-		expected := current.DeepCopy()
-		mdbc.ServerConfigMapTransform(expected)
-		checkAndPatchConfigMap(current, expected, o.Client.Core(), logger)
-		if err != nil {
-			logger.Error(err.Error())
-			return err
+		logger.Errorf("Error fetching object : %s", err.Error())
+		return nil, err
+	}
+	if err = mdbc.ServerConfigMapTransform(expected); err != nil {
+		logger.Error(err.Error())
+		return nil, err
+	}
+	if expected.Name == "" {
+		logger.WithField("event", "held").Debug("configuration not checked yet, keeping the current one")
+		return nil, nil
+	}
+	var edited []string
+	if current != nil && componentsv1alpha1.GetConfigMapContentHash(current.Data) != current.Annotations[componentsv1alpha1.MariaDBClusterContentAnnotation] {
+		edited = changedKeys(current.Data, expected.Data)
+	}
+	if err = o.apply(o.Client.CoreV1().RESTClient(), "configmaps", v1.SchemeGroupVersion.WithKind("ConfigMap"), expected, logger); err != nil {
+		return nil, err
+	}
+	return edited, nil
+}
+
+// changedKeys lists the keys whose values differ between two maps, sorted
+func changedKeys(current, expected map[string]string) []string {
+	var keys []string
+	for key, value := range current {
+		if other, ok := expected[key]; !ok || other != value {
+			keys = append(keys, key)
 		}
-		return nil
 	}
+	for key := range expected {
+		if _, ok := current[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
 }
 
 func reconcile(clientInterface interface{}, mdbc *componentsv1alpha1.MariaDBCluster, expected interface{}) error {
 	var expectedType string
 	var err error
-	var currentService, expectedService *v1.Service
 	var currentStatefulSet, expectedStatefulSet *appsv1.StatefulSet
 	var currentPVC, expectedPVC *v1.PersistentVolumeClaim
 	expectedType = reflect.TypeOf(expected).Elem().Name()
@@ -99,9 +290,6 @@ func reconcile(clientInterface interface{}, mdbc *componentsv1alpha1.MariaDBClus
 	case *v1.PersistentVolumeClaim:
 		expectedPVC = expected.(*v1.PersistentVolumeClaim)
 		currentPVC, err = clientInterface.(clientcorev1.CoreV1Interface).PersistentVolumeClaims(expectedPVC.Namespace).Get(expectedPVC.Name, metav1.GetOptions{})
-	case *v1.Service:
-		expectedService = expected.(*v1.Service)
-		currentService, err = clientInterface.(clientcorev1.CoreV1Interface).Services(expectedService.Namespace).Get(expectedService.Name, metav1.GetOptions{})
 	case *appsv1.StatefulSet:
 		expectedStatefulSet = expected.(*appsv1.StatefulSet)
 		currentStatefulSet, err = clientInterface.(clientappsv1.AppsV1Interface).StatefulSets(expectedStatefulSet.Namespace).Get(expectedStatefulSet.Name, metav1.GetOptions{})
@@ -112,8 +300,6 @@ func reconcile(clientInterface interface{}, mdbc *componentsv1alpha1.MariaDBClus
 			switch expected.(type) {
 			case *v1.PersistentVolumeClaim:
 				_, err = clientInterface.(clientcorev1.CoreV1Interface).PersistentVolumeClaims(mdbc.Namespace).Create(expectedPVC)
-			case *v1.Service:
-				_, err = clientInterface.(clientcorev1.CoreV1Interface).Services(mdbc.Namespace).Create(expectedService)
 			case *appsv1.StatefulSet:
 				_, err = clientInterface.(clientappsv1.AppsV1Interface).StatefulSets(mdbc.Namespace).Create(expectedStatefulSet)
 			}
@@ -133,8 +319,6 @@ func reconcile(clientInterface interface{}, mdbc *componentsv1alpha1.MariaDBClus
 		switch expected.(type) {
 		case *v1.PersistentVolumeClaim:
 			checkAndPatch(currentPVC, expectedPVC, clientInterface, logger)
-		case *v1.Service:
-			checkAndPatch(currentService, expectedService, clientInterface, logger)
 		case *appsv1.StatefulSet:
 			checkAndPatch(currentStatefulSet, expectedStatefulSet, clientInterface, logger)
 		}
@@ -150,10 +334,6 @@ func checkAndPatch(current, expected, clientInterface interface{}, logger *logru
 	var updated bool
 	var err error
 	switch expected.(type) {
-	case *v1.ConfigMap:
-		updated, err = checkAndPatchConfigMap(current.(*v1.ConfigMap), expected.(*v1.ConfigMap), clientInterface.(clientcorev1.CoreV1Interface), logger)
-	case *v1.Service:
-		updated, err = checkAndPatchService(current.(*v1.Service), expected.(*v1.Service), clientInterface.(clientcorev1.CoreV1Interface), logger)
 	case *v1.PersistentVolumeClaim:
 		updated, err = checkAndPatchPVC(current.(*v1.PersistentVolumeClaim), expected.(*v1.PersistentVolumeClaim), clientInterface.(clientcorev1.CoreV1Interface), logger)
 	}
@@ -221,6 +401,11 @@ func mergeObjectMeta(current, expected *metav1.ObjectMeta) error {
 	}
 	expected.Generation = current.Generation
 	expected.Annotations = mergeAnnotations(current.Annotations, expected.Annotations)
+	// labels set by others are kept like annotations, checks comparing labels
+	// only pick up those the operator adds, as from spec.inheritMetadata
+	if len(current.Labels) > 0 {
+		expected.Labels = mergeAnnotations(current.Labels, expected.Labels)
+	}
 	return nil
 }
 
@@ -241,31 +426,16 @@ func checkAndPatchPVC(current, expected *v1.PersistentVolumeClaim, client client
 	return false, nil
 }
 
-func checkAndPatchConfigMap(current, expected *v1.ConfigMap, client clientcorev1.CoreV1Interface, logger *logrus.Entry) (bool, error) {
-	// merge current values that should not trigger nor be included in patch
-	mergeObjectMeta(&current.ObjectMeta, &expected.ObjectMeta)
-
-	if !reflect.DeepEqual(expected.Data, current.Data) {
-		logger.Debug("Data differs between current and expected, updating")
-		patchBytes, _ := patchGen(current, expected, v1.ConfigMap{})
-		logger.Debugf(string(patchBytes))
-		_, err := client.ConfigMaps(expected.Namespace).Patch(expected.Name, types.MergePatchType, patchBytes)
-		return true, err
-	}
-	return false, nil
-}
-
 func checkAndPatchStatefulSet(current, expected *appsv1.StatefulSet, client clientappsv1.AppsV1Interface, logger *logrus.Entry) (bool, error) {
 	if !reflect.DeepEqual(expected, current) {
 		logger.WithField("event", "change").Info("changes detected")
 		patchBytes, _ := patchGen(current, expected, appsv1.StatefulSet{})
 		logger.Debugf(string(patchBytes))
-		// TODO : error handling
 		_, err := client.StatefulSets(expected.Namespace).Patch(expected.Name, types.StrategicMergePatchType, patchBytes)
 		if err != nil {
-			logger.Error(err.Error())
+			logger.Errorf("Patch failed with : %s", err.Error())
 		}
-		return true, nil
+		return true, err
 	} else {
 		logger.WithField("event", "nochange").Info("no changes")
 	}
@@ -0,0 +1,36 @@
+package operator
+
+import (
+	componentsv1alpha1 "github.com/dansksupermarked/mariadb-galera-operator/pkg/apis/components/v1alpha1"
+	"github.com/dansksupermarked/mariadb-galera-operator/pkg/util"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// reconcileServerPodDisruptionBudget applies the budget of the server pods,
+// or deletes it once the cluster asks for none
+func (c *Controller) reconcileServerPodDisruptionBudget(mdbc *componentsv1alpha1.MariaDBCluster) error {
+	name := mdbc.GetServerName()
+	logger := util.GetClusterLogger(mdbc).WithField("kind", "PodDisruptionBudget").WithField("action", "reconcile").WithField("name", name)
+	logger.WithField("event", "started").Debug()
+	defer logger.WithField("event", "finished").Debug()
+	if mdbc.GetDisruptionBudget() == componentsv1alpha1.DisruptionBudgetNone {
+		if _, err := c.pdbLister.PodDisruptionBudgets(mdbc.Namespace).Get(name); apierrors.IsNotFound(err) {
+			return nil
+		}
+		err := c.operator.Client.PolicyV1beta1().PodDisruptionBudgets(mdbc.Namespace).Delete(name, &metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			logger.Errorf("Deletion failed with : %s", err.Error())
+			return err
+		}
+		logger.WithField("event", "deleted").Info("disruptionBudget is None")
+		return nil
+	}
+	expected := &policyv1beta1.PodDisruptionBudget{}
+	if err := mdbc.ServerPodDisruptionBudgetTransform(expected); err != nil {
+		logger.Error(err.Error())
+		return err
+	}
+	return c.operator.apply(c.operator.Client.PolicyV1beta1().RESTClient(), "poddisruptionbudgets", policyv1beta1.SchemeGroupVersion.WithKind("PodDisruptionBudget"), expected, logger)
+}
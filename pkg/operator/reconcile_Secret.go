@@ -0,0 +1,44 @@
+package operator
+
+import (
+	componentsv1alpha1 "github.com/dansksupermarked/mariadb-galera-operator/pkg/apis/components/v1alpha1"
+	"github.com/dansksupermarked/mariadb-galera-operator/pkg/util"
+	"k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// reconcileSecret applies the operator's fields of a Secret. The transform
+// starts from the operator's keys of the current Secret so that passwords
+// already generated are kept.
+func (o *Operator) reconcileSecret(mdbc *componentsv1alpha1.MariaDBCluster, name string, transformer func(*v1.Secret) error) error {
+	logger := util.GetClusterLogger(mdbc).WithField("kind", "Secret").WithField("action", "reconcile").WithField("name", name)
+	logger.WithField("event", "started").Debug()
+	defer logger.WithField("event", "finished").Debug()
+	expected := &v1.Secret{Data: make(map[string][]byte)}
+	current, err := o.Client.CoreV1().Secrets(mdbc.Namespace).Get(name, metav1.GetOptions{})
+	if err == nil {
+		for _, key := range componentsv1alpha1.GeneratedSecretKeys {
+			if value, ok := current.Data[key]; ok {
+				expected.Data[key] = value
+			}
+		}
+	} else if !apierrors.IsNotFound(err) {
+		logger.Errorf("Error fetching object : %s", err.Error())
+		return err
+	}
+	// a Secret without its generated passwords must not be applied
+	if err = transformer(expected); err != nil {
+		logger.Error(err.Error())
+		return err
+	}
+	return o.apply(o.Client.CoreV1().RESTClient(), "secrets", v1.SchemeGroupVersion.WithKind("Secret"), expected, logger)
+}
+
+func (o *Operator) reconcileServerSecret(mdbc *componentsv1alpha1.MariaDBCluster) error {
+	return o.reconcileSecret(mdbc, mdbc.GetServerSecretName(), mdbc.ServerSecretTransform)
+}
+
+func (o *Operator) reconcileClientSecret(mdbc *componentsv1alpha1.MariaDBCluster) error {
+	return o.reconcileSecret(mdbc, mdbc.GetClientSecretName(), mdbc.ClientSecretTransform)
+}
@@ -0,0 +1,504 @@
+package operator
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	componentsv1alpha1 "github.com/dansksupermarked/mariadb-galera-operator/pkg/apis/components/v1alpha1"
+	"github.com/dansksupermarked/mariadb-galera-operator/pkg/phase"
+	"github.com/dansksupermarked/mariadb-galera-operator/pkg/util"
+	batch "k8s.io/api/batch/v1"
+	"k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// galera writes the nil UUID into grastate.dat of a node that never joined
+const nilGaleraUUID = "00000000-0000-0000-0000-000000000000"
+
+// position a wsrep-recover Job reports, <uuid>:<seqno>
+var recoveredPositionRegexp = regexp.MustCompile(`^([0-9a-f-]+):(-?[0-9]+)$`)
+
+const (
+	// wait before pods are restarted for new reports, doubled on each retry
+	reportRetryBackoff    = time.Minute
+	reportRetryMaxBackoff = 10 * time.Minute
+)
+
+// startRecovery moves the cluster into Recovery, dropping what is left of a
+// previous attempt
+func startRecovery(mdbc *componentsv1alpha1.MariaDBCluster, reason string) {
+	startTimeline(mdbc, reason)
+	mdbc.Status.Phase = componentsv1alpha1.PhaseRecovery
+	mdbc.Status.Stage = componentsv1alpha1.StageRestarting
+	mdbc.Status.RecoveryReports = nil
+	mdbc.Status.BootstrapFrom = ""
+	mdbc.Status.BootstrapFromTime = metav1.Time{}
+	mdbc.Status.BootstrapExcluded = nil
+	mdbc.Status.QueriedPositions = nil
+	mdbc.Status.PCBootstrap = nil
+	mdbc.Status.ReportRetry = nil
+}
+
+// recoverCluster drives a full cluster recovery. Pods are restarted so that
+// their initializer reports grastate.dat (running --wsrep-recover where the
+// seqno was not saved), the most advanced one is then bootstrapped as a new
+// primary component and the others are released to join it.
+func (c *Controller) recoverCluster(mdbc *componentsv1alpha1.MariaDBCluster) error {
+	logger := util.GetClusterLogger(mdbc).WithField("action", "recovery")
+	if recoverySuspended(mdbc) {
+		logger.WithField("event", "suspended").Debugf("recovery is suspended, holding in %s", mdbc.Status.Stage)
+		return nil
+	}
+	if mdbc.Spec.Recovery.ForceBootstrapFrom == "" {
+		mdbc.Status.ForcedBootstrapFrom = ""
+	}
+
+	switch mdbc.Status.Stage {
+	case componentsv1alpha1.StageRestarting, "":
+		snapshotQueriedPositions(mdbc)
+		// pods crash looping on mysqld never rerun their init container
+		if err := c.deleteServerPods(mdbc); err != nil {
+			return err
+		}
+		logger.WithField("event", "stageTransition").Info("pods restarted, waiting for grastate reports")
+		addTimelineEvent(mdbc, "pods restarted, waiting for grastate reports")
+		mdbc.Status.Stage = componentsv1alpha1.StageReporting
+
+	case componentsv1alpha1.StageReporting:
+		// A bootstrap pod has been indicated, parse status of the pod to verify
+		// if it bootstrapped successfully (indicated by readiness probe success)
+		// when successfull, transition to PrimaryRecovered stage of Recovery Phase
+		if mdbc.Status.BootstrapFrom != "" {
+			pod, err := c.operator.Client.Core().Pods(mdbc.Namespace).Get(mdbc.Status.BootstrapFrom, metav1.GetOptions{})
+			if err != nil && !apierrors.IsNotFound(err) {
+				return err
+			}
+			if err == nil && isPodReady(pod) {
+				// Bootstrap pod is alive and ready, remove bootstrap indicator and start joining others
+				logger.WithField("event", "stageTransition").Infof("primary component recovered on %s", pod.Name)
+				addTimelineEvent(mdbc, "primary component recovered on %s", pod.Name)
+				mdbc.Status.Stage = componentsv1alpha1.StagePrimaryRecovered
+				mdbc.Status.BootstrapFrom = ""
+				mdbc.Status.BootstrapFromTime = metav1.Time{}
+				return nil
+			}
+			return c.checkBootstrapReady(mdbc)
+		}
+		if c.forceBootstrap(mdbc) {
+			return nil
+		}
+		// Reports of pods removed by a scale down would never be refreshed
+		for name := range mdbc.Status.RecoveryReports {
+			if !mdbc.IsServerPod(name) {
+				delete(mdbc.Status.RecoveryReports, name)
+			}
+		}
+		// Wait for all pods to report their conditions and select the most advanced one
+		reported := int32(len(mdbc.Status.RecoveryReports))
+		if reported < mdbc.Spec.Replicas &&
+			(!mdbc.IsFeatureEnabled(componentsv1alpha1.FeatureAggressiveRecovery) || reported <= mdbc.Spec.Replicas/2) {
+			return nil
+		}
+		if recovered, err := c.recoverPositions(mdbc); err != nil || !recovered {
+			return err
+		}
+		reports, stale, err := reconcileQueriedPositions(mdbc)
+		if err != nil {
+			c.invalidReport(mdbc, err)
+			return nil
+		}
+		for _, message := range stale {
+			logger.WithField("event", "staleGRAState").Warn(message)
+			addTimelineEvent(mdbc, "%s", message)
+			c.recorder.Event(mdbc, v1.EventTypeWarning, "StaleGRAState", message)
+		}
+		hostname, err := selectBootstrapCandidate(mdbc, reports)
+		if err == nil {
+			err = checkLineage(mdbc, hostname, reports[hostname].GetGRAState())
+		}
+		if err != nil {
+			c.invalidReport(mdbc, err)
+			return nil
+		}
+		state := reports[hostname].GetGRAState()
+		if reported < mdbc.Spec.Replicas {
+			message := fmt.Sprintf("bootstrapping from %s with %d of %d pods reported, as %s is enabled",
+				hostname, reported, mdbc.Spec.Replicas, componentsv1alpha1.FeatureAggressiveRecovery)
+			logger.WithField("event", "aggressiveRecovery").Warn(message)
+			addTimelineEvent(mdbc, "%s", message)
+			c.recorder.Event(mdbc, v1.EventTypeWarning, "AggressiveRecovery", message)
+		}
+		logger.WithField("event", "bootstrapSelected").Infof("bootstrapping new primary component from %s", hostname)
+		if lineage := mdbc.Status.Lineage; lineage != nil && state.SeqNo < lineage.LastCommitted {
+			c.recorder.Eventf(mdbc, v1.EventTypeWarning, "BootstrapBehind",
+				"bootstrapping from %s at seqno %d while the cluster was last seen at %d, later transactions are lost",
+				hostname, state.SeqNo, lineage.LastCommitted)
+		}
+		recordBootstrap(mdbc, hostname, componentsv1alpha1.PhaseRecovery, state.UUID, state.SeqNo)
+		if timeline := mdbc.Status.RecoveryTimeline; timeline != nil {
+			timeline.Positions = make(map[string]componentsv1alpha1.GRAState)
+			for name, report := range reports {
+				timeline.Positions[name] = report.GetGRAState()
+			}
+			timeline.BootstrapFrom = hostname
+		}
+		addTimelineEvent(mdbc, "bootstrapping from %s at %s:%d", hostname, state.UUID, state.SeqNo)
+		mdbc.Status.BootstrapFrom = hostname
+		mdbc.Status.BootstrapFromTime = metav1.Now()
+
+	// Restart pods for new reports, they rerun --wsrep-recover where the seqno
+	// is unknown and pods that restarted since their report get a fresh one
+	case componentsv1alpha1.StageInvalidReport:
+		if c.forceBootstrap(mdbc) {
+			mdbc.Status.Stage = componentsv1alpha1.StageReporting
+			return nil
+		}
+		retry := mdbc.Status.ReportRetry
+		if retry == nil {
+			retry = &componentsv1alpha1.ReportRetryStatus{StartTime: metav1.Now(), LastTime: metav1.Now()}
+			mdbc.Status.ReportRetry = retry
+		}
+		if time.Since(retry.LastTime.Time) < reportRetryDelay(retry.Attempts) {
+			return nil
+		}
+		if err := c.deleteServerPods(mdbc); err != nil {
+			return err
+		}
+		for name := range mdbc.Status.RecoveryReports {
+			c.deleteJob(mdbc, mdbc.GetWSREPRecoverJobName(name))
+		}
+		retry.Attempts++
+		retry.LastTime = metav1.Now()
+		logger.WithField("event", "stageTransition").Infof("pods restarted for new grastate reports, attempt %d", retry.Attempts)
+		addTimelineEvent(mdbc, "pods restarted for new grastate reports, attempt %d", retry.Attempts)
+		setTimelineResult(mdbc, "")
+		mdbc.Status.RecoveryReports = nil
+		mdbc.Status.Stage = componentsv1alpha1.StageReporting
+
+	case componentsv1alpha1.StageManualRecovery:
+		if c.forceBootstrap(mdbc) {
+			mdbc.Status.Stage = componentsv1alpha1.StageReporting
+			setTimelineResult(mdbc, "")
+		}
+
+	// Transition to operational if Primary Component is recovered
+	// so that other galera cluster nodes can join new primary
+	case componentsv1alpha1.StagePrimaryRecovered:
+		sset, err := c.statefulsetLister.StatefulSets(mdbc.Namespace).Get(mdbc.GetServerStatefulSetName())
+		if err != nil {
+			return err
+		}
+		trackTransfers(mdbc)
+		if sset.Status.ReadyReplicas == 0 {
+			logger.WithField("event", "stageTransition").Warn("primary component lost, restarting recovery")
+			startRecovery(mdbc, "primary component lost, restarting recovery")
+		} else if phase.StatefulSetReady(sset) {
+			c.closeTimeline(mdbc)
+			logger.WithField("event", "phaseTransition").Info("Transitioning to Operational phase")
+			mdbc.Status.Phase = componentsv1alpha1.PhaseOperational
+			mdbc.Status.Stage = componentsv1alpha1.StageDegraded
+			mdbc.Status.RecoveryReports = nil
+			mdbc.Status.BootstrapFrom = ""
+			mdbc.Status.BootstrapExcluded = nil
+			mdbc.Status.QueriedPositions = nil
+			mdbc.Status.ReportRetry = nil
+		}
+	}
+	return nil
+}
+
+// invalidReport moves the cluster into InvalidReport, where pods are restarted
+// for new reports with growing delays, or into ManualRecovery once this went
+// on for Spec.Recovery.ReportRetryTimeout
+func (c *Controller) invalidReport(mdbc *componentsv1alpha1.MariaDBCluster, err error) {
+	logger := util.GetClusterLogger(mdbc).WithField("action", "recovery")
+	retry := mdbc.Status.ReportRetry
+	if retry == nil {
+		retry = &componentsv1alpha1.ReportRetryStatus{StartTime: metav1.Now()}
+		mdbc.Status.ReportRetry = retry
+	}
+	retry.Reason = err.Error()
+	retry.LastTime = metav1.Now()
+	if timeout := mdbc.Spec.Recovery.GetReportRetryTimeout(); time.Since(retry.StartTime.Time) >= timeout {
+		message := fmt.Sprintf("%s after %d retries within %s, set spec.recovery.forceBootstrapFrom to pick a pod by hand",
+			err.Error(), retry.Attempts, timeout)
+		logger.WithField("event", "manualRecovery").Warn(message)
+		addTimelineEvent(mdbc, "%s", message)
+		setTimelineResult(mdbc, componentsv1alpha1.StageManualRecovery)
+		c.recorder.Event(mdbc, v1.EventTypeWarning, componentsv1alpha1.StageManualRecovery, message)
+		mdbc.Status.Stage = componentsv1alpha1.StageManualRecovery
+		return
+	}
+	message := fmt.Sprintf("%s, restarting pods for new reports in %s", err.Error(), reportRetryDelay(retry.Attempts))
+	logger.WithField("event", "invalidReport").Warn(message)
+	addTimelineEvent(mdbc, "%s", message)
+	c.recorder.Event(mdbc, v1.EventTypeWarning, componentsv1alpha1.StageInvalidReport, message)
+	mdbc.Status.Stage = componentsv1alpha1.StageInvalidReport
+}
+
+// checkBootstrapReady gives up on the pod picked to bootstrap from once it did
+// not become ready within Spec.Recovery.BootstrapReadyTimeout, crash looping
+// or unable to start from its volume. It is restarted to wait among the others
+// and excluded for the rest of this Recovery, the next best pod is picked from
+// the reports at hand. A pod set through forceBootstrapFrom is waited on.
+func (c *Controller) checkBootstrapReady(mdbc *componentsv1alpha1.MariaDBCluster) error {
+	name := mdbc.Status.BootstrapFrom
+	timeout := mdbc.Spec.Recovery.GetBootstrapReadyTimeout()
+	if timeout == 0 || name == mdbc.Status.ForcedBootstrapFrom || mdbc.Status.BootstrapFromTime.IsZero() ||
+		time.Since(mdbc.Status.BootstrapFromTime.Time) < timeout {
+		return nil
+	}
+	logger := util.GetClusterLogger(mdbc).WithField("kind", "Pod").WithField("action", "delete")
+	err := c.operator.Client.CoreV1().Pods(mdbc.Namespace).Delete(name, &metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		logger.Errorf("Deletion failed with : %s", err.Error())
+		return err
+	}
+	message := fmt.Sprintf("%s did not become ready within %s of being picked to bootstrap from, excluding it and picking the next best pod", name, timeout)
+	util.GetClusterLogger(mdbc).WithField("action", "recovery").WithField("event", "bootstrapTimeout").Warn(message)
+	addTimelineEvent(mdbc, "%s", message)
+	c.recorder.Event(mdbc, v1.EventTypeWarning, "BootstrapTimeout", message)
+	mdbc.Status.BootstrapExcluded = append(mdbc.Status.BootstrapExcluded, name)
+	mdbc.Status.BootstrapFrom = ""
+	mdbc.Status.BootstrapFromTime = metav1.Time{}
+	return nil
+}
+
+// reportRetryDelay returns the wait before retry number attempts+1
+func reportRetryDelay(attempts int) time.Duration {
+	return doubledDelay(reportRetryBackoff, reportRetryMaxBackoff, attempts)
+}
+
+// doubledDelay returns base doubled attempts times, capped at max
+func doubledDelay(base, max time.Duration, attempts int) time.Duration {
+	delay := base
+	for i := 0; i < attempts && delay < max; i++ {
+		delay *= 2
+	}
+	if delay > max {
+		delay = max
+	}
+	return delay
+}
+
+// recoverPositions runs a wsrep-recover Job on the volume of each pod that
+// reported seqno -1, as its initializer could not establish it, and adds the
+// position found to the report of the pod. The operator signs the completed
+// report with the key of the agents, which leave it alone as long as their
+// own position did not change. Returns true once no Job is pending.
+func (c *Controller) recoverPositions(mdbc *componentsv1alpha1.MariaDBCluster) (bool, error) {
+	logger := util.GetClusterLogger(mdbc).WithField("action", "recovery")
+	var names []string
+	for name, report := range mdbc.Status.RecoveryReports {
+		if report.GRAState.UUID != "" && report.GRAState.SeqNo < 0 && report.Recovered == nil {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return true, nil
+	}
+	key, err := c.getReportKey(mdbc)
+	if err != nil {
+		return false, err
+	}
+	sort.Strings(names)
+	done := true
+	for _, name := range names {
+		pod, err := c.operator.Client.CoreV1().Pods(mdbc.Namespace).Get(name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		if pod.Spec.NodeName == "" {
+			done = false
+			continue
+		}
+		jobName := mdbc.GetWSREPRecoverJobName(name)
+		jobPod, failed, err := c.runCheckJob(mdbc, jobName, func(job *batch.Job) error {
+			return mdbc.WSREPRecoverJobTransform(job, name, pod.Spec.NodeName)
+		})
+		if err != nil {
+			return false, err
+		}
+		if jobPod == nil && !failed {
+			done = false
+			continue
+		}
+		report := mdbc.Status.RecoveryReports[name]
+		recovered := parseRecoveredPosition(jobPod)
+		uuid := report.GRAState.UUID
+		if recovered.SeqNo >= 0 && uuid != nilGaleraUUID && recovered.UUID != uuid {
+			logger.WithField("event", "wsrepRecover").Warnf("%s recovered cluster %s while its grastate.dat holds %s", name, recovered.UUID, uuid)
+			recovered.SeqNo = -1
+		}
+		if recovered.SeqNo < 0 {
+			message := fmt.Sprintf("could not recover the position of %s from its volume", name)
+			if failed {
+				message += ", job " + jobName + " failed"
+			}
+			logger.WithField("event", "wsrepRecover").Warn(message)
+			addTimelineEvent(mdbc, "%s", message)
+			c.recorder.Event(mdbc, v1.EventTypeWarning, "WSREPRecoverFailed", message)
+			recovered = componentsv1alpha1.GRAState{UUID: uuid, SeqNo: -1}
+		} else {
+			message := fmt.Sprintf("recovered position %s:%d of %s from its volume", recovered.UUID, recovered.SeqNo, name)
+			logger.WithField("event", "wsrepRecover").Info(message)
+			addTimelineEvent(mdbc, "%s", message)
+			c.recorder.Event(mdbc, v1.EventTypeNormal, "WSREPRecovered", message)
+		}
+		report.Recovered = &recovered
+		report.Sign(name, key)
+		mdbc.Status.RecoveryReports[name] = report
+	}
+	return done, nil
+}
+
+// parseRecoveredPosition reads the termination message of a wsrep-recover Job
+// pod, seqno is -1 when there is none
+func parseRecoveredPosition(pod *v1.Pod) componentsv1alpha1.GRAState {
+	state := componentsv1alpha1.GRAState{SeqNo: -1}
+	if pod == nil {
+		return state
+	}
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.State.Terminated == nil {
+			continue
+		}
+		result := recoveredPositionRegexp.FindStringSubmatch(strings.TrimSpace(status.State.Terminated.Message))
+		if len(result) > 2 {
+			state.UUID = result[1]
+			state.SeqNo, _ = strconv.ParseInt(result[2], 10, 64)
+		}
+	}
+	return state
+}
+
+// forceBootstrap applies Spec.Recovery.ForceBootstrapFrom once, without waiting
+// for other pods to report, as it is meant for cases automatic selection can
+// not resolve. Returns true when the bootstrap pod was set.
+func (c *Controller) forceBootstrap(mdbc *componentsv1alpha1.MariaDBCluster) bool {
+	pod := mdbc.Spec.Recovery.ForceBootstrapFrom
+	if pod == "" || pod == mdbc.Status.ForcedBootstrapFrom {
+		return false
+	}
+	if !mdbc.IsServerPod(pod) {
+		c.recorder.Eventf(mdbc, v1.EventTypeWarning, "ForceBootstrapIgnored", "%s is not a server pod of this cluster", pod)
+		mdbc.Status.ForcedBootstrapFrom = pod
+		return false
+	}
+	message := fmt.Sprintf("forcing bootstrap of a new primary component from %s, transactions only present on other pods will be lost", pod)
+	if automatic, err := selectBootstrapPod(mdbc.Status.RecoveryReports); err == nil && automatic != pod {
+		message += fmt.Sprintf(", %s reported a more advanced position", automatic)
+	}
+	util.GetClusterLogger(mdbc).WithField("action", "recovery").WithField("event", "forcedBootstrap").Warn(message)
+	c.recorder.Event(mdbc, v1.EventTypeWarning, "ForcedBootstrap", message)
+	addTimelineEvent(mdbc, "%s", message)
+	if timeline := mdbc.Status.RecoveryTimeline; timeline != nil {
+		timeline.BootstrapFrom = pod
+	}
+	report, ok := mdbc.Status.RecoveryReports[pod]
+	position := report.GetGRAState()
+	if !ok {
+		position.SeqNo = -1
+	}
+	recordBootstrap(mdbc, pod, "Forced", position.UUID, position.SeqNo)
+	mdbc.Status.ForcedBootstrapFrom = pod
+	mdbc.Status.BootstrapFrom = pod
+	mdbc.Status.BootstrapFromTime = metav1.Now()
+	return true
+}
+
+func (c *Controller) deleteServerPods(mdbc *componentsv1alpha1.MariaDBCluster) error {
+	logger := util.GetClusterLogger(mdbc).WithField("kind", "Pod").WithField("action", "delete")
+	pods, err := c.operator.Client.CoreV1().Pods(mdbc.Namespace).List(metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(mdbc.GetServerLabels()).String(),
+	})
+	if err != nil {
+		return err
+	}
+	for _, pod := range pods.Items {
+		if pod.DeletionTimestamp != nil {
+			continue
+		}
+		if err = c.operator.Client.CoreV1().Pods(mdbc.Namespace).Delete(pod.Name, &metav1.DeleteOptions{}); err != nil {
+			logger.Errorf("Deletion failed with : %s", err.Error())
+			return err
+		}
+		logger.WithField("event", "deleted").Debug(pod.Name)
+	}
+	return nil
+}
+
+// selectBootstrapPod picks the pod to start a new primary component from. A pod
+// marked safe_to_bootstrap shut down last and is taken as is, otherwise the
+// highest seqno wins. Pods without any state can not be picked but do not hold
+// the selection back, whereas one with an unknown position does, as it might
+// be the most advanced one.
+func selectBootstrapPod(reports map[string]componentsv1alpha1.RecoveryReport) (string, error) {
+	var names []string
+	for name := range reports {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var selected string
+	for _, name := range names {
+		state := reports[name].GetGRAState()
+		if state.UUID == "" || state.UUID == nilGaleraUUID {
+			continue
+		}
+		if state.SeqNo < 0 {
+			return "", fmt.Errorf("%s reported no recoverable position", name)
+		}
+		if selected == "" || isMoreAdvanced(state, reports[selected].GetGRAState()) {
+			selected = name
+		}
+	}
+	if selected == "" {
+		return "", fmt.Errorf("no pod reported any galera state")
+	}
+	uuid := reports[selected].GetGRAState().UUID
+	for _, name := range names {
+		if other := reports[name].GetGRAState().UUID; other != "" && other != nilGaleraUUID && other != uuid {
+			return "", fmt.Errorf("%s reported cluster %s while %s reported %s", name, other, selected, uuid)
+		}
+	}
+	return selected, nil
+}
+
+// selectBootstrapCandidate runs selectBootstrapPod on the reports of pods not
+// excluded from bootstrapping during this Recovery
+func selectBootstrapCandidate(mdbc *componentsv1alpha1.MariaDBCluster, reports map[string]componentsv1alpha1.RecoveryReport) (string, error) {
+	excluded := mdbc.Status.BootstrapExcluded
+	candidates := make(map[string]componentsv1alpha1.RecoveryReport)
+	for name, report := range reports {
+		if !containsString(excluded, name) {
+			candidates[name] = report
+		}
+	}
+	hostname, err := selectBootstrapPod(candidates)
+	if err != nil && len(excluded) > 0 {
+		return "", fmt.Errorf("%s, %s excluded as they did not bootstrap in time", err.Error(), strings.Join(excluded, ", "))
+	}
+	return hostname, err
+}
+
+func isMoreAdvanced(state, than componentsv1alpha1.GRAState) bool {
+	if state.SafeToBootstrap != than.SafeToBootstrap {
+		return state.SafeToBootstrap > than.SafeToBootstrap
+	}
+	return state.SeqNo > than.SeqNo
+}
+
+func isPodReady(pod *v1.Pod) bool {
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == v1.PodReady {
+			return cond.Status == v1.ConditionTrue
+		}
+	}
+	return false
+}
@@ -0,0 +1,156 @@
+package operator
+
+import (
+	"testing"
+
+	componentsv1alpha1 "github.com/dansksupermarked/mariadb-galera-operator/pkg/apis/components/v1alpha1"
+)
+
+const (
+	clusterUUID = "6b3e2a1c-2f4d-11e8-9a7e-0a580a800004"
+	otherUUID   = "0f7d6c5b-2f4d-11e8-8b1c-0a580a800005"
+)
+
+// recoveryReport returns the report of a pod with given grastate.dat
+func recoveryReport(uuid string, seqno int64, safeToBootstrap int) componentsv1alpha1.RecoveryReport {
+	return componentsv1alpha1.RecoveryReport{
+		GRAState: componentsv1alpha1.GRAState{Version: "2.1", UUID: uuid, SeqNo: seqno, SafeToBootstrap: safeToBootstrap},
+	}
+}
+
+// recoveredReport returns the report of a pod that saved no seqno, completed
+// with the position a wsrep-recover Job found
+func recoveredReport(uuid string, seqno int64) componentsv1alpha1.RecoveryReport {
+	report := recoveryReport(uuid, -1, 0)
+	report.Recovered = &componentsv1alpha1.GRAState{UUID: uuid, SeqNo: seqno}
+	return report
+}
+
+func TestSelectBootstrapPod(t *testing.T) {
+	tests := []struct {
+		name     string
+		reports  map[string]componentsv1alpha1.RecoveryReport
+		selected string
+		err      bool
+	}{
+		{
+			name: "highest seqno",
+			reports: map[string]componentsv1alpha1.RecoveryReport{
+				"mariadb-0": recoveryReport(clusterUUID, 120, 0),
+				"mariadb-1": recoveryReport(clusterUUID, 125, 0),
+				"mariadb-2": recoveryReport(clusterUUID, 118, 0),
+			},
+			selected: "mariadb-1",
+		},
+		{
+			name: "tie goes to the lowest name",
+			reports: map[string]componentsv1alpha1.RecoveryReport{
+				"mariadb-2": recoveryReport(clusterUUID, 125, 0),
+				"mariadb-0": recoveryReport(clusterUUID, 120, 0),
+				"mariadb-1": recoveryReport(clusterUUID, 125, 0),
+			},
+			selected: "mariadb-1",
+		},
+		{
+			name: "safe to bootstrap wins over a higher seqno",
+			reports: map[string]componentsv1alpha1.RecoveryReport{
+				"mariadb-0": recoveryReport(clusterUUID, 125, 0),
+				"mariadb-1": recoveryReport(clusterUUID, 120, 1),
+				"mariadb-2": recoveryReport(clusterUUID, 130, 0),
+			},
+			selected: "mariadb-1",
+		},
+		{
+			name: "recovered position",
+			reports: map[string]componentsv1alpha1.RecoveryReport{
+				"mariadb-0": recoveryReport(clusterUUID, 120, 0),
+				"mariadb-1": recoveredReport(clusterUUID, 131),
+			},
+			selected: "mariadb-1",
+		},
+		{
+			name: "pods without state are skipped",
+			reports: map[string]componentsv1alpha1.RecoveryReport{
+				"mariadb-0": recoveryReport("", 0, 0),
+				"mariadb-1": recoveryReport(nilGaleraUUID, -1, 0),
+				"mariadb-2": recoveryReport(clusterUUID, 118, 0),
+			},
+			selected: "mariadb-2",
+		},
+		{
+			name: "unknown position holds the selection back",
+			reports: map[string]componentsv1alpha1.RecoveryReport{
+				"mariadb-0": recoveryReport(clusterUUID, 120, 0),
+				"mariadb-1": recoveryReport(clusterUUID, -1, 0),
+			},
+			err: true,
+		},
+		{
+			name: "failed wsrep-recover holds the selection back",
+			reports: map[string]componentsv1alpha1.RecoveryReport{
+				"mariadb-0": recoveryReport(clusterUUID, 120, 0),
+				"mariadb-1": recoveredReport(clusterUUID, -1),
+			},
+			err: true,
+		},
+		{
+			name: "pods of different clusters",
+			reports: map[string]componentsv1alpha1.RecoveryReport{
+				"mariadb-0": recoveryReport(clusterUUID, 120, 0),
+				"mariadb-1": recoveryReport(otherUUID, 140, 0),
+			},
+			err: true,
+		},
+		{
+			name: "no state at all",
+			reports: map[string]componentsv1alpha1.RecoveryReport{
+				"mariadb-0": recoveryReport("", 0, 0),
+			},
+			err: true,
+		},
+		{
+			name: "no reports",
+			err:  true,
+		},
+	}
+	for _, test := range tests {
+		selected, err := selectBootstrapPod(test.reports)
+		if (err != nil) != test.err {
+			t.Errorf("%s: error %v, expected one %t", test.name, err, test.err)
+			continue
+		}
+		if selected != test.selected {
+			t.Errorf("%s: selected %q, expected %q", test.name, selected, test.selected)
+		}
+	}
+}
+
+func TestSelectBootstrapCandidate(t *testing.T) {
+	reports := map[string]componentsv1alpha1.RecoveryReport{
+		"mariadb-0": recoveryReport(clusterUUID, 120, 0),
+		"mariadb-1": recoveryReport(clusterUUID, 125, 0),
+		"mariadb-2": recoveryReport(clusterUUID, 118, 0),
+	}
+	tests := []struct {
+		name     string
+		excluded []string
+		selected string
+		err      bool
+	}{
+		{"none excluded", nil, "mariadb-1", false},
+		{"most advanced excluded", []string{"mariadb-1"}, "mariadb-0", false},
+		{"all excluded", []string{"mariadb-0", "mariadb-1", "mariadb-2"}, "", true},
+	}
+	for _, test := range tests {
+		mdbc := &componentsv1alpha1.MariaDBCluster{}
+		mdbc.Status.BootstrapExcluded = test.excluded
+		selected, err := selectBootstrapCandidate(mdbc, reports)
+		if (err != nil) != test.err {
+			t.Errorf("%s: error %v, expected one %t", test.name, err, test.err)
+			continue
+		}
+		if selected != test.selected {
+			t.Errorf("%s: selected %q, expected %q", test.name, selected, test.selected)
+		}
+	}
+}
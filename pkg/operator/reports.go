@@ -0,0 +1,59 @@
+package operator
+
+import (
+	componentsv1alpha1 "github.com/dansksupermarked/mariadb-galera-operator/pkg/apis/components/v1alpha1"
+	"github.com/dansksupermarked/mariadb-galera-operator/pkg/util"
+	"k8s.io/apimachinery/pkg/api/errors"
+)
+
+// verifyReports drops reports of server pods that are not signed with the key
+// of the server Secret, so that only agents of the cluster decide on recovery,
+// restarts and failover, not anyone else allowed to patch the object
+func (c *Controller) verifyReports(mdbc *componentsv1alpha1.MariaDBCluster) error {
+	if len(mdbc.Status.WSREP) == 0 && len(mdbc.Status.RecoveryReports) == 0 && len(mdbc.Status.Diverged) == 0 && len(mdbc.Status.Kernel) == 0 {
+		return nil
+	}
+	logger := util.GetClusterLogger(mdbc).WithField("action", "verifyReports")
+	key, err := c.getReportKey(mdbc)
+	if err != nil {
+		return err
+	}
+	for name, report := range mdbc.Status.WSREP {
+		if !report.Verify(name, key) {
+			logger.WithField("event", "rejected").Warnf("dropping wsrep status of %s, its signature does not match", name)
+			delete(mdbc.Status.WSREP, name)
+		}
+	}
+	for name, report := range mdbc.Status.RecoveryReports {
+		if !report.Verify(name, key) {
+			logger.WithField("event", "rejected").Warnf("dropping recovery report of %s, its signature does not match", name)
+			delete(mdbc.Status.RecoveryReports, name)
+		}
+	}
+	for name, report := range mdbc.Status.Diverged {
+		if !report.Verify(name, key) {
+			logger.WithField("event", "rejected").Warnf("dropping divergence report of %s, its signature does not match", name)
+			delete(mdbc.Status.Diverged, name)
+		}
+	}
+	for name, report := range mdbc.Status.Kernel {
+		if !report.Verify(name, key) {
+			logger.WithField("event", "rejected").Warnf("dropping kernel report of %s, its signature does not match", name)
+			delete(mdbc.Status.Kernel, name)
+		}
+	}
+	return nil
+}
+
+// getReportKey returns the key reports are signed with from the cache, nil
+// while the server Secret does not exist
+func (c *Controller) getReportKey(mdbc *componentsv1alpha1.MariaDBCluster) ([]byte, error) {
+	secret, err := c.secretLister.Secrets(mdbc.Namespace).Get(mdbc.GetServerSecretName())
+	if errors.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		util.GetClusterLogger(mdbc).WithField("kind", "Secret").WithField("action", "reconcile").Errorf("Error fetching object : %s", err.Error())
+		return nil, err
+	}
+	return secret.Data[componentsv1alpha1.ReportKeyKey], nil
+}
@@ -0,0 +1,119 @@
+package operator
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	componentsv1alpha1 "github.com/dansksupermarked/mariadb-galera-operator/pkg/apis/components/v1alpha1"
+	"github.com/dansksupermarked/mariadb-galera-operator/pkg/util"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// retries of a key failing to sync, the backoff of the rate limiter doubling
+// from the default RetryBaseDelay of 5ms they span about 5 minutes
+const maxRetries = 15
+
+// retry requeues a key that failed to sync after the backoff of the rate
+// limiter. Past maxRetries it is dropped until the cluster changes again and
+// the ReconcileFailed condition raised.
+func (c *Controller) retry(key string, err error) {
+	retries := c.workqueue.NumRequeues(key)
+	if retries < maxRetries {
+		c.workqueue.AddRateLimited(key)
+		return
+	}
+	c.workqueue.Forget(key)
+	mdbc := c.getQueuedCluster(key)
+	if mdbc == nil {
+		return
+	}
+	logger := util.GetClusterLogger(mdbc).WithField("kind", "MariaDBCluster").WithField("action", "reconcile")
+	message := fmt.Sprintf("reconciliation failed %d times, last with : %s, retrying on the next change of the cluster", retries+1, err.Error())
+	logger.WithField("event", "retriesExceeded").Error(message)
+	c.recorder.Event(mdbc, v1.EventTypeWarning, componentsv1alpha1.ConditionReconcile, message)
+	expected := mdbc.DeepCopy()
+	expected.Status.SetCondition(componentsv1alpha1.ConditionReconcile, true, "RetriesExceeded", message)
+	checkAndPatchMariaDBCluster(mdbc, expected, c.forCluster(mdbc).operator.ComponentsClient.Components(), logger)
+}
+
+// clearReconcileFailed drops the ReconcileFailed and ReconcileError
+// conditions of a cluster that synced again
+func (c *Controller) clearReconcileFailed(key string) {
+	// the lister tells whether there is anything to clear without a request,
+	// the conditions were raised by an earlier sync
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return
+	}
+	cached, err := c.mariadbclustersLister.MariaDBClusters(namespace).Get(name)
+	if err != nil || (cached.Status.GetCondition(componentsv1alpha1.ConditionReconcile) == nil &&
+		cached.Status.GetCondition(componentsv1alpha1.ConditionReconcileError) == nil) {
+		return
+	}
+	mdbc := c.getQueuedCluster(key)
+	if mdbc == nil {
+		return
+	}
+	logger := util.GetClusterLogger(mdbc).WithField("kind", "MariaDBCluster").WithField("action", "reconcile")
+	expected := mdbc.DeepCopy()
+	expected.Status.RemoveCondition(componentsv1alpha1.ConditionReconcile)
+	expected.Status.RemoveCondition(componentsv1alpha1.ConditionReconcileError)
+	checkAndPatchMariaDBCluster(mdbc, expected, c.forCluster(mdbc).operator.ComponentsClient.Components(), logger)
+}
+
+// getQueuedCluster returns the cluster of a queue key as stored, nil when it
+// is gone. It is read from the API server: the lister may not have seen the
+// status the sync just patched yet, and a patch of its conditions computed
+// from the lister copy would drop those raised meanwhile, as SplitBrain.
+func (c *Controller) getQueuedCluster(key string) *componentsv1alpha1.MariaDBCluster {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return nil
+	}
+	mdbc, err := c.operator.ComponentsClient.Components().MariaDBClusters(namespace).Get(name, metav1.GetOptions{})
+	if err != nil {
+		return nil
+	}
+	return mdbc
+}
+
+// bucketRateLimiter delays retries of all keys together to qps once burst of
+// them went through, as the BucketRateLimiter of workqueue does on top of a
+// rate package that differs across client-go versions
+type bucketRateLimiter struct {
+	mu     sync.Mutex
+	qps    float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newBucketRateLimiter(qps float64, burst int) *bucketRateLimiter {
+	return &bucketRateLimiter{qps: qps, burst: float64(burst), tokens: float64(burst), last: time.Now()}
+}
+
+// When takes a token, returning how long to wait for it when none is left
+func (r *bucketRateLimiter) When(item interface{}) time.Duration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	r.tokens += now.Sub(r.last).Seconds() * r.qps
+	if r.tokens > r.burst {
+		r.tokens = r.burst
+	}
+	r.last = now
+	r.tokens--
+	if r.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-r.tokens / r.qps * float64(time.Second))
+}
+
+func (r *bucketRateLimiter) Forget(item interface{}) {}
+
+func (r *bucketRateLimiter) NumRequeues(item interface{}) int {
+	return 0
+}
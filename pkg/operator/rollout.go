@@ -0,0 +1,92 @@
+package operator
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	componentsv1alpha1 "github.com/dansksupermarked/mariadb-galera-operator/pkg/apis/components/v1alpha1"
+	"github.com/dansksupermarked/mariadb-galera-operator/pkg/util"
+	apps "k8s.io/api/apps/v1"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// checkRollout handles server pods left on an outdated revision when the
+// StatefulSet does not replace them by itself. With the Operator strategy the
+// highest outdated ordinal is deleted once every pod is Ready, so only one
+// pod is away at a time, with OnDelete the pods waiting for a human are reported.
+func (c *Controller) checkRollout(mdbc *componentsv1alpha1.MariaDBCluster, sset *apps.StatefulSet) error {
+	strategy := mdbc.GetUpdateStrategy()
+	if strategy == componentsv1alpha1.UpdateStrategyRollingUpdate {
+		return nil
+	}
+	logger := util.GetClusterLogger(mdbc).WithField("kind", "Pod").WithField("action", "rollout")
+	pods, err := c.operator.Client.CoreV1().Pods(mdbc.Namespace).List(metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(mdbc.GetServerLabels()).String(),
+	})
+	if err != nil {
+		return err
+	}
+	outdated := outdatedPods(pods.Items, sset.Status.UpdateRevision)
+	if len(outdated) == 0 {
+		return nil
+	}
+	message := fmt.Sprintf("%d of %d pods waiting to be replaced with revision %s", len(outdated), len(pods.Items), sset.Status.UpdateRevision)
+	mdbc.Status.SetCondition(componentsv1alpha1.ConditionUpdatePending, true, strategy, message)
+	if strategy != componentsv1alpha1.UpdateStrategyOperator {
+		return nil
+	}
+	if restartsSuspended(mdbc) {
+		logger.WithField("event", "suspended").Debug("restarts are suspended, not replacing pods")
+		return nil
+	}
+
+	if int32(len(pods.Items)) != *sset.Spec.Replicas || sset.Status.ReadyReplicas != *sset.Spec.Replicas {
+		logger.WithField("event", "waiting").Debug("waiting for all pods to be ready")
+		return nil
+	}
+	for _, pod := range pods.Items {
+		if pod.DeletionTimestamp != nil {
+			return nil
+		}
+	}
+	name := outdated[0].Name
+	if err = c.operator.Client.CoreV1().Pods(mdbc.Namespace).Delete(name, &metav1.DeleteOptions{}); err != nil {
+		logger.Errorf("Deletion failed with : %s", err.Error())
+		return err
+	}
+	logger.WithField("event", "deleted").Infof("replacing %s, %s", name, message)
+	return nil
+}
+
+// outdatedPods returns pods not running given revision, highest ordinal first
+// as the StatefulSet controller would replace them
+func outdatedPods(pods []v1.Pod, revision string) []v1.Pod {
+	var outdated []v1.Pod
+	for _, pod := range pods {
+		if pod.Labels[apps.StatefulSetRevisionLabel] != revision {
+			outdated = append(outdated, pod)
+		}
+	}
+	sort.Slice(outdated, func(i, j int) bool {
+		return podOrdinal(outdated[i].Name) > podOrdinal(outdated[j].Name)
+	})
+	return outdated
+}
+
+// podOrdinal parses the ordinal suffix StatefulSet pods are named with, -1
+// when there is none
+func podOrdinal(name string) int {
+	i := strings.LastIndex(name, "-")
+	if i < 0 {
+		return -1
+	}
+	ordinal, err := strconv.Atoi(name[i+1:])
+	if err != nil {
+		return -1
+	}
+	return ordinal
+}
@@ -0,0 +1,64 @@
+package operator
+
+import (
+	"fmt"
+	"time"
+
+	componentsv1alpha1 "github.com/dansksupermarked/mariadb-galera-operator/pkg/apis/components/v1alpha1"
+	"github.com/dansksupermarked/mariadb-galera-operator/pkg/util"
+	"k8s.io/api/core/v1"
+)
+
+// checkUnsynced restarts a single serving pod that stayed out of Synced for
+// longer than Spec.Recovery.UnsyncedTimeout while every other pod is part of
+// the primary component and Synced. Pods receiving a state transfer or
+// desynced for flow control are left to checkSST and checkFlowControl, and so
+// are donors while a transfer is in progress. Nothing is done as long as more
+// than one pod is out, a restart would then rather add to the trouble.
+func (c *Controller) checkUnsynced(mdbc *componentsv1alpha1.MariaDBCluster) error {
+	timeout := mdbc.Spec.Recovery.GetUnsyncedTimeout()
+	if timeout == 0 || mdbc.IsSingleNode() {
+		return nil
+	}
+	logger := util.GetClusterLogger(mdbc).WithField("action", "selfHealing")
+	var reported int32
+	var transfer bool
+	var unsynced []string
+	for name, status := range mdbc.Status.WSREP {
+		color := status.Color
+		if color == "" {
+			color = componentsv1alpha1.ColorBlue
+		}
+		if color != mdbc.GetActiveColor() || !mdbc.IsServerPod(name) {
+			continue
+		}
+		reported++
+		if status.SST != nil {
+			transfer = true
+		}
+		if status.ClusterStatus != componentsv1alpha1.WSREPClusterStatusPrimary || status.LocalState != syncedState {
+			unsynced = append(unsynced, name)
+		}
+	}
+	if reported < mdbc.Spec.Replicas || len(unsynced) != 1 {
+		return nil
+	}
+	name := unsynced[0]
+	status := mdbc.Status.WSREP[name]
+	if status.ClusterStatus != componentsv1alpha1.WSREPClusterStatusPrimary || status.SST != nil || status.Desynced ||
+		(status.LocalState == donorState && transfer) {
+		return nil
+	}
+	if status.LocalStateSince.IsZero() || time.Since(status.LocalStateSince.Time) < timeout {
+		return nil
+	}
+	restarted, err := c.restartReportedPod(mdbc, name)
+	if err != nil || !restarted {
+		return err
+	}
+	message := fmt.Sprintf("%s was %s for %s while all other pods are Synced, restarted it",
+		name, status.LocalState, time.Since(status.LocalStateSince.Time).Truncate(time.Second))
+	logger.WithField("event", "restarted").Warn(message)
+	c.recorder.Event(mdbc, v1.EventTypeWarning, "UnsyncedRestarted", message)
+	return nil
+}
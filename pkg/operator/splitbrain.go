@@ -0,0 +1,186 @@
+package operator
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	componentsv1alpha1 "github.com/dansksupermarked/mariadb-galera-operator/pkg/apis/components/v1alpha1"
+	"github.com/dansksupermarked/mariadb-galera-operator/pkg/util"
+	"k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// agents refresh unchanged state every 30s, older reports are dropped
+const wsrepStatusStaleAfter = 90 * time.Second
+
+// primaryComponent groups pods reporting the same primary component
+type primaryComponent struct {
+	uuid          string
+	confID        int64
+	size          int
+	lastCommitted int64
+	pods          []string
+}
+
+func (p *primaryComponent) String() string {
+	return fmt.Sprintf("%s (cluster %s, seqno %d)", strings.Join(p.pods, ", "), p.uuid, p.lastCommitted)
+}
+
+// checkSplitBrain looks for serving pods that are part of different primary
+// components, as left behind by bootstrapping a node while the rest of the
+// cluster was still running. While split the proxy service is fenced off, as
+// each component would accept writes the other never sees. Returns true while
+// the split lasts, so that no other changes are made to the cluster.
+func (c *Controller) checkSplitBrain(mdbc *componentsv1alpha1.MariaDBCluster) (bool, error) {
+	logger := util.GetClusterLogger(mdbc).WithField("action", "splitBrain")
+	pruneWSREPStatus(mdbc)
+	components := primaryComponents(mdbc)
+	cond := mdbc.Status.GetCondition(componentsv1alpha1.ConditionSplitBrain)
+	if len(components) < 2 {
+		if cond != nil && cond.Status {
+			logger.WithField("event", "resolved").Info("single primary component left")
+			c.recorder.Event(mdbc, v1.EventTypeNormal, "SplitBrainResolved", "single primary component left, traffic restored")
+		}
+		mdbc.Status.RemoveCondition(componentsv1alpha1.ConditionSplitBrain)
+		return false, nil
+	}
+
+	var parts []string
+	for _, component := range components {
+		parts = append(parts, component.String())
+	}
+	message := "separate primary components on " + strings.Join(parts, " and ")
+	policy := mdbc.Spec.Recovery.GetSplitBrainPolicy()
+	if cond == nil || !cond.Status {
+		logger.WithField("event", "detected").Warn(message)
+		guidance := ", delete the pods outside of the lineage to keep so that they rejoin it or set spec.recovery.splitBrainPolicy to " +
+			componentsv1alpha1.SplitBrainPolicyKeepLargest
+		if policy == componentsv1alpha1.SplitBrainPolicyKeepLargest {
+			guidance = ", keeping " + components[0].String()
+		}
+		c.recorder.Event(mdbc, v1.EventTypeWarning, componentsv1alpha1.ConditionSplitBrain, message+guidance)
+	}
+	mdbc.Status.SetCondition(componentsv1alpha1.ConditionSplitBrain, true, policy, message)
+	if policy != componentsv1alpha1.SplitBrainPolicyKeepLargest {
+		return true, nil
+	}
+	for _, component := range components[1:] {
+		for _, name := range component.pods {
+			if _, err := c.restartReportedPod(mdbc, name); err != nil {
+				return true, err
+			}
+		}
+	}
+	return true, nil
+}
+
+// restartReportedPod deletes a server pod for what its agent reported, as when
+// part of a discarded primary component it joins the kept one once restarted.
+// Its report is dropped along, one older than the pod itself is not acted upon.
+// Returns true when the pod was deleted, it is not while restarts are suspended.
+func (c *Controller) restartReportedPod(mdbc *componentsv1alpha1.MariaDBCluster, name string) (bool, error) {
+	logger := util.GetClusterLogger(mdbc).WithField("kind", "Pod").WithField("action", "delete")
+	pod, err := c.operator.Client.CoreV1().Pods(mdbc.Namespace).Get(name, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			delete(mdbc.Status.WSREP, name)
+			return false, nil
+		}
+		logger.Errorf("Error fetching object : %s", err.Error())
+		return false, err
+	}
+	if pod.DeletionTimestamp != nil {
+		return false, nil
+	}
+	reported := mdbc.Status.WSREP[name].Reported
+	if reported.Before(&pod.CreationTimestamp) {
+		delete(mdbc.Status.WSREP, name)
+		return false, nil
+	}
+	if restartsSuspended(mdbc) {
+		logger.WithField("event", "suspended").Debugf("not restarting %s, restarts are suspended", name)
+		return false, nil
+	}
+	if err = c.operator.Client.CoreV1().Pods(mdbc.Namespace).Delete(name, &metav1.DeleteOptions{}); err != nil {
+		logger.Errorf("Deletion failed with : %s", err.Error())
+		return false, err
+	}
+	logger.WithField("event", "deleted").Info(name)
+	delete(mdbc.Status.WSREP, name)
+	return true, nil
+}
+
+// pruneWSREPStatus drops reports of pods no longer around
+func pruneWSREPStatus(mdbc *componentsv1alpha1.MariaDBCluster) {
+	for name, status := range mdbc.Status.WSREP {
+		if time.Since(status.Reported.Time) > wsrepStatusStaleAfter {
+			delete(mdbc.Status.WSREP, name)
+		}
+	}
+	if len(mdbc.Status.WSREP) == 0 {
+		mdbc.Status.WSREP = nil
+	}
+}
+
+// primaryComponents returns the primary components serving pods report, the
+// one to keep first: most pods, then most transactions. Pods of the same
+// cluster UUID report different components for a moment whenever membership
+// changes, so for a UUID only components all members have reported on count.
+func primaryComponents(mdbc *componentsv1alpha1.MariaDBCluster) []*primaryComponent {
+	grouped := make(map[string]*primaryComponent)
+	for name, status := range mdbc.Status.WSREP {
+		color := status.Color
+		if color == "" {
+			color = componentsv1alpha1.ColorBlue
+		}
+		if color != mdbc.GetActiveColor() || status.ClusterStatus != componentsv1alpha1.WSREPClusterStatusPrimary {
+			continue
+		}
+		key := fmt.Sprintf("%s/%d", status.ClusterStateUUID, status.ClusterConfID)
+		component, ok := grouped[key]
+		if !ok {
+			component = &primaryComponent{uuid: status.ClusterStateUUID, confID: status.ClusterConfID, size: status.ClusterSize}
+			grouped[key] = component
+		}
+		component.pods = append(component.pods, name)
+		if status.LastCommitted > component.lastCommitted {
+			component.lastCommitted = status.LastCommitted
+		}
+	}
+
+	byUUID := make(map[string][]*primaryComponent)
+	for _, component := range grouped {
+		sort.Strings(component.pods)
+		byUUID[component.uuid] = append(byUUID[component.uuid], component)
+	}
+	var components []*primaryComponent
+	for _, candidates := range byUUID {
+		var complete []*primaryComponent
+		largest := candidates[0]
+		for _, component := range candidates {
+			if len(component.pods) >= component.size {
+				complete = append(complete, component)
+			}
+			if len(component.pods) > len(largest.pods) {
+				largest = component
+			}
+		}
+		if len(complete) == 0 {
+			complete = []*primaryComponent{largest}
+		}
+		components = append(components, complete...)
+	}
+	sort.Slice(components, func(i, j int) bool {
+		if len(components[i].pods) != len(components[j].pods) {
+			return len(components[i].pods) > len(components[j].pods)
+		}
+		if components[i].lastCommitted != components[j].lastCommitted {
+			return components[i].lastCommitted > components[j].lastCommitted
+		}
+		return components[i].pods[0] < components[j].pods[0]
+	})
+	return components
+}
@@ -0,0 +1,116 @@
+package operator
+
+import (
+	"reflect"
+	"testing"
+
+	componentsv1alpha1 "github.com/dansksupermarked/mariadb-galera-operator/pkg/apis/components/v1alpha1"
+)
+
+// wsrepStatus returns what the agent of a pod in a primary component reports
+func wsrepStatus(uuid string, confID int64, size int, lastCommitted int64) componentsv1alpha1.WSREPStatus {
+	return componentsv1alpha1.WSREPStatus{
+		ClusterStateUUID: uuid,
+		ClusterStatus:    componentsv1alpha1.WSREPClusterStatusPrimary,
+		ClusterConfID:    confID,
+		ClusterSize:      size,
+		LastCommitted:    lastCommitted,
+	}
+}
+
+func TestPrimaryComponents(t *testing.T) {
+	nonPrimary := wsrepStatus(clusterUUID, 7, 1, 100)
+	nonPrimary.ClusterStatus = "non-Primary"
+	standby := wsrepStatus(otherUUID, 2, 1, 100)
+	standby.Color = componentsv1alpha1.ColorGreen
+	tests := []struct {
+		name       string
+		wsrep      map[string]componentsv1alpha1.WSREPStatus
+		components [][]string
+	}{
+		{
+			name: "single component",
+			wsrep: map[string]componentsv1alpha1.WSREPStatus{
+				"mariadb-0": wsrepStatus(clusterUUID, 5, 3, 100),
+				"mariadb-1": wsrepStatus(clusterUUID, 5, 3, 101),
+				"mariadb-2": wsrepStatus(clusterUUID, 5, 3, 99),
+			},
+			components: [][]string{{"mariadb-0", "mariadb-1", "mariadb-2"}},
+		},
+		{
+			name: "largest component first",
+			wsrep: map[string]componentsv1alpha1.WSREPStatus{
+				"mariadb-0": wsrepStatus(otherUUID, 2, 1, 400),
+				"mariadb-1": wsrepStatus(clusterUUID, 6, 2, 100),
+				"mariadb-2": wsrepStatus(clusterUUID, 6, 2, 100),
+			},
+			components: [][]string{{"mariadb-1", "mariadb-2"}, {"mariadb-0"}},
+		},
+		{
+			name: "most transactions first among equal sizes",
+			wsrep: map[string]componentsv1alpha1.WSREPStatus{
+				"mariadb-0": wsrepStatus(clusterUUID, 6, 1, 100),
+				"mariadb-1": wsrepStatus(otherUUID, 2, 1, 120),
+			},
+			components: [][]string{{"mariadb-1"}, {"mariadb-0"}},
+		},
+		{
+			name: "lowest pod first among equal components",
+			wsrep: map[string]componentsv1alpha1.WSREPStatus{
+				"mariadb-1": wsrepStatus(otherUUID, 2, 1, 100),
+				"mariadb-0": wsrepStatus(clusterUUID, 6, 1, 100),
+			},
+			components: [][]string{{"mariadb-0"}, {"mariadb-1"}},
+		},
+		{
+			name: "three primary components",
+			wsrep: map[string]componentsv1alpha1.WSREPStatus{
+				"mariadb-0": wsrepStatus(clusterUUID, 6, 1, 100),
+				"mariadb-1": wsrepStatus(otherUUID, 2, 1, 110),
+				"mariadb-2": wsrepStatus("13a5e0f2-2f4e-11e8-a1f3-0a580a800006", 1, 1, 90),
+			},
+			components: [][]string{{"mariadb-1"}, {"mariadb-0"}, {"mariadb-2"}},
+		},
+		{
+			name: "membership change only counts the complete component",
+			wsrep: map[string]componentsv1alpha1.WSREPStatus{
+				"mariadb-0": wsrepStatus(clusterUUID, 6, 2, 100),
+				"mariadb-1": wsrepStatus(clusterUUID, 6, 2, 100),
+				"mariadb-2": wsrepStatus(clusterUUID, 5, 3, 98),
+			},
+			components: [][]string{{"mariadb-0", "mariadb-1"}},
+		},
+		{
+			name: "membership change without a complete component counts the largest",
+			wsrep: map[string]componentsv1alpha1.WSREPStatus{
+				"mariadb-0": wsrepStatus(clusterUUID, 5, 3, 100),
+				"mariadb-1": wsrepStatus(clusterUUID, 5, 3, 100),
+				"mariadb-2": wsrepStatus(clusterUUID, 6, 3, 100),
+			},
+			components: [][]string{{"mariadb-0", "mariadb-1"}},
+		},
+		{
+			name: "non-primary and standby pods are left out",
+			wsrep: map[string]componentsv1alpha1.WSREPStatus{
+				"mariadb-0":       wsrepStatus(clusterUUID, 6, 1, 100),
+				"mariadb-1":       nonPrimary,
+				"mariadb-green-0": standby,
+			},
+			components: [][]string{{"mariadb-0"}},
+		},
+		{
+			name: "none reported",
+		},
+	}
+	for _, test := range tests {
+		mdbc := &componentsv1alpha1.MariaDBCluster{}
+		mdbc.Status.WSREP = test.wsrep
+		var components [][]string
+		for _, component := range primaryComponents(mdbc) {
+			components = append(components, component.pods)
+		}
+		if !reflect.DeepEqual(components, test.components) {
+			t.Errorf("%s: components %v, expected %v", test.name, components, test.components)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package operator
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	componentsv1alpha1 "github.com/dansksupermarked/mariadb-galera-operator/pkg/apis/components/v1alpha1"
+	"github.com/dansksupermarked/mariadb-galera-operator/pkg/util"
+	batch "k8s.io/api/batch/v1"
+	"k8s.io/api/core/v1"
+)
+
+const (
+	// wsrep local state of a pod serving a state transfer, or desynced
+	donorState  = "Donor/Desynced"
+	syncedState = "Synced"
+)
+
+// checkSST reports state transfers in progress through the StateTransfer
+// condition and restarts joiners still receiving one after Spec.Galera.SSTTimeout.
+// The donor of a timed out transfer is avoided by the restarted joiner, so a
+// donor that stopped sending does not hold it forever.
+func (c *Controller) checkSST(mdbc *componentsv1alpha1.MariaDBCluster) error {
+	logger := util.GetClusterLogger(mdbc).WithField("action", "sst")
+	timeout := mdbc.Spec.Galera.GetSSTTimeout()
+	var joiners, donors, progress []string
+	for name, status := range mdbc.Status.WSREP {
+		color := status.Color
+		if color == "" {
+			color = componentsv1alpha1.ColorBlue
+		}
+		if color != mdbc.GetActiveColor() {
+			continue
+		}
+		if status.LocalState == donorState {
+			donors = append(donors, name)
+		}
+		if status.SST != nil {
+			joiners = append(joiners, name)
+		}
+	}
+	if len(joiners) == 0 {
+		mdbc.Status.RemoveCondition(componentsv1alpha1.ConditionStateTransfer)
+		return nil
+	}
+	sort.Strings(joiners)
+	sort.Strings(donors)
+
+	for _, name := range joiners {
+		sst := mdbc.Status.WSREP[name].SST
+		elapsed := time.Since(sst.StartTime.Time)
+		progress = append(progress, fmt.Sprintf("%s received %dM in %s", name, sst.Bytes>>20, elapsed.Truncate(time.Second)))
+		if elapsed < timeout {
+			continue
+		}
+		// with several transfers at once the donor of each is not known, the
+		// exclusion has to be in place before the joiner renders its config
+		var donor string
+		if len(donors) == 1 && len(joiners) == 1 {
+			donor = donors[0]
+			if !containsString(mdbc.Status.SSTExcludedDonors, donor) {
+				mdbc.Status.SSTExcludedDonors = append(mdbc.Status.SSTExcludedDonors, donor)
+			}
+		}
+		restarted, err := c.restartReportedPod(mdbc, name)
+		if err != nil {
+			return err
+		}
+		if !restarted {
+			continue
+		}
+		message := fmt.Sprintf("state transfer to %s did not complete within %s, %dM received, restarted it", name, timeout, sst.Bytes>>20)
+		if donor != "" {
+			message += " to use another donor than " + donor
+		}
+		logger.WithField("event", "timeout").Warn(message)
+		c.recorder.Event(mdbc, v1.EventTypeWarning, "SSTTimeout", message)
+	}
+	mdbc.Status.SetCondition(componentsv1alpha1.ConditionStateTransfer, true, "InProgress", strings.Join(progress, ", "))
+	return nil
+}
+
+// checkSSTMethod puts Spec.Galera.SSTMethod into effect once the image of the
+// running version was found to ship the tools it needs, pods then restart with
+// it following Spec.UpdateStrategy. The galera default needs no rollout.
+func (c *Controller) checkSSTMethod(mdbc *componentsv1alpha1.MariaDBCluster) error {
+	method := mdbc.Spec.Galera.GetSSTMethod()
+	if method == mdbc.Status.SSTMethod || (mdbc.Status.SSTMethod == "" && method == componentsv1alpha1.DefaultSSTMethod) {
+		return nil
+	}
+	supported, done, err := c.probeSSTMethods(mdbc, mdbc.Status.CurrentVersion, componentsv1alpha1.ConditionSSTMethod)
+	if err != nil || !done {
+		return err
+	}
+	if !containsString(supported, method) {
+		mdbc.Status.SetCondition(componentsv1alpha1.ConditionSSTMethod, false, "Unsupported",
+			"image "+mdbc.GetServerImage()+" lacks the tools of sst method "+method)
+		return nil
+	}
+	util.GetClusterLogger(mdbc).WithField("action", "sst").WithField("event", "methodChanged").Infof("switching to sst method %s", method)
+	mdbc.Status.SSTMethod = method
+	mdbc.Status.SetCondition(componentsv1alpha1.ConditionSSTMethod, true, method, "state transfers use "+method)
+	return nil
+}
+
+// checkSSTMethodGate refuses upgrades to an image missing the tools of the
+// SST method in use, restarted pods could not join the cluster otherwise
+func (c *Controller) checkSSTMethodGate(mdbc *componentsv1alpha1.MariaDBCluster) (bool, error) {
+	method := mdbc.Status.SSTMethod
+	if method == "" {
+		return true, nil
+	}
+	supported, done, err := c.probeSSTMethods(mdbc, mdbc.Status.TargetVersion, componentsv1alpha1.ConditionUpgrading)
+	if err != nil || !done {
+		return false, err
+	}
+	if !containsString(supported, method) {
+		mdbc.Status.SetCondition(componentsv1alpha1.ConditionUpgrading, false, "SSTMethodUnsupported",
+			"image "+mdbc.GetServerImageForVersion(mdbc.Status.TargetVersion)+" lacks the tools of sst method "+method)
+		return false, nil
+	}
+	return true, nil
+}
+
+// probeSSTMethods returns the SST methods the server image of given version
+// ships the tools for, running a check Job on first use and caching its result
+// in status. Progress is reported through conditionType until done.
+func (c *Controller) probeSSTMethods(mdbc *componentsv1alpha1.MariaDBCluster, version, conditionType string) ([]string, bool, error) {
+	if methods, ok := mdbc.Status.SSTMethods[version]; ok {
+		return methods, true, nil
+	}
+	name := mdbc.GetSSTCheckJobName(version)
+	pod, failed, err := c.runCheckJob(mdbc, name, func(job *batch.Job) error {
+		return mdbc.SSTCheckJobTransform(job, version)
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	if failed {
+		mdbc.Status.SetCondition(conditionType, false, "SSTCheckFailed", "could not check sst tools, delete job "+name+" to retry")
+		return nil, false, nil
+	}
+	if pod == nil {
+		mdbc.Status.SetCondition(conditionType, false, "CheckingSSTMethods", "waiting for sst check job "+name)
+		return nil, false, nil
+	}
+	var message string
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.State.Terminated != nil {
+			message = strings.TrimSpace(status.State.Terminated.Message)
+		}
+	}
+	methods := strings.Fields(message)
+	if mdbc.Status.SSTMethods == nil {
+		mdbc.Status.SSTMethods = make(map[string][]string)
+	}
+	mdbc.Status.SSTMethods[version] = methods
+	util.GetClusterLogger(mdbc).WithField("action", "sst").WithField("event", "probed").Infof("version %s supports sst methods %v", version, methods)
+	return methods, true, nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,109 @@
+package operator
+
+import (
+	"encoding/json"
+
+	"github.com/Sirupsen/logrus"
+	componentsv1alpha1 "github.com/dansksupermarked/mariadb-galera-operator/pkg/apis/components/v1alpha1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// storageVersion is the version MariaDBClusters are stored in, as set in
+// crdManifest
+const storageVersion = componentsv1alpha1.Version
+
+// ensureStorageVersion marks a cluster as written in the storage version. Any
+// write has the API server store the object in that version, so clusters last
+// written in another one are rewritten once the operator reads them.
+func ensureStorageVersion(mdbc *componentsv1alpha1.MariaDBCluster) {
+	if mdbc.DeletionTimestamp != nil || mdbc.Annotations[componentsv1alpha1.MariaDBClusterStorageVersionAnnotation] == storageVersion {
+		return
+	}
+	if mdbc.Annotations == nil {
+		mdbc.Annotations = make(map[string]string)
+	}
+	mdbc.Annotations[componentsv1alpha1.MariaDBClusterStorageVersionAnnotation] = storageVersion
+}
+
+// migrateStorage rewrites every MariaDBCluster not yet marked as written in the
+// storage version, then drops the other versions from the stored versions of
+// the CustomResourceDefinition so that they can be removed from it. The stored
+// versions are left as they are when any cluster could not be rewritten, or
+// when only the clusters of the watched namespace or selector were.
+func (op *Operator) migrateStorage() error {
+	logger := logrus.WithField("kind", "MariaDBCluster").WithField("action", "migrateStorage")
+	clusters := op.ComponentsClient.ComponentsV1alpha1().MariaDBClusters(op.Namespace)
+	list, err := clusters.List(metav1.ListOptions{LabelSelector: op.InstanceSelector})
+	if err != nil {
+		logger.Errorf("Error fetching object : %s", err.Error())
+		return err
+	}
+	patch, err := json.Marshal(map[string]interface{}{"metadata": map[string]interface{}{
+		"annotations": map[string]string{componentsv1alpha1.MariaDBClusterStorageVersionAnnotation: storageVersion},
+	}})
+	if err != nil {
+		return err
+	}
+	var failed error
+	for _, mdbc := range list.Items {
+		if err = op.ctx.Err(); err != nil {
+			return err
+		}
+		if mdbc.Annotations[componentsv1alpha1.MariaDBClusterStorageVersionAnnotation] == storageVersion {
+			continue
+		}
+		clusterLogger := logger.WithField("cluster", mdbc.Namespace+"/"+mdbc.Name)
+		if _, err = clusters.Patch(mdbc.Name, types.MergePatchType, patch); apierrors.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			clusterLogger.Errorf("Patch failed with : %s", err.Error())
+			failed = err
+			continue
+		}
+		clusterLogger.WithField("event", "rewritten").Info()
+	}
+	if failed != nil {
+		return failed
+	}
+	if op.Namespace != "" || op.InstanceSelector != "" {
+		logger.WithField("event", "kept").Info("only the watched clusters rewritten, leaving the stored versions of the CRD")
+		return nil
+	}
+	return op.trimStoredVersions()
+}
+
+// trimStoredVersions sets the stored versions of the CustomResourceDefinition
+// to the storage version alone, once no object is stored in another one
+func (op *Operator) trimStoredVersions() error {
+	logger := logrus.WithField("kind", "CustomResourceDefinition").WithField("action", "migrateStorage")
+	client := op.ApiExtensionsClient.ApiextensionsV1beta1().RESTClient()
+	body, err := client.Get().Resource("customresourcedefinitions").Name(componentsv1alpha1.CRDName).Context(op.ctx).Do().Raw()
+	if err != nil {
+		logger.Errorf("Error fetching object : %s", err.Error())
+		return err
+	}
+	crd := map[string]interface{}{}
+	if err = json.Unmarshal(body, &crd); err != nil {
+		return err
+	}
+	status, _ := crd["status"].(map[string]interface{})
+	if status == nil {
+		return nil
+	}
+	stored, _ := status["storedVersions"].([]interface{})
+	if len(stored) == 1 && stored[0] == storageVersion {
+		return nil
+	}
+	status["storedVersions"] = []string{storageVersion}
+	if body, err = json.Marshal(crd); err != nil {
+		return err
+	}
+	if err = client.Put().Resource("customresourcedefinitions").Name(componentsv1alpha1.CRDName).SubResource("status").Body(body).Context(op.ctx).Do().Error(); err != nil {
+		logger.Errorf("Update failed with : %s", err.Error())
+		return err
+	}
+	logger.WithField("event", "updated").Infof("stored versions %v trimmed to %s", stored, storageVersion)
+	return nil
+}
@@ -0,0 +1,81 @@
+package operator
+
+import (
+	"fmt"
+	"strings"
+
+	componentsv1alpha1 "github.com/dansksupermarked/mariadb-galera-operator/pkg/apis/components/v1alpha1"
+	"github.com/dansksupermarked/mariadb-galera-operator/pkg/util"
+	"k8s.io/api/core/v1"
+)
+
+// reportSuspended raises the Suspended condition listing the automation held
+// back by Spec.Suspend
+func reportSuspended(mdbc *componentsv1alpha1.MariaDBCluster) {
+	var suspended []string
+	if mdbc.Spec.Suspend.Backups {
+		suspended = append(suspended, "backups")
+	}
+	if mdbc.Spec.Suspend.Restarts {
+		suspended = append(suspended, "restarts")
+	}
+	if mdbc.Spec.Suspend.Recovery {
+		suspended = append(suspended, "recovery")
+	}
+	if len(suspended) == 0 {
+		mdbc.Status.RemoveCondition(componentsv1alpha1.ConditionSuspended)
+		return
+	}
+	mdbc.Status.SetCondition(componentsv1alpha1.ConditionSuspended, true, "Suspended",
+		strings.Join(suspended, ", ")+" suspended through spec.suspend")
+}
+
+// pause only reports a cluster with Spec.Paused set, none of its objects are
+// reconciled until it is unset again
+func (c *Controller) pause(mdbc *componentsv1alpha1.MariaDBCluster) {
+	logger := util.GetClusterLogger(mdbc).WithField("kind", "MariaDBCluster").WithField("action", "reconcile")
+	logger.WithField("event", "paused").Debug("spec.paused is set, skipping reconciliation")
+	original := mdbc.DeepCopy()
+	mdbc.Status.SetCondition(componentsv1alpha1.ConditionSuspended, true, "Paused",
+		"spec.paused is set, the operator does not reconcile this cluster")
+	checkAndPatchMariaDBCluster(original, mdbc, c.operator.ComponentsClient.Components(), logger)
+}
+
+// restartsSuspended tells whether server pods are to be left running whatever
+// their agents report, through spec.suspend.restarts or maintenance
+func restartsSuspended(mdbc *componentsv1alpha1.MariaDBCluster) bool {
+	return mdbc.Spec.Suspend.Restarts || mdbc.IsInMaintenance()
+}
+
+// recoverySuspended tells whether a cluster without ready pods is left as it
+// is, through spec.suspend.recovery or maintenance
+func recoverySuspended(mdbc *componentsv1alpha1.MariaDBCluster) bool {
+	return mdbc.Spec.Suspend.Recovery || mdbc.IsInMaintenance()
+}
+
+// reportMaintenance publishes whether the cluster is in maintenance in
+// Status.Maintenance and the Maintenance condition, along with how many pods
+// are ready as health is still checked
+func (c *Controller) reportMaintenance(mdbc *componentsv1alpha1.MariaDBCluster) {
+	maintenance := mdbc.IsInMaintenance()
+	if maintenance != mdbc.Status.Maintenance {
+		message := "maintenance ended, corrective actions resumed"
+		if maintenance {
+			message = "maintenance started, no corrective action is taken until it ends"
+		}
+		util.GetClusterLogger(mdbc).WithField("action", "maintenance").WithField("event", "changed").Info(message)
+		c.recorder.Event(mdbc, v1.EventTypeNormal, componentsv1alpha1.ConditionMaintenance, message)
+		mdbc.Status.Maintenance = maintenance
+	}
+	if !maintenance {
+		mdbc.Status.RemoveCondition(componentsv1alpha1.ConditionMaintenance)
+		return
+	}
+	source := "spec.maintenance"
+	if !mdbc.Spec.Maintenance {
+		source = componentsv1alpha1.MariaDBClusterMaintenanceAnnotation
+	}
+	mdbc.Status.SetCondition(componentsv1alpha1.ConditionMaintenance, true, "Maintenance",
+		fmt.Sprintf("%s is set, no pod is restarted, recovered or upgraded, %d of %d pods ready in %s %s",
+			source, mdbc.Status.ReadyReplicas, mdbc.Spec.Replicas, mdbc.Status.Phase, mdbc.Status.Stage))
+}
@@ -0,0 +1,275 @@
+package operator
+
+import (
+	"fmt"
+
+	componentsv1alpha1 "github.com/dansksupermarked/mariadb-galera-operator/pkg/apis/components/v1alpha1"
+	"github.com/dansksupermarked/mariadb-galera-operator/pkg/util"
+	batch "k8s.io/api/batch/v1"
+	"k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// orphanPatch drops the owner references of an object so that the garbage
+// collector keeps it once the cluster is gone
+var orphanPatch = []byte(`{"metadata":{"ownerReferences":null}}`)
+
+// ensureFinalizer adds the teardown finalizer to a cluster not being deleted
+func ensureFinalizer(mdbc *componentsv1alpha1.MariaDBCluster) {
+	if mdbc.DeletionTimestamp != nil || hasFinalizer(mdbc) {
+		return
+	}
+	mdbc.Finalizers = append(mdbc.Finalizers, componentsv1alpha1.MariaDBClusterFinalizer)
+}
+
+func hasFinalizer(mdbc *componentsv1alpha1.MariaDBCluster) bool {
+	for _, finalizer := range mdbc.Finalizers {
+		if finalizer == componentsv1alpha1.MariaDBClusterFinalizer {
+			return true
+		}
+	}
+	return false
+}
+
+// teardown runs the cleanup of a deleted cluster in order: the final backup,
+// the client facing Service and proxy, the server StatefulSets, then the
+// volumes and Secret as their retention policies say. Each stage is left once
+// its objects are gone, and the finalizer is removed after the last one so
+// the garbage collector takes what the cluster still owns.
+func (c *Controller) teardown(mdbc *componentsv1alpha1.MariaDBCluster) error {
+	if !hasFinalizer(mdbc) {
+		return nil
+	}
+	logger := util.GetClusterLogger(mdbc).WithField("action", "teardown")
+	original := mdbc.DeepCopy()
+	if mdbc.Status.Phase != componentsv1alpha1.PhaseTeardown {
+		logger.WithField("event", "phaseTransition").Info("Cluster deleted, transitioning to Teardown phase")
+		mdbc.Status.Stage = componentsv1alpha1.StageRemovingClients
+		if mdbc.Spec.Teardown.FinalBackup {
+			if mdbc.Spec.Suspend.Backups {
+				message := "no final backup taken, backups are suspended"
+				logger.WithField("event", "skipped").Warn(message)
+				c.recorder.Event(mdbc, v1.EventTypeWarning, "FinalBackupSkipped", message)
+			} else if mdbc.Status.Phase == componentsv1alpha1.PhaseOperational && mdbc.Status.ReadyReplicas > 0 {
+				mdbc.Status.Stage = componentsv1alpha1.StageFinalBackup
+			} else {
+				message := fmt.Sprintf("no final backup taken, the cluster was %s without ready pods", mdbc.Status.Phase)
+				logger.WithField("event", "skipped").Warn(message)
+				c.recorder.Event(mdbc, v1.EventTypeWarning, "FinalBackupSkipped", message)
+			}
+		}
+		mdbc.Status.Phase = componentsv1alpha1.PhaseTeardown
+	}
+
+	for {
+		var done bool
+		var err error
+		var next string
+		switch mdbc.Status.Stage {
+		case componentsv1alpha1.StageFinalBackup, componentsv1alpha1.StageFinalBackupFailed:
+			done, err = c.finalBackup(mdbc)
+			next = componentsv1alpha1.StageRemovingClients
+		case componentsv1alpha1.StageRemovingClients:
+			done, err = c.removeClients(mdbc)
+			next = componentsv1alpha1.StageRemovingServers
+		case componentsv1alpha1.StageRemovingServers:
+			done, err = c.removeServers(mdbc)
+			next = componentsv1alpha1.StageReleasingStorage
+		default:
+			done, err = c.releaseStorage(mdbc)
+		}
+		if err != nil || !done {
+			recordTransition(original, mdbc)
+			checkAndPatchMariaDBCluster(original, mdbc, c.operator.ComponentsClient.Components(), logger)
+			return err
+		}
+		if next == "" {
+			break
+		}
+		logger.WithField("event", "stageTransition").Infof("%s done, moving on to %s", mdbc.Status.Stage, next)
+		mdbc.Status.Stage = next
+	}
+
+	// the status is left as it is, the object is gone once the finalizer is
+	expected := original.DeepCopy()
+	expected.Finalizers = nil
+	for _, finalizer := range original.Finalizers {
+		if finalizer != componentsv1alpha1.MariaDBClusterFinalizer {
+			expected.Finalizers = append(expected.Finalizers, finalizer)
+		}
+	}
+	logger.WithField("event", "finished").Info("teardown done, removing finalizer")
+	checkAndPatchMariaDBCluster(original, expected, c.operator.ComponentsClient.Components(), logger)
+	return nil
+}
+
+// finalBackup dumps the cluster through a backup Job into the snapshot volume.
+// A failed Job holds the teardown until it is deleted to retry, or the final
+// backup is disabled.
+func (c *Controller) finalBackup(mdbc *componentsv1alpha1.MariaDBCluster) (bool, error) {
+	if !mdbc.Spec.Teardown.FinalBackup {
+		return true, nil
+	}
+	logger := util.GetClusterLogger(mdbc).WithField("kind", "Job").WithField("action", "teardown")
+	name := mdbc.GetFinalBackupJobName()
+	jobs := c.operator.Client.BatchV1().Jobs(mdbc.Namespace)
+	job, err := jobs.Get(name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		expected := &batch.Job{}
+		mdbc.BackupJobTransform(expected, name)
+		if _, err = jobs.Create(expected); err != nil {
+			logger.Errorf("Creation failed with : %s", err.Error())
+			return false, err
+		}
+		logger.WithField("event", "created").Info()
+		mdbc.Status.Stage = componentsv1alpha1.StageFinalBackup
+		return false, nil
+	} else if err != nil {
+		logger.Errorf("Error fetching object : %s", err.Error())
+		return false, err
+	}
+
+	if isJobConditionTrue(job, batch.JobFailed) {
+		if mdbc.Status.Stage != componentsv1alpha1.StageFinalBackupFailed {
+			message := fmt.Sprintf("final backup job %s failed, delete it to retry or disable spec.teardown.finalBackup: %s", name, c.jobFailureMessage(mdbc, name))
+			logger.WithField("event", "failed").Warn(message)
+			c.recorder.Event(mdbc, v1.EventTypeWarning, "FinalBackupFailed", message)
+			mdbc.Status.Stage = componentsv1alpha1.StageFinalBackupFailed
+		}
+		return false, nil
+	}
+	if !isJobConditionTrue(job, batch.JobComplete) {
+		return false, nil
+	}
+	message := fmt.Sprintf("final backup %s.sql written to the snapshot volume", name)
+	logger.WithField("event", "completed").Info(message)
+	c.recorder.Event(mdbc, v1.EventTypeNormal, "FinalBackupCompleted", message)
+	return true, nil
+}
+
+// removeClients deletes the client facing Service and the proxy, so that no
+// client reaches the servers while they go away
+func (c *Controller) removeClients(mdbc *componentsv1alpha1.MariaDBCluster) (bool, error) {
+	logger := util.GetClusterLogger(mdbc).WithField("action", "teardown")
+	err := c.operator.Client.CoreV1().Services(mdbc.Namespace).Delete(mdbc.GetProxyServiceName(), &metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		logger.WithField("kind", "Service").Errorf("Deletion failed with : %s", err.Error())
+		return false, err
+	}
+	propagation := metav1.DeletePropagationBackground
+	err = c.operator.Client.AppsV1().Deployments(mdbc.Namespace).Delete(mdbc.GetProxyName(), &metav1.DeleteOptions{PropagationPolicy: &propagation})
+	if err != nil && !apierrors.IsNotFound(err) {
+		logger.WithField("kind", "Deployment").Errorf("Deletion failed with : %s", err.Error())
+		return false, err
+	}
+	return true, nil
+}
+
+// removeServers deletes the StatefulSets of both colors, done once they and
+// their pods are gone
+func (c *Controller) removeServers(mdbc *componentsv1alpha1.MariaDBCluster) (bool, error) {
+	logger := util.GetClusterLogger(mdbc).WithField("kind", "StatefulSet").WithField("action", "teardown")
+	propagation := metav1.DeletePropagationForeground
+	done := true
+	for _, color := range []string{componentsv1alpha1.ColorBlue, componentsv1alpha1.ColorGreen} {
+		name := mdbc.GetServerNameForColor(color)
+		statefulsets := c.operator.Client.AppsV1().StatefulSets(mdbc.Namespace)
+		sset, err := statefulsets.Get(name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			logger.Errorf("Error fetching object : %s", err.Error())
+			return false, err
+		}
+		done = false
+		if sset.DeletionTimestamp != nil {
+			continue
+		}
+		if err = statefulsets.Delete(name, &metav1.DeleteOptions{PropagationPolicy: &propagation}); err != nil && !apierrors.IsNotFound(err) {
+			logger.Errorf("Deletion failed with : %s", err.Error())
+			return false, err
+		}
+		logger.WithField("event", "deleted").Infof("removing %s servers", color)
+	}
+	return done, nil
+}
+
+// releaseStorage applies the retention policies: retained volumes, Secret and
+// backup Jobs are orphaned. Data volumes are deleted by the operator when not
+// retained, as claims of older clusters may have no owner, the rest is left to
+// the garbage collector.
+func (c *Controller) releaseStorage(mdbc *componentsv1alpha1.MariaDBCluster) (bool, error) {
+	logger := util.GetClusterLogger(mdbc).WithField("action", "teardown")
+	claims := c.operator.Client.CoreV1().PersistentVolumeClaims(mdbc.Namespace)
+	for _, color := range []string{componentsv1alpha1.ColorBlue, componentsv1alpha1.ColorGreen} {
+		// claims created from volumeClaimTemplates carry the StatefulSet selector labels
+		selector := labels.SelectorFromSet(mdbc.GetServerLabelsForColor(color)).String()
+		if mdbc.GetDataRetentionPolicy() == componentsv1alpha1.RetentionPolicyDelete {
+			if err := claims.DeleteCollection(&metav1.DeleteOptions{}, metav1.ListOptions{LabelSelector: selector}); err != nil {
+				logger.WithField("kind", "PersistentVolumeClaim").Errorf("Deletion failed with : %s", err.Error())
+				return false, err
+			}
+			continue
+		}
+		list, err := claims.List(metav1.ListOptions{LabelSelector: selector})
+		if err != nil {
+			logger.WithField("kind", "PersistentVolumeClaim").Errorf("Error fetching object : %s", err.Error())
+			return false, err
+		}
+		for _, claim := range list.Items {
+			if len(claim.OwnerReferences) == 0 {
+				continue
+			}
+			if _, err = claims.Patch(claim.Name, types.MergePatchType, orphanPatch); err != nil && !apierrors.IsNotFound(err) {
+				logger.WithField("kind", "PersistentVolumeClaim").Errorf("Patch failed with : %s", err.Error())
+				return false, err
+			}
+		}
+	}
+	if mdbc.GetDataRetentionPolicy() == componentsv1alpha1.RetentionPolicyDelete {
+		logger.WithField("kind", "PersistentVolumeClaim").WithField("event", "deleted").Info("removed data volumes")
+	} else {
+		logger.WithField("kind", "PersistentVolumeClaim").WithField("event", "retained").Info("data volumes kept")
+	}
+	if mdbc.GetSecretRetentionPolicy() == componentsv1alpha1.RetentionPolicyRetain {
+		// the client user is stored in the data along with the others
+		for _, name := range []string{mdbc.GetServerSecretName(), mdbc.GetClientSecretName()} {
+			_, err := c.operator.Client.CoreV1().Secrets(mdbc.Namespace).Patch(name, types.MergePatchType, orphanPatch)
+			if err != nil && !apierrors.IsNotFound(err) {
+				logger.WithField("kind", "Secret").WithField("name", name).Errorf("Patch failed with : %s", err.Error())
+				return false, err
+			}
+		}
+		logger.WithField("kind", "Secret").WithField("event", "retained").Info("Secrets kept")
+	}
+	if mdbc.GetBackupRetentionPolicy() == componentsv1alpha1.RetentionPolicyRetain {
+		jobs := c.operator.Client.BatchV1().Jobs(mdbc.Namespace)
+		list, err := jobs.List(metav1.ListOptions{LabelSelector: labels.SelectorFromSet(mdbc.GetBackupLabels()).String()})
+		if err != nil {
+			logger.WithField("kind", "Job").Errorf("Error fetching object : %s", err.Error())
+			return false, err
+		}
+		for _, job := range list.Items {
+			if len(job.OwnerReferences) == 0 {
+				continue
+			}
+			if _, err = jobs.Patch(job.Name, types.MergePatchType, orphanPatch); err != nil && !apierrors.IsNotFound(err) {
+				logger.WithField("kind", "Job").Errorf("Patch failed with : %s", err.Error())
+				return false, err
+			}
+		}
+		logger.WithField("kind", "Job").WithField("event", "retained").Infof("%d backup jobs kept", len(list.Items))
+	}
+	if mdbc.GetSnapshotRetentionPolicy() == componentsv1alpha1.RetentionPolicyRetain {
+		_, err := claims.Patch(mdbc.GetSnapshotPVC().Name, types.MergePatchType, orphanPatch)
+		if err != nil && !apierrors.IsNotFound(err) {
+			logger.WithField("kind", "PersistentVolumeClaim").Errorf("Patch failed with : %s", err.Error())
+			return false, err
+		}
+		logger.WithField("kind", "PersistentVolumeClaim").WithField("event", "retained").Info("snapshot volume kept")
+	}
+	return true, nil
+}
@@ -0,0 +1,104 @@
+package operator
+
+import (
+	"fmt"
+	"time"
+
+	componentsv1alpha1 "github.com/dansksupermarked/mariadb-galera-operator/pkg/apis/components/v1alpha1"
+	"github.com/dansksupermarked/mariadb-galera-operator/pkg/util"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// events kept in status.recoveryTimeline, older ones are dropped
+const maxTimelineEvents = 50
+
+// startTimeline opens the timeline of a Recovery phase, one left open by a
+// recovery that lost its primary component again is carried on
+func startTimeline(mdbc *componentsv1alpha1.MariaDBCluster, reason string) {
+	timeline := mdbc.Status.RecoveryTimeline
+	if timeline == nil || !timeline.EndTime.IsZero() {
+		mdbc.Status.RecoveryTimeline = &componentsv1alpha1.RecoveryTimeline{StartTime: metav1.Now()}
+	}
+	addTimelineEvent(mdbc, "%s", reason)
+}
+
+// addTimelineEvent records what happened in the Recovery phase in progress
+func addTimelineEvent(mdbc *componentsv1alpha1.MariaDBCluster, format string, args ...interface{}) {
+	timeline := mdbc.Status.RecoveryTimeline
+	if timeline == nil || !timeline.EndTime.IsZero() {
+		return
+	}
+	timeline.Events = append(timeline.Events, componentsv1alpha1.RecoveryEvent{
+		Time:    metav1.Now(),
+		Message: fmt.Sprintf(format, args...),
+	})
+	if len(timeline.Events) > maxTimelineEvents {
+		timeline.Events = timeline.Events[len(timeline.Events)-maxTimelineEvents:]
+	}
+}
+
+// setTimelineResult records the stage the Recovery phase waits in, or that it
+// carries on again when result is empty
+func setTimelineResult(mdbc *componentsv1alpha1.MariaDBCluster, result string) {
+	if timeline := mdbc.Status.RecoveryTimeline; timeline != nil && timeline.EndTime.IsZero() {
+		timeline.Result = result
+	}
+}
+
+// trackTransfers records state transfers of pods joining the recovered
+// primary component, along with how long they took once they completed
+func trackTransfers(mdbc *componentsv1alpha1.MariaDBCluster) {
+	timeline := mdbc.Status.RecoveryTimeline
+	if timeline == nil || !timeline.EndTime.IsZero() {
+		return
+	}
+	for i := range timeline.Transfers {
+		transfer := &timeline.Transfers[i]
+		if transfer.Duration.Duration > 0 {
+			continue
+		}
+		if status, ok := mdbc.Status.WSREP[transfer.Pod]; ok && status.SST != nil && status.SST.StartTime.Equal(&transfer.StartTime) {
+			transfer.Bytes = status.SST.Bytes
+			continue
+		}
+		transfer.Duration = metav1.Duration{Duration: time.Since(transfer.StartTime.Time).Truncate(time.Second)}
+		addTimelineEvent(mdbc, "state transfer to %s completed in %s, %dM received", transfer.Pod, transfer.Duration.Duration, transfer.Bytes>>20)
+	}
+	for name, status := range mdbc.Status.WSREP {
+		if status.SST == nil || hasTransfer(timeline, name, status.SST.StartTime) {
+			continue
+		}
+		timeline.Transfers = append(timeline.Transfers, componentsv1alpha1.RecoveryTransfer{
+			Pod:       name,
+			StartTime: status.SST.StartTime,
+			Bytes:     status.SST.Bytes,
+		})
+		addTimelineEvent(mdbc, "state transfer to %s started", name)
+	}
+}
+
+func hasTransfer(timeline *componentsv1alpha1.RecoveryTimeline, pod string, start metav1.Time) bool {
+	for _, transfer := range timeline.Transfers {
+		if transfer.Pod == pod && transfer.StartTime.Equal(&start) {
+			return true
+		}
+	}
+	return false
+}
+
+// closeTimeline completes the timeline once the cluster is Operational again
+func (c *Controller) closeTimeline(mdbc *componentsv1alpha1.MariaDBCluster) {
+	timeline := mdbc.Status.RecoveryTimeline
+	if timeline == nil || !timeline.EndTime.IsZero() {
+		return
+	}
+	trackTransfers(mdbc)
+	elapsed := time.Since(timeline.StartTime.Time).Truncate(time.Second)
+	addTimelineEvent(mdbc, "all pods ready after %s", elapsed)
+	timeline.Result = "Recovered"
+	timeline.EndTime = metav1.Now()
+	message := fmt.Sprintf("cluster recovered in %s from %s, see status.recoveryTimeline", elapsed, timeline.BootstrapFrom)
+	util.GetClusterLogger(mdbc).WithField("action", "recovery").WithField("event", "completed").Info(message)
+	c.recorder.Event(mdbc, v1.EventTypeNormal, "RecoveryCompleted", message)
+}
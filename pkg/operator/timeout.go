@@ -0,0 +1,154 @@
+package operator
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	componentsv1alpha1 "github.com/dansksupermarked/mariadb-galera-operator/pkg/apis/components/v1alpha1"
+	"github.com/dansksupermarked/mariadb-galera-operator/pkg/util"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// resumeStalledStage hands the stage a stalled phase is in back to the
+// transitions, they carry on while the cluster is reported as stalled
+func resumeStalledStage(mdbc *componentsv1alpha1.MariaDBCluster) {
+	mdbc.Status.Stage = mdbc.Status.GetStage()
+}
+
+// checkPhaseTimeout moves a cluster that spent longer than its timeout in the
+// phase it was in before the transitions into the Stalled stage, with what
+// holds it back in status.stalled and a Warning Event. The stage is left as
+// soon as the phase changes, or its timeout is raised.
+func (c *Controller) checkPhaseTimeout(mdbc *componentsv1alpha1.MariaDBCluster, phase string) error {
+	logger := util.GetClusterLogger(mdbc).WithField("action", "phaseTimeout")
+	timeout := mdbc.GetPhaseTimeout(phase)
+	if mdbc.Status.Phase != phase || mdbc.Status.PhaseStartTime.IsZero() {
+		mdbc.Status.PhaseStartTime = metav1.Now()
+	}
+	elapsed := time.Since(mdbc.Status.PhaseStartTime.Time)
+	if mdbc.Status.Phase != phase || timeout == 0 || elapsed < timeout {
+		if mdbc.Status.Stalled != nil {
+			message := fmt.Sprintf("%s phase no longer stalled after %s", phase, elapsed.Truncate(time.Second))
+			logger.WithField("event", "resumed").Info(message)
+			c.recorder.Event(mdbc, v1.EventTypeNormal, "Resumed", message)
+			if phase == componentsv1alpha1.PhaseRecovery {
+				addTimelineEvent(mdbc, "%s", message)
+				if timeline := mdbc.Status.RecoveryTimeline; timeline != nil && timeline.Result == componentsv1alpha1.StageStalled {
+					setTimelineResult(mdbc, "")
+				}
+			}
+			mdbc.Status.Stalled = nil
+		}
+		mdbc.Status.RemoveCondition(componentsv1alpha1.ConditionStalled)
+		return nil
+	}
+
+	diagnostics, err := c.phaseDiagnostics(mdbc)
+	if err != nil {
+		return err
+	}
+	stalled := &componentsv1alpha1.StalledStatus{Stage: mdbc.Status.Stage, Diagnostics: diagnostics}
+	message := fmt.Sprintf("%s phase did not complete within %s", phase, timeout)
+	if stalled.Stage != "" {
+		message += ", stuck in " + stalled.Stage + " stage"
+	}
+	if mdbc.Status.Stalled == nil {
+		logger.WithField("event", "stalled").Warn(message)
+		c.recorder.Event(mdbc, v1.EventTypeWarning, componentsv1alpha1.StageStalled, message+": "+strings.Join(diagnostics, ", "))
+		if phase == componentsv1alpha1.PhaseRecovery {
+			addTimelineEvent(mdbc, "%s: %s", message, strings.Join(diagnostics, ", "))
+			setTimelineResult(mdbc, componentsv1alpha1.StageStalled)
+		}
+	}
+	if !reflect.DeepEqual(mdbc.Status.Stalled, stalled) {
+		mdbc.Status.Stalled = stalled
+	}
+	mdbc.Status.Stage = componentsv1alpha1.StageStalled
+	mdbc.Status.SetCondition(componentsv1alpha1.ConditionStalled, true, phase, message)
+	return nil
+}
+
+// phaseDiagnostics lists what server pods and pending reports tell about a
+// phase not moving on
+func (c *Controller) phaseDiagnostics(mdbc *componentsv1alpha1.MariaDBCluster) ([]string, error) {
+	var diagnostics []string
+	pods, err := c.operator.Client.CoreV1().Pods(mdbc.Namespace).List(metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(mdbc.GetServerLabels()).String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(pods.Items, func(i, j int) bool {
+		return podOrdinal(pods.Items[i].Name) < podOrdinal(pods.Items[j].Name)
+	})
+	ready := 0
+	for _, pod := range pods.Items {
+		if isPodReady(&pod) {
+			ready++
+			continue
+		}
+		diagnostics = append(diagnostics, podDiagnostic(&pod))
+	}
+	diagnostics = append([]string{fmt.Sprintf("%d of %d pods ready", ready, mdbc.Spec.Replicas)}, diagnostics...)
+
+	switch mdbc.Status.GetStage() {
+	case componentsv1alpha1.StageReporting:
+		if mdbc.Status.BootstrapFrom != "" {
+			diagnostics = append(diagnostics, "waiting for "+mdbc.Status.BootstrapFrom+" to bootstrap")
+		} else {
+			diagnostics = append(diagnostics, fmt.Sprintf("%d of %d pods reported their galera state", len(mdbc.Status.RecoveryReports), mdbc.Spec.Replicas))
+		}
+		if excluded := mdbc.Status.BootstrapExcluded; len(excluded) > 0 {
+			diagnostics = append(diagnostics, strings.Join(excluded, ", ")+" excluded from bootstrapping")
+		}
+	case componentsv1alpha1.StageInvalidReport, componentsv1alpha1.StageManualRecovery:
+		if retry := mdbc.Status.ReportRetry; retry != nil {
+			diagnostics = append(diagnostics, fmt.Sprintf("reports retried %d times", retry.Attempts))
+		}
+		reports, _, err := reconcileQueriedPositions(mdbc)
+		var hostname string
+		if err == nil {
+			hostname, err = selectBootstrapCandidate(mdbc, reports)
+		}
+		if err != nil {
+			diagnostics = append(diagnostics, err.Error())
+		} else {
+			diagnostics = append(diagnostics, hostname+" can be bootstrapped")
+		}
+	}
+	return diagnostics, nil
+}
+
+// podDiagnostic tells why a pod is not ready, from the first container that is
+// not running or failing its probe
+func podDiagnostic(pod *v1.Pod) string {
+	if pod.DeletionTimestamp != nil {
+		return pod.Name + " terminating"
+	}
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == v1.PodScheduled && cond.Status != v1.ConditionTrue {
+			return fmt.Sprintf("%s not scheduled: %s", pod.Name, cond.Message)
+		}
+	}
+	statuses := append(append([]v1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+	for _, status := range statuses {
+		restarts := ""
+		if status.RestartCount > 0 {
+			restarts = fmt.Sprintf(", restarted %d times", status.RestartCount)
+		}
+		switch {
+		case status.State.Waiting != nil && status.State.Waiting.Reason != "PodInitializing":
+			return fmt.Sprintf("%s container %s waiting: %s%s", pod.Name, status.Name, status.State.Waiting.Reason, restarts)
+		case status.State.Terminated != nil && status.State.Terminated.ExitCode != 0:
+			return fmt.Sprintf("%s container %s exited with %d%s", pod.Name, status.Name, status.State.Terminated.ExitCode, restarts)
+		case status.State.Running != nil && !status.Ready:
+			return fmt.Sprintf("%s container %s running but not ready%s", pod.Name, status.Name, restarts)
+		}
+	}
+	return fmt.Sprintf("%s %s", pod.Name, pod.Status.Phase)
+}
@@ -0,0 +1,112 @@
+package operator
+
+import (
+	"time"
+
+	componentsv1alpha1 "github.com/dansksupermarked/mariadb-galera-operator/pkg/apis/components/v1alpha1"
+	apps "k8s.io/api/apps/v1"
+	"k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// registerTrimmedInformers sets up the informers of the kinds the controller
+// watches so that objects are trimmed by trimObject before they are cached.
+// It has to run before any of them is taken from the factory, which keeps the
+// first informer registered for a kind.
+func (op *Operator) registerTrimmedInformers(factory informers.SharedInformerFactory) {
+	all := func(options *metav1.ListOptions) {}
+	for _, kind := range []struct {
+		obj      runtime.Object
+		resource string
+		tweak    func(options *metav1.ListOptions)
+	}{
+		// pods of clusters only, server and Job pods carry the cluster name label
+		{&v1.Pod{}, "pods", func(options *metav1.ListOptions) {
+			options.LabelSelector = componentsv1alpha1.MariaDBClusterNameLabel
+		}},
+		{&apps.StatefulSet{}, "statefulsets", all},
+		{&v1.ConfigMap{}, "configmaps", all},
+		{&v1.Service{}, "services", all},
+		{&v1.Secret{}, "secrets", all},
+		{&v1.ServiceAccount{}, "serviceaccounts", all},
+		{&rbacv1.Role{}, "roles", all},
+		{&rbacv1.RoleBinding{}, "rolebindings", all},
+		{&v1.PersistentVolumeClaim{}, "persistentvolumeclaims", all},
+		{&policyv1beta1.PodDisruptionBudget{}, "poddisruptionbudgets", all},
+	} {
+		kind := kind
+		factory.InformerFor(kind.obj, func(client kubernetes.Interface, resync time.Duration) cache.SharedIndexInformer {
+			var getter cache.Getter = client.CoreV1().RESTClient()
+			switch kind.obj.(type) {
+			case *apps.StatefulSet:
+				getter = client.AppsV1().RESTClient()
+			case *rbacv1.Role, *rbacv1.RoleBinding:
+				getter = client.RbacV1().RESTClient()
+			case *policyv1beta1.PodDisruptionBudget:
+				getter = client.PolicyV1beta1().RESTClient()
+			}
+			lw := cache.NewFilteredListWatchFromClient(getter, kind.resource, op.Namespace, kind.tweak)
+			return cache.NewSharedIndexInformer(trimmingListWatch(lw), kind.obj, resync,
+				cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+		})
+	}
+}
+
+// trimmingListWatch passes the objects listed and watched through trimObject
+func trimmingListWatch(lw *cache.ListWatch) *cache.ListWatch {
+	return &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			list, err := lw.ListFunc(options)
+			if err != nil {
+				return nil, err
+			}
+			return list, meta.EachListItem(list, trimObject)
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			w, err := lw.WatchFunc(options)
+			if err != nil {
+				return nil, err
+			}
+			return watch.Filter(w, func(event watch.Event) (watch.Event, bool) {
+				trimObject(event.Object)
+				return event, true
+			}), nil
+		},
+	}
+}
+
+// trimObject drops what the controller never reads from the cache: the copy
+// kubectl apply keeps of an object, the spec of pods but their node, the data
+// of secrets but the report key of server Secrets and the rules of roles. Managed fields are already dropped on
+// decoding, as the vendored types predate them.
+func trimObject(obj runtime.Object) error {
+	if accessor, err := meta.Accessor(obj); err == nil {
+		if annotations := accessor.GetAnnotations(); annotations[v1.LastAppliedConfigAnnotation] != "" {
+			delete(annotations, v1.LastAppliedConfigAnnotation)
+			accessor.SetAnnotations(annotations)
+		}
+	}
+	switch obj := obj.(type) {
+	case *v1.Pod:
+		obj.Spec = v1.PodSpec{NodeName: obj.Spec.NodeName}
+	case *v1.Secret:
+		// reports are verified with the key on every reconcile
+		var data map[string][]byte
+		if key, ok := obj.Data[componentsv1alpha1.ReportKeyKey]; ok && obj.Labels[componentsv1alpha1.MariaDBClusterNameLabel] != "" {
+			data = map[string][]byte{componentsv1alpha1.ReportKeyKey: key}
+		}
+		obj.Data = data
+		obj.StringData = nil
+	case *rbacv1.Role:
+		obj.Rules = nil
+	}
+	return nil
+}
@@ -0,0 +1,348 @@
+package operator
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	componentsv1alpha1 "github.com/dansksupermarked/mariadb-galera-operator/pkg/apis/components/v1alpha1"
+	"github.com/dansksupermarked/mariadb-galera-operator/pkg/phase"
+	"github.com/dansksupermarked/mariadb-galera-operator/pkg/util"
+	apps "k8s.io/api/apps/v1"
+	batch "k8s.io/api/batch/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// checkUpgrade holds a change of Spec.Version back until all upgrade gates
+// pass, only then the new version is promoted to Status.CurrentVersion which
+// is what StatefulSetTransform renders into the server image. With the
+// BlueGreen strategy promotion happens once the parallel cluster took over.
+func (c *Controller) checkUpgrade(mdbc *componentsv1alpha1.MariaDBCluster, sset *apps.StatefulSet) error {
+	logger := util.GetClusterLogger(mdbc).WithField("action", "upgrade")
+	if mdbc.Status.CurrentVersion == "" {
+		mdbc.Status.CurrentVersion = mdbc.GetVersion()
+	}
+	if mdbc.Status.BlueGreen != nil {
+		return c.runBlueGreen(mdbc)
+	}
+
+	if mdbc.GetVersion() == mdbc.Status.CurrentVersion {
+		mdbc.Status.TargetVersion = ""
+		cond := mdbc.Status.GetCondition(componentsv1alpha1.ConditionUpgrading)
+		if cond != nil && cond.Status && phase.StatefulSetReady(sset) {
+			logger.WithField("event", "completed").Infof("running version %s", mdbc.Status.CurrentVersion)
+			mdbc.Status.SetCondition(componentsv1alpha1.ConditionUpgrading, false, "Completed", "running version "+mdbc.Status.CurrentVersion)
+		}
+		return nil
+	}
+
+	mdbc.Status.TargetVersion = mdbc.GetVersion()
+	blueGreen := mdbc.Spec.Upgrade.Strategy == componentsv1alpha1.UpgradeStrategyBlueGreen
+	// a blue/green upgrade never mixes versions within one galera cluster
+	if !blueGreen {
+		passed, err := c.checkProviderGate(mdbc)
+		if err != nil || !passed {
+			return err
+		}
+	}
+	passed, err := c.checkSSTMethodGate(mdbc)
+	if err != nil || !passed {
+		return err
+	}
+	passed, err = c.checkBackupGate(mdbc)
+	if err != nil || !passed {
+		return err
+	}
+	if !blueGreen {
+		passed, err = c.checkGCacheGate(mdbc, sset)
+		if err != nil || !passed {
+			return err
+		}
+	}
+	pinned, err := c.resolveTargetDigest(mdbc)
+	if err != nil || !pinned {
+		return err
+	}
+
+	if blueGreen {
+		c.startBlueGreen(mdbc)
+		return nil
+	}
+
+	logger.WithField("event", "promoted").Infof("upgrading from %s to %s", mdbc.Status.CurrentVersion, mdbc.Status.TargetVersion)
+	mdbc.Status.SetCondition(componentsv1alpha1.ConditionUpgrading, true, "RollingOut",
+		fmt.Sprintf("upgrading from %s to %s", mdbc.Status.CurrentVersion, mdbc.Status.TargetVersion))
+	mdbc.Status.CurrentVersion = mdbc.Status.TargetVersion
+	mdbc.Status.TargetVersion = ""
+	return nil
+}
+
+// checkProviderGate refuses rolling upgrades between server series or galera
+// providers that can not form a single cluster while the rollout is in progress
+func (c *Controller) checkProviderGate(mdbc *componentsv1alpha1.MariaDBCluster) (bool, error) {
+	if mdbc.Spec.Upgrade.SkipProviderCheck {
+		return true, nil
+	}
+	if ok, reason := isServerUpgradeCompatible(mdbc.Status.CurrentVersion, mdbc.Status.TargetVersion); !ok {
+		mdbc.Status.SetCondition(componentsv1alpha1.ConditionUpgrading, false, "IncompatibleVersion", reason)
+		return false, nil
+	}
+	current, err := c.probeProviderVersion(mdbc, mdbc.Status.CurrentVersion)
+	if err != nil || current == "" {
+		return false, err
+	}
+	target, err := c.probeProviderVersion(mdbc, mdbc.Status.TargetVersion)
+	if err != nil || target == "" {
+		return false, err
+	}
+	if ok, reason := isProviderUpgradeCompatible(current, target); !ok {
+		mdbc.Status.SetCondition(componentsv1alpha1.ConditionUpgrading, false, "IncompatibleProvider", reason)
+		return false, nil
+	}
+	return true, nil
+}
+
+// probeProviderVersion returns the galera provider shipped with the server image
+// of given version, running a check Job on first use and caching its result in
+// status. An empty result means the check is still in progress or failed.
+func (c *Controller) probeProviderVersion(mdbc *componentsv1alpha1.MariaDBCluster, version string) (string, error) {
+	if provider, ok := mdbc.Status.ProviderVersions[version]; ok {
+		return provider, nil
+	}
+	name := mdbc.GetProviderCheckJobName(version)
+	pod, failed, err := c.runCheckJob(mdbc, name, func(job *batch.Job) error {
+		return mdbc.ProviderCheckJobTransform(job, version)
+	})
+	if err != nil {
+		return "", err
+	}
+	if failed {
+		mdbc.Status.SetCondition(componentsv1alpha1.ConditionUpgrading, false, "ProviderCheckFailed",
+			"no galera provider found in image "+mdbc.GetImage(version)+", delete job "+name+" to retry")
+		return "", nil
+	}
+	if pod == nil {
+		mdbc.Status.SetCondition(componentsv1alpha1.ConditionUpgrading, false, "CheckingProvider", "waiting for provider check job "+name)
+		return "", nil
+	}
+	var provider string
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.State.Terminated != nil {
+			provider = strings.TrimSpace(status.State.Terminated.Message)
+		}
+	}
+	if provider == "" {
+		return "", fmt.Errorf("provider check job %s completed without reporting a provider", name)
+	}
+	if mdbc.Status.ProviderVersions == nil {
+		mdbc.Status.ProviderVersions = make(map[string]string)
+	}
+	mdbc.Status.ProviderVersions[version] = provider
+	util.GetClusterLogger(mdbc).WithField("action", "upgrade").WithField("event", "probed").Infof("version %s ships %s", version, provider)
+	return provider, nil
+}
+
+// resolveTargetDigest pins the target version before it is promoted, so that
+// the rollout already uses the digest and no second rollout follows the pinning
+func (c *Controller) resolveTargetDigest(mdbc *componentsv1alpha1.MariaDBCluster) (bool, error) {
+	if !mdbc.Spec.PinImageDigest || mdbc.Spec.ImageDigest != "" {
+		return true, nil
+	}
+	digest, err := c.resolveImageDigest(mdbc, mdbc.Status.TargetVersion)
+	if err != nil || digest == "" {
+		mdbc.Status.SetCondition(componentsv1alpha1.ConditionUpgrading, false, "ResolvingImage",
+			"resolving digest of "+mdbc.GetImage(mdbc.Status.TargetVersion))
+		return false, err
+	}
+	mdbc.Status.PinnedImage = &componentsv1alpha1.PinnedImageStatus{
+		Version:      mdbc.Status.TargetVersion,
+		Digest:       digest,
+		ResolvedTime: metav1.Now(),
+		Image:        mdbc.GetImage(mdbc.Status.TargetVersion),
+	}
+	return true, nil
+}
+
+// checkBackupGate returns true once a fresh backup of the running version exists,
+// starting one when the policy allows it. The backup used is recorded as the
+// rollback point for the upgrade.
+func (c *Controller) checkBackupGate(mdbc *componentsv1alpha1.MariaDBCluster) (bool, error) {
+	policy := mdbc.Spec.Upgrade
+	if !policy.RequireBackup {
+		return true, nil
+	}
+	if err := c.refreshLastBackup(mdbc); err != nil {
+		return false, err
+	}
+	if isBackupFresh(mdbc.Status.LastBackup, mdbc.Status.CurrentVersion, policy.GetBackupMaxAge()) {
+		mdbc.Status.UpgradeRollbackPoint = mdbc.Status.LastBackup.Name
+		return true, nil
+	}
+
+	waiting := fmt.Sprintf("upgrade to %s requires a backup of version %s not older than %s",
+		mdbc.Status.TargetVersion, mdbc.Status.CurrentVersion, policy.GetBackupMaxAge())
+	if policy.TriggerBackup && mdbc.Spec.Suspend.Backups {
+		mdbc.Status.SetCondition(componentsv1alpha1.ConditionUpgrading, false, "BackupsSuspended", waiting+", backups are suspended")
+		return false, nil
+	}
+	if !policy.TriggerBackup {
+		mdbc.Status.SetCondition(componentsv1alpha1.ConditionUpgrading, false, "WaitingForBackup", waiting)
+		return false, nil
+	}
+
+	logger := util.GetClusterLogger(mdbc).WithField("kind", "Job").WithField("action", "reconcile")
+	name := mdbc.GetUpgradeBackupJobName()
+	jobs := c.operator.Client.BatchV1().Jobs(mdbc.Namespace)
+	job, err := jobs.Get(name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		expected := &batch.Job{}
+		mdbc.BackupJobTransform(expected, name)
+		if _, err = jobs.Create(expected); err != nil {
+			logger.Errorf("Creation failed with : %s", err.Error())
+			return false, err
+		}
+		logger.WithField("event", "created").Info()
+		mdbc.Status.SetCondition(componentsv1alpha1.ConditionUpgrading, false, "BackupStarted", "started backup job "+name)
+		return false, nil
+	} else if err != nil {
+		logger.Errorf("Error fetching object : %s", err.Error())
+		return false, err
+	}
+
+	if isJobConditionTrue(job, batch.JobFailed) {
+		mdbc.Status.SetCondition(componentsv1alpha1.ConditionUpgrading, false, "BackupFailed",
+			"backup job "+name+" failed, delete it to retry")
+	} else if isJobConditionTrue(job, batch.JobComplete) {
+		// succeeded but too old to be used, drop it so a new one is started
+		logger.WithField("event", "expired").Info("removing stale backup job")
+		propagation := metav1.DeletePropagationBackground
+		if err = jobs.Delete(name, &metav1.DeleteOptions{PropagationPolicy: &propagation}); err != nil && !apierrors.IsNotFound(err) {
+			return false, err
+		}
+	} else {
+		mdbc.Status.SetCondition(componentsv1alpha1.ConditionUpgrading, false, "WaitingForBackup", "waiting for backup job "+name)
+	}
+	return false, nil
+}
+
+// refreshLastBackup records the most recently completed backup Job labeled for
+// this cluster, whether started by the operator or by a user managed schedule
+func (c *Controller) refreshLastBackup(mdbc *componentsv1alpha1.MariaDBCluster) error {
+	selector := labels.SelectorFromSet(mdbc.GetBackupLabels()).String()
+	list, err := c.operator.Client.BatchV1().Jobs(mdbc.Namespace).List(metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return err
+	}
+	for _, job := range list.Items {
+		if !isJobConditionTrue(&job, batch.JobComplete) || job.Status.CompletionTime == nil {
+			continue
+		}
+		if mdbc.Status.LastBackup != nil && !mdbc.Status.LastBackup.CompletionTime.Before(job.Status.CompletionTime) {
+			continue
+		}
+		mdbc.Status.LastBackup = &componentsv1alpha1.BackupStatus{
+			Name:           job.Name,
+			Version:        job.Annotations[componentsv1alpha1.MariaDBClusterVersionAnnotation],
+			CompletionTime: *job.Status.CompletionTime,
+		}
+	}
+	return nil
+}
+
+// isBackupFresh accepts backups that do not record a version, as those come
+// from user managed Jobs
+func isBackupFresh(backup *componentsv1alpha1.BackupStatus, version string, maxAge time.Duration) bool {
+	if backup == nil {
+		return false
+	}
+	if backup.Version != "" && backup.Version != version {
+		return false
+	}
+	return time.Since(backup.CompletionTime.Time) < maxAge
+}
+
+// last series of the major versions that are closed, the next series after
+// one of them is any of the following major, as 10.11 to 11.0 or 11.4
+var lastSeries = map[int]int{10: 11, 11: 8}
+
+// isServerUpgradeCompatible allows staying within a series or moving to the next
+// release, MariaDB does not support mixed clusters spanning more than that. The
+// release after the last series of a major is of the next major, whatever its
+// minor, as is the one after a series of a major not known to be closed.
+// Whether the servers can form a single cluster is then up to their providers.
+func isServerUpgradeCompatible(current, target string) (bool, string) {
+	currentMajor, currentMinor, err := parseSeries(current)
+	if err != nil {
+		return false, err.Error()
+	}
+	targetMajor, targetMinor, err := parseSeries(target)
+	if err != nil {
+		return false, err.Error()
+	}
+	switch {
+	case targetMajor == currentMajor && (targetMinor == currentMinor || targetMinor == currentMinor+1):
+		return true, ""
+	case targetMajor == currentMajor+1:
+		if last, ok := lastSeries[currentMajor]; !ok || currentMinor >= last {
+			return true, ""
+		}
+	}
+	return false, fmt.Sprintf("rolling upgrade from %s to %s is not supported, upgrade one series at a time", current, target)
+}
+
+// isProviderUpgradeCompatible compares the wsrep API versions (25, 26, ...) and
+// provider generations (galera 3, 4, ...) of providers reported as
+// <package>:<version>, refusing downgrades and skipped versions of either
+func isProviderUpgradeCompatible(current, target string) (bool, string) {
+	currentAPI, currentGeneration, err := parseProviderVersion(current)
+	if err != nil {
+		return false, err.Error()
+	}
+	targetAPI, targetGeneration, err := parseProviderVersion(target)
+	if err != nil {
+		return false, err.Error()
+	}
+	if targetAPI < currentAPI || targetAPI > currentAPI+1 || targetGeneration < currentGeneration || targetGeneration > currentGeneration+1 {
+		return false, fmt.Sprintf("galera provider %s can not run alongside %s during a rolling upgrade", target, current)
+	}
+	return true, ""
+}
+
+// parseSeries returns major and minor of a version like 10.2 or 10.2.14
+func parseSeries(version string) (int, int, error) {
+	parts := strings.Split(version, ".")
+	if len(parts) < 2 {
+		return 0, 0, fmt.Errorf("can not parse server version %q", version)
+	}
+	major, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, 0, fmt.Errorf("can not parse server version %q", version)
+	}
+	minor, err := strconv.Atoi(strings.SplitN(parts[1], "-", 2)[0])
+	if err != nil {
+		return 0, 0, fmt.Errorf("can not parse server version %q", version)
+	}
+	return major, minor, nil
+}
+
+// parseProviderVersion extracts the wsrep API version and the galera
+// generation from the first two components of the provider version, ie.
+// galera-3:25.3.23 or galera-4:26.4.2, past the epoch of the package if any
+func parseProviderVersion(provider string) (int, int, error) {
+	version := strings.Split(provider[strings.LastIndex(provider, ":")+1:], ".")
+	if len(version) < 2 {
+		return 0, 0, fmt.Errorf("can not parse galera provider %q", provider)
+	}
+	api, err := strconv.Atoi(version[0])
+	if err != nil {
+		return 0, 0, fmt.Errorf("can not parse galera provider %q", provider)
+	}
+	generation, err := strconv.Atoi(version[1])
+	if err != nil {
+		return 0, 0, fmt.Errorf("can not parse galera provider %q", provider)
+	}
+	return api, generation, nil
+}
@@ -0,0 +1,80 @@
+package operator
+
+import (
+	"testing"
+)
+
+func TestParseSeries(t *testing.T) {
+	tests := []struct {
+		version      string
+		major, minor int
+		err          bool
+	}{
+		{"10.2", 10, 2, false},
+		{"10.2.14", 10, 2, false},
+		{"10.11.6", 10, 11, false},
+		{"11.4-ubi", 11, 4, false},
+		{"10", 0, 0, true},
+		{"ten.two", 0, 0, true},
+		{"", 0, 0, true},
+	}
+	for _, test := range tests {
+		major, minor, err := parseSeries(test.version)
+		if (err != nil) != test.err {
+			t.Errorf("%q: error %v, expected one %t", test.version, err, test.err)
+			continue
+		}
+		if major != test.major || minor != test.minor {
+			t.Errorf("%q: %d.%d, expected %d.%d", test.version, major, minor, test.major, test.minor)
+		}
+	}
+}
+
+func TestIsServerUpgradeCompatible(t *testing.T) {
+	tests := []struct {
+		current, target string
+		ok              bool
+	}{
+		{"10.3", "10.3.14", true},
+		{"10.3.13", "10.4", true},
+		{"10.3", "10.5", false},
+		{"10.4", "10.3", false},
+		{"10.10", "10.11", true},
+		{"10.11", "11.0", true},
+		{"10.11.6", "11.4", true},
+		{"10.6", "11.0", false},
+		{"10.6", "11.4", false},
+		{"10.11", "12.0", false},
+		{"11.8", "12.0", true},
+		{"11.4", "11.5", true},
+		{"12.0", "13.1", true},
+		{"11.0", "10.11", false},
+		{"10.11", "eleven", false},
+	}
+	for _, test := range tests {
+		if ok, reason := isServerUpgradeCompatible(test.current, test.target); ok != test.ok {
+			t.Errorf("%s to %s: compatible %t (%s), expected %t", test.current, test.target, ok, reason, test.ok)
+		}
+	}
+}
+
+func TestIsProviderUpgradeCompatible(t *testing.T) {
+	tests := []struct {
+		current, target string
+		ok              bool
+	}{
+		{"galera-3:25.3.23", "galera-3:25.3.25", true},
+		{"galera-3:25.3.23", "galera-4:26.4.2", true},
+		{"galera-4:26.4.2", "galera-4:26.4.14-1", true},
+		{"galera-4:26.4.2", "galera-3:25.3.23", false},
+		{"galera-3:25.3.23", "galera-5:27.5.1", false},
+		{"galera-4:26.4.14", "galera-4:1:26.4.16", true},
+		{"galera-4:26.4.2", "galera-4:28.4.2", false},
+		{"galera-4:26.4.2", "galera-4:unknown", false},
+	}
+	for _, test := range tests {
+		if ok, reason := isProviderUpgradeCompatible(test.current, test.target); ok != test.ok {
+			t.Errorf("%s to %s: compatible %t (%s), expected %t", test.current, test.target, ok, reason, test.ok)
+		}
+	}
+}
@@ -0,0 +1,220 @@
+// Package phase holds the phase machine of a MariaDBCluster. Next takes what
+// the operator observed of a cluster and returns the phase it moves to along
+// with the actions the operator is to take, reading and writing nothing
+// itself, so that every transition can be checked without a cluster to run
+// it against.
+package phase
+
+import (
+	componentsv1alpha1 "github.com/dansksupermarked/mariadb-galera-operator/pkg/apis/components/v1alpha1"
+	apps "k8s.io/api/apps/v1"
+)
+
+// Action is a step the operator takes on a cluster as told by Next, in the
+// order they are returned
+type Action string
+
+const (
+	// the version the cluster is created at is recorded
+	ActionRecordVersion Action = "RecordVersion"
+	// the adopted StatefulSet already runs the cluster, bootstrap is skipped
+	ActionSkipBootstrap Action = "SkipBootstrap"
+	// bootstrap of a new cluster is recorded, from its first pod
+	ActionStartBootstrap Action = "StartBootstrap"
+	// the generation of the StatefulSet is recorded as
+	// Output.StatefulSetObservedGeneration, later transitions wait on the
+	// StatefulSet to observe a newer one
+	ActionObserveStatefulSet Action = "ObserveStatefulSet"
+	// no server pod is ready but recovery is suspended, the cluster is held
+	// in the Degraded stage
+	ActionHoldRecovery Action = "HoldRecovery"
+	// the hold is reported, only when the cluster was not held already
+	ActionReportRecoveryHeld Action = "ReportRecoveryHeld"
+	// no server pod is ready, the cluster goes through Recovery unless a new
+	// primary component can be bootstrapped without restarts
+	ActionStartRecovery Action = "StartRecovery"
+	// the operational checks of quorum, replication and flow control run
+	ActionCheckHealth Action = "CheckHealth"
+	// every pod is ready and updated, the checks of a synced cluster run
+	ActionCheckSynced Action = "CheckSynced"
+	// the StatefulSet rolls out a new revision, the rollout is followed
+	ActionCheckRollout Action = "CheckRollout"
+	// the recovery in progress goes on
+	ActionRecover Action = "Recover"
+)
+
+// Pod is a server pod of the cluster as seen by the operator
+type Pod struct {
+	Name  string
+	Ready bool
+}
+
+// Input is what the phase machine knows of a cluster
+type Input struct {
+	// status of the cluster
+	Phase                         string
+	Stage                         string
+	StatefulSetObservedGeneration int64
+	// spec of the cluster
+	Replicas     int32
+	ParallelJoin bool
+	// the cluster adopted a StatefulSet already running it
+	AdoptedStatefulSet bool
+	// recovery is suspended, or held off by maintenance
+	RecoverySuspended bool
+
+	// server StatefulSet of the cluster, nil when not found
+	StatefulSet *apps.StatefulSet
+	// server pods of the cluster, those of the StatefulSet or kept from it
+	ServerPods []Pod
+}
+
+// Output is what the phase machine decided for a cluster
+type Output struct {
+	// phase the cluster moves to, its current one when it stays
+	Phase string
+	// what led to the transition, empty when the phase stays
+	Reason  string
+	Actions []Action
+	// generation to record on ActionObserveStatefulSet
+	StatefulSetObservedGeneration int64
+}
+
+// Has tells whether an action is to be taken
+func (out Output) Has(action Action) bool {
+	for _, a := range out.Actions {
+		if a == action {
+			return true
+		}
+	}
+	return false
+}
+
+// Next returns the phase a cluster moves to and the actions taken on it. A
+// bootstrap phase is only left once the StatefulSet observed the generation
+// the previous phase wrote and all of its pods are ready. A cluster that
+// lost all of its ready pods while Operational goes into Recovery.
+func Next(in Input) Output {
+	out := Output{Phase: in.Phase, StatefulSetObservedGeneration: in.StatefulSetObservedGeneration}
+	sset := in.StatefulSet
+	transition := func(phase, reason string) {
+		out.Phase, out.Reason = phase, reason
+		out.Actions = append(out.Actions, ActionObserveStatefulSet)
+		out.StatefulSetObservedGeneration = sset.Status.ObservedGeneration
+	}
+
+	switch in.Phase {
+	case "":
+		out.Phase, out.Reason = componentsv1alpha1.PhasePreFlight, "new cluster"
+
+	case componentsv1alpha1.PhasePreFlight:
+		out.Actions = append(out.Actions, ActionRecordVersion)
+		if in.AdoptedStatefulSet {
+			out.Phase, out.Reason = componentsv1alpha1.PhaseOperational, "adopted StatefulSet already runs the cluster"
+			out.Actions = append(out.Actions, ActionSkipBootstrap)
+			break
+		}
+		out.Phase, out.Reason = componentsv1alpha1.PhaseBootstrapFirst, "bootstrapping the first pod"
+		out.Actions = append(out.Actions, ActionStartBootstrap)
+
+	// first pod started with --wsrep-new-cluster
+	case componentsv1alpha1.PhaseBootstrapFirst:
+		if !StatefulSetReady(sset) {
+			break
+		}
+		if in.Replicas == 1 {
+			transition(componentsv1alpha1.PhaseOperational, "single node bootstrapped")
+		} else if in.Replicas > 1 {
+			transition(componentsv1alpha1.PhaseBootstrapFirstRestart, "first pod bootstrapped, restarting it without --wsrep-new-cluster")
+		}
+
+	// first pod restarted without --wsrep-new-cluster, so that a restart
+	// later on does not start a new cluster
+	case componentsv1alpha1.PhaseBootstrapFirstRestart:
+		if in.Replicas <= 1 || !statefulSetRolledOut(in, sset) {
+			break
+		}
+		if in.Replicas > 2 && in.ParallelJoin {
+			transition(componentsv1alpha1.PhaseBootstrapThird, "first pod restarted, joining the others in parallel")
+		} else {
+			transition(componentsv1alpha1.PhaseBootstrapSecond, "first pod restarted, joining the second")
+		}
+
+	case componentsv1alpha1.PhaseBootstrapSecond:
+		if in.Replicas > 2 && statefulSetRolledOut(in, sset) {
+			transition(componentsv1alpha1.PhaseBootstrapThird, "second pod joined, joining the others")
+		}
+
+	case componentsv1alpha1.PhaseBootstrapThird:
+		if in.Replicas > 2 && statefulSetRolledOut(in, sset) {
+			transition(componentsv1alpha1.PhaseOperational, "all pods joined")
+		}
+
+	case componentsv1alpha1.PhaseOperational:
+		// the StatefulSet is recreated by the reconcile at hand
+		if sset == nil {
+			break
+		}
+		if sset.Status.ReadyReplicas == 0 || noPodReady(in.ServerPods) {
+			if in.RecoverySuspended {
+				out.Actions = append(out.Actions, ActionHoldRecovery)
+				if in.Stage != componentsv1alpha1.StageDegraded {
+					out.Actions = append(out.Actions, ActionReportRecoveryHeld)
+				}
+				break
+			}
+			out.Phase, out.Reason = componentsv1alpha1.PhaseRecovery, "no ready pods left"
+			out.Actions = append(out.Actions, ActionStartRecovery)
+			break
+		}
+		out.Actions = append(out.Actions, ActionCheckHealth)
+		if StatefulSetReady(sset) {
+			out.Actions = append(out.Actions, ActionCheckSynced)
+		} else if !statefulSetUpdated(sset) {
+			out.Actions = append(out.Actions, ActionCheckRollout)
+		}
+
+	case componentsv1alpha1.PhaseRecovery:
+		out.Actions = append(out.Actions, ActionRecover)
+	}
+	return out
+}
+
+// StatefulSetReady tells whether every pod of a StatefulSet is ready and at
+// its update revision
+func StatefulSetReady(sset *apps.StatefulSet) bool {
+	return sset != nil && sset.Spec.Replicas != nil &&
+		*sset.Spec.Replicas == sset.Status.Replicas &&
+		*sset.Spec.Replicas == sset.Status.ReadyReplicas &&
+		statefulSetUpdated(sset)
+}
+
+// statefulSetUpdated tells whether every pod of a StatefulSet runs its update
+// revision. Under OnDelete the StatefulSet controller never moves the current
+// revision forward, pods are counted at the update revision instead.
+func statefulSetUpdated(sset *apps.StatefulSet) bool {
+	if sset.Spec.Replicas == nil {
+		return false
+	}
+	if sset.Spec.UpdateStrategy.Type == apps.OnDeleteStatefulSetStrategyType {
+		return *sset.Spec.Replicas == sset.Status.UpdatedReplicas
+	}
+	return *sset.Spec.Replicas == sset.Status.CurrentReplicas && sset.Status.CurrentRevision == sset.Status.UpdateRevision
+}
+
+// statefulSetRolledOut tells whether the StatefulSet observed the changes of
+// the previous phase and is ready with them
+func statefulSetRolledOut(in Input, sset *apps.StatefulSet) bool {
+	return sset != nil && sset.Status.ObservedGeneration > in.StatefulSetObservedGeneration && StatefulSetReady(sset)
+}
+
+// noPodReady tells whether none of the pods is ready, false without any pod
+// as then there is nothing to tell from
+func noPodReady(pods []Pod) bool {
+	for _, pod := range pods {
+		if pod.Ready {
+			return false
+		}
+	}
+	return len(pods) > 0
+}
@@ -0,0 +1,262 @@
+package phase
+
+import (
+	"reflect"
+	"testing"
+
+	componentsv1alpha1 "github.com/dansksupermarked/mariadb-galera-operator/pkg/apis/components/v1alpha1"
+	apps "k8s.io/api/apps/v1"
+)
+
+// statefulSet returns a StatefulSet of replicas pods, ready of them ready, at
+// generation observed
+func statefulSet(replicas, ready int32, observed int64) *apps.StatefulSet {
+	sset := &apps.StatefulSet{}
+	sset.Spec.Replicas = &replicas
+	sset.Status.Replicas = replicas
+	sset.Status.CurrentReplicas = replicas
+	sset.Status.UpdatedReplicas = replicas
+	sset.Status.ReadyReplicas = ready
+	sset.Status.ObservedGeneration = observed
+	sset.Status.CurrentRevision = "rev-1"
+	sset.Status.UpdateRevision = "rev-1"
+	return sset
+}
+
+func rollingOut(sset *apps.StatefulSet) *apps.StatefulSet {
+	sset.Status.UpdateRevision = "rev-2"
+	sset.Status.UpdatedReplicas = 0
+	return sset
+}
+
+// onDelete returns the StatefulSet under OnDelete after updated of its pods
+// were replaced with rev-2, which the current revision never moves on to
+func onDelete(sset *apps.StatefulSet, updated int32) *apps.StatefulSet {
+	sset.Spec.UpdateStrategy.Type = apps.OnDeleteStatefulSetStrategyType
+	sset.Status.UpdateRevision = "rev-2"
+	sset.Status.CurrentReplicas = sset.Status.Replicas - updated
+	sset.Status.UpdatedReplicas = updated
+	return sset
+}
+
+func TestNext(t *testing.T) {
+	ready := []Pod{{Name: "mariadb-0", Ready: true}, {Name: "mariadb-1", Ready: false}}
+	notReady := []Pod{{Name: "mariadb-0"}, {Name: "mariadb-1"}}
+	tests := []struct {
+		name    string
+		in      Input
+		phase   string
+		actions []Action
+		// generation recorded, checked along with ActionObserveStatefulSet
+		observed int64
+	}{
+		{
+			name:  "new cluster",
+			in:    Input{},
+			phase: componentsv1alpha1.PhasePreFlight,
+		},
+		{
+			name:    "preflight bootstraps",
+			in:      Input{Phase: componentsv1alpha1.PhasePreFlight, Replicas: 3},
+			phase:   componentsv1alpha1.PhaseBootstrapFirst,
+			actions: []Action{ActionRecordVersion, ActionStartBootstrap},
+		},
+		{
+			name:    "preflight skips bootstrap of adopted StatefulSet",
+			in:      Input{Phase: componentsv1alpha1.PhasePreFlight, Replicas: 3, AdoptedStatefulSet: true},
+			phase:   componentsv1alpha1.PhaseOperational,
+			actions: []Action{ActionRecordVersion, ActionSkipBootstrap},
+		},
+		{
+			name:  "first pod waits on missing StatefulSet",
+			in:    Input{Phase: componentsv1alpha1.PhaseBootstrapFirst, Replicas: 3},
+			phase: componentsv1alpha1.PhaseBootstrapFirst,
+		},
+		{
+			name:  "first pod waits on readiness",
+			in:    Input{Phase: componentsv1alpha1.PhaseBootstrapFirst, Replicas: 3, StatefulSet: statefulSet(1, 0, 1)},
+			phase: componentsv1alpha1.PhaseBootstrapFirst,
+		},
+		{
+			name:     "single node bootstrapped",
+			in:       Input{Phase: componentsv1alpha1.PhaseBootstrapFirst, Replicas: 1, StatefulSet: statefulSet(1, 1, 1)},
+			phase:    componentsv1alpha1.PhaseOperational,
+			actions:  []Action{ActionObserveStatefulSet},
+			observed: 1,
+		},
+		{
+			name:     "first pod bootstrapped",
+			in:       Input{Phase: componentsv1alpha1.PhaseBootstrapFirst, Replicas: 3, StatefulSet: statefulSet(1, 1, 2)},
+			phase:    componentsv1alpha1.PhaseBootstrapFirstRestart,
+			actions:  []Action{ActionObserveStatefulSet},
+			observed: 2,
+		},
+		{
+			name:     "first restart waits on the StatefulSet observing the restart",
+			in:       Input{Phase: componentsv1alpha1.PhaseBootstrapFirstRestart, Replicas: 3, StatefulSetObservedGeneration: 2, StatefulSet: statefulSet(1, 1, 2)},
+			phase:    componentsv1alpha1.PhaseBootstrapFirstRestart,
+			observed: 2,
+		},
+		{
+			name:     "first restart joins the second pod",
+			in:       Input{Phase: componentsv1alpha1.PhaseBootstrapFirstRestart, Replicas: 3, StatefulSetObservedGeneration: 2, StatefulSet: statefulSet(1, 1, 3)},
+			phase:    componentsv1alpha1.PhaseBootstrapSecond,
+			actions:  []Action{ActionObserveStatefulSet},
+			observed: 3,
+		},
+		{
+			name:     "first restart joins the others in parallel",
+			in:       Input{Phase: componentsv1alpha1.PhaseBootstrapFirstRestart, Replicas: 3, ParallelJoin: true, StatefulSetObservedGeneration: 2, StatefulSet: statefulSet(1, 1, 3)},
+			phase:    componentsv1alpha1.PhaseBootstrapThird,
+			actions:  []Action{ActionObserveStatefulSet},
+			observed: 3,
+		},
+		{
+			name:     "parallel join needs more than two pods",
+			in:       Input{Phase: componentsv1alpha1.PhaseBootstrapFirstRestart, Replicas: 2, ParallelJoin: true, StatefulSetObservedGeneration: 2, StatefulSet: statefulSet(1, 1, 3)},
+			phase:    componentsv1alpha1.PhaseBootstrapSecond,
+			actions:  []Action{ActionObserveStatefulSet},
+			observed: 3,
+		},
+		{
+			name:     "second pod joined",
+			in:       Input{Phase: componentsv1alpha1.PhaseBootstrapSecond, Replicas: 3, StatefulSetObservedGeneration: 3, StatefulSet: statefulSet(2, 2, 4)},
+			phase:    componentsv1alpha1.PhaseBootstrapThird,
+			actions:  []Action{ActionObserveStatefulSet},
+			observed: 4,
+		},
+		{
+			name:     "all pods joined",
+			in:       Input{Phase: componentsv1alpha1.PhaseBootstrapThird, Replicas: 3, StatefulSetObservedGeneration: 4, StatefulSet: statefulSet(3, 3, 5)},
+			phase:    componentsv1alpha1.PhaseOperational,
+			actions:  []Action{ActionObserveStatefulSet},
+			observed: 5,
+		},
+		{
+			name:     "third phase waits on every pod",
+			in:       Input{Phase: componentsv1alpha1.PhaseBootstrapThird, Replicas: 3, StatefulSetObservedGeneration: 4, StatefulSet: statefulSet(3, 2, 5)},
+			phase:    componentsv1alpha1.PhaseBootstrapThird,
+			observed: 4,
+		},
+		{
+			name:    "operational synced",
+			in:      Input{Phase: componentsv1alpha1.PhaseOperational, Replicas: 3, StatefulSet: statefulSet(3, 3, 5), ServerPods: ready},
+			phase:   componentsv1alpha1.PhaseOperational,
+			actions: []Action{ActionCheckHealth, ActionCheckSynced},
+		},
+		{
+			name:    "operational rolling out",
+			in:      Input{Phase: componentsv1alpha1.PhaseOperational, Replicas: 3, StatefulSet: rollingOut(statefulSet(3, 2, 6)), ServerPods: ready},
+			phase:   componentsv1alpha1.PhaseOperational,
+			actions: []Action{ActionCheckHealth, ActionCheckRollout},
+		},
+		{
+			name:    "operational on delete rolled out",
+			in:      Input{Phase: componentsv1alpha1.PhaseOperational, Replicas: 3, StatefulSet: onDelete(statefulSet(3, 3, 6), 3), ServerPods: ready},
+			phase:   componentsv1alpha1.PhaseOperational,
+			actions: []Action{ActionCheckHealth, ActionCheckSynced},
+		},
+		{
+			name:    "operational on delete rolling out",
+			in:      Input{Phase: componentsv1alpha1.PhaseOperational, Replicas: 3, StatefulSet: onDelete(statefulSet(3, 3, 6), 1), ServerPods: ready},
+			phase:   componentsv1alpha1.PhaseOperational,
+			actions: []Action{ActionCheckHealth, ActionCheckRollout},
+		},
+		{
+			name:    "operational with a pod not ready",
+			in:      Input{Phase: componentsv1alpha1.PhaseOperational, Replicas: 3, StatefulSet: statefulSet(3, 2, 5), ServerPods: ready},
+			phase:   componentsv1alpha1.PhaseOperational,
+			actions: []Action{ActionCheckHealth},
+		},
+		{
+			name:  "operational without StatefulSet",
+			in:    Input{Phase: componentsv1alpha1.PhaseOperational, Replicas: 3},
+			phase: componentsv1alpha1.PhaseOperational,
+		},
+		{
+			name:    "recovery when no pod is ready",
+			in:      Input{Phase: componentsv1alpha1.PhaseOperational, Replicas: 3, StatefulSet: statefulSet(3, 0, 5)},
+			phase:   componentsv1alpha1.PhaseRecovery,
+			actions: []Action{ActionStartRecovery},
+		},
+		{
+			name:    "recovery when the pods lost readiness ahead of the StatefulSet",
+			in:      Input{Phase: componentsv1alpha1.PhaseOperational, Replicas: 3, StatefulSet: statefulSet(3, 3, 5), ServerPods: notReady},
+			phase:   componentsv1alpha1.PhaseRecovery,
+			actions: []Action{ActionStartRecovery},
+		},
+		{
+			name:    "no cached pods are no sign of lost readiness",
+			in:      Input{Phase: componentsv1alpha1.PhaseOperational, Replicas: 3, StatefulSet: statefulSet(3, 3, 5), ServerPods: []Pod{}},
+			phase:   componentsv1alpha1.PhaseOperational,
+			actions: []Action{ActionCheckHealth, ActionCheckSynced},
+		},
+		{
+			name:    "recovery suspended",
+			in:      Input{Phase: componentsv1alpha1.PhaseOperational, Stage: componentsv1alpha1.StageSynced, Replicas: 3, RecoverySuspended: true, StatefulSet: statefulSet(3, 0, 5)},
+			phase:   componentsv1alpha1.PhaseOperational,
+			actions: []Action{ActionHoldRecovery, ActionReportRecoveryHeld},
+		},
+		{
+			name:    "recovery still suspended is not reported again",
+			in:      Input{Phase: componentsv1alpha1.PhaseOperational, Stage: componentsv1alpha1.StageDegraded, Replicas: 3, RecoverySuspended: true, StatefulSet: statefulSet(3, 0, 5)},
+			phase:   componentsv1alpha1.PhaseOperational,
+			actions: []Action{ActionHoldRecovery},
+		},
+		{
+			name:    "recovery suspended only holds clusters without ready pods",
+			in:      Input{Phase: componentsv1alpha1.PhaseOperational, Stage: componentsv1alpha1.StageDegraded, Replicas: 3, RecoverySuspended: true, StatefulSet: statefulSet(3, 3, 5), ServerPods: ready},
+			phase:   componentsv1alpha1.PhaseOperational,
+			actions: []Action{ActionCheckHealth, ActionCheckSynced},
+		},
+		{
+			name:    "recovery goes on",
+			in:      Input{Phase: componentsv1alpha1.PhaseRecovery, Stage: componentsv1alpha1.StageReporting, Replicas: 3, StatefulSet: statefulSet(3, 0, 5)},
+			phase:   componentsv1alpha1.PhaseRecovery,
+			actions: []Action{ActionRecover},
+		},
+		{
+			name:    "recovery goes on once pods are ready again",
+			in:      Input{Phase: componentsv1alpha1.PhaseRecovery, Stage: componentsv1alpha1.StagePrimaryRecovered, Replicas: 3, StatefulSet: statefulSet(3, 3, 5), ServerPods: ready},
+			phase:   componentsv1alpha1.PhaseRecovery,
+			actions: []Action{ActionRecover},
+		},
+	}
+	for _, test := range tests {
+		out := Next(test.in)
+		if out.Phase != test.phase {
+			t.Errorf("%s: phase %q, expected %q", test.name, out.Phase, test.phase)
+		}
+		if !reflect.DeepEqual(out.Actions, test.actions) {
+			t.Errorf("%s: actions %v, expected %v", test.name, out.Actions, test.actions)
+		}
+		if out.StatefulSetObservedGeneration != test.observed {
+			t.Errorf("%s: observed generation %d, expected %d", test.name, out.StatefulSetObservedGeneration, test.observed)
+		}
+		if (out.Phase != test.in.Phase) != (out.Reason != "") {
+			t.Errorf("%s: reason %q given for a transition from %q to %q", test.name, out.Reason, test.in.Phase, out.Phase)
+		}
+	}
+}
+
+func TestStatefulSetReady(t *testing.T) {
+	tests := []struct {
+		name  string
+		sset  *apps.StatefulSet
+		ready bool
+	}{
+		{"missing", nil, false},
+		{"without replicas", &apps.StatefulSet{}, false},
+		{"ready", statefulSet(3, 3, 1), true},
+		{"pod not ready", statefulSet(3, 2, 1), false},
+		{"rolling out", rollingOut(statefulSet(3, 3, 1)), false},
+		{"on delete rolled out", onDelete(statefulSet(3, 3, 1), 3), true},
+		{"on delete rolling out", onDelete(statefulSet(3, 3, 1), 2), false},
+		{"on delete rolled out with a pod not ready", onDelete(statefulSet(3, 2, 1), 3), false},
+	}
+	for _, test := range tests {
+		if ready := StatefulSetReady(test.sset); ready != test.ready {
+			t.Errorf("%s: ready %t, expected %t", test.name, ready, test.ready)
+		}
+	}
+}
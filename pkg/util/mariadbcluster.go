@@ -6,21 +6,57 @@ import (
 	"github.com/Sirupsen/logrus"
 	componentsv1alpha1 "github.com/dansksupermarked/mariadb-galera-operator/pkg/apis/components/v1alpha1"
 	componentsclient "github.com/dansksupermarked/mariadb-galera-operator/pkg/generated/clientset/versioned/typed/components/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 )
 
+// CheckAndPatchMariaDBCluster patches the metadata and spec, then the status
+// through its subresource, each only when it changed. With the status apart,
+// status writes never carry spec fields that might have been edited since
+// current was read. Clusters whose CustomResourceDefinition was created
+// without the subresource get their status patched on the resource itself.
+// The first failed patch is returned, the status is patched regardless.
 func CheckAndPatchMariaDBCluster(current, expected *componentsv1alpha1.MariaDBCluster, client componentsclient.ComponentsV1alpha1Interface, logger *logrus.Entry) (bool, error) {
-	if !reflect.DeepEqual(expected, current) {
-		patchBytes, _ := PatchGen(current, expected, componentsv1alpha1.MariaDBCluster{})
+	if reflect.DeepEqual(expected, current) {
+		return false, nil
+	}
+	var patchErr error
+	specChanged := !reflect.DeepEqual(expected.ObjectMeta, current.ObjectMeta) || !reflect.DeepEqual(expected.Spec, current.Spec)
+	if specChanged {
+		withoutStatus := expected.DeepCopy()
+		withoutStatus.Status = current.Status
+		patchBytes, _ := PatchGen(current, withoutStatus, componentsv1alpha1.MariaDBCluster{})
+		logger.Debugf(string(patchBytes))
+		if _, err := client.MariaDBClusters(expected.Namespace).Patch(expected.Name, types.MergePatchType, patchBytes); err != nil {
+			logger.Error(err.Error())
+			patchErr = err
+		}
+	}
+	if !reflect.DeepEqual(expected.Status, current.Status) {
+		statusOnly := current.DeepCopy()
+		statusOnly.Status = expected.Status
+		patchBytes, _ := PatchGen(current, statusOnly, componentsv1alpha1.MariaDBCluster{})
 		logger.Debugf(string(patchBytes))
-		// TODO : error handling
-		_, err := client.MariaDBClusters(expected.Namespace).Patch(expected.Name, types.MergePatchType, patchBytes)
+		_, err := client.MariaDBClusters(expected.Namespace).Patch(expected.Name, types.MergePatchType, patchBytes, "status")
+		if errors.IsNotFound(err) && !isGone(expected, client) {
+			_, err = client.MariaDBClusters(expected.Namespace).Patch(expected.Name, types.MergePatchType, patchBytes)
+		}
 		if err != nil {
 			logger.Error(err.Error())
+			if patchErr == nil {
+				patchErr = err
+			}
 		}
-		return true, nil
 	}
-	return false, nil
+	return true, patchErr
+}
+
+// isGone tells a deleted cluster apart from a missing status subresource, as
+// both answer a status patch with NotFound.
+func isGone(mdbc *componentsv1alpha1.MariaDBCluster, client componentsclient.ComponentsV1alpha1Interface) bool {
+	_, err := client.MariaDBClusters(mdbc.Namespace).Get(mdbc.Name, metav1.GetOptions{})
+	return errors.IsNotFound(err)
 }
 
 func GetClusterLogger(mdbc *componentsv1alpha1.MariaDBCluster) *logrus.Entry {
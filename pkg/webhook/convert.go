@@ -0,0 +1,113 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/dansksupermarked/mariadb-galera-operator/pkg/apis/components/v1alpha1"
+	"github.com/dansksupermarked/mariadb-galera-operator/pkg/apis/components/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// ConversionReview of apiextensions.k8s.io/v1beta1, the vendored
+// apiextensions types predate CRD conversion
+type ConversionReview struct {
+	metav1.TypeMeta `json:",inline"`
+	Request         *ConversionRequest  `json:"request,omitempty"`
+	Response        *ConversionResponse `json:"response,omitempty"`
+}
+
+type ConversionRequest struct {
+	UID               types.UID              `json:"uid"`
+	DesiredAPIVersion string                 `json:"desiredAPIVersion"`
+	Objects           []runtime.RawExtension `json:"objects"`
+}
+
+type ConversionResponse struct {
+	UID              types.UID              `json:"uid"`
+	ConvertedObjects []runtime.RawExtension `json:"convertedObjects"`
+	Result           metav1.Status          `json:"result"`
+}
+
+func serveConvert(w http.ResponseWriter, r *http.Request) {
+	logger := logrus.WithField("action", "webhook")
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxReviewBytes))
+	if err != nil {
+		logger.Errorf("Error reading conversion review : %s", err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	review := &ConversionReview{}
+	if err = json.Unmarshal(body, review); err != nil || review.Request == nil {
+		logger.Errorf("Error decoding conversion review : %v", err)
+		http.Error(w, "not a conversion review", http.StatusBadRequest)
+		return
+	}
+	review.Response = Convert(review.Request)
+	review.Response.UID = review.Request.UID
+	review.Request = nil
+	response, err := json.Marshal(review)
+	if err != nil {
+		logger.Errorf("Error encoding conversion review : %s", err.Error())
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(response)
+}
+
+// Convert turns the MariaDBClusters of a conversion request into the desired
+// version, failing the whole request on the first object it can not convert
+func Convert(request *ConversionRequest) *ConversionResponse {
+	response := &ConversionResponse{}
+	for _, object := range request.Objects {
+		converted, err := convertObject(object.Raw, request.DesiredAPIVersion)
+		if err != nil {
+			logrus.WithField("action", "webhook").WithField("event", "conversionFailed").Warnf("can not convert to %s : %s", request.DesiredAPIVersion, err.Error())
+			response.ConvertedObjects = nil
+			response.Result = metav1.Status{Status: metav1.StatusFailure, Message: err.Error()}
+			return response
+		}
+		response.ConvertedObjects = append(response.ConvertedObjects, runtime.RawExtension{Raw: converted})
+	}
+	response.Result = metav1.Status{Status: metav1.StatusSuccess}
+	return response
+}
+
+func convertObject(raw []byte, desired string) ([]byte, error) {
+	typeMeta := metav1.TypeMeta{}
+	if err := json.Unmarshal(raw, &typeMeta); err != nil {
+		return nil, err
+	}
+	if typeMeta.APIVersion == desired {
+		return raw, nil
+	}
+	var alpha *v1alpha1.MariaDBCluster
+	switch typeMeta.APIVersion {
+	case v1alpha1.SchemeGroupVersion.String():
+		alpha = &v1alpha1.MariaDBCluster{}
+		if err := json.Unmarshal(raw, alpha); err != nil {
+			return nil, err
+		}
+	case v1beta1.SchemeGroupVersion.String():
+		beta := &v1beta1.MariaDBCluster{}
+		if err := json.Unmarshal(raw, beta); err != nil {
+			return nil, err
+		}
+		alpha = beta.ConvertTo()
+	default:
+		return nil, fmt.Errorf("unknown version %q", typeMeta.APIVersion)
+	}
+	switch desired {
+	case v1alpha1.SchemeGroupVersion.String():
+		return json.Marshal(alpha)
+	case v1beta1.SchemeGroupVersion.String():
+		return json.Marshal(v1beta1.ConvertFrom(alpha))
+	}
+	return nil, fmt.Errorf("unknown version %q", desired)
+}
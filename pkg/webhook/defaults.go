@@ -0,0 +1,114 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/Sirupsen/logrus"
+	components "github.com/dansksupermarked/mariadb-galera-operator/pkg/apis/components/v1alpha1"
+	admission "k8s.io/api/admission/v1beta1"
+)
+
+// specDefaults are the values filled into a spec where left out, keyed by
+// their path below spec. Storage sizes are only defaulted on creation, the
+// claims of a cluster stored without one were not rendered from the default.
+var specDefaults = []struct {
+	path       []string
+	value      interface{}
+	createOnly bool
+}{
+	{[]string{"version"}, components.DefaultVersion, false},
+	{[]string{"replicas"}, components.DefaultReplicas, false},
+	{[]string{"updateStrategy"}, components.UpdateStrategyRollingUpdate, false},
+	{[]string{"storages", "data", "initSize"}, components.DefaultStorageSize, true},
+	{[]string{"storages", "snapshot", "initSize"}, components.DefaultStorageSize, true},
+	{[]string{"galera", "sstMethod"}, components.DefaultSSTMethod, false},
+	{[]string{"probes", "liveness", "initialDelaySeconds"}, components.DefaultLivenessProbe.InitialDelaySeconds, false},
+	{[]string{"probes", "liveness", "periodSeconds"}, components.DefaultLivenessProbe.PeriodSeconds, false},
+	{[]string{"probes", "liveness", "timeoutSeconds"}, components.DefaultLivenessProbe.TimeoutSeconds, false},
+	{[]string{"probes", "liveness", "failureThreshold"}, components.DefaultLivenessProbe.FailureThreshold, false},
+	{[]string{"probes", "readiness", "initialDelaySeconds"}, components.DefaultReadinessProbe.InitialDelaySeconds, false},
+	{[]string{"probes", "readiness", "periodSeconds"}, components.DefaultReadinessProbe.PeriodSeconds, false},
+	{[]string{"probes", "readiness", "timeoutSeconds"}, components.DefaultReadinessProbe.TimeoutSeconds, false},
+	{[]string{"probes", "readiness", "failureThreshold"}, components.DefaultReadinessProbe.FailureThreshold, false},
+}
+
+type patchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// Mutate fills the defaults of a MariaDBCluster into its spec on creation and
+// update, so that the stored object shows what the operator runs it with. The
+// spec is handled as plain JSON to leave fields this version does not know of
+// as they are.
+func Mutate(request *admission.AdmissionRequest) *admission.AdmissionResponse {
+	if request.Operation != admission.Create && request.Operation != admission.Update {
+		return &admission.AdmissionResponse{Allowed: true}
+	}
+	object := map[string]interface{}{}
+	if err := json.Unmarshal(request.Object.Raw, &object); err != nil {
+		return deny(fmt.Sprintf("can not decode MariaDBCluster : %s", err.Error()))
+	}
+	spec, ok := object["spec"].(map[string]interface{})
+	if !ok {
+		spec = map[string]interface{}{}
+	}
+	var defaulted []string
+	for _, d := range specDefaults {
+		if d.createOnly && request.Operation != admission.Create {
+			continue
+		}
+		if setDefault(spec, d.path, d.value) {
+			defaulted = append(defaulted, strings.Join(d.path, "."))
+		}
+	}
+	if len(defaulted) == 0 {
+		return &admission.AdmissionResponse{Allowed: true}
+	}
+	patch, err := json.Marshal([]patchOperation{{Op: "add", Path: "/spec", Value: spec}})
+	if err != nil {
+		return deny(fmt.Sprintf("can not encode defaults : %s", err.Error()))
+	}
+	metadata, _ := object["metadata"].(map[string]interface{})
+	logrus.WithField("action", "webhook").WithField("cluster", fmt.Sprintf("%s/%v", request.Namespace, metadata["name"])).
+		WithField("event", "defaulted").Debugf("%s defaulted %v", request.Operation, defaulted)
+	patchType := admission.PatchTypeJSONPatch
+	return &admission.AdmissionResponse{Allowed: true, Patch: patch, PatchType: &patchType}
+}
+
+// setDefault sets value at path below object when it is missing or zero,
+// creating the objects leading to it. Returns false when a value was there,
+// or something else than an object is in the way.
+func setDefault(object map[string]interface{}, path []string, value interface{}) bool {
+	for _, key := range path[:len(path)-1] {
+		child, found := object[key]
+		if !found || child == nil {
+			child = map[string]interface{}{}
+			object[key] = child
+		}
+		next, ok := child.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		object = next
+	}
+	key := path[len(path)-1]
+	switch current := object[key].(type) {
+	case nil:
+	case string:
+		if current != "" {
+			return false
+		}
+	case float64:
+		if current != 0 {
+			return false
+		}
+	default:
+		return false
+	}
+	object[key] = value
+	return true
+}
@@ -0,0 +1,160 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	components "github.com/dansksupermarked/mariadb-galera-operator/pkg/apis/components/v1alpha1"
+	admission "k8s.io/api/admission/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	DefaultAddr = ":8443"
+	// path of the ValidatingWebhookConfiguration for MariaDBClusters
+	ValidatePath = "/validate"
+	// path of the MutatingWebhookConfiguration for MariaDBClusters
+	MutatePath = "/mutate"
+	// path of the conversion webhook in the CustomResourceDefinition
+	ConvertPath = "/convert"
+	// admission reviews are small, anything larger is not one
+	maxReviewBytes = 1 << 20
+)
+
+// Server answers admission reviews of MariaDBClusters, filling in defaults and
+// rejecting specs the operator would otherwise only fail on while reconciling,
+// and converts them between the served versions
+type Server struct {
+	Addr     string
+	CertFile string
+	KeyFile  string
+}
+
+// Run serves admission reviews over TLS until the listener fails
+func (s *Server) Run() error {
+	addr := s.Addr
+	if addr == "" {
+		addr = DefaultAddr
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(ValidatePath, func(w http.ResponseWriter, r *http.Request) {
+		serve(w, r, Validate)
+	})
+	mux.HandleFunc(MutatePath, func(w http.ResponseWriter, r *http.Request) {
+		serve(w, r, Mutate)
+	})
+	mux.HandleFunc(ConvertPath, serveConvert)
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	server := &http.Server{
+		Addr:         addr,
+		Handler:      mux,
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: 10 * time.Second,
+	}
+	logrus.WithField("action", "webhook").WithField("event", "started").Infof("serving admission reviews on %s", addr)
+	return server.ListenAndServeTLS(s.CertFile, s.KeyFile)
+}
+
+// serve decodes the admission review of a request and answers it with the
+// response of review
+func serve(w http.ResponseWriter, r *http.Request, review func(*admission.AdmissionRequest) *admission.AdmissionResponse) {
+	logger := logrus.WithField("action", "webhook")
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxReviewBytes))
+	if err != nil {
+		logger.Errorf("Error reading admission review : %s", err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	admissionReview := &admission.AdmissionReview{}
+	if err = json.Unmarshal(body, admissionReview); err != nil || admissionReview.Request == nil {
+		logger.Errorf("Error decoding admission review : %v", err)
+		http.Error(w, "not an admission review", http.StatusBadRequest)
+		return
+	}
+	admissionReview.Response = review(admissionReview.Request)
+	admissionReview.Response.UID = admissionReview.Request.UID
+	admissionReview.Request = nil
+	response, err := json.Marshal(admissionReview)
+	if err != nil {
+		logger.Errorf("Error encoding admission review : %s", err.Error())
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(response)
+}
+
+// Validate admits a MariaDBCluster creation or update whose spec validates.
+// Updates leaving the spec as it is, as the status updates of the operator
+// and agents, are always admitted so that clusters accepted before the
+// webhook was registered keep being reported on. Deletions are admitted unless
+// the cluster is protected.
+func Validate(request *admission.AdmissionRequest) *admission.AdmissionResponse {
+	if request.Operation == admission.Delete {
+		return validateDelete(request)
+	}
+	mdbc := &components.MariaDBCluster{}
+	if err := json.Unmarshal(request.Object.Raw, mdbc); err != nil {
+		return deny(fmt.Sprintf("can not decode MariaDBCluster : %s", err.Error()))
+	}
+	logger := logrus.WithField("action", "webhook").WithField("cluster", request.Namespace+"/"+mdbc.Name)
+	var err error
+	switch request.Operation {
+	case admission.Create:
+		err = mdbc.Validate()
+	case admission.Update:
+		old := &components.MariaDBCluster{}
+		if err = json.Unmarshal(request.OldObject.Raw, old); err != nil {
+			return deny(fmt.Sprintf("can not decode previous MariaDBCluster : %s", err.Error()))
+		}
+		if reflect.DeepEqual(old.Spec, mdbc.Spec) {
+			return &admission.AdmissionResponse{Allowed: true}
+		}
+		err = mdbc.ValidateUpdate(old)
+	default:
+		return &admission.AdmissionResponse{Allowed: true}
+	}
+	if err != nil {
+		logger.WithField("event", "rejected").Infof("%s rejected : %s", request.Operation, err.Error())
+		return deny(err.Error())
+	}
+	return &admission.AdmissionResponse{Allowed: true}
+}
+
+// validateDelete refuses the deletion of a MariaDBCluster carrying the deletion
+// protection annotation. The object is only sent along with deletions from
+// Kubernetes 1.15 on, without it the deletion is admitted.
+func validateDelete(request *admission.AdmissionRequest) *admission.AdmissionResponse {
+	if len(request.OldObject.Raw) == 0 {
+		return &admission.AdmissionResponse{Allowed: true}
+	}
+	mdbc := &components.MariaDBCluster{}
+	if err := json.Unmarshal(request.OldObject.Raw, mdbc); err != nil {
+		return deny(fmt.Sprintf("can not decode MariaDBCluster : %s", err.Error()))
+	}
+	if !mdbc.IsDeletionProtected() {
+		return &admission.AdmissionResponse{Allowed: true}
+	}
+	logrus.WithField("action", "webhook").WithField("cluster", request.Namespace+"/"+mdbc.Name).
+		WithField("event", "rejected").Info("DELETE rejected : deletion protection")
+	return deny(fmt.Sprintf("%s is protected from deletion, remove its %s annotation first", mdbc.Name, components.MariaDBClusterDeletionProtectionAnnotation))
+}
+
+func deny(message string) *admission.AdmissionResponse {
+	return &admission.AdmissionResponse{
+		Allowed: false,
+		Result: &metav1.Status{
+			Status:  metav1.StatusFailure,
+			Reason:  metav1.StatusReasonInvalid,
+			Message: message,
+			Code:    http.StatusUnprocessableEntity,
+		},
+	}
+}